@@ -0,0 +1,162 @@
+package benchmarks
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// Result is the result of a single benchmark
+type Result struct {
+	Name        string  `json:"name"`
+	Iterations  int     `json:"iterations"`
+	NsPerOp     float64 `json:"ns_per_op"`
+	MBPerSec    float64 `json:"mb_per_sec,omitempty"`
+	AllocsPerOp int64   `json:"allocs_per_op"`
+	BytesPerOp  int64   `json:"bytes_per_op"`
+}
+
+// Report is the result of a benchmark suite run, along with the
+// environment it ran in.
+type Report struct {
+	Time              time.Time `json:"time"`
+	GoVersion         string    `json:"go_version"`
+	GOOS              string    `json:"goos"`
+	GOARCH            string    `json:"goarch"`
+	NumCPU            int       `json:"num_cpu"`
+	LibrdkafkaVersion string    `json:"librdkafka_version"`
+	Results           []Result  `json:"results"`
+}
+
+// Run runs the benchmarks whose name matches filter (all if nil),
+// repeating each benchmark count times and keeping its fastest run
+// to reduce noise.
+func Run(filter *regexp.Regexp, count int) *Report {
+	// Required for testing.B logging outside of go test
+	testing.Init()
+
+	_, librdkafkaVersion := kafka.LibraryVersion()
+	report := &Report{
+		Time:              time.Now(),
+		GoVersion:         runtime.Version(),
+		GOOS:              runtime.GOOS,
+		GOARCH:            runtime.GOARCH,
+		NumCPU:            runtime.NumCPU(),
+		LibrdkafkaVersion: librdkafkaVersion,
+	}
+
+	if count < 1 {
+		count = 1
+	}
+
+	for _, bm := range Benchmarks {
+		if filter != nil && !filter.MatchString(bm.Name) {
+			continue
+		}
+
+		var best *Result
+		for i := 0; i < count; i++ {
+			r := testing.Benchmark(bm.Fn)
+			if r.N == 0 {
+				// The benchmark failed
+				continue
+			}
+
+			res := Result{
+				Name:        bm.Name,
+				Iterations:  r.N,
+				NsPerOp:     float64(r.T.Nanoseconds()) / float64(r.N),
+				AllocsPerOp: r.AllocsPerOp(),
+				BytesPerOp:  r.AllocedBytesPerOp(),
+			}
+			if r.Bytes > 0 && r.T > 0 {
+				res.MBPerSec = float64(r.Bytes) * float64(r.N) / 1e6 / r.T.Seconds()
+			}
+
+			if best == nil || res.NsPerOp < best.NsPerOp {
+				best = &res
+			}
+		}
+
+		if best != nil {
+			report.Results = append(report.Results, *best)
+		}
+	}
+
+	return report
+}
+
+// WriteJSON writes the report as JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// ReadReport reads a report written by WriteJSON()
+func ReadReport(rd io.Reader) (*Report, error) {
+	var r Report
+	if err := json.NewDecoder(rd).Decode(&r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Regression is a benchmark that is slower than in the baseline
+type Regression struct {
+	Name string
+	// Baseline and Current are the ns/op of the baseline and current runs
+	Baseline float64
+	Current  float64
+}
+
+func (r Regression) String() string {
+	return fmt.Sprintf("%s: %.0f ns/op -> %.0f ns/op (%+.1f%%)",
+		r.Name, r.Baseline, r.Current, (r.Current/r.Baseline-1)*100)
+}
+
+// Compare returns the benchmarks of r that are more than threshold
+// (e.g., 0.1 for 10%) slower than in baseline.
+// Benchmarks missing from either report are ignored.
+func (r *Report) Compare(baseline *Report, threshold float64) []Regression {
+	base := make(map[string]Result, len(baseline.Results))
+	for _, res := range baseline.Results {
+		base[res.Name] = res
+	}
+
+	var regressions []Regression
+	for _, res := range r.Results {
+		b, found := base[res.Name]
+		if !found || b.NsPerOp <= 0 {
+			continue
+		}
+		if res.NsPerOp > b.NsPerOp*(1+threshold) {
+			regressions = append(regressions,
+				Regression{res.Name, b.NsPerOp, res.NsPerOp})
+		}
+	}
+
+	return regressions
+}
@@ -0,0 +1,237 @@
+// Package benchmarks provides a reproducible benchmark suite for the Go
+// client, measuring produce and consume throughput, cgo call overhead and
+// AdminClient call latency against an in-process MockCluster, so no
+// Kafka cluster is needed.
+//
+// The suite runs through `go test -bench . ./benchmarks`, or
+// programmatically with Run(), whose Report can be saved as a JSON
+// baseline and compared with later runs to detect performance
+// regressions, see the kafkabench command.
+package benchmarks
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// MessageSize is the value size of the messages produced and consumed
+// by the throughput benchmarks.
+const MessageSize = 100
+
+// Benchmark is a named benchmark function of the suite
+type Benchmark struct {
+	Name string
+	Fn   func(b *testing.B)
+}
+
+// Benchmarks is the benchmark suite, in run order.
+var Benchmarks = []Benchmark{
+	{"Produce", benchProduce},
+	{"Consume", benchConsume},
+	{"CgoCall", benchCgoCall},
+	{"AdminMetadata", benchAdminMetadata},
+}
+
+// newMockCluster returns a single broker MockCluster, failing b on error
+func newMockCluster(b *testing.B) *kafka.MockCluster {
+	mc, err := kafka.NewMockCluster(1)
+	if err != nil {
+		b.Fatalf("Failed to create MockCluster: %v", err)
+	}
+	return mc
+}
+
+// produce produces cnt messages to partition 0 of topic and waits for
+// their delivery reports.
+func produce(b *testing.B, p *kafka.Producer, topic string, cnt int) {
+	value := make([]byte, MessageSize)
+	drChan := make(chan kafka.Event, 100000)
+	done := make(chan error, 1)
+
+	go func() {
+		for i := 0; i < cnt; i++ {
+			m := (<-drChan).(*kafka.Message)
+			if m.TopicPartition.Error != nil {
+				done <- m.TopicPartition.Error
+				return
+			}
+		}
+		done <- nil
+	}()
+
+	for i := 0; i < cnt; i++ {
+		err := p.Produce(&kafka.Message{
+			TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 0},
+			Value:          value,
+		}, drChan)
+		if err != nil {
+			if err.(kafka.Error).Code() == kafka.ErrQueueFull {
+				p.Flush(10)
+				i--
+				continue
+			}
+			b.Fatalf("Produce failed: %v", err)
+		}
+	}
+
+	if err := <-done; err != nil {
+		b.Fatalf("Delivery failed: %v", err)
+	}
+}
+
+// benchProduce measures produce throughput, including delivery reports
+func benchProduce(b *testing.B) {
+	mc := newMockCluster(b)
+	defer mc.Close()
+
+	p, err := kafka.NewProducer(&kafka.ConfigMap{
+		"bootstrap.servers": mc.BootstrapServers(),
+		"linger.ms":         5,
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer p.Close()
+
+	// Warm up: connect and create the topic
+	produce(b, p, "bench", 1)
+
+	b.SetBytes(MessageSize)
+	b.ReportAllocs()
+	// Exclude the deferred Close() calls
+	defer b.StopTimer()
+	b.ResetTimer()
+
+	produce(b, p, "bench", b.N)
+}
+
+// consumeChunk is the number of messages produced at a time by
+// benchConsume, the MockCluster only retains about 5MB per partition.
+const consumeChunk = 10000
+
+// benchConsume measures consume throughput
+func benchConsume(b *testing.B) {
+	mc := newMockCluster(b)
+	defer mc.Close()
+
+	p, err := kafka.NewProducer(&kafka.ConfigMap{
+		"bootstrap.servers": mc.BootstrapServers(),
+		"linger.ms":         5,
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer p.Close()
+
+	c, err := kafka.NewConsumer(&kafka.ConfigMap{
+		"bootstrap.servers": mc.BootstrapServers(),
+		"group.id":          "bench",
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer c.Close()
+
+	// Warm up: connect and start fetching
+	topic := "bench"
+	produce(b, p, topic, 1)
+	err = c.Assign([]kafka.TopicPartition{{Topic: &topic, Partition: 0,
+		Offset: kafka.OffsetBeginning}})
+	if err != nil {
+		b.Fatal(err)
+	}
+	if _, err = c.ReadMessage(10 * time.Second); err != nil {
+		b.Fatal(err)
+	}
+
+	b.SetBytes(MessageSize)
+	b.ReportAllocs()
+	// Exclude the deferred Close() calls
+	defer b.StopTimer()
+	b.ResetTimer()
+
+	for consumed := 0; consumed < b.N; {
+		cnt := b.N - consumed
+		if cnt > consumeChunk {
+			cnt = consumeChunk
+		}
+
+		b.StopTimer()
+		produce(b, p, topic, cnt)
+		b.StartTimer()
+
+		for i := 0; i < cnt; i++ {
+			_, err := c.ReadMessage(10 * time.Second)
+			if err != nil {
+				b.Fatalf("ReadMessage failed after %d messages: %v",
+					consumed+i, err)
+			}
+		}
+		consumed += cnt
+	}
+}
+
+// benchCgoCall measures the overhead of a trivial call into librdkafka
+func benchCgoCall(b *testing.B) {
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		kafka.LibraryVersion()
+	}
+}
+
+// newAdminClient returns an AdminClient for mc, failing b on error
+func newAdminClient(b *testing.B, mc *kafka.MockCluster) *kafka.AdminClient {
+	a, err := kafka.NewAdminClient(&kafka.ConfigMap{
+		"bootstrap.servers": mc.BootstrapServers(),
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	return a
+}
+
+// benchAdminMetadata measures the latency of a cluster metadata request,
+// as used by AdminClient.ClusterID(), ControllerID() and GetMetadata().
+// The MockCluster does not implement the other Admin APIs.
+func benchAdminMetadata(b *testing.B) {
+	mc := newMockCluster(b)
+	defer mc.Close()
+
+	a := newAdminClient(b, mc)
+	defer a.Close()
+
+	// Warm up: connect to the cluster
+	if _, err := a.GetMetadata(nil, true, 10000); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	// Exclude the deferred Close() calls
+	defer b.StopTimer()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := a.GetMetadata(nil, true, 10000); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package benchmarks
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+)
+
+// BenchmarkProduce benchmarks produce throughput
+func BenchmarkProduce(b *testing.B) {
+	benchProduce(b)
+}
+
+// BenchmarkConsume benchmarks consume throughput
+func BenchmarkConsume(b *testing.B) {
+	benchConsume(b)
+}
+
+// BenchmarkCgoCall benchmarks cgo call overhead
+func BenchmarkCgoCall(b *testing.B) {
+	benchCgoCall(b)
+}
+
+// BenchmarkAdminMetadata benchmarks metadata request latency
+func BenchmarkAdminMetadata(b *testing.B) {
+	benchAdminMetadata(b)
+}
+
+// TestReportCompare tests the baseline JSON round-trip and regression
+// detection.
+func TestReportCompare(t *testing.T) {
+	report := Run(regexp.MustCompile("^CgoCall$"), 1)
+	if len(report.Results) != 1 || report.Results[0].NsPerOp <= 0 {
+		t.Fatalf("Unexpected report %+v", report)
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	baseline, err := ReadReport(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if regressions := report.Compare(baseline, 0.1); len(regressions) != 0 {
+		t.Errorf("Expected no regressions against itself, got %v", regressions)
+	}
+
+	baseline.Results[0].NsPerOp = report.Results[0].NsPerOp / 2
+	regressions := report.Compare(baseline, 0.1)
+	if len(regressions) != 1 || regressions[0].Name != "CgoCall" {
+		t.Errorf("Expected CgoCall regression, got %v", regressions)
+	}
+}
@@ -0,0 +1,110 @@
+// kafkabench runs the client benchmark suite against an in-process
+// MockCluster, optionally saving the results as a JSON baseline and
+// comparing them with a previous baseline.
+//
+// Usage:
+//
+//	kafkabench -o baseline.json
+//	kafkabench -baseline baseline.json -threshold 0.1
+//
+// Exits with status 1 if any benchmark regressed by more than the
+// threshold compared to the baseline.
+package main
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/confluentinc/confluent-kafka-go/benchmarks"
+)
+
+func main() {
+	run := flag.String("run", "", "Only run benchmarks matching this regexp")
+	count := flag.Int("count", 3, "Run each benchmark this many times, keeping the fastest run")
+	output := flag.String("o", "", "Write the results as JSON to this file")
+	baselineFile := flag.String("baseline", "", "Compare the results with this JSON baseline")
+	threshold := flag.Float64("threshold", 0.1, "Relative slowdown considered a regression")
+	flag.Parse()
+
+	var filter *regexp.Regexp
+	if *run != "" {
+		var err error
+		filter, err = regexp.Compile(*run)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid -run regexp: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	report := benchmarks.Run(filter, *count)
+
+	fmt.Printf("Go %s %s/%s, %d CPUs, librdkafka %s\n",
+		report.GoVersion, report.GOOS, report.GOARCH, report.NumCPU,
+		report.LibrdkafkaVersion)
+	for _, r := range report.Results {
+		throughput := ""
+		if r.MBPerSec > 0 {
+			throughput = fmt.Sprintf("%8.2f MB/s", r.MBPerSec)
+		}
+		fmt.Printf("%-20s %10d %14.0f ns/op %6d allocs/op %s\n",
+			r.Name, r.Iterations, r.NsPerOp, r.AllocsPerOp, throughput)
+	}
+
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err == nil {
+			err = report.WriteJSON(f)
+			f.Close()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write %s: %v\n", *output, err)
+			os.Exit(2)
+		}
+	}
+
+	if *baselineFile == "" {
+		return
+	}
+
+	f, err := os.Open(*baselineFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open baseline: %v\n", err)
+		os.Exit(2)
+	}
+	baseline, err := benchmarks.ReadReport(f)
+	f.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read baseline %s: %v\n", *baselineFile, err)
+		os.Exit(2)
+	}
+
+	regressions := report.Compare(baseline, *threshold)
+	if len(regressions) == 0 {
+		fmt.Printf("No regressions compared to %s\n", *baselineFile)
+		return
+	}
+
+	fmt.Printf("Regressions compared to %s:\n", *baselineFile)
+	for _, r := range regressions {
+		fmt.Printf("  %v\n", r)
+	}
+	os.Exit(1)
+}
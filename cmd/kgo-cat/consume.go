@@ -0,0 +1,287 @@
+package main
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// parseOffset parses the -o offset: beginning, end, stored, an absolute
+// offset, a negative offset relative to the end, or s@<timestamp ms>,
+// which is returned as the timestamp to look the offset up for.
+func parseOffset(s string) (offset kafka.Offset, timestamp int64, err error) {
+	timestamp = -1
+
+	switch {
+	case s == "beginning":
+		offset = kafka.OffsetBeginning
+	case s == "end":
+		offset = kafka.OffsetEnd
+	case s == "stored":
+		offset = kafka.OffsetStored
+	case strings.HasPrefix(s, "s@"):
+		timestamp, err = strconv.ParseInt(s[2:], 10, 64)
+		if err == nil && timestamp < 0 {
+			err = fmt.Errorf("negative timestamp")
+		}
+	default:
+		var n int64
+		n, err = strconv.ParseInt(s, 10, 64)
+		if n < 0 {
+			offset = kafka.OffsetTail(kafka.Offset(-n))
+		} else {
+			offset = kafka.Offset(n)
+		}
+	}
+
+	if err != nil {
+		return offset, timestamp, fmt.Errorf("invalid offset %q: %v", s, err)
+	}
+
+	return offset, timestamp, nil
+}
+
+// topicPartitions returns the partitions of topic, or only partition
+// if it isn't kafka.PartitionAny.
+func topicPartitions(c *kafka.Consumer, topic string, partition int32) ([]kafka.TopicPartition, error) {
+	if partition != kafka.PartitionAny {
+		return []kafka.TopicPartition{{Topic: &topic, Partition: partition}}, nil
+	}
+
+	md, err := c.GetMetadata(&topic, false, 10000)
+	if err != nil {
+		return nil, err
+	}
+
+	t, ok := md.Topics[topic]
+	if !ok {
+		return nil, fmt.Errorf("topic %s not found", topic)
+	}
+	if t.Error.Code() != kafka.ErrNoError {
+		return nil, t.Error
+	}
+
+	partitions := make([]kafka.TopicPartition, len(t.Partitions))
+	for i, p := range t.Partitions {
+		partitions[i] = kafka.TopicPartition{Topic: &topic, Partition: p.ID}
+	}
+	return partitions, nil
+}
+
+// assignPartitions assigns the partitions of the topic from the -o offset.
+// Returns the number of partitions assigned.
+func assignPartitions(c *kafka.Consumer, opts *options) int {
+	offset, timestamp, err := parseOffset(opts.offset)
+	if err != nil {
+		fatalf("%v", err)
+	}
+
+	partitions, err := topicPartitions(c, opts.topics[0], int32(opts.partition))
+	if err != nil {
+		fatalf("Failed to get partitions: %v", err)
+	}
+
+	for i := range partitions {
+		if timestamp >= 0 {
+			partitions[i].Offset = kafka.Offset(timestamp)
+		} else {
+			partitions[i].Offset = offset
+		}
+	}
+
+	if timestamp >= 0 {
+		partitions, err = c.OffsetsForTimes(partitions, 10000)
+		if err != nil {
+			fatalf("Failed to look up offsets for timestamp %d: %v", timestamp, err)
+		}
+	}
+
+	if err = c.Assign(partitions); err != nil {
+		fatalf("Failed to assign partitions: %v", err)
+	}
+	opts.infof("Assigned %v", partitions)
+
+	return len(partitions)
+}
+
+// runConsumer consumes messages to stdout until interrupted, or until
+// -c messages were consumed, or the end of all partitions with -e.
+func runConsumer(conf kafka.ConfigMap, opts *options) {
+	if opts.group != "" {
+		conf.SetKey("group.id", opts.group)
+	} else {
+		// The consumer requires a group, which isn't joined when
+		// assigning partitions, nor committed to.
+		if v, _ := conf.Get("group.id", nil); v == nil {
+			conf.SetKey("group.id", "kgo-cat")
+		}
+		conf.SetKey("enable.auto.commit", false)
+	}
+	if opts.exitEOF {
+		conf.SetKey("enable.partition.eof", true)
+	}
+	if opts.offset == "beginning" || opts.offset == "end" {
+		// Where group members start without committed offsets
+		conf.SetKey("auto.offset.reset", opts.offset)
+	}
+
+	c, err := kafka.NewConsumer(&conf)
+	if err != nil {
+		fatalf("Failed to create consumer: %v", err)
+	}
+	defer c.Close()
+	opts.infof("Created consumer %v", c)
+
+	partitionCnt := 0
+	if opts.group != "" {
+		if err = c.SubscribeTopics(opts.topics, nil); err != nil {
+			fatalf("Failed to subscribe to %v: %v", opts.topics, err)
+		}
+	} else {
+		partitionCnt = assignPartitions(c, opts)
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+
+	consumed := 0
+	eofs := make(map[string]bool)
+	for {
+		select {
+		case sig := <-sigs:
+			opts.infof("Terminating on signal %v", sig)
+			return
+		default:
+		}
+
+		switch e := c.Poll(100).(type) {
+		case *kafka.Message:
+			if e.TopicPartition.Error != nil {
+				opts.infof("Consume error: %v", e.TopicPartition)
+				continue
+			}
+			if err = printMessage(e, opts); err != nil {
+				fatalf("%v at %v", err, e.TopicPartition)
+			}
+			consumed++
+			if opts.count > 0 && consumed >= opts.count {
+				return
+			}
+
+		case kafka.PartitionEOF:
+			opts.infof("Reached end of %v", kafka.TopicPartition(e))
+			eofs[fmt.Sprintf("%s[%d]", *e.Topic, e.Partition)] = true
+			if opts.exitEOF && partitionCnt > 0 && len(eofs) >= partitionCnt {
+				return
+			}
+
+		case kafka.Error:
+			opts.infof("Error: %v", e)
+			if e.IsFatal() {
+				fatalf("%v", e)
+			}
+		}
+	}
+}
+
+// messageEnvelope is the JSON envelope of a consumed message, see -J.
+type messageEnvelope struct {
+	Topic     string            `json:"topic"`
+	Partition int32             `json:"partition"`
+	Offset    int64             `json:"offset"`
+	Timestamp int64             `json:"ts"`
+	Key       interface{}       `json:"key"`
+	Value     interface{}       `json:"payload"`
+	Headers   map[string]string `json:"headers,omitempty"`
+}
+
+// deserialize deserializes b with s, JSON documents are embedded as is
+// in JSON envelopes.
+func deserialize(s serde, b []byte, envelope bool) (interface{}, error) {
+	if b == nil {
+		return nil, nil
+	}
+
+	str, err := s.Deserialize(b)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, isJSON := s.(jsonSerde); isJSON && envelope {
+		return json.RawMessage(str), nil
+	}
+	return str, nil
+}
+
+// printMessage prints the message to stdout.
+func printMessage(m *kafka.Message, opts *options) error {
+	key, err := deserialize(opts.serde.key, m.Key, opts.json)
+	if err != nil {
+		return fmt.Errorf("failed to deserialize key: %v", err)
+	}
+	value, err := deserialize(opts.serde.value, m.Value, opts.json)
+	if err != nil {
+		return fmt.Errorf("failed to deserialize value: %v", err)
+	}
+
+	if !opts.json {
+		if opts.keyDelim != "" {
+			if key == nil {
+				key = ""
+			}
+			fmt.Printf("%s%s", key, opts.keyDelim)
+		}
+		if value == nil {
+			value = ""
+		}
+		fmt.Printf("%s\n", value)
+		return nil
+	}
+
+	env := messageEnvelope{
+		Topic:     *m.TopicPartition.Topic,
+		Partition: m.TopicPartition.Partition,
+		Offset:    int64(m.TopicPartition.Offset),
+		Timestamp: -1,
+		Key:       key,
+		Value:     value,
+	}
+	if m.TimestampType != kafka.TimestampNotAvailable {
+		env.Timestamp = m.Timestamp.UnixNano() / int64(1000000)
+	}
+	if len(m.Headers) > 0 {
+		env.Headers = make(map[string]string, len(m.Headers))
+		for _, h := range m.Headers {
+			env.Headers[h.Key] = string(h.Value)
+		}
+	}
+
+	b, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s\n", b)
+	return nil
+}
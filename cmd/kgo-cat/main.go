@@ -0,0 +1,166 @@
+// kgo-cat is a kcat-style command line client built on the kafka package,
+// exercising the same code paths as applications: it produces messages
+// read from stdin, consumes messages to stdout, lists metadata and
+// queries offsets.
+//
+// Usage:
+//
+//	kgo-cat -b <brokers> -P -t <topic> [-p <partition>] [-K <delim>]
+//	kgo-cat -b <brokers> -C -t <topic> [-p <partition>] [-o <offset>] [-c <count>] [-e] [-J]
+//	kgo-cat -b <brokers> -C -G <group> <topic>...
+//	kgo-cat -b <brokers> -L [-t <topic>]
+//	kgo-cat -b <brokers> -Q -t <topic> [-p <partition>] [-G <group>] [-T <timestamp>]
+//
+// Keys and values are converted with the serde selected by -s, e.g.,
+// -s value=json consumes JSON values, including values framed in the
+// Schema Registry wire format.
+package main
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// configFlag is the repeatable -X flag setting configuration properties.
+type configFlag struct {
+	conf kafka.ConfigMap
+}
+
+func (f *configFlag) String() string {
+	return ""
+}
+
+func (f *configFlag) Set(v string) error {
+	return f.conf.Set(v)
+}
+
+// options holds the command line options shared by the modes.
+type options struct {
+	topics    []string
+	partition int
+	group     string
+	offset    string
+	count     int
+	exitEOF   bool
+	keyDelim  string
+	json      bool
+	timestamp int64
+	serde     serdeFlag
+	verbose   bool
+}
+
+// fatalf prints an error and exits.
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "%% ERROR: "+format+"\n", args...)
+	os.Exit(1)
+}
+
+// infof prints informational messages in verbose mode.
+func (o *options) infof(format string, args ...interface{}) {
+	if o.verbose {
+		fmt.Fprintf(os.Stderr, "%% "+format+"\n", args...)
+	}
+}
+
+func main() {
+	conf := configFlag{conf: kafka.ConfigMap{}}
+	opts := options{serde: serdeFlag{key: stringSerde{}, value: stringSerde{}}}
+
+	produce := flag.Bool("P", false, "Producer mode: produce messages read from stdin, one per line")
+	consume := flag.Bool("C", false, "Consumer mode: consume messages to stdout")
+	metadata := flag.Bool("L", false, "Metadata list mode")
+	query := flag.Bool("Q", false, "Query mode: print partition offsets")
+	brokers := flag.String("b", "", "Bootstrap broker(s) (host[:port])")
+	configFile := flag.String("F", "", "Read configuration properties from a .properties, .json or .yaml file")
+	topic := flag.String("t", "", "Topic")
+	flag.Var(&conf, "X", "Set configuration property prop=val, may be repeated")
+	flag.IntVar(&opts.partition, "p", int(kafka.PartitionAny), "Partition, -1 for all partitions")
+	flag.StringVar(&opts.group, "G", "", "Consumer group: consume the topics given as arguments, or query committed offsets")
+	flag.StringVar(&opts.offset, "o", "beginning",
+		"Offset to start consuming from: beginning, end, stored, <value>, -<value> from end, s@<timestamp ms>")
+	flag.IntVar(&opts.count, "c", 0, "Exit after consuming this many messages")
+	flag.BoolVar(&opts.exitEOF, "e", false, "Exit when the last message of each partition was consumed")
+	flag.StringVar(&opts.keyDelim, "K", "", "Key delimiter, messages are key<delim>value")
+	flag.BoolVar(&opts.json, "J", false, "Output consumed messages as JSON envelopes")
+	flag.Int64Var(&opts.timestamp, "T", -1, "Query the offsets for this timestamp (ms)")
+	flag.Var(&opts.serde, "s", "Key and value serde, [key=|value=]<serde>: "+serdeNames())
+	flag.BoolVar(&opts.verbose, "v", false, "Verbose output")
+	flag.Parse()
+
+	if *configFile != "" {
+		if err := conf.conf.LoadFromFile(*configFile); err != nil {
+			fatalf("%v", err)
+		}
+	}
+	if *brokers != "" {
+		conf.conf.SetKey("bootstrap.servers", *brokers)
+	}
+
+	if *topic != "" {
+		opts.topics = append(opts.topics, *topic)
+	}
+	opts.topics = append(opts.topics, flag.Args()...)
+
+	modes := 0
+	for _, mode := range []bool{*produce, *consume, *metadata, *query} {
+		if mode {
+			modes++
+		}
+	}
+	if modes != 1 {
+		fmt.Fprintf(os.Stderr, "Exactly one of -P, -C, -L or -Q is required\n\n")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if (*produce || *query || (*consume && opts.group == "")) && len(opts.topics) != 1 {
+		fatalf("A single topic is required (-t)")
+	}
+
+	switch {
+	case *produce:
+		runProducer(conf.conf, &opts)
+	case *consume:
+		runConsumer(conf.conf, &opts)
+	case *metadata:
+		runMetadata(conf.conf, &opts)
+	case *query:
+		runQuery(conf.conf, &opts)
+	}
+}
+
+// splitKey splits a line into its key and value at the key delimiter,
+// the key is nil if there is no delimiter.
+func splitKey(line string, keyDelim string) (key *string, value string) {
+	if keyDelim == "" {
+		return nil, line
+	}
+
+	i := strings.Index(line, keyDelim)
+	if i == -1 {
+		return nil, line
+	}
+
+	k := line[:i]
+	return &k, line[i+len(keyDelim):]
+}
@@ -0,0 +1,145 @@
+package main
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// runMetadata lists the brokers and the topics, or only the -t topic.
+func runMetadata(conf kafka.ConfigMap, opts *options) {
+	a, err := kafka.NewAdminClient(&conf)
+	if err != nil {
+		fatalf("Failed to create admin client: %v", err)
+	}
+	defer a.Close()
+
+	var topic *string
+	if len(opts.topics) > 0 {
+		topic = &opts.topics[0]
+	}
+
+	md, err := a.GetMetadata(topic, topic == nil, 10000)
+	if err != nil {
+		fatalf("Failed to get metadata: %v", err)
+	}
+
+	what := "all topics"
+	if topic != nil {
+		what = "topic " + *topic
+	}
+	fmt.Printf("Metadata for %s (from broker %d: %s:%d):\n", what,
+		md.OriginatingBroker.ID, md.OriginatingBroker.Host, md.OriginatingBroker.Port)
+
+	fmt.Printf(" %d brokers:\n", len(md.Brokers))
+	for _, b := range md.Brokers {
+		fmt.Printf("  broker %d at %s:%d\n", b.ID, b.Host, b.Port)
+	}
+
+	topics := make([]string, 0, len(md.Topics))
+	for t := range md.Topics {
+		topics = append(topics, t)
+	}
+	sort.Strings(topics)
+
+	fmt.Printf(" %d topics:\n", len(topics))
+	for _, name := range topics {
+		t := md.Topics[name]
+		fmt.Printf("  topic \"%s\" with %d partitions:", t.Topic, len(t.Partitions))
+		if t.Error.Code() != kafka.ErrNoError {
+			fmt.Printf(" %v", t.Error)
+		}
+		fmt.Printf("\n")
+
+		for _, p := range t.Partitions {
+			fmt.Printf("    partition %d, leader %d, replicas: %v, isrs: %v",
+				p.ID, p.Leader, p.Replicas, p.Isrs)
+			if p.Error.Code() != kafka.ErrNoError {
+				fmt.Printf(", %v", p.Error)
+			}
+			fmt.Printf("\n")
+		}
+	}
+}
+
+// topicName returns the -t topic, or an empty string.
+func (o *options) topicName() string {
+	if len(o.topics) == 0 {
+		return ""
+	}
+	return o.topics[0]
+}
+
+// runQuery prints the watermark offsets of the partitions of the topic,
+// and the offsets for -T timestamp and the committed offsets of -G group.
+func runQuery(conf kafka.ConfigMap, opts *options) {
+	if opts.group != "" {
+		conf.SetKey("group.id", opts.group)
+	} else if v, _ := conf.Get("group.id", nil); v == nil {
+		conf.SetKey("group.id", "kgo-cat")
+	}
+
+	c, err := kafka.NewConsumer(&conf)
+	if err != nil {
+		fatalf("Failed to create consumer: %v", err)
+	}
+	defer c.Close()
+
+	partitions, err := topicPartitions(c, opts.topicName(), int32(opts.partition))
+	if err != nil {
+		fatalf("Failed to get partitions: %v", err)
+	}
+
+	var times, committed []kafka.TopicPartition
+	if opts.timestamp >= 0 {
+		times = make([]kafka.TopicPartition, len(partitions))
+		for i, tp := range partitions {
+			times[i] = tp
+			times[i].Offset = kafka.Offset(opts.timestamp)
+		}
+		times, err = c.OffsetsForTimes(times, 10000)
+		if err != nil {
+			fatalf("Failed to look up offsets for timestamp %d: %v", opts.timestamp, err)
+		}
+	}
+
+	if opts.group != "" {
+		committed, err = c.Committed(partitions, 10000)
+		if err != nil {
+			fatalf("Failed to get committed offsets: %v", err)
+		}
+	}
+
+	for i, tp := range partitions {
+		low, high, err := c.QueryWatermarkOffsets(*tp.Topic, tp.Partition, 10000)
+		if err != nil {
+			fatalf("Failed to query offsets of %s [%d]: %v", *tp.Topic, tp.Partition, err)
+		}
+
+		fmt.Printf("%s [%d] low %d high %d", *tp.Topic, tp.Partition, low, high)
+		if times != nil {
+			fmt.Printf(" timestamp %d offset %v", opts.timestamp, times[i].Offset)
+		}
+		if committed != nil {
+			fmt.Printf(" committed %v", committed[i].Offset)
+		}
+		fmt.Printf("\n")
+	}
+}
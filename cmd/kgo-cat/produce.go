@@ -0,0 +1,101 @@
+package main
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"bufio"
+	"os"
+	"sync"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// runProducer produces the lines read from stdin until EOF.
+func runProducer(conf kafka.ConfigMap, opts *options) {
+	p, err := kafka.NewProducer(&conf)
+	if err != nil {
+		fatalf("Failed to create producer: %v", err)
+	}
+	opts.infof("Created producer %v", p)
+
+	var failed int
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for ev := range p.Events() {
+			switch e := ev.(type) {
+			case *kafka.Message:
+				if e.TopicPartition.Error != nil {
+					failed++
+					opts.infof("Delivery failed: %v", e.TopicPartition)
+				} else {
+					opts.infof("Delivered %v", e)
+				}
+			case kafka.Error:
+				opts.infof("Error: %v", e)
+			}
+		}
+	}()
+
+	topic := opts.topics[0]
+	produced := 0
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Buffer(make([]byte, 64*1024), 100*1024*1024)
+	for scanner.Scan() {
+		key, value := splitKey(scanner.Text(), opts.keyDelim)
+
+		msg := &kafka.Message{
+			TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: int32(opts.partition)},
+		}
+		if key != nil {
+			if msg.Key, err = opts.serde.key.Serialize(*key); err != nil {
+				fatalf("Failed to serialize key %q: %v", *key, err)
+			}
+		}
+		if msg.Value, err = opts.serde.value.Serialize(value); err != nil {
+			fatalf("Failed to serialize value %q: %v", value, err)
+		}
+
+		for {
+			err = p.Produce(msg, nil)
+			if err == nil {
+				break
+			}
+			if err.(kafka.Error).Code() != kafka.ErrQueueFull {
+				fatalf("Failed to produce: %v", err)
+			}
+			p.Flush(100)
+		}
+		produced++
+	}
+
+	if err = scanner.Err(); err != nil {
+		fatalf("Failed to read stdin: %v", err)
+	}
+
+	for p.Flush(1000) > 0 {
+		opts.infof("Waiting for %d messages to be delivered", p.Len())
+	}
+	p.Close()
+	wg.Wait()
+
+	opts.infof("%d messages produced, %d failed", produced, failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// serde converts message keys and values between their wire and
+// command line representations.
+type serde interface {
+	// Serialize converts the command line representation to bytes
+	Serialize(s string) ([]byte, error)
+	// Deserialize converts bytes to their command line representation
+	Deserialize(b []byte) (string, error)
+}
+
+// serdes are the available serdes keyed by name, see -s.
+var serdes = map[string]serde{
+	"string": stringSerde{},
+	"hex":    hexSerde{},
+	"base64": base64Serde{},
+	"json":   jsonSerde{},
+}
+
+// serdeNames returns the names of the available serdes.
+func serdeNames() string {
+	names := make([]string, 0, len(serdes))
+	for name := range serdes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// serdeFlag is the -s flag, selecting the key and value serdes:
+// "<serde>" for both, or "key=<serde>" and "value=<serde>".
+type serdeFlag struct {
+	key   serde
+	value serde
+}
+
+func (f *serdeFlag) String() string {
+	return ""
+}
+
+func (f *serdeFlag) Set(v string) error {
+	field := ""
+	name := v
+	if i := strings.Index(v, "="); i != -1 {
+		field, name = v[:i], v[i+1:]
+	}
+
+	s, ok := serdes[name]
+	if !ok {
+		return fmt.Errorf("unknown serde %q, expected one of %s", name, serdeNames())
+	}
+
+	switch field {
+	case "":
+		f.key = s
+		f.value = s
+	case "key":
+		f.key = s
+	case "value":
+		f.value = s
+	default:
+		return fmt.Errorf("unknown serde field %q, expected key or value", field)
+	}
+
+	return nil
+}
+
+type stringSerde struct{}
+
+func (stringSerde) Serialize(s string) ([]byte, error) {
+	return []byte(s), nil
+}
+
+func (stringSerde) Deserialize(b []byte) (string, error) {
+	return string(b), nil
+}
+
+type hexSerde struct{}
+
+func (hexSerde) Serialize(s string) ([]byte, error) {
+	return hex.DecodeString(s)
+}
+
+func (hexSerde) Deserialize(b []byte) (string, error) {
+	return hex.EncodeToString(b), nil
+}
+
+type base64Serde struct{}
+
+func (base64Serde) Serialize(s string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(s)
+}
+
+func (base64Serde) Deserialize(b []byte) (string, error) {
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// jsonSerde validates and compacts JSON documents.
+// Deserialize also accepts documents framed in the Schema Registry wire
+// format, a zero magic byte followed by the 4 byte schema id, which
+// is stripped.
+type jsonSerde struct{}
+
+func (jsonSerde) Serialize(s string) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, []byte(s)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (jsonSerde) Deserialize(b []byte) (string, error) {
+	if _, payload, ok := schemaRegistryFraming(b); ok {
+		b = payload
+	}
+
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, b); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// schemaRegistryFraming returns the schema id and payload of b if it
+// is framed in the Schema Registry wire format.
+func schemaRegistryFraming(b []byte) (schemaID uint32, payload []byte, ok bool) {
+	if len(b) < 5 || b[0] != 0 {
+		return 0, nil, false
+	}
+	return binary.BigEndian.Uint32(b[1:5]), b[5:], true
+}
@@ -0,0 +1,105 @@
+package main
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// TestSerdes tests that the serdes round-trip values
+func TestSerdes(t *testing.T) {
+	for name, s := range serdes {
+		in := "{\"a\":[1,2]}"
+		if name == "hex" {
+			in = "00ff10"
+		} else if name == "base64" {
+			in = "AP8Q"
+		}
+
+		b, err := s.Serialize(in)
+		if err != nil {
+			t.Errorf("%s: Serialize(%q) failed: %v", name, in, err)
+			continue
+		}
+
+		out, err := s.Deserialize(b)
+		if err != nil || out != in {
+			t.Errorf("%s: expected %q, not %q (%v)", name, in, out, err)
+		}
+	}
+
+	// Schema Registry framing is stripped
+	framed := append([]byte{0, 0, 0, 0, 42}, []byte(" {\"a\": 1}")...)
+	if out, err := (jsonSerde{}).Deserialize(framed); err != nil || out != "{\"a\":1}" {
+		t.Errorf("Expected framed JSON to be decoded, not %q (%v)", out, err)
+	}
+
+	if _, err := (jsonSerde{}).Serialize("{"); err == nil {
+		t.Errorf("Expected invalid JSON to fail")
+	}
+
+	var f serdeFlag
+	if err := f.Set("value=json"); err != nil || f.value != (jsonSerde{}) || f.key != nil {
+		t.Errorf("Expected value=json to set the value serde: %v", err)
+	}
+	if err := f.Set("avro"); err == nil {
+		t.Errorf("Expected unknown serde to fail")
+	}
+}
+
+// TestParseOffset tests the -o offset formats
+func TestParseOffset(t *testing.T) {
+	for _, c := range []struct {
+		s         string
+		offset    kafka.Offset
+		timestamp int64
+	}{
+		{"beginning", kafka.OffsetBeginning, -1},
+		{"end", kafka.OffsetEnd, -1},
+		{"stored", kafka.OffsetStored, -1},
+		{"12", 12, -1},
+		{"-5", kafka.OffsetTail(5), -1},
+		{"s@1650000000000", 0, 1650000000000},
+	} {
+		offset, timestamp, err := parseOffset(c.s)
+		if err != nil || offset != c.offset || timestamp != c.timestamp {
+			t.Errorf("%s: expected %v, %d, not %v, %d (%v)",
+				c.s, c.offset, c.timestamp, offset, timestamp, err)
+		}
+	}
+
+	for _, s := range []string{"", "first", "s@", "s@-1"} {
+		if _, _, err := parseOffset(s); err == nil {
+			t.Errorf("Expected %q to fail", s)
+		}
+	}
+}
+
+// TestSplitKey tests splitting lines into keys and values
+func TestSplitKey(t *testing.T) {
+	if key, value := splitKey("k:v:w", ":"); key == nil || *key != "k" || value != "v:w" {
+		t.Errorf("Unexpected key %v, value %q", key, value)
+	}
+	if key, value := splitKey("v", ":"); key != nil || value != "v" {
+		t.Errorf("Unexpected key %v, value %q", key, value)
+	}
+	if key, value := splitKey("k:v", ""); key != nil || value != "k:v" {
+		t.Errorf("Unexpected key %v, value %q", key, value)
+	}
+}
@@ -56,3 +56,18 @@ func versionCheck() error {
 	}
 	return nil
 }
+
+// requireLibrdkafkaVersion returns an ErrUnsupportedFeature error if the
+// linked librdkafka is older than minVersion (hex, e.g., 0x01070000 for
+// v1.7.0), which may be the case when dynamically linked (`-tags dynamic`)
+// to a system librdkafka older than the headers the Go client was built with.
+func requireLibrdkafkaVersion(feature string, minVersion int) error {
+	ver, verstr := LibraryVersion()
+	if ver < minVersion {
+		return newErrorFromString(ErrUnsupportedFeature,
+			fmt.Sprintf("%s requires librdkafka v%d.%d.%d or later: librdkafka version %s (0x%x) detected",
+				feature, (minVersion>>24)&0xff, (minVersion>>16)&0xff, (minVersion>>8)&0xff,
+				verstr, ver))
+	}
+	return nil
+}
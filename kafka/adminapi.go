@@ -52,9 +52,13 @@ ConfigEntry_by_idx (const rd_kafka_ConfigEntry_t **entries, size_t cnt, size_t i
 import "C"
 
 // AdminClient is derived from an existing Producer or Consumer
+//
+// AdminClient methods may be called concurrently: the requests are in flight
+// concurrently over the same client instance.
 type AdminClient struct {
 	handle    *handle
 	isDerived bool // Derived from existing client handle
+	requests  adminRequests
 }
 
 func durationToMilliseconds(t time.Duration) int {
@@ -250,6 +254,8 @@ type ConfigEntryResult struct {
 	Source ConfigSource
 	// IsReadOnly indicates whether the configuration entry can be altered.
 	IsReadOnly bool
+	// IsDefault indicates whether the value is at its default.
+	IsDefault bool
 	// IsSensitive indicates whether the configuration entry contains sensitive information, in which case the value will be unset.
 	IsSensitive bool
 	// IsSynonym indicates whether the configuration entry is a synonym for another configuration property.
@@ -272,6 +278,7 @@ func configEntryResultFromC(cEntry *C.rd_kafka_ConfigEntry_t) (entry ConfigEntry
 	}
 	entry.Source = ConfigSource(C.rd_kafka_ConfigEntry_source(cEntry))
 	entry.IsReadOnly = cint2bool(C.rd_kafka_ConfigEntry_is_read_only(cEntry))
+	entry.IsDefault = cint2bool(C.rd_kafka_ConfigEntry_is_default(cEntry))
 	entry.IsSensitive = cint2bool(C.rd_kafka_ConfigEntry_is_sensitive(cEntry))
 	entry.IsSynonym = cint2bool(C.rd_kafka_ConfigEntry_is_synonym(cEntry))
 
@@ -312,40 +319,27 @@ func (c ConfigResourceResult) String() string {
 	return fmt.Sprintf("ResourceResult(%s, %s, %d config(s))", c.Type, c.Name, len(c.Config))
 }
 
-// waitResult waits for a result event on cQueue or the ctx to be cancelled, whichever happens
-// first.
-// The returned result event is checked for errors its error is returned if set.
-func (a *AdminClient) waitResult(ctx context.Context, cQueue *C.rd_kafka_queue_t, cEventType C.rd_kafka_event_type_t) (rkev *C.rd_kafka_event_t, err error) {
-
-	resultChan := make(chan *C.rd_kafka_event_t)
-	closeChan := make(chan bool) // never written to, just closed
-
-	go func() {
-		for {
-			select {
-			case _, ok := <-closeChan:
-				if !ok {
-					// Context cancelled/timed out
-					close(resultChan)
-					return
-				}
-
-			default:
-				// Wait for result event for at most 50ms
-				// to avoid blocking for too long if
-				// context is cancelled.
-				rkev := C.rd_kafka_queue_poll(cQueue, 50)
-				if rkev != nil {
-					resultChan <- rkev
-					close(resultChan)
-					return
-				}
-			}
+// NonDefaultConfigs returns the config entries of the resource that
+// override their default value, i.e., that are neither marked as default
+// nor sourced from the built-in defaults.
+func (c ConfigResourceResult) NonDefaultConfigs() map[string]ConfigEntryResult {
+	overrides := make(map[string]ConfigEntryResult)
+	for name, entry := range c.Config {
+		if entry.IsDefault || entry.Source == ConfigSourceDefault {
+			continue
 		}
-	}()
+		overrides[name] = entry
+	}
+	return overrides
+}
+
+// waitResult waits for the result event of req or the ctx to be cancelled,
+// whichever happens first.
+// The returned result event is checked for errors its error is returned if set.
+func (a *AdminClient) waitResult(ctx context.Context, req *adminRequest, cEventType C.rd_kafka_event_type_t) (rkev *C.rd_kafka_event_t, err error) {
 
 	select {
-	case rkev = <-resultChan:
+	case rkev = <-req.resultChan:
 		// Result type check
 		if cEventType != C.rd_kafka_event_type(rkev) {
 			err = newErrorFromString(ErrInvalidType,
@@ -361,18 +355,10 @@ func (a *AdminClient) waitResult(ctx context.Context, cQueue *C.rd_kafka_queue_t
 			C.rd_kafka_event_destroy(rkev)
 			return nil, err
 		}
-		close(closeChan)
 		return rkev, nil
 	case <-ctx.Done():
-		// signal close to go-routine
-		close(closeChan)
-		// wait for close from go-routine to make sure it is done
-		// using cQueue before we return.
-		rkev, ok := <-resultChan
-		if ok {
-			// throw away result since context was cancelled
-			C.rd_kafka_event_destroy(rkev)
-		}
+		// throw away result, if any, since context was cancelled
+		a.cancelRequest(req)
 		return nil, ctx.Err()
 	}
 }
@@ -565,20 +551,16 @@ func (a *AdminClient) CreateTopics(ctx context.Context, topics []TopicSpecificat
 	}
 	defer C.rd_kafka_AdminOptions_destroy(cOptions)
 
-	// Create temporary queue for async operation
-	cQueue := C.rd_kafka_queue_new(a.handle.rk)
-	defer C.rd_kafka_queue_destroy(cQueue)
-
-	// Asynchronous call
-	C.rd_kafka_CreateTopics(
-		a.handle.rk,
-		(**C.rd_kafka_NewTopic_t)(&cTopics[0]),
-		C.size_t(len(cTopics)),
-		cOptions,
-		cQueue)
-
-	// Wait for result, error or context timeout
-	rkev, err := a.waitResult(ctx, cQueue, C.RD_KAFKA_EVENT_CREATETOPICS_RESULT)
+	rkev, err := a.doRequest(ctx, cOptions, genericOptions, C.RD_KAFKA_EVENT_CREATETOPICS_RESULT,
+		func(cQueue *C.rd_kafka_queue_t) {
+			// Asynchronous call
+			C.rd_kafka_CreateTopics(
+				a.handle.rk,
+				(**C.rd_kafka_NewTopic_t)(&cTopics[0]),
+				C.size_t(len(cTopics)),
+				cOptions,
+				cQueue)
+		})
 	if err != nil {
 		return nil, err
 	}
@@ -631,20 +613,16 @@ func (a *AdminClient) DeleteTopics(ctx context.Context, topics []string, options
 	}
 	defer C.rd_kafka_AdminOptions_destroy(cOptions)
 
-	// Create temporary queue for async operation
-	cQueue := C.rd_kafka_queue_new(a.handle.rk)
-	defer C.rd_kafka_queue_destroy(cQueue)
-
-	// Asynchronous call
-	C.rd_kafka_DeleteTopics(
-		a.handle.rk,
-		(**C.rd_kafka_DeleteTopic_t)(&cTopics[0]),
-		C.size_t(len(cTopics)),
-		cOptions,
-		cQueue)
-
-	// Wait for result, error or context timeout
-	rkev, err := a.waitResult(ctx, cQueue, C.RD_KAFKA_EVENT_DELETETOPICS_RESULT)
+	rkev, err := a.doRequest(ctx, cOptions, genericOptions, C.RD_KAFKA_EVENT_DELETETOPICS_RESULT,
+		func(cQueue *C.rd_kafka_queue_t) {
+			// Asynchronous call
+			C.rd_kafka_DeleteTopics(
+				a.handle.rk,
+				(**C.rd_kafka_DeleteTopic_t)(&cTopics[0]),
+				C.size_t(len(cTopics)),
+				cOptions,
+				cQueue)
+		})
 	if err != nil {
 		return nil, err
 	}
@@ -705,20 +683,16 @@ func (a *AdminClient) CreatePartitions(ctx context.Context, partitions []Partiti
 	}
 	defer C.rd_kafka_AdminOptions_destroy(cOptions)
 
-	// Create temporary queue for async operation
-	cQueue := C.rd_kafka_queue_new(a.handle.rk)
-	defer C.rd_kafka_queue_destroy(cQueue)
-
-	// Asynchronous call
-	C.rd_kafka_CreatePartitions(
-		a.handle.rk,
-		(**C.rd_kafka_NewPartitions_t)(&cParts[0]),
-		C.size_t(len(cParts)),
-		cOptions,
-		cQueue)
-
-	// Wait for result, error or context timeout
-	rkev, err := a.waitResult(ctx, cQueue, C.RD_KAFKA_EVENT_CREATEPARTITIONS_RESULT)
+	rkev, err := a.doRequest(ctx, cOptions, genericOptions, C.RD_KAFKA_EVENT_CREATEPARTITIONS_RESULT,
+		func(cQueue *C.rd_kafka_queue_t) {
+			// Asynchronous call
+			C.rd_kafka_CreatePartitions(
+				a.handle.rk,
+				(**C.rd_kafka_NewPartitions_t)(&cParts[0]),
+				C.size_t(len(cParts)),
+				cOptions,
+				cQueue)
+		})
 	if err != nil {
 		return nil, err
 	}
@@ -798,20 +772,16 @@ func (a *AdminClient) AlterConfigs(ctx context.Context, resources []ConfigResour
 	}
 	defer C.rd_kafka_AdminOptions_destroy(cOptions)
 
-	// Create temporary queue for async operation
-	cQueue := C.rd_kafka_queue_new(a.handle.rk)
-	defer C.rd_kafka_queue_destroy(cQueue)
-
-	// Asynchronous call
-	C.rd_kafka_AlterConfigs(
-		a.handle.rk,
-		(**C.rd_kafka_ConfigResource_t)(&cRes[0]),
-		C.size_t(len(cRes)),
-		cOptions,
-		cQueue)
-
-	// Wait for result, error or context timeout
-	rkev, err := a.waitResult(ctx, cQueue, C.RD_KAFKA_EVENT_ALTERCONFIGS_RESULT)
+	rkev, err := a.doRequest(ctx, cOptions, genericOptions, C.RD_KAFKA_EVENT_ALTERCONFIGS_RESULT,
+		func(cQueue *C.rd_kafka_queue_t) {
+			// Asynchronous call
+			C.rd_kafka_AlterConfigs(
+				a.handle.rk,
+				(**C.rd_kafka_ConfigResource_t)(&cRes[0]),
+				C.size_t(len(cRes)),
+				cOptions,
+				cQueue)
+		})
 	if err != nil {
 		return nil, err
 	}
@@ -830,7 +800,8 @@ func (a *AdminClient) AlterConfigs(ctx context.Context, resources []ConfigResour
 //
 // The returned configuration includes default values, use
 // ConfigEntryResult.IsDefault or ConfigEntryResult.Source to distinguish
-// default values from manually configured settings.
+// default values from manually configured settings, or
+// ConfigResourceResult.NonDefaultConfigs() to only keep the latter.
 //
 // The value of config entries where .IsSensitive is true
 // will always be nil to avoid disclosing sensitive
@@ -878,20 +849,16 @@ func (a *AdminClient) DescribeConfigs(ctx context.Context, resources []ConfigRes
 	}
 	defer C.rd_kafka_AdminOptions_destroy(cOptions)
 
-	// Create temporary queue for async operation
-	cQueue := C.rd_kafka_queue_new(a.handle.rk)
-	defer C.rd_kafka_queue_destroy(cQueue)
-
-	// Asynchronous call
-	C.rd_kafka_DescribeConfigs(
-		a.handle.rk,
-		(**C.rd_kafka_ConfigResource_t)(&cRes[0]),
-		C.size_t(len(cRes)),
-		cOptions,
-		cQueue)
-
-	// Wait for result, error or context timeout
-	rkev, err := a.waitResult(ctx, cQueue, C.RD_KAFKA_EVENT_DESCRIBECONFIGS_RESULT)
+	rkev, err := a.doRequest(ctx, cOptions, genericOptions, C.RD_KAFKA_EVENT_DESCRIBECONFIGS_RESULT,
+		func(cQueue *C.rd_kafka_queue_t) {
+			// Asynchronous call
+			C.rd_kafka_DescribeConfigs(
+				a.handle.rk,
+				(**C.rd_kafka_ConfigResource_t)(&cRes[0]),
+				C.size_t(len(cRes)),
+				cOptions,
+				cQueue)
+		})
 	if err != nil {
 		return nil, err
 	}
@@ -915,6 +882,17 @@ func (a *AdminClient) GetMetadata(topic *string, allTopics bool, timeoutMs int)
 	return getMetadata(a, topic, allTopics, timeoutMs)
 }
 
+// Metadata queries the cluster for the metadata of the topics selected
+// by opts, until ctx is done.
+// The metadata includes the controller and cluster ids, the brokers'
+// racks and the topic ids are not provided by this librdkafka version.
+//
+// Note on cancellation: the request is bound by ctx's deadline, if any,
+// but it currently cannot be manually cancelled.
+func (a *AdminClient) Metadata(ctx context.Context, opts MetadataOptions) (*Metadata, error) {
+	return metadata(a, ctx, opts)
+}
+
 // String returns a human readable name for an AdminClient instance
 func (a *AdminClient) String() string {
 	return fmt.Sprintf("admin-%s", a.handle.String())
@@ -994,6 +972,7 @@ func NewAdminClient(conf *ConfigMap) (*AdminClient, error) {
 
 	a.isDerived = false
 	a.handle.setup()
+	a.handle.register("admin", conf)
 
 	return a, nil
 }
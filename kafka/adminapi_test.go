@@ -19,6 +19,7 @@ package kafka
 import (
 	"context"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -383,3 +384,143 @@ func TestAdminAPIs(t *testing.T) {
 	testAdminAPIs("Derived from same Producer", a, t)
 	a.Close()
 }
+
+// TestAdminConcurrentRequests tests that concurrent requests are in flight
+// concurrently and get their own results, no broker is needed.
+func TestAdminConcurrentRequests(t *testing.T) {
+	a, err := NewAdminClient(&ConfigMap{})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer a.Close()
+
+	const reqcnt = 50
+	var wg sync.WaitGroup
+	errs := make([]error, reqcnt)
+	durations := make([]time.Duration, reqcnt)
+
+	start := time.Now()
+	for i := 0; i < reqcnt; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			// Requests time out in reverse order
+			timeout := time.Duration(reqcnt-i) * 20 * time.Millisecond
+			t0 := time.Now()
+			_, errs[i] = a.DescribeConfigs(ctx,
+				[]ConfigResource{{Type: ResourceTopic, Name: "topic"}},
+				SetAdminRequestTimeout(timeout))
+			durations[i] = time.Since(t0)
+		}(i)
+	}
+	wg.Wait()
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected requests to be in flight concurrently, took %v", elapsed)
+	}
+
+	for i, err := range errs {
+		if err == nil || err.(Error).Code() != ErrTimedOut {
+			t.Errorf("Request %d: expected ErrTimedOut, not %v", i, err)
+		}
+		// Each request got its own result
+		if exp := time.Duration(reqcnt-i) * 20 * time.Millisecond; durations[i] < exp {
+			t.Errorf("Request %d: result after %v, before its timeout %v",
+				i, durations[i], exp)
+		}
+	}
+
+	// Cancelled request
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = a.DescribeConfigs(ctx, []ConfigResource{{Type: ResourceTopic, Name: "topic"}},
+		SetAdminRequestTimeout(200*time.Millisecond))
+	if err != context.DeadlineExceeded {
+		t.Errorf("Expected DeadlineExceeded, not %v", err)
+	}
+
+	// The dispatcher exits once no requests are outstanding
+	tEnd := time.Now().Add(5 * time.Second)
+	for time.Now().Before(tEnd) {
+		a.requests.lock.Lock()
+		running := a.requests.cQueue != nil
+		a.requests.lock.Unlock()
+		if !running {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("Expected result dispatcher to exit")
+}
+
+// TestConfigResourceResultNonDefaultConfigs tests that only overridden
+// config entries are kept.
+func TestConfigResourceResultNonDefaultConfigs(t *testing.T) {
+	result := ConfigResourceResult{
+		Type: ResourceTopic,
+		Name: "mytopic",
+		Config: map[string]ConfigEntryResult{
+			"compression.type": {Name: "compression.type", Value: "snappy",
+				Source: ConfigSourceDynamicTopic},
+			"retention.ms": {Name: "retention.ms", Value: "86400000",
+				Source: ConfigSourceStaticBroker},
+			"cleanup.policy": {Name: "cleanup.policy", Value: "delete",
+				Source: ConfigSourceDefault, IsDefault: true},
+			"segment.bytes": {Name: "segment.bytes", Value: "1073741824",
+				IsDefault: true},
+		},
+	}
+
+	overrides := result.NonDefaultConfigs()
+	if len(overrides) != 2 {
+		t.Fatalf("Expected 2 overrides, got %v", overrides)
+	}
+	for _, name := range []string{"compression.type", "retention.ms"} {
+		if entry, found := overrides[name]; !found || entry.Value != result.Config[name].Value {
+			t.Errorf("Expected override %s, got %v", name, overrides)
+		}
+	}
+}
+
+// TestAdminRequestRetries tests that requests timing out are retried
+// as set by the options, no broker is needed.
+func TestAdminRequestRetries(t *testing.T) {
+	a, err := NewAdminClient(&ConfigMap{})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer a.Close()
+
+	requestTimeout := 200 * time.Millisecond
+	backoff := 100 * time.Millisecond
+
+	start := time.Now()
+	res, err := a.DescribeConfigs(context.Background(),
+		[]ConfigResource{{Type: ResourceTopic, Name: "mytopic"}},
+		SetAdminRequestTimeout(requestTimeout),
+		SetAdminRequestRetries(2),
+		SetAdminRetryBackoff(backoff))
+	elapsed := time.Since(start)
+	if res != nil || err == nil {
+		t.Fatalf("Expected DescribeConfigs to fail, but got result: %v, err: %v", res, err)
+	}
+	if err.(Error).Code() != ErrTimedOut {
+		t.Fatalf("Expected ErrTimedOut, not %v", err)
+	}
+	if min := 3*requestTimeout + 2*backoff; elapsed < min {
+		t.Fatalf("Expected 3 attempts taking at least %v, took %v", min, elapsed)
+	}
+
+	// The retries are bound by the context
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	res, err = a.DescribeConfigs(ctx,
+		[]ConfigResource{{Type: ResourceTopic, Name: "mytopic"}},
+		SetAdminRequestTimeout(requestTimeout),
+		SetAdminRequestRetries(100))
+	if res != nil || err != context.DeadlineExceeded {
+		t.Fatalf("Expected DeadlineExceeded, got result: %v, err: %v", res, err)
+	}
+}
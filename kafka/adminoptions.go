@@ -200,9 +200,113 @@ func SetAdminValidateOnly(validateOnly bool) (ao AdminOptionValidateOnly) {
 	return ao
 }
 
+// AdminOptionRequestRetries sets the number of times a request failing
+// with a retriable request-level error, such as a timeout or no controller
+// being available, is retried, e.g., for CreateTopics to wait for a
+// starting cluster.
+// Errors of the individual resources of the result are not retried.
+//
+// Default: 0 (no retries).
+//
+// Valid for all Admin API methods taking options.
+type AdminOptionRequestRetries struct {
+	isSet bool
+	val   int
+}
+
+func (ao AdminOptionRequestRetries) supportsCreateTopics() {
+}
+func (ao AdminOptionRequestRetries) supportsDeleteTopics() {
+}
+func (ao AdminOptionRequestRetries) supportsCreatePartitions() {
+}
+func (ao AdminOptionRequestRetries) supportsAlterConfigs() {
+}
+func (ao AdminOptionRequestRetries) supportsDescribeConfigs() {
+}
+
+// apply is a no-op, requests are retried by the AdminClient.
+func (ao AdminOptionRequestRetries) apply(cOptions *C.rd_kafka_AdminOptions_t) error {
+	return nil
+}
+
+// SetAdminRequestRetries sets the number of times a request failing
+// with a retriable request-level error, such as a timeout or no controller
+// being available, is retried, e.g., for CreateTopics to wait for a
+// starting cluster.
+// Errors of the individual resources of the result are not retried.
+//
+// Default: 0 (no retries).
+//
+// Valid for all Admin API methods taking options.
+func SetAdminRequestRetries(retries int) (ao AdminOptionRequestRetries) {
+	ao.isSet = true
+	ao.val = retries
+	return ao
+}
+
+// AdminOptionRetryBackoff sets the time to wait before retrying a failed
+// request, see SetAdminRequestRetries.
+//
+// Default: 100ms.
+//
+// Valid for all Admin API methods taking options.
+type AdminOptionRetryBackoff struct {
+	isSet bool
+	val   time.Duration
+}
+
+func (ao AdminOptionRetryBackoff) supportsCreateTopics() {
+}
+func (ao AdminOptionRetryBackoff) supportsDeleteTopics() {
+}
+func (ao AdminOptionRetryBackoff) supportsCreatePartitions() {
+}
+func (ao AdminOptionRetryBackoff) supportsAlterConfigs() {
+}
+func (ao AdminOptionRetryBackoff) supportsDescribeConfigs() {
+}
+
+// apply is a no-op, requests are retried by the AdminClient.
+func (ao AdminOptionRetryBackoff) apply(cOptions *C.rd_kafka_AdminOptions_t) error {
+	return nil
+}
+
+// SetAdminRetryBackoff sets the time to wait before retrying a failed
+// request, see SetAdminRequestRetries.
+//
+// Default: 100ms.
+//
+// Valid for all Admin API methods taking options.
+func SetAdminRetryBackoff(backoff time.Duration) (ao AdminOptionRetryBackoff) {
+	ao.isSet = true
+	ao.val = backoff
+	return ao
+}
+
+// adminRetryPolicy returns the request retries and retry backoff set
+// by options.
+func adminRetryPolicy(options []AdminOption) (retries int, backoff time.Duration) {
+	backoff = 100 * time.Millisecond
+	for _, opt := range options {
+		switch o := opt.(type) {
+		case AdminOptionRequestRetries:
+			if o.isSet {
+				retries = o.val
+			}
+		case AdminOptionRetryBackoff:
+			if o.isSet {
+				backoff = o.val
+			}
+		}
+	}
+	return retries, backoff
+}
+
 // CreateTopicsAdminOption - see setters.
 //
-// See SetAdminRequestTimeout, SetAdminOperationTimeout, SetAdminValidateOnly.
+// See SetAdminRequestTimeout, SetAdminOperationTimeout, SetAdminValidateOnly,
+// SetAdminRequestRetries, SetAdminRetryBackoff.
 type CreateTopicsAdminOption interface {
 	supportsCreateTopics()
 	apply(cOptions *C.rd_kafka_AdminOptions_t) error
@@ -210,7 +314,8 @@ type CreateTopicsAdminOption interface {
 
 // DeleteTopicsAdminOption - see setters.
 //
-// See SetAdminRequestTimeout, SetAdminOperationTimeout.
+// See SetAdminRequestTimeout, SetAdminOperationTimeout,
+// SetAdminRequestRetries, SetAdminRetryBackoff.
 type DeleteTopicsAdminOption interface {
 	supportsDeleteTopics()
 	apply(cOptions *C.rd_kafka_AdminOptions_t) error
@@ -218,7 +323,8 @@ type DeleteTopicsAdminOption interface {
 
 // CreatePartitionsAdminOption - see setters.
 //
-// See SetAdminRequestTimeout, SetAdminOperationTimeout, SetAdminValidateOnly.
+// See SetAdminRequestTimeout, SetAdminOperationTimeout, SetAdminValidateOnly,
+// SetAdminRequestRetries, SetAdminRetryBackoff.
 type CreatePartitionsAdminOption interface {
 	supportsCreatePartitions()
 	apply(cOptions *C.rd_kafka_AdminOptions_t) error
@@ -226,7 +332,8 @@ type CreatePartitionsAdminOption interface {
 
 // AlterConfigsAdminOption - see setters.
 //
-// See SetAdminRequestTimeout, SetAdminValidateOnly, SetAdminIncremental.
+// See SetAdminRequestTimeout, SetAdminValidateOnly, SetAdminIncremental,
+// SetAdminRequestRetries, SetAdminRetryBackoff.
 type AlterConfigsAdminOption interface {
 	supportsAlterConfigs()
 	apply(cOptions *C.rd_kafka_AdminOptions_t) error
@@ -234,7 +341,7 @@ type AlterConfigsAdminOption interface {
 
 // DescribeConfigsAdminOption - see setters.
 //
-// See SetAdminRequestTimeout.
+// See SetAdminRequestTimeout, SetAdminRequestRetries, SetAdminRetryBackoff.
 type DescribeConfigsAdminOption interface {
 	supportsDescribeConfigs()
 	apply(cOptions *C.rd_kafka_AdminOptions_t) error
@@ -0,0 +1,179 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+/*
+#include "select_rdkafka.h"
+
+static void admin_options_set_request_id (rd_kafka_AdminOptions_t *options,
+                                          uintptr_t id) {
+    rd_kafka_AdminOptions_set_opaque(options, (void *)id);
+}
+
+static uintptr_t event_request_id (rd_kafka_event_t *rkev) {
+    return (uintptr_t)rd_kafka_event_opaque(rkev);
+}
+*/
+import "C"
+
+// adminRequest is an outstanding AdminClient request
+type adminRequest struct {
+	id         uintptr
+	resultChan chan *C.rd_kafka_event_t
+}
+
+// adminRequests tracks the outstanding requests of an AdminClient.
+// All requests share a single result queue, served by one dispatcher
+// goroutine which routes each result to its request, so that any number
+// of requests can be in flight concurrently.
+// The dispatcher and queue only exist while requests are outstanding.
+type adminRequests struct {
+	lock     sync.Mutex
+	idNext   uintptr
+	requests map[uintptr]*adminRequest
+	// Result queue, nil while no dispatcher runs
+	cQueue *C.rd_kafka_queue_t
+}
+
+// newRequest registers a new request, with cOptions set up to route
+// its result to it, and returns it along with the result queue the
+// request must be enqueued with.
+func (a *AdminClient) newRequest(cOptions *C.rd_kafka_AdminOptions_t) (*adminRequest, *C.rd_kafka_queue_t) {
+	ar := &a.requests
+	ar.lock.Lock()
+	defer ar.lock.Unlock()
+
+	ar.idNext++
+	req := &adminRequest{
+		id:         ar.idNext,
+		resultChan: make(chan *C.rd_kafka_event_t, 1),
+	}
+	if ar.requests == nil {
+		ar.requests = make(map[uintptr]*adminRequest)
+	}
+	ar.requests[req.id] = req
+
+	C.admin_options_set_request_id(cOptions, C.uintptr_t(req.id))
+
+	if ar.cQueue == nil {
+		ar.cQueue = C.rd_kafka_queue_new(a.handle.rk)
+		go a.dispatchResults(ar.cQueue)
+	}
+
+	return req, ar.cQueue
+}
+
+// cancelRequest unregisters a request whose result is no longer awaited,
+// destroying the result if already dispatched.
+func (a *AdminClient) cancelRequest(req *adminRequest) {
+	ar := &a.requests
+	ar.lock.Lock()
+	defer ar.lock.Unlock()
+
+	if _, found := ar.requests[req.id]; found {
+		delete(ar.requests, req.id)
+		return
+	}
+
+	// Results are dispatched with the lock held
+	C.rd_kafka_event_destroy(<-req.resultChan)
+}
+
+// isAdminRequestRetriable returns true if a request failing with err may
+// succeed once retried.
+func isAdminRequestRetriable(err error) bool {
+	kerr, ok := err.(Error)
+	if !ok {
+		return false
+	}
+	if kerr.IsRetriable() {
+		return true
+	}
+
+	switch kerr.Code() {
+	case ErrTimedOut, ErrTransport, ErrAllBrokersDown,
+		ErrRequestTimedOut, ErrNetworkException,
+		ErrBrokerNotAvailable, ErrLeaderNotAvailable, ErrNotController:
+		return true
+	}
+	return false
+}
+
+// doRequest registers a request, enqueues it with call and waits for its
+// result, retrying retriable errors as set by options,
+// see SetAdminRequestRetries.
+func (a *AdminClient) doRequest(ctx context.Context, cOptions *C.rd_kafka_AdminOptions_t, options []AdminOption, cEventType C.rd_kafka_event_type_t, call func(cQueue *C.rd_kafka_queue_t)) (*C.rd_kafka_event_t, error) {
+	retries, backoff := adminRetryPolicy(options)
+
+	for attempt := 0; ; attempt++ {
+		// Register request, its result is dispatched from the shared queue
+		req, cQueue := a.newRequest(cOptions)
+
+		// Asynchronous call
+		call(cQueue)
+
+		// Wait for result, error or context timeout
+		rkev, err := a.waitResult(ctx, req, cEventType)
+		if err == nil || attempt >= retries || !isAdminRequestRetriable(err) {
+			return rkev, err
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// dispatchResults serves the result queue, routing results to their
+// requests, until no requests are outstanding.
+func (a *AdminClient) dispatchResults(cQueue *C.rd_kafka_queue_t) {
+	ar := &a.requests
+
+	for {
+		rkev := C.rd_kafka_queue_poll(cQueue, 100)
+
+		ar.lock.Lock()
+		if rkev != nil {
+			id := uintptr(C.event_request_id(rkev))
+			if req, found := ar.requests[id]; found {
+				delete(ar.requests, id)
+				req.resultChan <- rkev
+			} else {
+				// Cancelled request
+				C.rd_kafka_event_destroy(rkev)
+			}
+		}
+
+		if len(ar.requests) == 0 {
+			// Results of cancelled requests, if any,
+			// are destroyed along with the queue.
+			ar.cQueue = nil
+			ar.lock.Unlock()
+			C.rd_kafka_queue_destroy(cQueue)
+			return
+		}
+		ar.lock.Unlock()
+	}
+}
@@ -0,0 +1,158 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// BrokerUp is emitted when the connection to a broker is established
+// or re-established.
+//
+// Broker state events are enabled by setting the `go.broker.state.events`
+// configuration property to true. Since broker up transitions are
+// detected from the client statistics, BrokerUp events also require
+// `statistics.interval.ms` to be set, and are emitted at most once
+// per statistics interval.
+type BrokerUp struct {
+	// Broker name, "host:port/id"
+	Broker string
+	// Broker id, or -1 for bootstrap brokers
+	BrokerID int32
+	// Recovered is true if this is the first broker to come up after
+	// an AllBrokersDown event.
+	Recovered bool
+}
+
+func (e BrokerUp) String() string {
+	return fmt.Sprintf("BrokerUp: %s (recovered: %v)", e.Broker, e.Recovered)
+}
+
+// BrokerDown is emitted, instead of an ErrTransport Error,
+// when the connection to a broker fails or is lost.
+//
+// Requires `go.broker.state.events`, see BrokerUp.
+type BrokerDown struct {
+	// Broker name, "host:port/id"
+	Broker string
+	// Broker id, or -1 for bootstrap brokers
+	BrokerID int32
+	// Reason is the underlying ErrTransport error
+	Reason Error
+}
+
+func (e BrokerDown) String() string {
+	return fmt.Sprintf("BrokerDown: %s: %v", e.Broker, e.Reason)
+}
+
+// AllBrokersDown is emitted, instead of an ErrAllBrokersDown Error,
+// when the connections to all brokers are down.
+// A BrokerUp event with .Recovered set to true is emitted when
+// a broker connection is re-established.
+//
+// Requires `go.broker.state.events`, see BrokerUp.
+type AllBrokersDown struct {
+	// Reason is the underlying ErrAllBrokersDown error
+	Reason Error
+}
+
+func (e AllBrokersDown) String() string {
+	return fmt.Sprintf("AllBrokersDown: %v", e.Reason)
+}
+
+// brokerIDFromName returns the broker id from a "host:port/id" broker name,
+// or -1 if the name has no numeric id (e.g., "host:port/bootstrap").
+func brokerIDFromName(name string) int32 {
+	i := strings.LastIndex(name, "/")
+	if i == -1 {
+		return -1
+	}
+	id, err := strconv.Atoi(name[i+1:])
+	if err != nil {
+		return -1
+	}
+	return int32(id)
+}
+
+// newBrokerDown creates a BrokerDown event from an ErrTransport error,
+// the broker name is extracted from the "host:port/id: reason" error string.
+func (h *handle) newBrokerDown(err Error) BrokerDown {
+	broker := err.str
+	if i := strings.Index(broker, ": "); i != -1 {
+		broker = broker[:i]
+	}
+
+	h.brokerStateLock.Lock()
+	h.brokersUp[broker] = false
+	h.brokerStateLock.Unlock()
+
+	return BrokerDown{Broker: broker, BrokerID: brokerIDFromName(broker), Reason: err}
+}
+
+// newAllBrokersDown creates an AllBrokersDown event from an
+// ErrAllBrokersDown error.
+func (h *handle) newAllBrokersDown(err Error) AllBrokersDown {
+	h.brokerStateLock.Lock()
+	h.allBrokersDown = true
+	for broker := range h.brokersUp {
+		h.brokersUp[broker] = false
+	}
+	h.brokerStateLock.Unlock()
+
+	return AllBrokersDown{Reason: err}
+}
+
+// updateBrokerStates enqueues BrokerUp events for brokers that are up in
+// stats but were not up in the previous statistics.
+func (h *handle) updateBrokerStates(stats *Statistics) {
+	h.brokerStateLock.Lock()
+	defer h.brokerStateLock.Unlock()
+
+	for _, b := range stats.Brokers {
+		if b.Source == "logical" || b.Source == "internal" {
+			continue
+		}
+
+		up := b.State == "UP"
+		if up && !h.brokersUp[b.Name] {
+			h.pendingEvents = append(h.pendingEvents, BrokerUp{
+				Broker:    b.Name,
+				BrokerID:  b.NodeID,
+				Recovered: h.allBrokersDown,
+			})
+			h.allBrokersDown = false
+		}
+		h.brokersUp[b.Name] = up
+	}
+}
+
+// popPendingEvent removes and returns the oldest event enqueued
+// by the Go client itself, or nil if there is none.
+func (h *handle) popPendingEvent() Event {
+	h.brokerStateLock.Lock()
+	defer h.brokerStateLock.Unlock()
+
+	if len(h.pendingEvents) == 0 {
+		return nil
+	}
+
+	ev := h.pendingEvents[0]
+	h.pendingEvents = h.pendingEvents[1:]
+	return ev
+}
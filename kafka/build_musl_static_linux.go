@@ -0,0 +1,12 @@
+// +build !dynamic
+// +build musl
+// +build static
+
+package kafka
+
+// Fully static linking of the application, including the musl libc,
+// e.g., for scratch or distroless images.
+// Used in addition to build_musl_linux.go with `-tags musl,static`.
+
+// #cgo LDFLAGS: -static
+import "C"
@@ -0,0 +1,152 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"fmt"
+)
+
+// StatsCallback is called with the statistics JSON document,
+// see the `go.stats.callback` configuration property.
+//
+// Callbacks are called from the Producer's internal poller goroutine, or
+// from the goroutine calling Consumer.Poll() or ReadMessage(), and must
+// not block for long.
+type StatsCallback func(statsJSON string)
+
+// StatisticsCallback is called with the parsed statistics, see the
+// `go.statistics.callback` configuration property and StatsCallback.
+// The statistics are parsed straight from librdkafka's JSON document,
+// which is not copied to a Go string.
+type StatisticsCallback func(stats *Statistics)
+
+// ErrorCallback is called with client errors,
+// see the `go.error.callback` configuration property and StatsCallback.
+type ErrorCallback func(err Error)
+
+// CommitCallback is called with the result of every offset commit,
+// successful or not, see the `go.commit.callback` configuration property.
+// The per-partition Error of result.Offsets tells which partitions failed.
+//
+// CommitCallback is called from the goroutine calling Commit(),
+// CommitMessage() or CommitOffsets(), and, for automatic commits, from
+// the goroutine calling Poll() or ReadMessage().
+type CommitCallback func(result OffsetsCommitted)
+
+// extractCommitCallbackConfig extracts the go.commit.callback
+// configuration property.
+func (m ConfigMap) extractCommitCallbackConfig() (commitCb CommitCallback, err error) {
+	v, err := m.extract("go.commit.callback", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	switch x := v.(type) {
+	case nil:
+	case CommitCallback:
+		commitCb = x
+	case func(OffsetsCommitted):
+		commitCb = x
+	default:
+		return nil, newErrorFromString(ErrInvalidArg,
+			fmt.Sprintf("go.commit.callback expects a kafka.CommitCallback, not %T", v))
+	}
+
+	return commitCb, nil
+}
+
+// extractCallbackConfig extracts the go.stats.callback, go.statistics.callback
+// and go.error.callback configuration properties. The go.log.callback
+// property is extracted by extractLogConfig().
+func (m ConfigMap) extractCallbackConfig() (statsCb StatsCallback, statisticsCb StatisticsCallback, errorCb ErrorCallback, err error) {
+	v, err := m.extract("go.stats.callback", nil)
+	if err != nil {
+		return
+	}
+
+	switch x := v.(type) {
+	case nil:
+	case StatsCallback:
+		statsCb = x
+	case func(string):
+		statsCb = x
+	default:
+		err = newErrorFromString(ErrInvalidArg,
+			fmt.Sprintf("go.stats.callback expects a kafka.StatsCallback, not %T", v))
+		return
+	}
+
+	v, err = m.extract("go.statistics.callback", nil)
+	if err != nil {
+		return
+	}
+
+	switch x := v.(type) {
+	case nil:
+	case StatisticsCallback:
+		statisticsCb = x
+	case func(*Statistics):
+		statisticsCb = x
+	default:
+		err = newErrorFromString(ErrInvalidArg,
+			fmt.Sprintf("go.statistics.callback expects a kafka.StatisticsCallback, not %T", v))
+		return
+	}
+
+	v, err = m.extract("go.error.callback", nil)
+	if err != nil {
+		return
+	}
+
+	switch x := v.(type) {
+	case nil:
+	case ErrorCallback:
+		errorCb = x
+	case func(Error):
+		errorCb = x
+	default:
+		err = newErrorFromString(ErrInvalidArg,
+			fmt.Sprintf("go.error.callback expects a kafka.ErrorCallback, not %T", v))
+	}
+
+	return
+}
+
+// invokeCallback calls the stats or error callback, if configured,
+// for ev. Returns true if ev was handled by a callback and must not
+// be forwarded to the application.
+func (h *handle) invokeCallback(ev Event) bool {
+	switch e := ev.(type) {
+	case Error:
+		if h.errorCb != nil {
+			h.errorCb(e)
+			return true
+		}
+	case *Stats:
+		if h.statsCb != nil {
+			h.statsCb(e.statsJSON)
+			return true
+		}
+	case *StatsEvent:
+		if h.statsCb != nil {
+			h.statsCb(e.JSON)
+			return true
+		}
+	}
+
+	return false
+}
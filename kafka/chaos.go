@@ -0,0 +1,396 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ChaosConfig configures the faults injected by a ChaosProducer or
+// ChaosConsumer. Rates are probabilities between 0 and 1.
+type ChaosConfig struct {
+	// Seed seeds the random fault selection, the same seed and sequence of
+	// calls injects the same faults.
+	Seed int64
+	// DropRate is the rate of produced messages that are dropped:
+	// the message is not produced and its delivery report fails
+	// with ErrMsgTimedOut. Producer only.
+	DropRate float64
+	// DeliveryDelay delays all delivery reports. Producer only.
+	DeliveryDelay time.Duration
+	// DuplicateRate is the rate of messages that are duplicated:
+	// produced twice by a ChaosProducer, with a single delivery report,
+	// or returned twice by a ChaosConsumer.
+	DuplicateRate float64
+	// CorruptHeaderRate is the rate of messages with headers of which
+	// one header value is corrupted, on produce or consume.
+	CorruptHeaderRate float64
+}
+
+// ChaosCounts holds the number of faults injected, see ChaosProducer.Counts()
+// and ChaosConsumer.Counts().
+type ChaosCounts struct {
+	Dropped          int64
+	Duplicated       int64
+	CorruptedHeaders int64
+}
+
+func (cc ChaosCounts) String() string {
+	return fmt.Sprintf("%d dropped, %d duplicated, %d with corrupted headers",
+		cc.Dropped, cc.Duplicated, cc.CorruptedHeaders)
+}
+
+// chaos holds the fault injection state shared by the chaos clients.
+type chaos struct {
+	conf   ChaosConfig
+	lock   sync.Mutex
+	rnd    *rand.Rand
+	counts ChaosCounts
+}
+
+func newChaos(conf ChaosConfig) *chaos {
+	return &chaos{conf: conf, rnd: rand.New(rand.NewSource(conf.Seed))}
+}
+
+// inject returns true with probability rate.
+func (c *chaos) inject(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.rnd.Float64() < rate
+}
+
+// corruptHeaders returns a copy of msg with one header value corrupted,
+// or msg itself if it has no headers.
+func (c *chaos) corruptHeaders(msg *Message) *Message {
+	if len(msg.GetHeaders()) == 0 {
+		return msg
+	}
+
+	corrupted := msg.Clone()
+
+	c.lock.Lock()
+	h := &corrupted.Headers[c.rnd.Intn(len(corrupted.Headers))]
+	if len(h.Value) == 0 {
+		h.Value = []byte{byte(c.rnd.Intn(256))}
+	} else {
+		h.Value[c.rnd.Intn(len(h.Value))] ^= 0xff
+	}
+	c.counts.CorruptedHeaders++
+	c.lock.Unlock()
+
+	return corrupted
+}
+
+// count increments the counter selected by fn.
+func (c *chaos) count(fn func(counts *ChaosCounts)) {
+	c.lock.Lock()
+	fn(&c.counts)
+	c.lock.Unlock()
+}
+
+// Counts returns the number of faults injected so far.
+func (c *chaos) Counts() ChaosCounts {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.counts
+}
+
+// chaosOpaque wraps the application's Message.Opaque while a message
+// is produced by a ChaosProducer.
+type chaosOpaque struct {
+	opaque       interface{}
+	deliveryChan chan Event
+	duplicate    bool
+}
+
+// chaosDr is a delivery report waiting for the delivery delay.
+type chaosDr struct {
+	msg *Message
+	due time.Time
+}
+
+// ChaosProducer wraps a ProducerClient, the Producer or the MockProducer,
+// injecting faults configured by ChaosConfig into the produce path
+// to test the application's retry and idempotency logic.
+//
+// Delivery reports are routed through the ChaosProducer, which must be
+// used for Events() and Flush() rather than the wrapped producer.
+type ChaosProducer struct {
+	ProducerClient
+	*chaos
+
+	events         chan Event
+	produceChannel chan *Message
+	drChan         chan Event
+	delayed        chan chaosDr
+	drDone         chan bool
+	pending        int64
+	closing        int32
+	waitGroup      sync.WaitGroup
+}
+
+// NewChaosProducer returns a ChaosProducer injecting faults into p.
+func NewChaosProducer(p ProducerClient, conf ChaosConfig) *ChaosProducer {
+	cp := &ChaosProducer{
+		ProducerClient: p,
+		chaos:          newChaos(conf),
+		events:         make(chan Event, 10000),
+		produceChannel: make(chan *Message, 10000),
+		drChan:         make(chan Event, 10000),
+		delayed:        make(chan chaosDr, 10000),
+		drDone:         make(chan bool),
+	}
+
+	cp.waitGroup.Add(2)
+	go func() {
+		defer cp.waitGroup.Done()
+		for m := range cp.produceChannel {
+			err := cp.Produce(m, nil)
+			if err != nil {
+				m.TopicPartition.Error = err
+				cp.events <- m
+			}
+		}
+	}()
+
+	go func() {
+		defer cp.waitGroup.Done()
+		for ev := range p.Events() {
+			cp.events <- ev
+		}
+	}()
+
+	go cp.delayDeliveryReports()
+
+	return cp
+}
+
+func (cp *ChaosProducer) String() string {
+	return fmt.Sprintf("chaos(%s)", cp.ProducerClient.String())
+}
+
+// Produce produces msg through the wrapped producer, possibly dropping,
+// duplicating or corrupting it. See Producer.Produce().
+func (cp *ChaosProducer) Produce(msg *Message, deliveryChan chan Event) error {
+	opaque := &chaosOpaque{opaque: msg.Opaque, deliveryChan: deliveryChan}
+
+	if cp.inject(cp.conf.DropRate) {
+		cp.count(func(c *ChaosCounts) { c.Dropped++ })
+		dr := msg.Clone()
+		dr.Opaque = opaque
+		dr.TopicPartition.Error = newErrorFromString(ErrMsgTimedOut,
+			"Message dropped by ChaosProducer")
+		dr.TopicPartition.Offset = OffsetInvalid
+		atomic.AddInt64(&cp.pending, 1)
+		cp.drChan <- dr
+		return nil
+	}
+
+	if cp.inject(cp.conf.CorruptHeaderRate) {
+		msg = cp.corruptHeaders(msg)
+	}
+
+	m := *msg
+	m.Opaque = opaque
+	atomic.AddInt64(&cp.pending, 1)
+	err := cp.ProducerClient.Produce(&m, cp.drChan)
+	if err != nil {
+		atomic.AddInt64(&cp.pending, -1)
+		return err
+	}
+
+	if cp.inject(cp.conf.DuplicateRate) {
+		dup := m.Clone()
+		dup.Opaque = &chaosOpaque{duplicate: true}
+		if cp.ProducerClient.Produce(dup, cp.drChan) == nil {
+			cp.count(func(c *ChaosCounts) { c.Duplicated++ })
+		}
+	}
+
+	return nil
+}
+
+// delayDeliveryReports applies the delivery delay to the delivery
+// reports and forwards them to the application.
+func (cp *ChaosProducer) delayDeliveryReports() {
+	defer close(cp.drDone)
+
+	go func() {
+		for ev := range cp.drChan {
+			m, ok := ev.(*Message)
+			if !ok {
+				cp.events <- ev
+				continue
+			}
+			cp.delayed <- chaosDr{m, time.Now().Add(cp.conf.DeliveryDelay)}
+		}
+		close(cp.delayed)
+	}()
+
+	for dr := range cp.delayed {
+		if atomic.LoadInt32(&cp.closing) != 0 {
+			continue
+		}
+
+		time.Sleep(time.Until(dr.due))
+
+		opaque := dr.msg.Opaque.(*chaosOpaque)
+		if opaque.duplicate {
+			continue
+		}
+
+		dr.msg.Opaque = opaque.opaque
+		if opaque.deliveryChan != nil {
+			opaque.deliveryChan <- dr.msg
+		} else {
+			cp.events <- dr.msg
+		}
+		atomic.AddInt64(&cp.pending, -1)
+	}
+}
+
+// Events returns the Events channel (read), including delivery reports.
+func (cp *ChaosProducer) Events() chan Event {
+	return cp.events
+}
+
+// ProduceChannel returns the produce *Message channel (write),
+// messages are produced through the ChaosProducer.
+func (cp *ChaosProducer) ProduceChannel() chan *Message {
+	return cp.produceChannel
+}
+
+// Len returns the number of messages waiting to be transmitted or
+// for their delivery report to be forwarded, and events queued
+// for the application.
+func (cp *ChaosProducer) Len() int {
+	return int(atomic.LoadInt64(&cp.pending)) + len(cp.produceChannel) + len(cp.events)
+}
+
+// Flush flushes the wrapped producer and waits for the delayed delivery
+// reports to be forwarded, for at most timeoutMs.
+// Returns the number of outstanding events still un-flushed.
+func (cp *ChaosProducer) Flush(timeoutMs int) int {
+	tEnd := time.Now().Add(time.Duration(timeoutMs) * time.Millisecond)
+
+	cp.ProducerClient.Flush(timeoutMs)
+
+	for atomic.LoadInt64(&cp.pending) > 0 && time.Now().Before(tEnd) {
+		time.Sleep(time.Millisecond)
+	}
+
+	return int(atomic.LoadInt64(&cp.pending))
+}
+
+// Close closes the wrapped producer.
+// Delivery reports not yet forwarded are discarded.
+func (cp *ChaosProducer) Close() {
+	close(cp.produceChannel)
+	cp.ProducerClient.Close()
+	cp.waitGroup.Wait()
+	atomic.StoreInt32(&cp.closing, 1)
+	close(cp.drChan)
+	<-cp.drDone
+	close(cp.events)
+}
+
+// ChaosConsumer wraps a ConsumerClient, the Consumer or the MockConsumer,
+// injecting faults configured by ChaosConfig into the messages returned
+// by Poll() and ReadMessage().
+type ChaosConsumer struct {
+	ConsumerClient
+	*chaos
+
+	duplicate *Message
+}
+
+// NewChaosConsumer returns a ChaosConsumer injecting faults into c.
+func NewChaosConsumer(c ConsumerClient, conf ChaosConfig) *ChaosConsumer {
+	return &ChaosConsumer{ConsumerClient: c, chaos: newChaos(conf)}
+}
+
+func (cc *ChaosConsumer) String() string {
+	return fmt.Sprintf("chaos(%s)", cc.ConsumerClient.String())
+}
+
+// Poll polls the wrapped consumer, possibly duplicating the returned
+// message, which is then returned again by the next call, or corrupting
+// its headers. See Consumer.Poll().
+func (cc *ChaosConsumer) Poll(timeoutMs int) (event Event) {
+	cc.lock.Lock()
+	if cc.duplicate != nil {
+		dup := cc.duplicate
+		cc.duplicate = nil
+		cc.lock.Unlock()
+		return dup
+	}
+	cc.lock.Unlock()
+
+	ev := cc.ConsumerClient.Poll(timeoutMs)
+
+	m, ok := ev.(*Message)
+	if !ok || m.TopicPartition.Error != nil {
+		return ev
+	}
+
+	if cc.inject(cc.conf.CorruptHeaderRate) {
+		m = cc.corruptHeaders(m)
+	}
+
+	if cc.inject(cc.conf.DuplicateRate) {
+		cc.lock.Lock()
+		cc.duplicate = m.Clone()
+		cc.counts.Duplicated++
+		cc.lock.Unlock()
+	}
+
+	return m
+}
+
+// ReadMessage polls the consumer for a message, see Consumer.ReadMessage().
+func (cc *ChaosConsumer) ReadMessage(timeout time.Duration) (*Message, error) {
+	tEnd := time.Now().Add(timeout)
+
+	for {
+		timeoutMs := -1
+		if timeout >= 0 {
+			timeoutMs = int(time.Until(tEnd) / time.Millisecond)
+			if timeoutMs < 0 {
+				timeoutMs = 0
+			}
+		}
+
+		switch e := cc.Poll(timeoutMs).(type) {
+		case *Message:
+			if e.TopicPartition.Error != nil {
+				return e, e.TopicPartition.Error
+			}
+			return e, nil
+		case Error:
+			return nil, e
+		case nil:
+			return nil, newErrorFromString(ErrTimedOut, "")
+		}
+	}
+}
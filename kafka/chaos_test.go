@@ -0,0 +1,170 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"testing"
+	"time"
+)
+
+// TestChaosProducer tests fault injection by the ChaosProducer
+// wrapping a MockProducer.
+func TestChaosProducer(t *testing.T) {
+	mp := NewMockProducer(true)
+	cp := NewChaosProducer(mp, ChaosConfig{
+		Seed:              1,
+		DropRate:          0.2,
+		DuplicateRate:     0.2,
+		CorruptHeaderRate: 0.2,
+		DeliveryDelay:     10 * time.Millisecond,
+	})
+
+	var _ ProducerClient = cp
+
+	topic := "chaos"
+	msgCnt := 200
+	start := time.Now()
+	for i := 0; i < msgCnt; i++ {
+		err := cp.Produce(&Message{
+			TopicPartition: TopicPartition{Topic: &topic, Partition: 0},
+			Value:          []byte("value"),
+			Headers:        []Header{{"hdr", []byte("hdrvalue")}},
+			Opaque:         i,
+		}, nil)
+		if err != nil {
+			t.Fatalf("Produce failed: %v", err)
+		}
+	}
+
+	if remaining := cp.Flush(5000); remaining != 0 {
+		t.Fatalf("Expected Flush to complete, %d remaining", remaining)
+	}
+
+	counts := cp.Counts()
+	t.Logf("Injected %v", counts)
+	if counts.Dropped == 0 || counts.Duplicated == 0 || counts.CorruptedHeaders == 0 {
+		t.Errorf("Expected all fault types to be injected: %v", counts)
+	}
+
+	var delivered, failed int
+	opaques := make(map[int]bool)
+	for len(cp.Events()) > 0 {
+		m := (<-cp.Events()).(*Message)
+		if time.Since(start) < 10*time.Millisecond {
+			t.Errorf("Delivery report not delayed")
+		}
+		opaques[m.Opaque.(int)] = true
+		if m.TopicPartition.Error != nil {
+			if m.TopicPartition.Error.(Error).Code() != ErrMsgTimedOut {
+				t.Errorf("Unexpected delivery error %v", m.TopicPartition.Error)
+			}
+			failed++
+		} else {
+			delivered++
+		}
+	}
+
+	if delivered+failed != msgCnt || len(opaques) != msgCnt {
+		t.Errorf("Expected one delivery report per message (%d), "+
+			"got %d delivered, %d failed, %d distinct opaques",
+			msgCnt, delivered, failed, len(opaques))
+	}
+	if int64(failed) != counts.Dropped {
+		t.Errorf("Expected %d failed deliveries, not %d", counts.Dropped, failed)
+	}
+
+	produced := len(mp.Messages())
+	if int64(produced) != int64(msgCnt)-counts.Dropped+counts.Duplicated {
+		t.Errorf("Expected %d-%d+%d messages produced, not %d",
+			msgCnt, counts.Dropped, counts.Duplicated, produced)
+	}
+
+	corrupted := 0
+	for _, m := range mp.Messages() {
+		if string(m.Headers[0].Value) != "hdrvalue" {
+			corrupted++
+		}
+	}
+	if corrupted == 0 {
+		t.Errorf("Expected corrupted headers to be produced")
+	}
+
+	// The same seed injects the same faults
+	cp2 := NewChaosProducer(NewMockProducer(true), ChaosConfig{Seed: 1, DropRate: 0.2,
+		DuplicateRate: 0.2, CorruptHeaderRate: 0.2})
+	for i := 0; i < msgCnt; i++ {
+		cp2.Produce(&Message{TopicPartition: TopicPartition{Topic: &topic},
+			Headers: []Header{{"hdr", []byte("hdrvalue")}}}, nil)
+	}
+	cp2.Flush(5000)
+	if cp2.Counts() != counts {
+		t.Errorf("Expected same faults with same seed: %v != %v", cp2.Counts(), counts)
+	}
+	cp2.Close()
+
+	cp.Close()
+}
+
+// TestChaosConsumer tests fault injection by the ChaosConsumer
+// wrapping a MockConsumer.
+func TestChaosConsumer(t *testing.T) {
+	mc := NewMockConsumer()
+	cc := NewChaosConsumer(mc, ChaosConfig{Seed: 3, DuplicateRate: 0.3,
+		CorruptHeaderRate: 0.3})
+	defer cc.Close()
+
+	var _ ConsumerClient = cc
+
+	topic := "chaos"
+	msgCnt := 100
+	for i := 0; i < msgCnt; i++ {
+		mc.AddMessage(&Message{
+			TopicPartition: TopicPartition{Topic: &topic, Partition: 0,
+				Offset: OffsetInvalid},
+			Headers: []Header{{"hdr", []byte("hdrvalue")}},
+		})
+	}
+
+	if err := cc.Subscribe(topic, nil); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	mc.Rebalance([]TopicPartition{{Topic: &topic, Partition: 0}})
+
+	var received, corrupted int
+	for {
+		m, err := cc.ReadMessage(100 * time.Millisecond)
+		if err != nil {
+			break
+		}
+		received++
+		if string(m.Headers[0].Value) != "hdrvalue" {
+			corrupted++
+		}
+	}
+
+	counts := cc.Counts()
+	if counts.Duplicated == 0 || counts.CorruptedHeaders == 0 {
+		t.Errorf("Expected duplicates and corrupted headers: %v", counts)
+	}
+	if int64(received) != int64(msgCnt)+counts.Duplicated {
+		t.Errorf("Expected %d+%d messages, not %d", msgCnt, counts.Duplicated, received)
+	}
+	if corrupted < int(counts.CorruptedHeaders) {
+		t.Errorf("Expected at least %d corrupted messages, not %d",
+			counts.CorruptedHeaders, corrupted)
+	}
+}
@@ -0,0 +1,452 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CloudEventsMode is the CloudEvents Kafka protocol binding content mode
+type CloudEventsMode int
+
+const (
+	// CloudEventsBinary - event attributes are carried in ce_* headers
+	// and the event data is the message value
+	CloudEventsBinary CloudEventsMode = iota
+	// CloudEventsStructured - the whole event, attributes and data,
+	// is encoded as JSON in the message value
+	CloudEventsStructured
+)
+
+func (mode CloudEventsMode) String() string {
+	switch mode {
+	case CloudEventsBinary:
+		return "binary"
+	case CloudEventsStructured:
+		return "structured"
+	default:
+		return fmt.Sprintf("CloudEventsMode(%d)", int(mode))
+	}
+}
+
+const (
+	// cloudEventsSpecVersion is the supported CloudEvents specification version
+	cloudEventsSpecVersion = "1.0"
+	// cloudEventsHeaderPrefix prefixes attribute headers in binary mode
+	cloudEventsHeaderPrefix = "ce_"
+	// cloudEventsContentType is the content-type of structured mode messages
+	cloudEventsContentType = "application/cloudevents+json"
+	// cloudEventsPartitionKey is the extension mapped to the message key
+	cloudEventsPartitionKey = "partitionkey"
+)
+
+// CloudEvent is a CloudEvents v1.0 event, see
+// https://github.com/cloudevents/spec/blob/v1.0.2/cloudevents/spec.md
+//
+// Use NewCloudEventMessage() to produce an event with the
+// CloudEvents Kafka protocol binding, and CloudEventFromMessage() to
+// read an event from a consumed message.
+type CloudEvent struct {
+	// ID identifies the event (required)
+	ID string
+	// Source identifies the context in which the event happened (required)
+	Source string
+	// SpecVersion is the CloudEvents specification version,
+	// "1.0" if left empty
+	SpecVersion string
+	// Type is the type of the event (required)
+	Type string
+	// DataContentType is the content type of Data, e.g., "application/json"
+	DataContentType string
+	// DataSchema is the URI of the schema Data adheres to
+	DataSchema string
+	// Subject is the subject of the event in the context of Source.
+	// The subject is used as the message key, and thus for partitioning,
+	// unless the "partitionkey" extension is set.
+	Subject string
+	// Time is the time the event happened, zero if not set
+	Time time.Time
+	// Extensions are the extension context attributes.
+	// Extension names consist of lower-case letters and digits.
+	Extensions map[string]string
+	// Data is the event payload
+	Data []byte
+}
+
+func (e *CloudEvent) String() string {
+	return fmt.Sprintf("CloudEvent %s from %s (type %s, subject %s)",
+		e.ID, e.Source, e.Type, e.Subject)
+}
+
+// validate checks the required attributes and extension names
+func (e *CloudEvent) validate() error {
+	if e.ID == "" || e.Source == "" || e.Type == "" {
+		return newErrorFromString(ErrInvalidArg,
+			"CloudEvent requires the id, source and type attributes")
+	}
+
+	if e.SpecVersion != "" && e.SpecVersion != cloudEventsSpecVersion {
+		return newErrorFromString(ErrInvalidArg,
+			fmt.Sprintf("Unsupported CloudEvents specversion %s", e.SpecVersion))
+	}
+
+	for name := range e.Extensions {
+		if !isCloudEventsAttributeName(name) || isCloudEventsContextAttribute(name) {
+			return newErrorFromString(ErrInvalidArg,
+				fmt.Sprintf("Invalid CloudEvent extension name \"%s\"", name))
+		}
+	}
+
+	return nil
+}
+
+// key returns the message key for the event: the partitionkey extension
+// if set, else the subject, else nil.
+func (e *CloudEvent) key() []byte {
+	if k, ok := e.Extensions[cloudEventsPartitionKey]; ok {
+		return []byte(k)
+	}
+	if e.Subject != "" {
+		return []byte(e.Subject)
+	}
+	return nil
+}
+
+// isCloudEventsAttributeName returns true if name is a valid attribute name:
+// lower-case ASCII letters and digits only.
+func isCloudEventsAttributeName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z') && !(c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// isCloudEventsContextAttribute returns true if name is a context attribute
+// defined by the specification, which can't be used as an extension name.
+func isCloudEventsContextAttribute(name string) bool {
+	switch name {
+	case "id", "source", "specversion", "type", "datacontenttype",
+		"dataschema", "subject", "time", "data", "data_base64":
+		return true
+	}
+	return false
+}
+
+// isJSONContentType returns true if contentType is JSON:
+// application/json or any +json media type.
+func isJSONContentType(contentType string) bool {
+	mediaType := strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0])
+	return mediaType == "application/json" || mediaType == "text/json" ||
+		strings.HasSuffix(mediaType, "+json")
+}
+
+// NewCloudEventMessage returns a Message for producing ev to topicPartition
+// using the CloudEvents Kafka protocol binding in the given content mode.
+//
+// In binary mode the event attributes are set as ce_* headers, the data
+// content type as the content-type header, and Data as the message value.
+// In structured mode the event is encoded as JSON in the message value,
+// with the data embedded as JSON if the data content type is JSON,
+// or base64 encoded otherwise.
+//
+// In both modes the message key is set to the "partitionkey" extension,
+// or to the event Subject, so that events with the same subject are
+// produced to the same partition.
+func NewCloudEventMessage(ev *CloudEvent, topicPartition TopicPartition, mode CloudEventsMode) (*Message, error) {
+	err := ev.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &Message{
+		TopicPartition: topicPartition,
+		Key:            ev.key(),
+	}
+
+	switch mode {
+	case CloudEventsBinary:
+		msg.Headers = ev.binaryHeaders()
+		msg.Value = ev.Data
+
+	case CloudEventsStructured:
+		msg.Value, err = ev.marshalStructured()
+		if err != nil {
+			return nil, err
+		}
+		msg.Headers = []Header{{"content-type",
+			[]byte(cloudEventsContentType + "; charset=UTF-8")}}
+
+	default:
+		return nil, newErrorFromString(ErrInvalidArg,
+			fmt.Sprintf("Invalid CloudEvents mode %v", mode))
+	}
+
+	return msg, nil
+}
+
+// binaryHeaders returns the binary mode headers for the event attributes
+func (e *CloudEvent) binaryHeaders() []Header {
+	hdr := func(name, value string) Header {
+		return Header{cloudEventsHeaderPrefix + name, []byte(value)}
+	}
+
+	specVersion := e.SpecVersion
+	if specVersion == "" {
+		specVersion = cloudEventsSpecVersion
+	}
+
+	headers := []Header{
+		hdr("specversion", specVersion),
+		hdr("id", e.ID),
+		hdr("source", e.Source),
+		hdr("type", e.Type),
+	}
+	if e.DataSchema != "" {
+		headers = append(headers, hdr("dataschema", e.DataSchema))
+	}
+	if e.Subject != "" {
+		headers = append(headers, hdr("subject", e.Subject))
+	}
+	if !e.Time.IsZero() {
+		headers = append(headers, hdr("time", e.Time.Format(time.RFC3339Nano)))
+	}
+	names := make([]string, 0, len(e.Extensions))
+	for name := range e.Extensions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		headers = append(headers, hdr(name, e.Extensions[name]))
+	}
+	if e.DataContentType != "" {
+		headers = append(headers, Header{"content-type", []byte(e.DataContentType)})
+	}
+
+	return headers
+}
+
+// marshalStructured encodes the event in the JSON event format
+func (e *CloudEvent) marshalStructured() ([]byte, error) {
+	obj := make(map[string]interface{}, 8+len(e.Extensions))
+
+	for name, value := range e.Extensions {
+		obj[name] = value
+	}
+
+	obj["specversion"] = cloudEventsSpecVersion
+	obj["id"] = e.ID
+	obj["source"] = e.Source
+	obj["type"] = e.Type
+	if e.DataContentType != "" {
+		obj["datacontenttype"] = e.DataContentType
+	}
+	if e.DataSchema != "" {
+		obj["dataschema"] = e.DataSchema
+	}
+	if e.Subject != "" {
+		obj["subject"] = e.Subject
+	}
+	if !e.Time.IsZero() {
+		obj["time"] = e.Time.Format(time.RFC3339Nano)
+	}
+
+	if e.Data != nil {
+		if (e.DataContentType == "" || isJSONContentType(e.DataContentType)) &&
+			json.Valid(e.Data) {
+			obj["data"] = json.RawMessage(e.Data)
+		} else {
+			obj["data_base64"] = base64.StdEncoding.EncodeToString(e.Data)
+		}
+	}
+
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return nil, newErrorFromString(ErrInvalidArg,
+			fmt.Sprintf("Failed to encode CloudEvent: %v", err))
+	}
+
+	return b, nil
+}
+
+// CloudEventsModeOf returns the CloudEvents content mode of msg, and false
+// if msg is not a CloudEvent.
+func CloudEventsModeOf(msg *Message) (CloudEventsMode, bool) {
+	for _, h := range msg.GetHeaders() {
+		switch {
+		case h.Key == "content-type" &&
+			strings.HasPrefix(string(h.Value), "application/cloudevents"):
+			return CloudEventsStructured, true
+		case h.Key == cloudEventsHeaderPrefix+"specversion":
+			return CloudEventsBinary, true
+		}
+	}
+
+	return CloudEventsBinary, false
+}
+
+// CloudEventFromMessage decodes the CloudEvent carried by msg, in either
+// binary or structured mode.
+// Returns an ErrInvalidArg error if msg is not a valid CloudEvent.
+//
+// A message key that differs from the event subject is returned as the
+// "partitionkey" extension.
+func CloudEventFromMessage(msg *Message) (*CloudEvent, error) {
+	mode, ok := CloudEventsModeOf(msg)
+	if !ok {
+		return nil, newErrorFromString(ErrInvalidArg,
+			"Message is not a CloudEvent: no ce_specversion or "+
+				"cloudevents content-type header")
+	}
+
+	var ev *CloudEvent
+	var err error
+	if mode == CloudEventsStructured {
+		ev, err = unmarshalStructuredCloudEvent(msg.Value)
+	} else {
+		ev, err = decodeBinaryCloudEvent(msg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if msg.Key != nil && string(msg.Key) != ev.Subject {
+		if _, ok := ev.Extensions[cloudEventsPartitionKey]; !ok {
+			if ev.Extensions == nil {
+				ev.Extensions = make(map[string]string)
+			}
+			ev.Extensions[cloudEventsPartitionKey] = string(msg.Key)
+		}
+	}
+
+	err = ev.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return ev, nil
+}
+
+// decodeBinaryCloudEvent decodes a binary mode CloudEvent from the
+// message headers and value
+func decodeBinaryCloudEvent(msg *Message) (*CloudEvent, error) {
+	ev := &CloudEvent{Data: msg.Value}
+
+	for _, h := range msg.GetHeaders() {
+		if h.Key == "content-type" {
+			ev.DataContentType = string(h.Value)
+			continue
+		}
+
+		if !strings.HasPrefix(h.Key, cloudEventsHeaderPrefix) {
+			continue
+		}
+
+		name := strings.TrimPrefix(h.Key, cloudEventsHeaderPrefix)
+		value := string(h.Value)
+		if err := ev.setAttribute(name, value); err != nil {
+			return nil, err
+		}
+	}
+
+	return ev, nil
+}
+
+// unmarshalStructuredCloudEvent decodes a structured mode CloudEvent
+func unmarshalStructuredCloudEvent(value []byte) (*CloudEvent, error) {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(value, &obj); err != nil {
+		return nil, newErrorFromString(ErrInvalidArg,
+			fmt.Sprintf("Failed to decode structured CloudEvent: %v", err))
+	}
+
+	ev := &CloudEvent{}
+
+	for name, raw := range obj {
+		switch name {
+		case "data":
+			ev.Data = []byte(raw)
+			continue
+		case "data_base64":
+			var s string
+			err := json.Unmarshal(raw, &s)
+			if err == nil {
+				ev.Data, err = base64.StdEncoding.DecodeString(s)
+			}
+			if err != nil {
+				return nil, newErrorFromString(ErrInvalidArg,
+					fmt.Sprintf("Invalid CloudEvent data_base64: %v", err))
+			}
+			continue
+		}
+
+		// Attributes are strings, extensions may also be
+		// JSON numbers or booleans, which are kept as is.
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			value = string(raw)
+		}
+		if err := ev.setAttribute(name, value); err != nil {
+			return nil, err
+		}
+	}
+
+	return ev, nil
+}
+
+// setAttribute sets the context attribute or extension name to value
+func (e *CloudEvent) setAttribute(name string, value string) error {
+	switch name {
+	case "id":
+		e.ID = value
+	case "source":
+		e.Source = value
+	case "specversion":
+		e.SpecVersion = value
+	case "type":
+		e.Type = value
+	case "datacontenttype":
+		e.DataContentType = value
+	case "dataschema":
+		e.DataSchema = value
+	case "subject":
+		e.Subject = value
+	case "time":
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return newErrorFromString(ErrInvalidArg,
+				fmt.Sprintf("Invalid CloudEvent time \"%s\": %v", value, err))
+		}
+		e.Time = t
+	default:
+		if e.Extensions == nil {
+			e.Extensions = make(map[string]string)
+		}
+		e.Extensions[name] = value
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestCloudEvents tests CloudEvents binary and structured mode round-trips
+func TestCloudEvents(t *testing.T) {
+	topic := "events"
+	tp := TopicPartition{Topic: &topic, Partition: PartitionAny}
+
+	ev := &CloudEvent{
+		ID:              "A234-1234-1234",
+		Source:          "/mycontext/subcontext",
+		SpecVersion:     "1.0",
+		Type:            "com.example.someevent",
+		DataContentType: "application/json",
+		Subject:         "order-17",
+		Time:            time.Date(2022, 3, 1, 12, 30, 0, 0, time.UTC),
+		Extensions:      map[string]string{"traceparent": "00-abc-def-01"},
+		Data:            []byte(`{"amount":42}`),
+	}
+
+	for _, mode := range []CloudEventsMode{CloudEventsBinary, CloudEventsStructured} {
+		msg, err := NewCloudEventMessage(ev, tp, mode)
+		if err != nil {
+			t.Fatalf("%v: %v", mode, err)
+		}
+
+		if string(msg.Key) != ev.Subject {
+			t.Errorf("%v: expected key %s, not %s", mode, ev.Subject, msg.Key)
+		}
+
+		detected, ok := CloudEventsModeOf(msg)
+		if !ok || detected != mode {
+			t.Errorf("%v: detected mode %v (%v)", mode, detected, ok)
+		}
+
+		ev2, err := CloudEventFromMessage(msg)
+		if err != nil {
+			t.Fatalf("%v: %v", mode, err)
+		}
+
+		if !reflect.DeepEqual(ev, ev2) {
+			t.Errorf("%v: round-trip mismatch:\n %+v\n %+v", mode, ev, ev2)
+		}
+	}
+
+	msg, _ := NewCloudEventMessage(ev, tp, CloudEventsBinary)
+	expHeaders := map[string]string{
+		"ce_specversion": "1.0",
+		"ce_id":          ev.ID,
+		"ce_time":        "2022-03-01T12:30:00Z",
+		"ce_traceparent": "00-abc-def-01",
+		"content-type":   "application/json",
+	}
+	for _, h := range msg.Headers {
+		if exp, ok := expHeaders[h.Key]; ok && exp != string(h.Value) {
+			t.Errorf("Expected header %s=%s, not %s", h.Key, exp, h.Value)
+		}
+	}
+	if string(msg.Value) != string(ev.Data) {
+		t.Errorf("Expected binary mode value %s, not %s", ev.Data, msg.Value)
+	}
+
+	// Binary data is base64 encoded in structured mode
+	bin := &CloudEvent{ID: "1", Source: "src", Type: "t",
+		DataContentType: "application/octet-stream",
+		Extensions:      map[string]string{"partitionkey": "pk"},
+		Data:            []byte{0, 1, 2, 0xff}}
+	msg, err := NewCloudEventMessage(bin, tp, CloudEventsStructured)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(msg.Key) != "pk" {
+		t.Errorf("Expected partitionkey as key, not %s", msg.Key)
+	}
+	var obj map[string]interface{}
+	if err = json.Unmarshal(msg.Value, &obj); err != nil {
+		t.Fatal(err)
+	}
+	if obj["data_base64"] != "AAEC/w==" {
+		t.Errorf("Expected data_base64, not %v", obj)
+	}
+	bin2, err := CloudEventFromMessage(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(bin.Data, bin2.Data) ||
+		bin2.Extensions["partitionkey"] != "pk" {
+		t.Errorf("Unexpected decoded event %+v", bin2)
+	}
+
+	// Invalid events
+	for _, invalid := range []*CloudEvent{
+		{Source: "src", Type: "t"},
+		{ID: "1", Source: "src", Type: "t", SpecVersion: "0.3"},
+		{ID: "1", Source: "src", Type: "t",
+			Extensions: map[string]string{"Not-Valid": "x"}},
+		{ID: "1", Source: "src", Type: "t",
+			Extensions: map[string]string{"subject": "x"}},
+	} {
+		_, err = NewCloudEventMessage(invalid, tp, CloudEventsBinary)
+		if err == nil || err.(Error).Code() != ErrInvalidArg {
+			t.Errorf("Expected ErrInvalidArg for %+v, not %v", invalid, err)
+		}
+	}
+
+	if _, err = CloudEventFromMessage(&Message{Value: []byte("x")}); err == nil {
+		t.Errorf("Expected plain message to not be a CloudEvent")
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"time"
 	"unsafe"
 )
 
@@ -217,6 +218,13 @@ func (m ConfigMap) get(key string, defval ConfigValue) (ConfigValue, error) {
 		return defval, nil
 	}
 
+	// Integral values of float properties, e.g., loaded from a file
+	if i, isInt := v.(int); isInt {
+		if _, isFloat := defval.(float64); isFloat {
+			return float64(i), nil
+		}
+	}
+
 	if defval != nil && reflect.TypeOf(defval) != reflect.TypeOf(v) {
 		return nil, newErrorFromString(ErrInvalidArg, fmt.Sprintf("%s expects type %T, not %T", key, defval, v))
 	}
@@ -237,8 +245,40 @@ func (m ConfigMap) extract(key string, defval ConfigValue) (ConfigValue, error)
 	return v, nil
 }
 
-// extractLogConfig extracts generic go.logs.* configuration properties.
-func (m ConfigMap) extractLogConfig() (logsChanEnable bool, logsChan chan LogEvent, err error) {
+// extractThrottleConfig extracts generic go.throttle.* configuration properties.
+func (m ConfigMap) extractThrottleConfig() (throttleEvents bool, backoff ThrottleBackoffPolicy, err error) {
+	v, err := m.extract("go.throttle.events", false)
+	if err != nil {
+		return
+	}
+
+	throttleEvents = v.(bool)
+
+	v, err = m.extract("go.throttle.backoff", nil)
+	if err != nil {
+		return
+	}
+
+	switch x := v.(type) {
+	case nil:
+	case bool:
+		if x {
+			backoff = ThrottleBackoffTime
+		}
+	case ThrottleBackoffPolicy:
+		backoff = x
+	case func(ThrottleEvent) time.Duration:
+		backoff = x
+	default:
+		err = newErrorFromString(ErrInvalidArg,
+			fmt.Sprintf("go.throttle.backoff expects a bool or kafka.ThrottleBackoffPolicy, not %T", v))
+	}
+
+	return
+}
+
+// extractLogConfig extracts generic go.logs.*, go.logger and go.log.callback configuration properties.
+func (m ConfigMap) extractLogConfig() (logsChanEnable bool, logsChan chan LogEvent, logger Logger, err error) {
 	v, err := m.extract("go.logs.channel.enable", false)
 	if err != nil {
 		return
@@ -255,6 +295,41 @@ func (m ConfigMap) extractLogConfig() (logsChanEnable bool, logsChan chan LogEve
 		logsChan = v.(chan LogEvent)
 	}
 
+	v, err = m.extract("go.logger", nil)
+	if err != nil {
+		return
+	}
+
+	if v != nil {
+		var ok bool
+		logger, ok = v.(Logger)
+		if !ok {
+			err = newErrorFromString(ErrInvalidArg,
+				fmt.Sprintf("go.logger expects a kafka.Logger, not %T", v))
+			return
+		}
+		logsChanEnable = true
+	}
+
+	v, err = m.extract("go.log.callback", nil)
+	if err != nil {
+		return
+	}
+
+	if v != nil {
+		switch x := v.(type) {
+		case LoggerFunc:
+			logger = x
+		case func(LogEvent):
+			logger = LoggerFunc(x)
+		default:
+			err = newErrorFromString(ErrInvalidArg,
+				fmt.Sprintf("go.log.callback expects a func(kafka.LogEvent), not %T", v))
+			return
+		}
+		logsChanEnable = true
+	}
+
 	if logsChanEnable {
 		// Tell librdkafka to forward logs to the log queue
 		m.Set("log.queue=true")
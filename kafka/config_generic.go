@@ -0,0 +1,82 @@
+//go:build go1.18
+// +build go1.18
+
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// GetConfigValue returns the value of key in m as type T,
+// or defval if the key is not set.
+//
+// String values, e.g., loaded from files or the environment, are parsed
+// when T is a bool, int, int64 or float64.
+// An ErrInvalidArg error is returned if the value can't be converted to T.
+//
+// E.g.:
+//
+//	size, err := kafka.GetConfigValue(m, "go.events.channel.size", 1000)
+func GetConfigValue[T any](m ConfigMap, key string, defval T) (T, error) {
+	v, ok := m[key]
+	if !ok {
+		return defval, nil
+	}
+
+	if t, ok := v.(T); ok {
+		return t, nil
+	}
+
+	var zero T
+	if s, ok := v.(string); ok {
+		var parsed interface{}
+		var err error
+
+		switch interface{}(zero).(type) {
+		case bool:
+			parsed, err = strconv.ParseBool(s)
+		case int:
+			parsed, err = strconv.Atoi(s)
+		case int64:
+			parsed, err = strconv.ParseInt(s, 10, 64)
+		case float64:
+			parsed, err = strconv.ParseFloat(s, 64)
+		default:
+			err = fmt.Errorf("not convertible")
+		}
+
+		if err == nil {
+			return parsed.(T), nil
+		}
+	}
+
+	return zero, newErrorFromString(ErrInvalidArg,
+		fmt.Sprintf("%s expects type %T, not %T", key, zero, v))
+}
+
+// MustGetConfigValue is like GetConfigValue but panics if the value
+// can't be converted to T.
+func MustGetConfigValue[T any](m ConfigMap, key string, defval T) T {
+	v, err := GetConfigValue(m, key, defval)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
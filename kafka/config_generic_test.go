@@ -0,0 +1,60 @@
+//go:build go1.18
+// +build go1.18
+
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"testing"
+)
+
+// TestGetConfigValue tests the generic ConfigMap getters
+func TestGetConfigValue(t *testing.T) {
+	m := ConfigMap{
+		"go.events.channel.size": 100,
+		"go.logs.channel.enable": "true",
+		"client.id":              "myclient",
+	}
+
+	if v, err := GetConfigValue(m, "go.events.channel.size", 0); err != nil || v != 100 {
+		t.Errorf("Expected 100, got %v, %v", v, err)
+	}
+
+	if v, err := GetConfigValue(m, "go.logs.channel.enable", false); err != nil || !v {
+		t.Errorf("Expected true from string, got %v, %v", v, err)
+	}
+
+	if v, err := GetConfigValue(m, "not.set", "default"); err != nil || v != "default" {
+		t.Errorf("Expected default, got %v, %v", v, err)
+	}
+
+	if _, err := GetConfigValue(m, "client.id", 0); err == nil || err.(Error).Code() != ErrInvalidArg {
+		t.Errorf("Expected ErrInvalidArg, got %v", err)
+	}
+
+	if v := MustGetConfigValue(m, "client.id", ""); v != "myclient" {
+		t.Errorf("Expected myclient, got %v", v)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected MustGetConfigValue to panic")
+		}
+	}()
+	MustGetConfigValue(m, "client.id", true)
+}
@@ -0,0 +1,339 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+/*
+#include <stdlib.h>
+#include "select_rdkafka.h"
+*/
+import "C"
+
+// configValidator validates configuration properties against
+// librdkafka's known properties using a scratch rd_kafka_conf_t.
+type configValidator struct {
+	cConf *C.rd_kafka_conf_t
+}
+
+func newConfigValidator() *configValidator {
+	return &configValidator{cConf: C.rd_kafka_conf_new()}
+}
+
+func (cv *configValidator) destroy() {
+	C.rd_kafka_conf_destroy(cv.cConf)
+}
+
+// validate returns an error if key is not a known librdkafka property
+// or value is not valid for the property.
+// Go client properties (go.*) are not validated.
+func (cv *configValidator) validate(key string, value ConfigValue) error {
+	if strings.HasPrefix(key, "go.") {
+		return nil
+	}
+	return anyconfSet((*rdkConf)(cv.cConf), strings.TrimPrefix(key, "{topic}."), value)
+}
+
+// known returns true if key is a Go client property (go.*) or a known
+// librdkafka property, including aliases and topic properties,
+// regardless of whether value is valid for the property.
+func (cv *configValidator) known(key string, value string) bool {
+	if strings.HasPrefix(key, "go.") {
+		return true
+	}
+
+	cKey := C.CString(key)
+	defer C.free(unsafe.Pointer(cKey))
+	cVal := C.CString(value)
+	defer C.free(unsafe.Pointer(cVal))
+	cErrstr := (*C.char)(C.malloc(C.size_t(128)))
+	defer C.free(unsafe.Pointer(cErrstr))
+
+	return C.rd_kafka_conf_set(cv.cConf, cKey, cVal, cErrstr, 128) != C.RD_KAFKA_CONF_UNKNOWN
+}
+
+// coerceConfigValue converts the string value of a Go client property
+// (go.*) to a bool, int or float64, if possible, since these properties
+// are typed, while librdkafka properties are passed on as strings.
+// Only "true" and "false" are bools, e.g., "1" is an int.
+func coerceConfigValue(key string, value ConfigValue) ConfigValue {
+	s, ok := value.(string)
+	if !ok || !strings.HasPrefix(key, "go.") {
+		return value
+	}
+
+	switch s {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if i, err := strconv.Atoi(s); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+	return s
+}
+
+// load validates and sets the given properties,
+// source is used in error messages.
+func (m ConfigMap) load(source string, keys []string, values map[string]ConfigValue) error {
+	cv := newConfigValidator()
+	defer cv.destroy()
+
+	for _, key := range keys {
+		value := coerceConfigValue(key, values[key])
+		if err := cv.validate(key, value); err != nil {
+			return newErrorFromString(ErrInvalidArg,
+				fmt.Sprintf("%s: %s", source, err))
+		}
+		m.SetKey(key, value)
+	}
+
+	return nil
+}
+
+// LoadFromFile loads configuration properties from a file into the
+// ConfigMap, overwriting existing properties.
+//
+// The file format is determined by the file extension:
+//
+//	.json - a JSON object, nested objects are flattened into
+//	        dot-separated keys.
+//	.yaml, .yml - a YAML mapping of scalar values, nested mappings are
+//	              flattened into dot-separated keys. Sequences, anchors
+//	              and multi-line values are not supported.
+//	any other extension - Java properties style key=value lines,
+//	                      lines starting with # or ! are comments.
+//
+// Each property is validated against librdkafka's known configuration
+// properties, Go client properties (go.*) are converted to bool, int or
+// float64 where applicable.
+func (m ConfigMap) LoadFromFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var keys []string
+	var values map[string]ConfigValue
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		keys, values, err = parseJSONConfig(data)
+	case ".yaml", ".yml":
+		keys, values, err = parseYAMLConfig(data)
+	default:
+		keys, values, err = parsePropertiesConfig(data)
+	}
+	if err != nil {
+		return newErrorFromString(ErrInvalidArg, fmt.Sprintf("%s: %s", path, err))
+	}
+
+	return m.load(path, keys, values)
+}
+
+// LoadFromEnv loads configuration properties from environment variables
+// named prefix followed by the upper-cased property name with dots
+// replaced by underscores, overwriting existing properties.
+// A double underscore maps to a literal underscore.
+//
+// E.g., with prefix "KAFKA_" the environment variable
+// KAFKA_BOOTSTRAP_SERVERS sets the bootstrap.servers property.
+//
+// Environment variables that don't map to a known property, e.g.,
+// KAFKA_HOME or KAFKA_OPTS, are ignored.
+// Each property's value is validated against librdkafka's known
+// configuration properties, Go client properties (go.*) are converted
+// to bool, int or float64 where applicable.
+func (m ConfigMap) LoadFromEnv(prefix string) error {
+	var keys []string
+	values := make(map[string]ConfigValue)
+
+	cv := newConfigValidator()
+	defer cv.destroy()
+
+	for _, env := range os.Environ() {
+		i := strings.Index(env, "=")
+		if i == -1 || !strings.HasPrefix(env[:i], prefix) || i == len(prefix) {
+			continue
+		}
+
+		name := strings.ToLower(env[len(prefix):i])
+		name = strings.Replace(name, "__", "\x00", -1)
+		name = strings.Replace(name, "_", ".", -1)
+		key := strings.Replace(name, "\x00", "_", -1)
+		if !cv.known(key, env[i+1:]) {
+			continue
+		}
+
+		keys = append(keys, key)
+		values[key] = env[i+1:]
+	}
+
+	return m.load("environment", keys, values)
+}
+
+// parsePropertiesConfig parses Java properties style key=value lines.
+func parsePropertiesConfig(data []byte) (keys []string, values map[string]ConfigValue, err error) {
+	values = make(map[string]ConfigValue)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+
+		i := strings.IndexAny(line, "=:")
+		if i == -1 {
+			return nil, nil, fmt.Errorf("line %d: expected key=value", lineno)
+		}
+
+		key := strings.TrimSpace(line[:i])
+		keys = append(keys, key)
+		values[key] = strings.TrimSpace(line[i+1:])
+	}
+
+	return keys, values, scanner.Err()
+}
+
+// parseJSONConfig parses a JSON object, flattening nested objects.
+func parseJSONConfig(data []byte) (keys []string, values map[string]ConfigValue, err error) {
+	var obj map[string]interface{}
+	if err = json.Unmarshal(data, &obj); err != nil {
+		return nil, nil, err
+	}
+
+	values = make(map[string]ConfigValue)
+
+	var flatten func(prefix string, obj map[string]interface{}) error
+	flatten = func(prefix string, obj map[string]interface{}) error {
+		for k, v := range obj {
+			key := prefix + k
+			switch x := v.(type) {
+			case map[string]interface{}:
+				if err := flatten(key+".", x); err != nil {
+					return err
+				}
+				continue
+			case float64:
+				if x == math.Trunc(x) && math.Abs(x) <= math.MaxInt32 {
+					values[key] = int(x)
+				} else {
+					values[key] = strconv.FormatFloat(x, 'f', -1, 64)
+				}
+			case bool, string:
+				values[key] = x
+			default:
+				return fmt.Errorf("unsupported value type %T for key %s", v, key)
+			}
+			keys = append(keys, key)
+		}
+		return nil
+	}
+
+	if err = flatten("", obj); err != nil {
+		return nil, nil, err
+	}
+
+	return keys, values, nil
+}
+
+// parseYAMLConfig parses a YAML mapping of scalar values,
+// flattening nested mappings.
+func parseYAMLConfig(data []byte) (keys []string, values map[string]ConfigValue, err error) {
+	values = make(map[string]ConfigValue)
+
+	type level struct {
+		indent int
+		prefix string
+	}
+	stack := []level{{indent: -1}}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineno := 1; scanner.Scan(); lineno++ {
+		raw := strings.TrimRight(scanner.Text(), " \t\r")
+		line := strings.TrimLeft(raw, " ")
+		if line == "" || line[0] == '#' || line == "---" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "- ") || line == "-" {
+			return nil, nil, fmt.Errorf("line %d: sequences are not supported", lineno)
+		}
+
+		indent := len(raw) - len(line)
+		for indent <= stack[len(stack)-1].indent {
+			stack = stack[:len(stack)-1]
+		}
+
+		i := strings.Index(line, ":")
+		if i == -1 {
+			return nil, nil, fmt.Errorf("line %d: expected key: value", lineno)
+		}
+
+		key := stack[len(stack)-1].prefix + unquoteYAML(strings.TrimSpace(line[:i]))
+		value := strings.TrimSpace(line[i+1:])
+
+		if value == "" {
+			// Nested mapping
+			stack = append(stack, level{indent: indent, prefix: key + "."})
+			continue
+		}
+
+		if value[0] != '"' && value[0] != '\'' {
+			// Strip trailing comment from unquoted values
+			if c := strings.Index(value, " #"); c != -1 {
+				value = strings.TrimSpace(value[:c])
+			}
+		}
+
+		keys = append(keys, key)
+		values[key] = unquoteYAML(value)
+	}
+
+	return keys, values, scanner.Err()
+}
+
+// unquoteYAML removes single or double quotes around a YAML scalar.
+func unquoteYAML(s string) string {
+	if len(s) >= 2 {
+		if s[0] == '"' && s[len(s)-1] == '"' {
+			if u, err := strconv.Unquote(s); err == nil {
+				return u
+			}
+		} else if s[0] == '\'' && s[len(s)-1] == '\'' {
+			return strings.Replace(s[1:len(s)-1], "''", "'", -1)
+		}
+	}
+	return s
+}
@@ -18,7 +18,12 @@ package kafka
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 )
 
 // A custom type with Stringer interface to be used to test config map APIs
@@ -150,3 +155,272 @@ func TestConfigPluginPaths(t *testing.T) {
 		}
 	}
 }
+
+// TestConfigLoadFromFile tests loading properties, JSON and YAML files
+func TestConfigLoadFromFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kafka-config")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"client.properties": `# comment
+! another comment
+bootstrap.servers=localhost:9092
+linger.ms = 5
+go.events.channel.size=100
+go.delivery.reports=false
+go.produce.size.compression.ratio=0.5
+`,
+		"client.json": `{
+  "bootstrap.servers": "localhost:9092",
+  "linger": {"ms": 5},
+  "go.events.channel.size": 100,
+  "go.delivery.reports": false,
+  "go.produce.size.compression.ratio": 0.5
+}`,
+		"client.yaml": `---
+bootstrap.servers: "localhost:9092"
+linger:
+  ms: 5 # trailing comment
+go:
+  events.channel.size: 100
+  delivery.reports: 'false'
+  produce.size.compression.ratio: 0.5
+`,
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err = ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("%s", err)
+		}
+
+		config := ConfigMap{"client.id": "keep"}
+		if err = config.LoadFromFile(path); err != nil {
+			t.Errorf("%s: LoadFromFile failed: %s", name, err)
+			continue
+		}
+
+		expected := ConfigMap{
+			"client.id":                         "keep",
+			"bootstrap.servers":                 "localhost:9092",
+			"go.events.channel.size":            100,
+			"go.delivery.reports":               false,
+			"go.produce.size.compression.ratio": 0.5,
+		}
+		for k, v := range expected {
+			if config[k] != v {
+				t.Errorf("%s: expected %s=%v (%T), got %v (%T)",
+					name, k, v, v, config[k], config[k])
+			}
+		}
+		if fmt.Sprintf("%v", config["linger.ms"]) != "5" {
+			t.Errorf("%s: expected linger.ms=5, got %v", name, config["linger.ms"])
+		}
+
+		// Typed go.* properties must be usable by extract()
+		if _, err = config.extract("go.events.channel.size", 0); err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+	}
+
+	path := filepath.Join(dir, "bad.properties")
+	ioutil.WriteFile(path, []byte("bootstrap.servers=localhost\nno.such.property=1\n"), 0644)
+	err = (ConfigMap{}).LoadFromFile(path)
+	if err == nil || err.(Error).Code() != ErrInvalidArg {
+		t.Errorf("Expected ErrInvalidArg for unknown property, got %v", err)
+	}
+
+	path = filepath.Join(dir, "bad.yaml")
+	ioutil.WriteFile(path, []byte("hosts:\n  - a\n"), 0644)
+	if err = (ConfigMap{}).LoadFromFile(path); err == nil {
+		t.Errorf("Expected error for YAML sequence")
+	}
+}
+
+// TestConfigLoadFromEnv tests loading properties from environment variables
+func TestConfigLoadFromEnv(t *testing.T) {
+	env := map[string]string{
+		"TESTKAFKA_BOOTSTRAP_SERVERS":      "localhost:9092",
+		"TESTKAFKA_GO_EVENTS_CHANNEL_SIZE": "10",
+		"TESTKAFKA_ENABLE_IDEMPOTENCE":     "true",
+	}
+	for k, v := range env {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	config := ConfigMap{}
+	if err := config.LoadFromEnv("TESTKAFKA_"); err != nil {
+		t.Fatalf("LoadFromEnv failed: %s", err)
+	}
+
+	expected := ConfigMap{
+		"bootstrap.servers":      "localhost:9092",
+		"go.events.channel.size": 10,
+		"enable.idempotence":     "true",
+	}
+	if len(config) != len(expected) {
+		t.Errorf("Expected %v, got %v", expected, config)
+	}
+	for k, v := range expected {
+		if config[k] != v {
+			t.Errorf("Expected %s=%v, got %v", k, v, config[k])
+		}
+	}
+
+	// Numeric go.* properties must not be mistaken for bools
+	coerced := map[string]ConfigValue{
+		"go.events.channel.size":            1,
+		"go.logs.channel.size":              0,
+		"go.produce.size.compression.ratio": 0.5,
+		"go.delivery.reports":               false,
+		"go.produce.size.check":             true,
+		"go.application.rebalance.enable":   "t",
+	}
+	for k, v := range coerced {
+		s := fmt.Sprintf("%v", v)
+		if c := coerceConfigValue(k, s); c != v {
+			t.Errorf("Expected %s=%s to be %v (%T), got %v (%T)", k, s, v, v, c, c)
+		}
+	}
+
+	producerEnv := map[string]string{
+		"TESTKAFKA_GO_EVENTS_CHANNEL_SIZE":            "1",
+		"TESTKAFKA_GO_LOGS_CHANNEL_SIZE":              "0",
+		"TESTKAFKA_GO_PRODUCE_SIZE_COMPRESSION_RATIO": "1",
+		"TESTKAFKA_GO_PRODUCE_SIZE_CHECK":             "true",
+	}
+	for k, v := range producerEnv {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+	config = ConfigMap{}
+	if err := config.LoadFromEnv("TESTKAFKA_"); err != nil {
+		t.Fatalf("LoadFromEnv failed: %s", err)
+	}
+	p, err := NewProducer(&config)
+	if err != nil {
+		t.Fatalf("NewProducer with %v failed: %s", config, err)
+	}
+	p.Close()
+
+	os.Setenv("TESTKAFKA_ENABLE_IDEMPOTENCE", "maybe")
+	if err := (ConfigMap{}).LoadFromEnv("TESTKAFKA_"); err == nil {
+		t.Errorf("Expected error for invalid property value")
+	}
+}
+
+// TestConfigLoadFromEnvUnrelated tests that environment variables with
+// the prefix that don't map to a known property are ignored
+func TestConfigLoadFromEnvUnrelated(t *testing.T) {
+	env := map[string]string{
+		"TESTKAFKA_HOME":               "/opt/kafka",
+		"TESTKAFKA_OPTS":               "-Dlog4j.configuration=file:log4j.properties",
+		"TESTKAFKA_HEAP_OPTS":          "-Xmx1G",
+		"TESTKAFKA_BOOTSTRAP_SERVERS":  "localhost:9092",
+		"TESTKAFKA_MESSAGE_TIMEOUT_MS": "1000",
+	}
+	for k, v := range env {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	config := ConfigMap{}
+	if err := config.LoadFromEnv("TESTKAFKA_"); err != nil {
+		t.Fatalf("LoadFromEnv failed: %s", err)
+	}
+
+	expected := ConfigMap{
+		"bootstrap.servers":  "localhost:9092",
+		"message.timeout.ms": "1000",
+	}
+	if !reflect.DeepEqual(config, expected) {
+		t.Errorf("Expected %v, got %v", expected, config)
+	}
+}
+
+// TestTypedConfig tests conversion of typed configurations to ConfigMaps
+func TestTypedConfig(t *testing.T) {
+	pc := ProducerConfig{
+		ClientConfig: ClientConfig{
+			BootstrapServers: []string{"a:9092", "b:9092"},
+			Debug:            []string{"broker", "topic"},
+			Extra:            ConfigMap{"linger.ms": 0},
+		},
+		Acks:                   AcksAll,
+		Compression:            CompressionZstd,
+		Linger:                 20 * time.Millisecond,
+		MessageTimeout:         time.Minute,
+		DisableDeliveryReports: true,
+	}
+
+	m, err := pc.ConfigMap()
+	if err != nil {
+		t.Fatalf("ConfigMap failed: %s", err)
+	}
+
+	expected := ConfigMap{
+		"bootstrap.servers":   "a:9092,b:9092",
+		"debug":               "broker,topic",
+		"acks":                "all",
+		"compression.type":    "zstd",
+		"linger.ms":           0,
+		"message.timeout.ms":  60000,
+		"go.delivery.reports": false,
+	}
+	if len(*m) != len(expected) {
+		t.Errorf("Expected %v, got %v", expected, *m)
+	}
+	for k, v := range expected {
+		if (*m)[k] != v {
+			t.Errorf("Expected %s=%v, got %v", k, v, (*m)[k])
+		}
+	}
+
+	cv := newConfigValidator()
+	defer cv.destroy()
+	for k, v := range *m {
+		if err = cv.validate(k, v); err != nil {
+			t.Errorf("Invalid property %s: %s", k, err)
+		}
+	}
+
+	cc := ConsumerConfig{
+		ClientConfig:      ClientConfig{BootstrapServers: []string{"a:9092"}},
+		GroupID:           "group",
+		AutoOffsetReset:   OffsetResetEarliest,
+		IsolationLevel:    ReadUncommitted,
+		SessionTimeout:    10 * time.Second,
+		DisableAutoCommit: true,
+	}
+
+	if m, err = cc.ConfigMap(); err != nil {
+		t.Fatalf("ConfigMap failed: %s", err)
+	}
+	if (*m)["session.timeout.ms"] != 10000 || (*m)["enable.auto.commit"] != false ||
+		(*m)["enable.auto.offset.store"] != nil || (*m)["isolation.level"] != "read_uncommitted" {
+		t.Errorf("Unexpected consumer ConfigMap %v", *m)
+	}
+
+	// Unit mistakes and missing required fields
+	for _, c := range []interface {
+		ConfigMap() (*ConfigMap, error)
+	}{
+		&ProducerConfig{},
+		&ProducerConfig{
+			ClientConfig: ClientConfig{BootstrapServers: []string{"a:9092"}},
+			Linger:       5},
+		&ProducerConfig{
+			ClientConfig: ClientConfig{BootstrapServers: []string{"a:9092"}},
+			BatchSize:    -1},
+		&ConsumerConfig{
+			ClientConfig: ClientConfig{BootstrapServers: []string{"a:9092"}}},
+	} {
+		if _, err = c.ConfigMap(); err == nil || err.(Error).Code() != ErrInvalidArg {
+			t.Errorf("Expected ErrInvalidArg for %+v, got %v", c, err)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AcksPolicy is the producer acknowledgement policy (`acks`)
+type AcksPolicy int
+
+const (
+	// AcksDefault uses the librdkafka default (all)
+	AcksDefault AcksPolicy = iota
+	// AcksNone does not wait for any broker acknowledgement
+	AcksNone
+	// AcksLeader waits for the partition leader to acknowledge the write
+	AcksLeader
+	// AcksAll waits for all in-sync replicas to acknowledge the write
+	AcksAll
+)
+
+// String returns the `acks` configuration value of the policy
+func (a AcksPolicy) String() string {
+	switch a {
+	case AcksNone:
+		return "0"
+	case AcksLeader:
+		return "1"
+	case AcksAll:
+		return "all"
+	default:
+		return ""
+	}
+}
+
+// SecurityProtocol is the protocol used to communicate with brokers
+// (`security.protocol`)
+type SecurityProtocol string
+
+const (
+	// SecurityProtocolPlaintext - unauthenticated, unencrypted
+	SecurityProtocolPlaintext SecurityProtocol = "plaintext"
+	// SecurityProtocolSSL - TLS encrypted
+	SecurityProtocolSSL SecurityProtocol = "ssl"
+	// SecurityProtocolSASLPlaintext - SASL authenticated, unencrypted
+	SecurityProtocolSASLPlaintext SecurityProtocol = "sasl_plaintext"
+	// SecurityProtocolSASLSSL - SASL authenticated, TLS encrypted
+	SecurityProtocolSASLSSL SecurityProtocol = "sasl_ssl"
+)
+
+// CompressionType is the producer compression codec (`compression.type`)
+type CompressionType string
+
+const (
+	// CompressionNone - no compression
+	CompressionNone CompressionType = "none"
+	// CompressionGzip - gzip compression
+	CompressionGzip CompressionType = "gzip"
+	// CompressionSnappy - snappy compression
+	CompressionSnappy CompressionType = "snappy"
+	// CompressionLZ4 - lz4 compression
+	CompressionLZ4 CompressionType = "lz4"
+	// CompressionZstd - zstd compression
+	CompressionZstd CompressionType = "zstd"
+)
+
+// OffsetReset is the consumer action when there is no initial offset
+// or the offset is out of range (`auto.offset.reset`)
+type OffsetReset string
+
+const (
+	// OffsetResetEarliest resets to the earliest offset
+	OffsetResetEarliest OffsetReset = "earliest"
+	// OffsetResetLatest resets to the latest offset
+	OffsetResetLatest OffsetReset = "latest"
+	// OffsetResetError emits an ErrAutoOffsetReset error
+	OffsetResetError OffsetReset = "error"
+)
+
+// IsolationLevel controls how transactional messages are read
+// (`isolation.level`)
+type IsolationLevel string
+
+const (
+	// ReadCommitted only returns committed transactional messages
+	ReadCommitted IsolationLevel = "read_committed"
+	// ReadUncommitted returns all messages, including aborted ones
+	ReadUncommitted IsolationLevel = "read_uncommitted"
+)
+
+// ClientConfig holds the configuration common to producers and consumers.
+//
+// Zero values leave the corresponding property unset, i.e., the librdkafka
+// default applies. Properties without a field, or that need to be set
+// to a zero value, can be set through Extra.
+type ClientConfig struct {
+	// BootstrapServers is the initial list of brokers as host:port (required)
+	BootstrapServers []string
+	// ClientID is the client identifier sent to brokers
+	ClientID string
+	// SecurityProtocol used to communicate with brokers
+	SecurityProtocol SecurityProtocol
+	// SASLMechanism, e.g., PLAIN, SCRAM-SHA-256, OAUTHBEARER
+	SASLMechanism string
+	// SASLUsername for the PLAIN and SCRAM mechanisms
+	SASLUsername string
+	// SASLPassword for the PLAIN and SCRAM mechanisms
+	SASLPassword string
+	// SSLCALocation is the path to the CA certificate(s) file
+	SSLCALocation string
+	// SocketTimeout is the network request timeout
+	SocketTimeout time.Duration
+	// StatisticsInterval enables Stats events at the given interval
+	StatisticsInterval time.Duration
+	// Debug is a list of debug contexts, e.g., "broker", "protocol"
+	Debug []string
+	// Extra properties, applied last and overriding the typed fields
+	Extra ConfigMap
+}
+
+// ProducerConfig is a typed producer configuration,
+// see ConfigMap() and ClientConfig.
+type ProducerConfig struct {
+	ClientConfig
+
+	// Acks is the acknowledgement policy
+	Acks AcksPolicy
+	// EnableIdempotence ensures messages are produced exactly once and in order
+	EnableIdempotence bool
+	// TransactionalID enables the transactional producer
+	TransactionalID string
+	// Compression codec
+	Compression CompressionType
+	// Linger is the time to wait for messages to accumulate in a batch
+	Linger time.Duration
+	// BatchSize is the maximum size in bytes of a message batch
+	BatchSize int
+	// MessageTimeout is the local message delivery timeout
+	MessageTimeout time.Duration
+	// DisableDeliveryReports disables delivery reports (go.delivery.reports)
+	DisableDeliveryReports bool
+}
+
+// ConsumerConfig is a typed consumer configuration,
+// see ConfigMap() and ClientConfig.
+type ConsumerConfig struct {
+	ClientConfig
+
+	// GroupID is the consumer group id (required)
+	GroupID string
+	// GroupInstanceID enables static group membership
+	GroupInstanceID string
+	// AutoOffsetReset is the action when there is no valid committed offset
+	AutoOffsetReset OffsetReset
+	// IsolationLevel for transactional messages
+	IsolationLevel IsolationLevel
+	// PartitionAssignmentStrategy, e.g., "range,roundrobin" or "cooperative-sticky"
+	PartitionAssignmentStrategy string
+	// SessionTimeout is the group session timeout
+	SessionTimeout time.Duration
+	// HeartbeatInterval is the group heartbeat interval
+	HeartbeatInterval time.Duration
+	// MaxPollInterval is the maximum time between Poll() calls
+	MaxPollInterval time.Duration
+	// AutoCommitInterval is the offset auto commit interval
+	AutoCommitInterval time.Duration
+	// DisableAutoCommit disables automatic offset commits (enable.auto.commit)
+	DisableAutoCommit bool
+	// DisableAutoOffsetStore disables storing the offset of messages
+	// returned to the application (enable.auto.offset.store)
+	DisableAutoOffsetStore bool
+	// EnablePartitionEOF emits PartitionEOF events
+	EnablePartitionEOF bool
+}
+
+// typedConfigBuilder builds a ConfigMap from typed fields,
+// recording the first error.
+type typedConfigBuilder struct {
+	m   ConfigMap
+	err error
+}
+
+func (b *typedConfigBuilder) fail(format string, args ...interface{}) {
+	if b.err == nil {
+		b.err = newErrorFromString(ErrInvalidArg, fmt.Sprintf(format, args...))
+	}
+}
+
+func (b *typedConfigBuilder) setString(key string, value string) {
+	if value != "" {
+		b.m[key] = value
+	}
+}
+
+func (b *typedConfigBuilder) setInt(key string, value int) {
+	if value < 0 {
+		b.fail("%s must not be negative", key)
+	} else if value != 0 {
+		b.m[key] = value
+	}
+}
+
+// setDuration sets a millisecond property, rejecting durations that are
+// negative or below millisecond resolution, which usually indicates a
+// unit mistake (e.g., 100 rather than 100*time.Millisecond).
+func (b *typedConfigBuilder) setDuration(key string, value time.Duration) {
+	if value < 0 {
+		b.fail("%s must not be negative", key)
+	} else if value != 0 && value%time.Millisecond != 0 {
+		b.fail("%s (%v) must be a whole number of milliseconds", key, value)
+	} else if value != 0 {
+		b.m[key] = int(value / time.Millisecond)
+	}
+}
+
+func (b *typedConfigBuilder) setBool(key string, value bool, setIf bool) {
+	if value == setIf {
+		b.m[key] = value
+	}
+}
+
+func (b *typedConfigBuilder) client(c *ClientConfig) {
+	if len(c.BootstrapServers) == 0 {
+		b.fail("BootstrapServers is required")
+	}
+	b.setString("bootstrap.servers", strings.Join(c.BootstrapServers, ","))
+	b.setString("client.id", c.ClientID)
+	b.setString("security.protocol", string(c.SecurityProtocol))
+	b.setString("sasl.mechanism", c.SASLMechanism)
+	b.setString("sasl.username", c.SASLUsername)
+	b.setString("sasl.password", c.SASLPassword)
+	b.setString("ssl.ca.location", c.SSLCALocation)
+	b.setDuration("socket.timeout.ms", c.SocketTimeout)
+	b.setDuration("statistics.interval.ms", c.StatisticsInterval)
+	b.setString("debug", strings.Join(c.Debug, ","))
+}
+
+func (b *typedConfigBuilder) build(extra ConfigMap) (*ConfigMap, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	for k, v := range extra {
+		b.m[k] = v
+	}
+	return &b.m, nil
+}
+
+// ConfigMap returns the ConfigMap for the typed producer configuration,
+// to be passed to NewProducer().
+// An ErrInvalidArg error is returned for missing required fields,
+// negative values and durations that are not whole milliseconds.
+func (c *ProducerConfig) ConfigMap() (*ConfigMap, error) {
+	b := &typedConfigBuilder{m: ConfigMap{}}
+
+	b.client(&c.ClientConfig)
+	b.setString("acks", c.Acks.String())
+	b.setBool("enable.idempotence", c.EnableIdempotence, true)
+	b.setString("transactional.id", c.TransactionalID)
+	b.setString("compression.type", string(c.Compression))
+	b.setDuration("linger.ms", c.Linger)
+	b.setInt("batch.size", c.BatchSize)
+	b.setDuration("message.timeout.ms", c.MessageTimeout)
+	b.setBool("go.delivery.reports", !c.DisableDeliveryReports, false)
+
+	return b.build(c.Extra)
+}
+
+// ConfigMap returns the ConfigMap for the typed consumer configuration,
+// to be passed to NewConsumer().
+// An ErrInvalidArg error is returned for missing required fields,
+// negative values and durations that are not whole milliseconds.
+func (c *ConsumerConfig) ConfigMap() (*ConfigMap, error) {
+	b := &typedConfigBuilder{m: ConfigMap{}}
+
+	b.client(&c.ClientConfig)
+	if c.GroupID == "" {
+		b.fail("GroupID is required")
+	}
+	b.setString("group.id", c.GroupID)
+	b.setString("group.instance.id", c.GroupInstanceID)
+	b.setString("auto.offset.reset", string(c.AutoOffsetReset))
+	b.setString("isolation.level", string(c.IsolationLevel))
+	b.setString("partition.assignment.strategy", c.PartitionAssignmentStrategy)
+	b.setDuration("session.timeout.ms", c.SessionTimeout)
+	b.setDuration("heartbeat.interval.ms", c.HeartbeatInterval)
+	b.setDuration("max.poll.interval.ms", c.MaxPollInterval)
+	b.setDuration("auto.commit.interval.ms", c.AutoCommitInterval)
+	b.setBool("enable.auto.commit", !c.DisableAutoCommit, false)
+	b.setBool("enable.auto.offset.store", !c.DisableAutoOffsetStore, false)
+	b.setBool("enable.partition.eof", c.EnablePartitionEOF, true)
+
+	return b.build(c.Extra)
+}
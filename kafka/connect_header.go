@@ -0,0 +1,476 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// ConnectHeaderType is the Kafka Connect schema type of a header value,
+// see DecodeConnectHeader().
+type ConnectHeaderType int
+
+const (
+	// ConnectHeaderInfer - infer the type from the header value,
+	// as Kafka Connect's SimpleHeaderConverter does for schemaless headers
+	ConnectHeaderInfer ConnectHeaderType = iota
+	// ConnectHeaderString - string
+	ConnectHeaderString
+	// ConnectHeaderBoolean - bool
+	ConnectHeaderBoolean
+	// ConnectHeaderInt8 - int8
+	ConnectHeaderInt8
+	// ConnectHeaderInt16 - int16
+	ConnectHeaderInt16
+	// ConnectHeaderInt32 - int32
+	ConnectHeaderInt32
+	// ConnectHeaderInt64 - int64
+	ConnectHeaderInt64
+	// ConnectHeaderFloat32 - float32
+	ConnectHeaderFloat32
+	// ConnectHeaderFloat64 - float64
+	ConnectHeaderFloat64
+	// ConnectHeaderBytes - []byte, base64 encoded in the header value
+	ConnectHeaderBytes
+	// ConnectHeaderDecimal - ConnectDecimal, the Decimal logical type
+	ConnectHeaderDecimal
+)
+
+func (t ConnectHeaderType) String() string {
+	switch t {
+	case ConnectHeaderInfer:
+		return "infer"
+	case ConnectHeaderString:
+		return "string"
+	case ConnectHeaderBoolean:
+		return "boolean"
+	case ConnectHeaderInt8:
+		return "int8"
+	case ConnectHeaderInt16:
+		return "int16"
+	case ConnectHeaderInt32:
+		return "int32"
+	case ConnectHeaderInt64:
+		return "int64"
+	case ConnectHeaderFloat32:
+		return "float32"
+	case ConnectHeaderFloat64:
+		return "float64"
+	case ConnectHeaderBytes:
+		return "bytes"
+	case ConnectHeaderDecimal:
+		return "decimal"
+	default:
+		return fmt.Sprintf("ConnectHeaderType(%d)", int(t))
+	}
+}
+
+// ConnectDecimal is a Kafka Connect Decimal logical type value:
+// Unscaled * 10^-Scale.
+type ConnectDecimal struct {
+	Unscaled *big.Int
+	Scale    int
+}
+
+// String returns the decimal in plain notation, e.g., "12.50",
+// the same representation as Java's BigDecimal.toPlainString().
+func (d ConnectDecimal) String() string {
+	if d.Unscaled == nil {
+		return "0"
+	}
+
+	digits := new(big.Int).Abs(d.Unscaled).String()
+	sign := ""
+	if d.Unscaled.Sign() < 0 {
+		sign = "-"
+	}
+
+	if d.Scale <= 0 {
+		return sign + digits + strings.Repeat("0", -d.Scale)
+	}
+
+	if len(digits) <= d.Scale {
+		digits = strings.Repeat("0", d.Scale-len(digits)+1) + digits
+	}
+
+	return sign + digits[:len(digits)-d.Scale] + "." + digits[len(digits)-d.Scale:]
+}
+
+// Rat returns the decimal as a big.Rat
+func (d ConnectDecimal) Rat() *big.Rat {
+	r := new(big.Rat)
+	if d.Unscaled == nil {
+		return r
+	}
+
+	r.SetInt(d.Unscaled)
+	scale := d.Scale
+	if scale < 0 {
+		scale = -scale
+	}
+	pow := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil))
+	if d.Scale > 0 {
+		return r.Quo(r, pow)
+	}
+	return r.Mul(r, pow)
+}
+
+// parseConnectDecimal parses a decimal in plain or scientific notation
+func parseConnectDecimal(s string) (ConnectDecimal, error) {
+	mantissa := s
+	exp := 0
+	if i := strings.IndexAny(s, "eE"); i != -1 {
+		var err error
+		exp, err = strconv.Atoi(s[i+1:])
+		if err != nil {
+			return ConnectDecimal{}, err
+		}
+		mantissa = s[:i]
+	}
+
+	scale := 0
+	if i := strings.Index(mantissa, "."); i != -1 {
+		scale = len(mantissa) - i - 1
+		mantissa = mantissa[:i] + mantissa[i+1:]
+	}
+
+	unscaled, ok := new(big.Int).SetString(mantissa, 10)
+	if !ok {
+		return ConnectDecimal{}, fmt.Errorf("invalid decimal \"%s\"", s)
+	}
+
+	return ConnectDecimal{Unscaled: unscaled, Scale: scale - exp}, nil
+}
+
+// NewConnectHeader returns a Header with value encoded the way
+// Kafka Connect's SimpleHeaderConverter encodes header values:
+// strings as is, numbers, booleans and decimals in their string
+// representation, and []byte base64 encoded.
+// A nil value results in a nil header value.
+func NewConnectHeader(key string, value interface{}) (Header, error) {
+	var s string
+
+	switch x := value.(type) {
+	case nil:
+		return Header{Key: key}, nil
+	case string:
+		s = x
+	case bool:
+		s = strconv.FormatBool(x)
+	case int:
+		s = strconv.FormatInt(int64(x), 10)
+	case int8:
+		s = strconv.FormatInt(int64(x), 10)
+	case int16:
+		s = strconv.FormatInt(int64(x), 10)
+	case int32:
+		s = strconv.FormatInt(int64(x), 10)
+	case int64:
+		s = strconv.FormatInt(x, 10)
+	case float32:
+		s = formatConnectFloat(float64(x), 32)
+	case float64:
+		s = formatConnectFloat(x, 64)
+	case []byte:
+		s = base64.StdEncoding.EncodeToString(x)
+	case ConnectDecimal:
+		s = x.String()
+	case *ConnectDecimal:
+		s = x.String()
+	default:
+		return Header{}, newErrorFromString(ErrInvalidArg,
+			fmt.Sprintf("Unsupported Connect header value type %T for header %s", value, key))
+	}
+
+	return Header{Key: key, Value: []byte(s)}, nil
+}
+
+// formatConnectFloat formats f as Java's Float/Double.toString() would
+// for the common cases: always with a fraction or exponent.
+func formatConnectFloat(f float64, bitSize int) string {
+	if math.IsInf(f, 1) {
+		return "Infinity"
+	} else if math.IsInf(f, -1) {
+		return "-Infinity"
+	} else if math.IsNaN(f) {
+		return "NaN"
+	}
+
+	s := strconv.FormatFloat(f, 'G', -1, bitSize)
+	if !strings.ContainsAny(s, ".E") {
+		s += ".0"
+	}
+	return strings.Replace(s, "E+", "E", 1)
+}
+
+// parseConnectBool parses "true" or "false", in any case, as Kafka
+// Connect does, unlike strconv.ParseBool() which also accepts "1", "t", etc.
+func parseConnectBool(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid boolean \"%s\"", s)
+}
+
+// parseConnectFloat parses a float, including Java's special values
+func parseConnectFloat(s string, bitSize int) (float64, error) {
+	switch s {
+	case "Infinity":
+		return math.Inf(1), nil
+	case "-Infinity":
+		return math.Inf(-1), nil
+	case "NaN":
+		return math.NaN(), nil
+	}
+	return strconv.ParseFloat(s, bitSize)
+}
+
+// DecodeConnectHeader decodes the value of a header written by a Kafka
+// Connect source connector, or any producer using the same encoding,
+// as the given type.
+//
+// With ConnectHeaderInfer the type is inferred from the value, as
+// SimpleHeaderConverter does when no schema is known: "true" and "false"
+// are decoded as bool, integers as the smallest fitting intN type,
+// other numbers as float64, and anything else as string.
+// Bytes and decimals can't be inferred and require their type to be given.
+//
+// Headers written by JsonConverter are decoded by DecodeConnectJSONHeader().
+//
+// A nil header value is decoded as nil.
+// Returns an ErrInvalidArg error if the value can't be decoded as typ.
+func DecodeConnectHeader(h Header, typ ConnectHeaderType) (interface{}, error) {
+	if h.Value == nil {
+		return nil, nil
+	}
+
+	s := string(h.Value)
+	var v interface{}
+	var err error
+
+	switch typ {
+	case ConnectHeaderInfer:
+		return inferConnectHeaderValue(s), nil
+	case ConnectHeaderString:
+		v = s
+	case ConnectHeaderBoolean:
+		v, err = parseConnectBool(s)
+	case ConnectHeaderInt8:
+		var i int64
+		i, err = strconv.ParseInt(s, 10, 8)
+		v = int8(i)
+	case ConnectHeaderInt16:
+		var i int64
+		i, err = strconv.ParseInt(s, 10, 16)
+		v = int16(i)
+	case ConnectHeaderInt32:
+		var i int64
+		i, err = strconv.ParseInt(s, 10, 32)
+		v = int32(i)
+	case ConnectHeaderInt64:
+		v, err = strconv.ParseInt(s, 10, 64)
+	case ConnectHeaderFloat32:
+		var f float64
+		f, err = parseConnectFloat(s, 32)
+		v = float32(f)
+	case ConnectHeaderFloat64:
+		v, err = parseConnectFloat(s, 64)
+	case ConnectHeaderBytes:
+		v, err = base64.StdEncoding.DecodeString(s)
+	case ConnectHeaderDecimal:
+		v, err = parseConnectDecimal(s)
+	default:
+		err = fmt.Errorf("unknown type")
+	}
+
+	if err != nil {
+		return nil, newErrorFromString(ErrInvalidArg,
+			fmt.Sprintf("Failed to decode header %s as Connect %s: %v", h.Key, typ, err))
+	}
+
+	return v, nil
+}
+
+// inferConnectHeaderValue infers the type of a schemaless header value
+func inferConnectHeaderValue(s string) interface{} {
+	switch strings.ToLower(s) {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		switch {
+		case i >= math.MinInt8 && i <= math.MaxInt8:
+			return int8(i)
+		case i >= math.MinInt16 && i <= math.MaxInt16:
+			return int16(i)
+		case i >= math.MinInt32 && i <= math.MaxInt32:
+			return int32(i)
+		default:
+			return i
+		}
+	}
+
+	// Only plain decimal numbers are inferred as floats, not
+	// strings such as "NaN" or "Inf" that ParseFloat also accepts.
+	if strings.IndexFunc(s, func(r rune) bool {
+		return !strings.ContainsRune("0123456789.eE+-", r)
+	}) == -1 {
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	}
+
+	return s
+}
+
+// connectDecimalSchemaName is the schema name of the Decimal logical type
+const connectDecimalSchemaName = "org.apache.kafka.connect.data.Decimal"
+
+// connectJSONHeader is a header value written by Kafka Connect's
+// JsonConverter with schemas.enable=true.
+type connectJSONHeader struct {
+	Schema *struct {
+		Type       string            `json:"type"`
+		Name       string            `json:"name"`
+		Parameters map[string]string `json:"parameters"`
+	} `json:"schema"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// DecodeConnectJSONHeader decodes the value of a header written by Kafka
+// Connect's JsonConverter with schemas enabled, a JSON object with the
+// schema and payload of the value, to the Go type of the schema type,
+// as DecodeConnectHeader() does for the corresponding ConnectHeaderType.
+// Decimals are decoded from either decimal.format, BASE64 or NUMERIC.
+//
+// Structs, arrays and maps are not supported. Schemaless JsonConverter
+// headers are plain JSON values, which can be decoded with encoding/json.
+//
+// A nil header value or null payload is decoded as nil.
+// Returns an ErrInvalidArg error if the value can't be decoded.
+func DecodeConnectJSONHeader(h Header) (interface{}, error) {
+	if h.Value == nil {
+		return nil, nil
+	}
+
+	var env connectJSONHeader
+	err := json.Unmarshal(h.Value, &env)
+	if err == nil && env.Schema == nil {
+		err = fmt.Errorf("no schema")
+	}
+	if err != nil {
+		return nil, newErrorFromString(ErrInvalidArg,
+			fmt.Sprintf("Failed to decode header %s as Connect JSON: %v", h.Key, err))
+	}
+
+	if len(env.Payload) == 0 || string(env.Payload) == "null" {
+		return nil, nil
+	}
+
+	var v interface{}
+	switch env.Schema.Type {
+	case "string":
+		var x string
+		err = json.Unmarshal(env.Payload, &x)
+		v = x
+	case "boolean":
+		var x bool
+		err = json.Unmarshal(env.Payload, &x)
+		v = x
+	case "int8":
+		var x int8
+		err = json.Unmarshal(env.Payload, &x)
+		v = x
+	case "int16":
+		var x int16
+		err = json.Unmarshal(env.Payload, &x)
+		v = x
+	case "int32":
+		var x int32
+		err = json.Unmarshal(env.Payload, &x)
+		v = x
+	case "int64":
+		var x int64
+		err = json.Unmarshal(env.Payload, &x)
+		v = x
+	case "float":
+		var x float32
+		err = json.Unmarshal(env.Payload, &x)
+		v = x
+	case "double":
+		var x float64
+		err = json.Unmarshal(env.Payload, &x)
+		v = x
+	case "bytes":
+		if env.Schema.Name == connectDecimalSchemaName {
+			v, err = decodeConnectJSONDecimal(env.Schema.Parameters["scale"], env.Payload)
+			break
+		}
+		var x []byte
+		err = json.Unmarshal(env.Payload, &x)
+		v = x
+	default:
+		err = fmt.Errorf("unsupported schema type \"%s\"", env.Schema.Type)
+	}
+
+	if err != nil {
+		return nil, newErrorFromString(ErrInvalidArg,
+			fmt.Sprintf("Failed to decode header %s as Connect JSON %s: %v",
+				h.Key, env.Schema.Type, err))
+	}
+
+	return v, nil
+}
+
+// decodeConnectJSONDecimal decodes a JsonConverter Decimal payload:
+// the base64 encoded big-endian two's complement unscaled value,
+// or a JSON number.
+func decodeConnectJSONDecimal(scale string, payload json.RawMessage) (ConnectDecimal, error) {
+	var b []byte
+	if err := json.Unmarshal(payload, &b); err != nil {
+		// decimal.format=NUMERIC
+		var n json.Number
+		if json.Unmarshal(payload, &n) != nil {
+			return ConnectDecimal{}, err
+		}
+		return parseConnectDecimal(n.String())
+	}
+
+	d := ConnectDecimal{Unscaled: new(big.Int).SetBytes(b)}
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		d.Unscaled.Sub(d.Unscaled, new(big.Int).Lsh(big.NewInt(1), uint(8*len(b))))
+	}
+
+	var err error
+	if d.Scale, err = strconv.Atoi(scale); err != nil {
+		return ConnectDecimal{}, fmt.Errorf("invalid decimal scale \"%s\"", scale)
+	}
+
+	return d, nil
+}
@@ -0,0 +1,169 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+// TestConnectHeader tests Kafka Connect header value encoding and decoding
+func TestConnectHeader(t *testing.T) {
+	dec := ConnectDecimal{Unscaled: big.NewInt(-1250), Scale: 2}
+
+	for _, c := range []struct {
+		value   interface{}
+		encoded string
+		typ     ConnectHeaderType
+	}{
+		{"hello", "hello", ConnectHeaderString},
+		{true, "true", ConnectHeaderBoolean},
+		{int8(-5), "-5", ConnectHeaderInt8},
+		{int16(1000), "1000", ConnectHeaderInt16},
+		{int32(70000), "70000", ConnectHeaderInt32},
+		{int64(1) << 40, "1099511627776", ConnectHeaderInt64},
+		{float32(1.5), "1.5", ConnectHeaderFloat32},
+		{float64(3), "3.0", ConnectHeaderFloat64},
+		{[]byte{0, 1, 0xfe}, "AAH+", ConnectHeaderBytes},
+		{dec, "-12.50", ConnectHeaderDecimal},
+	} {
+		h, err := NewConnectHeader("k", c.value)
+		if err != nil {
+			t.Fatalf("%v: %v", c.value, err)
+		}
+		if string(h.Value) != c.encoded {
+			t.Errorf("Expected %v to be encoded as %s, not %s",
+				c.value, c.encoded, h.Value)
+		}
+
+		v, err := DecodeConnectHeader(h, c.typ)
+		if err != nil {
+			t.Fatalf("%s as %v: %v", h.Value, c.typ, err)
+		}
+
+		if d, ok := v.(ConnectDecimal); ok {
+			if d.Rat().Cmp(dec.Rat()) != 0 || d.String() != dec.String() {
+				t.Errorf("Expected decimal %v, not %v", dec, d)
+			}
+		} else if !reflect.DeepEqual(v, c.value) {
+			t.Errorf("Expected %s as %v to decode to %#v, not %#v",
+				h.Value, c.typ, c.value, v)
+		}
+	}
+
+	// Inferred (schemaless) values
+	for encoded, exp := range map[string]interface{}{
+		"TRUE":        true,
+		"false":       false,
+		"12":          int8(12),
+		"300":         int16(300),
+		"-40000":      int32(-40000),
+		"5000000000":  int64(5000000000),
+		"2.25":        2.25,
+		"1e3":         1000.0,
+		"NaN":         "NaN",
+		"some string": "some string",
+	} {
+		v, err := DecodeConnectHeader(Header{"k", []byte(encoded)}, ConnectHeaderInfer)
+		if err != nil || !reflect.DeepEqual(v, exp) {
+			t.Errorf("Expected %s to be inferred as %#v, not %#v (%v)",
+				encoded, exp, v, err)
+		}
+	}
+
+	// Scientific notation decimals, as written by BigDecimal.toString()
+	v, err := DecodeConnectHeader(Header{"k", []byte("1.2E+3")}, ConnectHeaderDecimal)
+	if err != nil || v.(ConnectDecimal).String() != "1200" {
+		t.Errorf("Expected decimal 1200, not %v (%v)", v, err)
+	}
+
+	// Null values
+	h, _ := NewConnectHeader("k", nil)
+	if h.Value != nil {
+		t.Errorf("Expected nil header value, not %v", h.Value)
+	}
+	if v, err = DecodeConnectHeader(h, ConnectHeaderInt32); v != nil || err != nil {
+		t.Errorf("Expected nil, not %v (%v)", v, err)
+	}
+
+	// Errors
+	if _, err = NewConnectHeader("k", struct{}{}); err == nil {
+		t.Errorf("Expected unsupported type error")
+	}
+	for _, typ := range []ConnectHeaderType{ConnectHeaderInt8,
+		ConnectHeaderBoolean, ConnectHeaderBytes, ConnectHeaderDecimal} {
+		_, err = DecodeConnectHeader(Header{"k", []byte("1000 x")}, typ)
+		if err == nil || err.(Error).Code() != ErrInvalidArg {
+			t.Errorf("Expected ErrInvalidArg decoding as %v, not %v", typ, err)
+		}
+	}
+
+	// Only true and false are booleans
+	for _, encoded := range []string{"1", "t", "F"} {
+		if v, err = DecodeConnectHeader(Header{"k", []byte(encoded)}, ConnectHeaderBoolean); err == nil {
+			t.Errorf("Expected %s not to decode as boolean, got %v", encoded, v)
+		}
+	}
+	if v, err = DecodeConnectHeader(Header{"k", []byte("False")}, ConnectHeaderBoolean); err != nil || v != false {
+		t.Errorf("Expected false, not %v (%v)", v, err)
+	}
+}
+
+// TestConnectJSONHeader tests decoding of JsonConverter headers with schemas
+func TestConnectJSONHeader(t *testing.T) {
+	for encoded, exp := range map[string]interface{}{
+		`{"schema":{"type":"string","optional":false},"payload":"hello"}`: "hello",
+		`{"schema":{"type":"boolean"},"payload":true}`:                    true,
+		`{"schema":{"type":"int8"},"payload":-5}`:                         int8(-5),
+		`{"schema":{"type":"int16"},"payload":1000}`:                      int16(1000),
+		`{"schema":{"type":"int32"},"payload":70000}`:                     int32(70000),
+		`{"schema":{"type":"int64"},"payload":1099511627776}`:             int64(1) << 40,
+		`{"schema":{"type":"float"},"payload":1.5}`:                       float32(1.5),
+		`{"schema":{"type":"double"},"payload":3.0}`:                      float64(3),
+		`{"schema":{"type":"bytes"},"payload":"AAH+"}`:                    []byte{0, 1, 0xfe},
+		`{"schema":{"type":"int32","optional":true},"payload":null}`:      nil,
+	} {
+		v, err := DecodeConnectJSONHeader(Header{"k", []byte(encoded)})
+		if err != nil || !reflect.DeepEqual(v, exp) {
+			t.Errorf("Expected %s to decode to %#v, not %#v (%v)", encoded, exp, v, err)
+		}
+	}
+
+	// Decimals, -12.50 as BASE64 (0xfb1e) and NUMERIC
+	for _, payload := range []string{`"+x4="`, `-12.50`} {
+		encoded := `{"schema":{"type":"bytes","name":"org.apache.kafka.connect.data.Decimal",` +
+			`"parameters":{"scale":"2"}},"payload":` + payload + `}`
+		v, err := DecodeConnectJSONHeader(Header{"k", []byte(encoded)})
+		if d, ok := v.(ConnectDecimal); !ok || d.String() != "-12.50" {
+			t.Errorf("Expected decimal -12.50 from %s, not %v (%v)", payload, v, err)
+		}
+	}
+
+	for _, encoded := range []string{
+		`hello`,
+		`{"payload":1}`,
+		`{"schema":{"type":"int8"},"payload":300}`,
+		`{"schema":{"type":"boolean"},"payload":"true"}`,
+		`{"schema":{"type":"struct","fields":[]},"payload":{}}`,
+	} {
+		_, err := DecodeConnectJSONHeader(Header{"k", []byte(encoded)})
+		if err == nil || err.(Error).Code() != ErrInvalidArg {
+			t.Errorf("Expected ErrInvalidArg decoding %s, not %v", encoded, err)
+		}
+	}
+}
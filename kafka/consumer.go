@@ -17,6 +17,7 @@ package kafka
  */
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"time"
@@ -47,6 +48,10 @@ type Consumer struct {
 	rebalanceCb        RebalanceCb
 	appReassigned      bool
 	appRebalanceEnable bool // Config setting
+	prefetch           prefetchBudget
+	rebalanceHistory   rebalanceHistory
+	commitCb           CommitCallback
+	ranges             rangeAssignment
 }
 
 // Strings returns a human readable name for a Consumer instance
@@ -104,6 +109,7 @@ func (c *Consumer) Unsubscribe() (err error) {
 // This replaces the current assignment.
 func (c *Consumer) Assign(partitions []TopicPartition) (err error) {
 	c.appReassigned = true
+	c.ranges.reset()
 
 	cparts := newCPartsFromTopicPartitions(partitions)
 	defer C.rd_kafka_topic_partition_list_destroy(cparts)
@@ -113,18 +119,23 @@ func (c *Consumer) Assign(partitions []TopicPartition) (err error) {
 		return newError(e)
 	}
 
+	c.rebalanceHistory.assigned(c)
+
 	return nil
 }
 
 // Unassign the current set of partitions to consume.
 func (c *Consumer) Unassign() (err error) {
 	c.appReassigned = true
+	c.ranges.reset()
 
 	e := C.rd_kafka_assign(c.handle.rk, nil)
 	if e != C.RD_KAFKA_RESP_ERR_NO_ERROR {
 		return newError(e)
 	}
 
+	c.rebalanceHistory.assigned(c)
+
 	return nil
 }
 
@@ -149,6 +160,8 @@ func (c *Consumer) IncrementalAssign(partitions []TopicPartition) (err error) {
 		return newErrorFromCErrorDestroy(cError)
 	}
 
+	c.rebalanceHistory.assigned(c)
+
 	return nil
 }
 
@@ -169,6 +182,8 @@ func (c *Consumer) IncrementalUnassign(partitions []TopicPartition) (err error)
 		return newErrorFromCErrorDestroy(cError)
 	}
 
+	c.rebalanceHistory.assigned(c)
+
 	return nil
 }
 
@@ -213,7 +228,11 @@ func (c *Consumer) commit(offsets []TopicPartition) (committedOffsets []TopicPar
 
 	cErr := C.rd_kafka_commit_queue(c.handle.rk, coffsets, rkqu, nil, nil)
 	if cErr != C.RD_KAFKA_RESP_ERR_NO_ERROR {
-		return nil, newError(cErr)
+		err = newError(cErr)
+		if c.commitCb != nil {
+			c.commitCb(OffsetsCommitted{Error: err, Offsets: offsets})
+		}
+		return nil, err
 	}
 
 	rkev := C.rd_kafka_queue_poll(rkqu, C.int(-1))
@@ -230,16 +249,27 @@ func (c *Consumer) commit(offsets []TopicPartition) (committedOffsets []TopicPar
 
 	cErr = C.rd_kafka_event_error(rkev)
 	if cErr != C.RD_KAFKA_RESP_ERR_NO_ERROR {
-		return nil, newErrorFromCString(cErr, C.rd_kafka_event_error_string(rkev))
+		err = newErrorFromCString(cErr, C.rd_kafka_event_error_string(rkev))
+		if c.commitCb != nil {
+			c.commitCb(cEventToOffsetsCommitted(rkev))
+		}
+		return nil, err
 	}
 
 	cRetoffsets := C.rd_kafka_event_topic_partition_list(rkev)
 	if cRetoffsets == nil {
 		// no offsets, no error
+		if c.commitCb != nil {
+			c.commitCb(OffsetsCommitted{})
+		}
 		return nil, nil
 	}
 	committedOffsets = newTopicPartitionsFromCparts(cRetoffsets)
 
+	if c.commitCb != nil {
+		c.commitCb(OffsetsCommitted{Offsets: committedOffsets})
+	}
+
 	return committedOffsets, nil
 }
 
@@ -341,8 +371,41 @@ func (c *Consumer) Seek(partition TopicPartition, timeoutMs int) error {
 //
 // Returns nil on timeout, else an Event
 func (c *Consumer) Poll(timeoutMs int) (event Event) {
+	timeoutMs, ok := c.handle.waitThrottleBackoff(timeoutMs, c.readerTermChan)
+	if !ok {
+		return nil
+	}
+
+	if ev := c.ranges.nextPending(); ev != nil {
+		return ev
+	}
+
 	ev, _ := c.handle.eventPoll(nil, timeoutMs, 1, nil)
-	return ev
+	return c.ranges.filter(c, ev)
+}
+
+// PollMessage polls the consumer for messages or events as Poll() does,
+// but sets up a consumed message in the application-provided msg, which
+// is then returned, instead of allocating a new Message.
+//
+// The storage of msg's previous topic, key, value and headers is reused
+// when large enough, so the application must not retain them, nor msg,
+// across calls: use Message.Clone() to keep a message.
+// With `go.zerocopy.enable` msg's previous message is released.
+//
+// Returns nil on timeout, else msg or another Event
+func (c *Consumer) PollMessage(msg *Message, timeoutMs int) (event Event) {
+	timeoutMs, ok := c.handle.waitThrottleBackoff(timeoutMs, c.readerTermChan)
+	if !ok {
+		return nil
+	}
+
+	if ev := c.ranges.nextPending(); ev != nil {
+		return ev
+	}
+
+	ev, _ := c.handle.eventPollInto(msg, nil, timeoutMs, 1, nil)
+	return c.ranges.filter(c, ev)
 }
 
 // Events returns the Events channel (if enabled)
@@ -441,12 +504,16 @@ func (c *Consumer) Close() (err error) {
 			run = false
 
 		default:
-			c.Poll(100)
+			if msg, ok := c.Poll(100).(*Message); ok {
+				msg.Release()
+			}
 		}
 	}
 
 	close(doneChan)
 
+	c.handle.releaseZeroCopyEvents()
+
 	// Destroy our queue
 	C.rd_kafka_queue_destroy(c.handle.rkq)
 	c.handle.rkq = nil
@@ -469,8 +536,51 @@ func (c *Consumer) Close() (err error) {
 //                                        respectively.
 //   go.events.channel.enable (bool, false) - [deprecated] Enable the Events() channel. Messages and events will be pushed on the Events() channel and the Poll() interface will be disabled.
 //   go.events.channel.size (int, 1000) - Events() channel size
+//   go.events.channel.overflow (string, "block") - Policy for events that don't fit in the full Events() channel:
+//                                        "block" blocks until the application reads the channel, "drop-oldest" drops
+//                                        the oldest event in the channel, counted by DroppedEvents(), "callback" passes
+//                                        the event to go.overflow.callback. "drop-oldest" requires a buffered channel
+//                                        and can't be combined with go.application.rebalance.enable.
+//   go.overflow.callback (func(kafka.Event), nil) - Called from the poller for events and log events that don't fit
+//                                        in their channel with the "callback" overflow policy.
+//   go.statistics.parse (bool, false) - Emit parsed *StatsEvent events instead of raw JSON *Stats events.
+//   go.topic.intern (bool, false) - If enabled, messages of the same topic share the TopicPartition.Topic string pointer,
+//                                   which must then not be modified by the application.
+//   go.zerocopy.enable (bool, false) - Let consumed messages' Key and Value refer to librdkafka's buffers instead of copies,
+//                                      the application must call Message.Release() when done with each message.
+//                                      Messages garbage collected without being released are counted by ZeroCopyLeaks(),
+//                                      unreleased buffers are freed by Close(). Message.Clone() returns a regular message.
+//   go.prefetch.max.bytes (int, 0) - Bound the bytes of prefetched messages not yet consumed across all assigned partitions,
+//                                    pausing the partitions with the largest backlog, which discards their prefetched messages,
+//                                    while it's exceeded, see PrefetchPaused(). 0 disables the budget.
+//                                    The backlog is derived from the statistics (requires statistics.interval.ms).
+//   go.rebalance.history.size (int, 0) - Record the last rebalances: partitions added and removed, generation and duration,
+//                                        see RebalanceHistory(). 0 disables the recording.
+//   go.integrity.verify (bool, false) - Verify consumed messages' values against their go.integrity.checksum header, if any,
+//                                       setting TopicPartition.Error to an ErrBadMsg error on mismatch. Parses lazy headers.
+//   go.headers.lazy (bool, false) - Leave consumed messages' headers unparsed, and Headers nil, until Message.GetHeaders() is called,
+//                                   for applications that seldom read headers. Message.Release() pools the parsed headers,
+//                                   which, including their values, must not be used after Release().
+//   go.broker.state.events (bool, false) - Emit BrokerDown and AllBrokersDown events instead of the corresponding
+//                                          Errors, and BrokerUp events (requires statistics.interval.ms).
+//   go.throttle.events (bool, false) - Emit ThrottleEvent events for brokers throttling the client (requires statistics.interval.ms).
+//   go.throttle.backoff (bool or kafka.ThrottleBackoffPolicy, nil) - Delay Poll() calls while brokers throttle the client, true backs off
+//                                          for the broker's throttle time (requires statistics.interval.ms).
+//                                          This adds to librdkafka's own throttling of the requests to the throttling brokers.
 //   go.logs.channel.enable (bool, false) - Forward log to Logs() channel.
 //   go.logs.channel (chan kafka.LogEvent, nil) - Forward logs to application-provided channel instead of Logs(). Requires go.logs.channel.enable=true.
+//   go.logs.channel.size (int, 10000) - Logs() channel size
+//   go.logs.channel.overflow (string, "block") - Policy for log events that don't fit in the full Logs() or go.logs.channel
+//                                        channel, see go.events.channel.overflow. Dropped log events are counted by DroppedLogs().
+//   go.logger (kafka.Logger, nil) - Forward logs to the application-provided Logger instead of Logs(), see NewSlogLogger().
+//   go.log.callback (func(kafka.LogEvent), nil) - Call the application-provided function for each log instead of forwarding logs to Logs().
+//   go.stats.callback (kafka.StatsCallback, nil) - Call the application-provided function with the statistics JSON instead of emitting Stats events on Poll() or Events().
+//   go.statistics.callback (kafka.StatisticsCallback, nil) - Call the application-provided function with the parsed statistics,
+//                                          parsed without copying the JSON document, instead of emitting statistics events.
+//   go.error.callback (kafka.ErrorCallback, nil) - Call the application-provided function for each client Error instead of emitting Error events on Poll() or Events().
+//   go.commit.callback (kafka.CommitCallback, nil) - Call the application-provided function with the result of every offset commit,
+//                                          manual or automatic, successful or failed, with the per-partition offsets and errors.
+//                                          Automatic commits are still emitted as OffsetsCommitted events.
 //
 // WARNING: Due to the buffering nature of channels (and queues in general) the
 // use of the events channel risks receiving outdated events and
@@ -517,10 +627,99 @@ func NewConsumer(conf *ConfigMap) (*Consumer, error) {
 	}
 	eventsChanSize := v.(int)
 
-	logsChanEnable, logsChan, err := confCopy.extractLogConfig()
+	v, err = confCopy.extract("go.logs.channel.size", 10000)
+	if err != nil {
+		return nil, err
+	}
+	logsChanSize := v.(int)
+
+	c.handle.eventsOverflow, c.handle.logsOverflow, c.handle.overflowCb, err = confCopy.extractOverflowConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if c.appRebalanceEnable && c.handle.eventsOverflow == overflowDropOldest {
+		return nil, newErrorFromString(ErrInvalidArg,
+			"go.events.channel.overflow=drop-oldest could drop the rebalance events of go.application.rebalance.enable")
+	}
+
+	v, err = confCopy.extract("go.statistics.parse", false)
+	if err != nil {
+		return nil, err
+	}
+	c.handle.parseStats = v.(bool)
+
+	v, err = confCopy.extract("go.zerocopy.enable", false)
+	if err != nil {
+		return nil, err
+	}
+	c.handle.zeroCopy = v.(bool)
+
+	v, err = confCopy.extract("go.topic.intern", false)
 	if err != nil {
 		return nil, err
 	}
+	c.handle.internTopics = v.(bool)
+
+	v, err = confCopy.extract("go.prefetch.max.bytes", 0)
+	if err != nil {
+		return nil, err
+	}
+	c.prefetch.maxBytes = int64(v.(int))
+	c.prefetch.paused = make(map[partitionKey]TopicPartition)
+	c.prefetch.appPaused = make(map[partitionKey]bool)
+
+	v, err = confCopy.extract("go.rebalance.history.size", 0)
+	if err != nil {
+		return nil, err
+	}
+	if v.(int) < 0 {
+		return nil, newErrorFromString(ErrInvalidArg,
+			"go.rebalance.history.size must not be negative")
+	}
+	c.rebalanceHistory.records = make([]RebalanceRecord, 0, v.(int))
+
+	v, err = confCopy.extract("go.headers.lazy", false)
+	if err != nil {
+		return nil, err
+	}
+	c.handle.lazyHeaders = v.(bool)
+
+	v, err = confCopy.extract("go.integrity.verify", false)
+	if err != nil {
+		return nil, err
+	}
+	c.handle.integrityVerify = v.(bool)
+
+	v, err = confCopy.extract("go.broker.state.events", false)
+	if err != nil {
+		return nil, err
+	}
+	c.handle.brokerStateEvents = v.(bool)
+
+	c.handle.throttleEvents, c.handle.throttleBackoff, err = confCopy.extractThrottleConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	c.handle.statsCb, c.handle.statisticsCb, c.handle.errorCb, err = confCopy.extractCallbackConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	c.commitCb, err = confCopy.extractCommitCallbackConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	logsChanEnable, logsChan, logger, err := confCopy.extractLogConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = c.handle.checkDropOldest(eventsChanSize, logsChanEnable, logsChan, logsChanSize); err != nil {
+		return nil, err
+	}
 
 	cConf, err := confCopy.convert()
 	if err != nil {
@@ -540,6 +739,7 @@ func NewConsumer(conf *ConfigMap) (*Consumer, error) {
 
 	c.handle.c = c
 	c.handle.setup()
+	c.handle.register("consumer", conf)
 	c.readerTermChan = make(chan bool)
 	c.handle.rkq = C.rd_kafka_queue_get_consumer(c.handle.rk)
 	if c.handle.rkq == nil {
@@ -548,7 +748,7 @@ func NewConsumer(conf *ConfigMap) (*Consumer, error) {
 	}
 
 	if logsChanEnable {
-		c.handle.setupLogQueue(logsChan, c.readerTermChan)
+		c.handle.setupLogQueue(logsChan, logsChanSize, logger, c.readerTermChan)
 	}
 
 	if c.eventsChanEnable {
@@ -591,6 +791,17 @@ func (c *Consumer) GetMetadata(topic *string, allTopics bool, timeoutMs int) (*M
 	return getMetadata(c, topic, allTopics, timeoutMs)
 }
 
+// Metadata queries the cluster for the metadata of the topics selected
+// by opts, until ctx is done.
+// The metadata includes the controller and cluster ids, the brokers'
+// racks and the topic ids are not provided by this librdkafka version.
+//
+// Note on cancellation: the request is bound by ctx's deadline, if any,
+// but it currently cannot be manually cancelled.
+func (c *Consumer) Metadata(ctx context.Context, opts MetadataOptions) (*Metadata, error) {
+	return metadata(c, ctx, opts)
+}
+
 // QueryWatermarkOffsets queries the broker for the low and high offsets for the given topic and partition.
 func (c *Consumer) QueryWatermarkOffsets(topic string, partition int32, timeoutMs int) (low, high int64, err error) {
 	return queryWatermarkOffsets(c, topic, partition, timeoutMs)
@@ -694,6 +905,7 @@ func (c *Consumer) Position(partitions []TopicPartition) (offsets []TopicPartiti
 // (if `go.events.channel.enable` has been set) will NOT be purged by
 // this call, set `go.events.channel.size` accordingly.
 func (c *Consumer) Pause(partitions []TopicPartition) (err error) {
+	c.prefetch.appPause(partitions, true)
 	cparts := newCPartsFromTopicPartitions(partitions)
 	defer C.rd_kafka_topic_partition_list_destroy(cparts)
 	cerr := C.rd_kafka_pause_partitions(c.handle.rk, cparts)
@@ -705,6 +917,7 @@ func (c *Consumer) Pause(partitions []TopicPartition) (err error) {
 
 // Resume consumption for the provided list of partitions
 func (c *Consumer) Resume(partitions []TopicPartition) (err error) {
+	c.prefetch.appPause(partitions, false)
 	cparts := newCPartsFromTopicPartitions(partitions)
 	defer C.rd_kafka_topic_partition_list_destroy(cparts)
 	cerr := C.rd_kafka_resume_partitions(c.handle.rk, cparts)
@@ -856,6 +1069,8 @@ func (c *Consumer) handleRebalanceEvent(channel chan Event, rkev *C.rd_kafka_eve
 
 	var ev Event
 
+	c.rebalanceHistory.record(c, rkev)
+
 	if c.rebalanceCb != nil || c.appRebalanceEnable {
 		// Application has a rebalance callback or has enabled
 		// rebalances on the events channel, create the appropriate Event.
@@ -920,6 +1135,8 @@ func (c *Consumer) handleRebalanceEvent(channel chan Event, rkev *C.rd_kafka_eve
 		c.events <- newErrorFromCErrorDestroy(cError)
 	} else if cErr != 0 {
 		c.events <- newError(cErr)
+	} else {
+		c.rebalanceHistory.assigned(c)
 	}
 
 	return nil
@@ -702,3 +702,178 @@ func testConsumerWaitAssignment(c *Consumer, t *testing.T) {
 		}
 	}
 }
+
+// TestConsumerPollMessage tests PollMessage() reusing a Message.
+func TestConsumerPollMessage(t *testing.T) {
+	mc, err := NewMockCluster(1)
+	if err != nil {
+		t.Fatalf("Failed to create MockCluster: %v", err)
+	}
+	defer mc.Close()
+
+	topic := "pollmessage"
+
+	p, err := NewProducer(&ConfigMap{"bootstrap.servers": mc.BootstrapServers()})
+	if err != nil {
+		t.Fatalf("Failed to create Producer: %v", err)
+	}
+	defer p.Close()
+
+	// Messages with shrinking and growing keys, values and headers
+	expected := []*Message{
+		{Key: []byte("key-0"), Value: []byte("a longer value"),
+			Headers: []Header{{"h1", []byte("v1")}, {"h2", nil}}},
+		{Value: []byte("short")},
+		{Key: []byte("k"), Value: []byte("a much longer value than before"),
+			Headers: []Header{{"h3", []byte("value 3")}}},
+	}
+
+	drChan := make(chan Event, len(expected))
+	for _, m := range expected {
+		m.TopicPartition = TopicPartition{Topic: &topic, Partition: 0}
+		if err = p.Produce(m.Clone(), drChan); err != nil {
+			t.Fatalf("Produce failed: %v", err)
+		}
+	}
+	for range expected {
+		if m := (<-drChan).(*Message); m.TopicPartition.Error != nil {
+			t.Fatalf("Delivery failed: %v", m.TopicPartition)
+		}
+	}
+
+	c, err := NewConsumer(&ConfigMap{
+		"bootstrap.servers":  mc.BootstrapServers(),
+		"group.id":           "pollmessage",
+		"enable.auto.commit": false,
+		"auto.offset.reset":  "earliest"})
+	if err != nil {
+		t.Fatalf("Failed to create Consumer: %v", err)
+	}
+	defer c.Close()
+
+	if err = c.Assign([]TopicPartition{{Topic: &topic, Partition: 0}}); err != nil {
+		t.Fatalf("Assign failed: %v", err)
+	}
+
+	msg := &Message{Opaque: "stale"}
+	tEnd := time.Now().Add(10 * time.Second)
+	for i := 0; i < len(expected); {
+		if time.Now().After(tEnd) {
+			t.Fatalf("Timed out waiting for message %d", i)
+		}
+
+		ev := c.PollMessage(msg, 100)
+		if ev == nil {
+			continue
+		}
+		if ev != Event(msg) {
+			t.Fatalf("Expected msg to be returned, not %v", ev)
+		}
+
+		exp := expected[i]
+		if *msg.TopicPartition.Topic != topic ||
+			msg.TopicPartition.Offset != Offset(i) ||
+			msg.Opaque != nil ||
+			string(msg.Key) != string(exp.Key) ||
+			string(msg.Value) != string(exp.Value) ||
+			len(msg.Headers) != len(exp.Headers) {
+			t.Fatalf("Expected %v, got %v", exp, msg)
+		}
+		if (exp.Key == nil) != (msg.Key == nil) {
+			t.Errorf("Expected key %v, got %v", exp.Key, msg.Key)
+		}
+		for n, h := range exp.Headers {
+			if msg.Headers[n].Key != h.Key ||
+				string(msg.Headers[n].Value) != string(h.Value) ||
+				(h.Value == nil) != (msg.Headers[n].Value == nil) {
+				t.Errorf("Expected header %v, got %v", h, msg.Headers[n])
+			}
+		}
+		i++
+	}
+}
+
+// TestConsumerCommitCallback tests that go.commit.callback is called
+// for manual and automatic commits, successful or not.
+func TestConsumerCommitCallback(t *testing.T) {
+	mc, err := NewMockCluster(1)
+	if err != nil {
+		t.Fatalf("Failed to create MockCluster: %v", err)
+	}
+	defer mc.Close()
+
+	topic := "commitcallback"
+	if err = mc.CreateTopic(topic, 1, 1); err != nil {
+		t.Fatalf("CreateTopic failed: %v", err)
+	}
+
+	var lock sync.Mutex
+	var results []OffsetsCommitted
+	c, err := NewConsumer(&ConfigMap{
+		"bootstrap.servers":        mc.BootstrapServers(),
+		"group.id":                 "commitcallback",
+		"enable.auto.commit":       true,
+		"enable.auto.offset.store": false,
+		"auto.commit.interval.ms":  100,
+		"go.commit.callback": func(result OffsetsCommitted) {
+			lock.Lock()
+			results = append(results, result)
+			lock.Unlock()
+		}})
+	if err != nil {
+		t.Fatalf("Failed to create Consumer: %v", err)
+	}
+	defer c.Close()
+
+	tp := TopicPartition{Topic: &topic, Partition: 0}
+	if err = c.Assign([]TopicPartition{tp}); err != nil {
+		t.Fatalf("Assign failed: %v", err)
+	}
+
+	// Nothing to commit
+	if _, err = c.Commit(); err == nil || err.(Error).Code() != ErrNoOffset {
+		t.Fatalf("Expected ErrNoOffset, not %v", err)
+	}
+
+	tp.Offset = 5
+	if _, err = c.CommitOffsets([]TopicPartition{tp}); err != nil {
+		t.Fatalf("CommitOffsets failed: %v", err)
+	}
+
+	lock.Lock()
+	if len(results) != 2 ||
+		results[0].Error == nil || results[0].Error.(Error).Code() != ErrNoOffset ||
+		results[1].Error != nil || len(results[1].Offsets) != 1 ||
+		results[1].Offsets[0].Offset != 5 || results[1].Offsets[0].Error != nil {
+		t.Errorf("Unexpected manual commit results %v", results)
+	}
+	results = nil
+	lock.Unlock()
+
+	// Automatic commit of the stored offset
+	tp.Offset = 7
+	if _, err = c.StoreOffsets([]TopicPartition{tp}); err != nil {
+		t.Fatalf("StoreOffsets failed: %v", err)
+	}
+
+	var auto *OffsetsCommitted
+	for tEnd := time.Now().Add(10 * time.Second); auto == nil && time.Now().Before(tEnd); {
+		c.Poll(100)
+		lock.Lock()
+		for i, r := range results {
+			if r.Error == nil && len(r.Offsets) == 1 && r.Offsets[0].Offset == 7 {
+				auto = &results[i]
+			}
+		}
+		lock.Unlock()
+	}
+	if auto == nil {
+		t.Errorf("Expected the automatic commit result, have %v", results)
+	}
+
+	_, err = NewConsumer(&ConfigMap{"group.id": "commitcallback",
+		"go.commit.callback": func(error) {}})
+	if err == nil || err.(Error).Code() != ErrInvalidArg {
+		t.Errorf("Expected ErrInvalidArg for invalid go.commit.callback, got %v", err)
+	}
+}
@@ -0,0 +1,192 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"container/heap"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DedupStore remembers the ids of the messages processed by a Deduplicator.
+type DedupStore interface {
+	// Seen returns true if id was marked as processed and had not
+	// expired at now.
+	Seen(id string, now time.Time) (bool, error)
+	// Mark records id as processed until expiry.
+	Mark(id string, expiry time.Time) error
+}
+
+// DedupIDFunc returns the id identifying duplicates of msg, and false
+// if msg can't be identified and is never considered a duplicate.
+type DedupIDFunc func(msg *Message) (id string, ok bool)
+
+// DedupByHeader identifies messages by the value of their last header key,
+// e.g., an id set by the producing application.
+func DedupByHeader(key string) DedupIDFunc {
+	return func(msg *Message) (string, bool) {
+		headers := msg.GetHeaders()
+		for i := len(headers) - 1; i >= 0; i-- {
+			if headers[i].Key == key {
+				return string(headers[i].Value), headers[i].Value != nil
+			}
+		}
+		return "", false
+	}
+}
+
+// DedupByOffset identifies messages by their topic, partition and offset,
+// which identifies messages redelivered after a rebalance or restart,
+// but not messages produced twice.
+func DedupByOffset() DedupIDFunc {
+	return func(msg *Message) (string, bool) {
+		tp := msg.TopicPartition
+		if tp.Topic == nil || tp.Offset < 0 {
+			return "", false
+		}
+		return fmt.Sprintf("%s/%d/%d", *tp.Topic, tp.Partition, tp.Offset), true
+	}
+}
+
+// Deduplicator detects messages already processed within a time window,
+// for at-least-once consumers to skip the messages they already processed
+// without transactions:
+//
+//	dup, err := d.IsDuplicate(msg)
+//	if err == nil && !dup {
+//		if err = process(msg); err == nil {
+//			err = d.MarkProcessed(msg)
+//		}
+//	}
+//
+// A message is only marked once processed, so that a message whose
+// processing failed, or was interrupted by a crash, is processed again
+// when redelivered.
+//
+// The producer id and sequence of idempotent producers are not exposed
+// by this librdkafka version, use DedupByHeader() with an application id.
+type Deduplicator struct {
+	idFunc DedupIDFunc
+	window time.Duration
+	store  DedupStore
+}
+
+// NewDeduplicator creates a Deduplicator identifying messages with idFunc,
+// which remembers them for window in store, or in memory if store is nil.
+// A store may be shared by Deduplicators with different windows.
+func NewDeduplicator(idFunc DedupIDFunc, window time.Duration, store DedupStore) *Deduplicator {
+	if store == nil {
+		store = NewMemoryDedupStore()
+	}
+	return &Deduplicator{idFunc: idFunc, window: window, store: store}
+}
+
+// IsDuplicate returns true if msg was marked as processed within the
+// window, see MarkProcessed().
+// Messages that idFunc can't identify are never duplicates.
+func (d *Deduplicator) IsDuplicate(msg *Message) (bool, error) {
+	id, ok := d.idFunc(msg)
+	if !ok {
+		return false, nil
+	}
+
+	return d.store.Seen(id, time.Now())
+}
+
+// MarkProcessed records msg as processed for the window, its later
+// copies are then duplicates.
+// Call it once msg was successfully processed.
+func (d *Deduplicator) MarkProcessed(msg *Message) error {
+	id, ok := d.idFunc(msg)
+	if !ok {
+		return nil
+	}
+
+	return d.store.Mark(id, time.Now().Add(d.window))
+}
+
+// memoryDedupEntry is an id remembered by memoryDedupStore until expiry.
+type memoryDedupEntry struct {
+	id     string
+	expiry time.Time
+}
+
+// memoryDedupExpiries orders the entries of memoryDedupStore by expiry,
+// it implements heap.Interface.
+type memoryDedupExpiries []memoryDedupEntry
+
+func (h memoryDedupExpiries) Len() int           { return len(h) }
+func (h memoryDedupExpiries) Less(i, j int) bool { return h[i].expiry.Before(h[j].expiry) }
+func (h memoryDedupExpiries) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *memoryDedupExpiries) Push(x interface{}) {
+	*h = append(*h, x.(memoryDedupEntry))
+}
+
+func (h *memoryDedupExpiries) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
+
+// memoryDedupStore is the default in-memory DedupStore.
+type memoryDedupStore struct {
+	lock     sync.Mutex
+	expiry   map[string]time.Time
+	expiries memoryDedupExpiries
+}
+
+// NewMemoryDedupStore returns a DedupStore remembering the ids in memory.
+func NewMemoryDedupStore() DedupStore {
+	return &memoryDedupStore{expiry: make(map[string]time.Time)}
+}
+
+// forget forgets the ids expired at now.
+// Must be called with s.lock held.
+func (s *memoryDedupStore) forget(now time.Time) {
+	for len(s.expiries) > 0 && !s.expiries[0].expiry.After(now) {
+		e := heap.Pop(&s.expiries).(memoryDedupEntry)
+		// The id may have been marked again since
+		if s.expiry[e.id].Equal(e.expiry) {
+			delete(s.expiry, e.id)
+		}
+	}
+}
+
+func (s *memoryDedupStore) Seen(id string, now time.Time) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.forget(now)
+	expiry, seen := s.expiry[id]
+	return seen && expiry.After(now), nil
+}
+
+func (s *memoryDedupStore) Mark(id string, expiry time.Time) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.forget(time.Now())
+	if prev, found := s.expiry[id]; found && !expiry.After(prev) {
+		return nil
+	}
+	s.expiry[id] = expiry
+	heap.Push(&s.expiries, memoryDedupEntry{id, expiry})
+	return nil
+}
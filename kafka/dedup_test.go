@@ -0,0 +1,124 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"testing"
+	"time"
+)
+
+// TestDeduplicator tests duplicate detection by offset and by header,
+// and that ids are forgotten after the window.
+func TestDeduplicator(t *testing.T) {
+	topic := "dedup"
+	msg := func(partition int32, offset Offset, headers ...Header) *Message {
+		return &Message{
+			TopicPartition: TopicPartition{Topic: &topic, Partition: partition, Offset: offset},
+			Headers:        headers,
+		}
+	}
+
+	// process checks msg and marks it as processed if not a duplicate
+	process := func(d *Deduplicator, m *Message, expected bool) {
+		dup, err := d.IsDuplicate(m)
+		if err != nil || dup != expected {
+			t.Errorf("%v: expected duplicate %v, not %v (%v)", m, expected, dup, err)
+		}
+		if !dup {
+			if err = d.MarkProcessed(m); err != nil {
+				t.Errorf("%v: MarkProcessed failed: %v", m, err)
+			}
+		}
+	}
+
+	d := NewDeduplicator(DedupByOffset(), time.Hour, nil)
+	process(d, msg(0, 1), false)
+	process(d, msg(1, 1), false)
+	process(d, msg(0, 2), false)
+	process(d, msg(0, 1), true)
+	process(d, msg(0, OffsetInvalid), false)
+	process(d, msg(0, OffsetInvalid), false)
+
+	d = NewDeduplicator(DedupByHeader("id"), time.Hour, NewMemoryDedupStore())
+	process(d, msg(0, 1, Header{"id", []byte("a")}), false)
+	process(d, msg(0, 2, Header{"id", []byte("a")}), true)
+	process(d, msg(0, 3, Header{"id", []byte("a")}, Header{"id", []byte("b")}), false)
+	process(d, msg(0, 4), false)
+	process(d, msg(0, 4), false)
+
+	d = NewDeduplicator(DedupByOffset(), 20*time.Millisecond, nil)
+	process(d, msg(0, 1), false)
+	process(d, msg(0, 1), true)
+	time.Sleep(50 * time.Millisecond)
+	process(d, msg(0, 1), false)
+	process(d, msg(0, 1), true)
+
+	if n := len(d.store.(*memoryDedupStore).expiry); n != 1 {
+		t.Errorf("Expected the expired id to be forgotten, %d ids remembered", n)
+	}
+}
+
+// TestDeduplicatorUnprocessed tests that messages checked but not marked
+// as processed, e.g., after a processing failure, are not duplicates.
+func TestDeduplicatorUnprocessed(t *testing.T) {
+	topic := "dedup"
+	m := &Message{TopicPartition: TopicPartition{Topic: &topic, Offset: 1}}
+
+	d := NewDeduplicator(DedupByOffset(), time.Hour, nil)
+	for i := 0; i < 2; i++ {
+		if dup, err := d.IsDuplicate(m); err != nil || dup {
+			t.Errorf("Expected unprocessed message not to be a duplicate, got %v (%v)", dup, err)
+		}
+	}
+
+	if err := d.MarkProcessed(m); err != nil {
+		t.Fatalf("MarkProcessed failed: %v", err)
+	}
+	if dup, err := d.IsDuplicate(m); err != nil || !dup {
+		t.Errorf("Expected processed message to be a duplicate, got %v (%v)", dup, err)
+	}
+}
+
+// TestDeduplicatorSharedStore tests that Deduplicators with different
+// windows can share a store.
+func TestDeduplicatorSharedStore(t *testing.T) {
+	topic := "dedup"
+	store := NewMemoryDedupStore()
+	long := NewDeduplicator(DedupByHeader("id"), time.Hour, store)
+	short := NewDeduplicator(DedupByHeader("id"), 20*time.Millisecond, store)
+
+	msg := func(id string) *Message {
+		return &Message{TopicPartition: TopicPartition{Topic: &topic},
+			Headers: []Header{{"id", []byte(id)}}}
+	}
+
+	if err := long.MarkProcessed(msg("long")); err != nil {
+		t.Fatalf("MarkProcessed failed: %v", err)
+	}
+	if err := short.MarkProcessed(msg("short")); err != nil {
+		t.Fatalf("MarkProcessed failed: %v", err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	if dup, _ := short.IsDuplicate(msg("long")); !dup {
+		t.Errorf("Expected the id marked for an hour to be remembered")
+	}
+	if dup, _ := long.IsDuplicate(msg("short")); dup {
+		t.Errorf("Expected the id marked for 20ms to be forgotten")
+	}
+}
@@ -131,6 +131,166 @@ func (e Error) TxnRequiresAbort() bool {
 	return e.txnRequiresAbort
 }
 
+// Unwrap returns the ErrorCode of the Error, allowing
+// errors.Is(err, kafka.ErrTransport) style matching of error codes.
+func (e Error) Unwrap() error {
+	return e.code
+}
+
+// Is returns true if target is an ErrorCategory that the Error belongs to,
+// or an Error with the same ErrorCode, for use with errors.Is().
+func (e Error) Is(target error) bool {
+	switch t := target.(type) {
+	case ErrorCategory:
+		return e.InCategory(t)
+	case Error:
+		return e.code == t.code
+	}
+	return false
+}
+
+// Error returns a human readable representation of an ErrorCode,
+// allowing ErrorCodes to be used as errors.Is() targets.
+// Same as ErrorCode.String()
+func (c ErrorCode) Error() string {
+	return c.String()
+}
+
+// ErrorCategory is a class of errors that call for the same handling
+// by the application, regardless of the specific ErrorCode.
+//
+// ErrorCategory implements the error interface so that categories can be
+// used as errors.Is() targets:
+//
+//	if errors.Is(err, kafka.ErrorCategoryRetriable) { ... }
+type ErrorCategory int
+
+const (
+	// ErrorCategoryRetriable - the operation may be retried,
+	// see Error.IsRetriable().
+	ErrorCategoryRetriable ErrorCategory = iota
+	// ErrorCategoryFatal - the client instance is no longer operable,
+	// see Error.IsFatal().
+	ErrorCategoryFatal
+	// ErrorCategoryAbortable - the current transaction must be aborted,
+	// see Error.TxnRequiresAbort().
+	ErrorCategoryAbortable
+	// ErrorCategoryAuthentication - authentication with the broker failed.
+	ErrorCategoryAuthentication
+	// ErrorCategoryAuthorization - the operation was not authorized
+	// by the broker.
+	ErrorCategoryAuthorization
+	// ErrorCategoryTimeout - the operation or request timed out.
+	ErrorCategoryTimeout
+)
+
+// String returns the name of the ErrorCategory
+func (c ErrorCategory) String() string {
+	switch c {
+	case ErrorCategoryRetriable:
+		return "Retriable"
+	case ErrorCategoryFatal:
+		return "Fatal"
+	case ErrorCategoryAbortable:
+		return "Abortable"
+	case ErrorCategoryAuthentication:
+		return "Authentication"
+	case ErrorCategoryAuthorization:
+		return "Authorization"
+	case ErrorCategoryTimeout:
+		return "Timeout"
+	default:
+		return fmt.Sprintf("ErrorCategory(%d)", int(c))
+	}
+}
+
+// Error returns a human readable representation of an ErrorCategory
+func (c ErrorCategory) Error() string {
+	return fmt.Sprintf("%s error", c.String())
+}
+
+// Error codes per category, the Retriable, Fatal and Abortable categories
+// also include errors flagged as such by librdkafka.
+var errorCategoryCodes = map[ErrorCategory]map[ErrorCode]bool{
+	ErrorCategoryRetriable: {
+		ErrTransport:                    true,
+		ErrAllBrokersDown:               true,
+		ErrQueueFull:                    true,
+		ErrTimedOut:                     true,
+		ErrTimedOutQueue:                true,
+		ErrRequestTimedOut:              true,
+		ErrLeaderNotAvailable:           true,
+		ErrNotLeaderForPartition:        true,
+		ErrBrokerNotAvailable:           true,
+		ErrReplicaNotAvailable:          true,
+		ErrNetworkException:             true,
+		ErrCoordinatorLoadInProgress:    true,
+		ErrCoordinatorNotAvailable:      true,
+		ErrNotCoordinator:               true,
+		ErrNotEnoughReplicas:            true,
+		ErrNotEnoughReplicasAfterAppend: true,
+		ErrNotController:                true,
+		ErrKafkaStorageError:            true,
+		ErrFetchSessionIDNotFound:       true,
+		ErrInvalidFetchSessionEpoch:     true,
+		ErrListenerNotFound:             true,
+		ErrFencedLeaderEpoch:            true,
+		ErrUnknownLeaderEpoch:           true,
+		ErrOffsetNotAvailable:           true,
+		ErrPreferredLeaderNotAvailable:  true,
+		ErrEligibleLeadersNotAvailable:  true,
+		ErrUnstableOffsetCommit:         true,
+		ErrThrottlingQuotaExceeded:      true,
+		ErrConcurrentTransactions:       true,
+	},
+	ErrorCategoryFatal: {
+		ErrFatal: true,
+	},
+	ErrorCategoryAbortable: {},
+	ErrorCategoryAuthentication: {
+		ErrAuthentication:           true,
+		ErrSaslAuthenticationFailed: true,
+		ErrUnsupportedSaslMechanism: true,
+		ErrIllegalSaslState:         true,
+		ErrUnacceptableCredential:   true,
+	},
+	ErrorCategoryAuthorization: {
+		ErrTopicAuthorizationFailed:           true,
+		ErrGroupAuthorizationFailed:           true,
+		ErrClusterAuthorizationFailed:         true,
+		ErrTransactionalIDAuthorizationFailed: true,
+		ErrDelegationTokenAuthorizationFailed: true,
+	},
+	ErrorCategoryTimeout: {
+		ErrTimedOut:        true,
+		ErrTimedOutQueue:   true,
+		ErrMsgTimedOut:     true,
+		ErrRequestTimedOut: true,
+	},
+}
+
+// InCategory returns true if the Error belongs to the given ErrorCategory.
+// An Error may belong to several categories, e.g., both
+// ErrorCategoryTimeout and ErrorCategoryRetriable.
+func (e Error) InCategory(category ErrorCategory) bool {
+	switch category {
+	case ErrorCategoryRetriable:
+		if e.retriable {
+			return true
+		}
+	case ErrorCategoryFatal:
+		if e.fatal {
+			return true
+		}
+	case ErrorCategoryAbortable:
+		if e.txnRequiresAbort {
+			return true
+		}
+	}
+
+	return errorCategoryCodes[category][e.code]
+}
+
 // getFatalError returns an Error object if the client instance has raised a fatal error, else nil.
 func getFatalError(H Handle) error {
 	cErrstr := (*C.char)(C.malloc(C.size_t(512)))
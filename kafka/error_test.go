@@ -17,6 +17,8 @@ package kafka
  */
 
 import (
+	"errors"
+	"fmt"
 	"strings"
 	"testing"
 )
@@ -69,3 +71,52 @@ func TestFatalErrorClient(t *testing.T) {
 
 	p.Close()
 }
+
+// TestErrorIs tests errors.Is and errors.As support
+func TestErrorIs(t *testing.T) {
+	var err error = fmt.Errorf("wrapped: %w",
+		newErrorFromString(ErrMsgTimedOut, "Message timed out"))
+
+	if !errors.Is(err, ErrMsgTimedOut) {
+		t.Errorf("Expected %v to match ErrMsgTimedOut", err)
+	}
+	if errors.Is(err, ErrTransport) {
+		t.Errorf("Expected %v to not match ErrTransport", err)
+	}
+	if !errors.Is(err, NewError(ErrMsgTimedOut, "", false)) {
+		t.Errorf("Expected %v to match an Error with the same code", err)
+	}
+	if !errors.Is(err, ErrorCategoryTimeout) {
+		t.Errorf("Expected %v to be in the Timeout category", err)
+	}
+	if errors.Is(err, ErrorCategoryFatal) {
+		t.Errorf("Expected %v to not be in the Fatal category", err)
+	}
+
+	var kerr Error
+	if !errors.As(err, &kerr) || kerr.Code() != ErrMsgTimedOut {
+		t.Errorf("Expected errors.As to return the Error, got %v", kerr)
+	}
+
+	fatalErr := newErrorFromString(ErrOutOfOrderSequenceNumber, "fatal")
+	fatalErr.fatal = true
+	if !errors.Is(fatalErr, ErrorCategoryFatal) {
+		t.Errorf("Expected %v to be in the Fatal category", fatalErr)
+	}
+
+	abortableErr := newErrorFromString(ErrInvalidTxnState, "abortable")
+	abortableErr.txnRequiresAbort = true
+	if !errors.Is(abortableErr, ErrorCategoryAbortable) {
+		t.Errorf("Expected %v to be in the Abortable category", abortableErr)
+	}
+
+	for code, category := range map[ErrorCode]ErrorCategory{
+		ErrNotLeaderForPartition:    ErrorCategoryRetriable,
+		ErrSaslAuthenticationFailed: ErrorCategoryAuthentication,
+		ErrTopicAuthorizationFailed: ErrorCategoryAuthorization,
+	} {
+		if !errors.Is(NewError(code, "", false), category) {
+			t.Errorf("Expected %v to be in category %v", code, category)
+		}
+	}
+}
@@ -19,27 +19,19 @@ package kafka
 import (
 	"fmt"
 	"os"
+	"time"
 	"unsafe"
 )
 
 /*
 #include <stdlib.h>
+#include <string.h>
 #include "select_rdkafka.h"
 #include "glue_rdkafka.h"
 
 
-void chdrs_to_tmphdrs (glue_msg_t *gMsg) {
+void hdrs_to_tmphdrs (rd_kafka_headers_t *chdrs, glue_msg_t *gMsg) {
     size_t i = 0;
-    const char *name;
-    const void *val;
-    size_t size;
-    rd_kafka_headers_t *chdrs;
-
-    if (rd_kafka_message_headers(gMsg->msg, &chdrs)) {
-        gMsg->tmphdrs = NULL;
-        gMsg->tmphdrsCnt = 0;
-        return;
-    }
 
     gMsg->tmphdrsCnt = rd_kafka_header_cnt(chdrs);
     gMsg->tmphdrs = malloc(sizeof(*gMsg->tmphdrs) * gMsg->tmphdrsCnt);
@@ -51,27 +43,56 @@ void chdrs_to_tmphdrs (glue_msg_t *gMsg) {
         i++;
 }
 
-rd_kafka_event_t *_rk_queue_poll (rd_kafka_queue_t *rkq, int timeoutMs,
-                                  rd_kafka_event_type_t *evtype,
-                                  glue_msg_t *gMsg,
-                                  rd_kafka_event_t *prev_rkev) {
-    rd_kafka_event_t *rkev;
-
-    if (prev_rkev)
-      rd_kafka_event_destroy(prev_rkev);
+void chdrs_to_tmphdrs (glue_msg_t *gMsg) {
+    rd_kafka_headers_t *chdrs;
 
-    rkev = rd_kafka_queue_poll(rkq, timeoutMs);
-    *evtype = rd_kafka_event_type(rkev);
+    if (rd_kafka_message_headers(gMsg->msg, &chdrs)) {
+        gMsg->tmphdrs = NULL;
+        gMsg->tmphdrsCnt = 0;
+        return;
+    }
 
-    if (*evtype == RD_KAFKA_EVENT_FETCH) {
-        gMsg->msg = (rd_kafka_message_t *)rd_kafka_event_message_next(rkev);
-        gMsg->ts = rd_kafka_message_timestamp(gMsg->msg, &gMsg->tstype);
+    hdrs_to_tmphdrs(chdrs, gMsg);
+}
 
-        if (gMsg->want_hdrs)
-            chdrs_to_tmphdrs(gMsg);
+// Polls up to size events from rkq into evs, waiting at most timeoutMs
+// for the first event, to serve many events per cgo call.
+// Only fetch and delivery report events are batched: the batch ends
+// after any other event, e.g., a rebalance, which must be handled
+// before polling further messages that it may render outdated.
+// Returns the number of events polled.
+size_t _rk_queue_poll_batch (rd_kafka_queue_t *rkq, int timeoutMs,
+                             glue_ev_t *evs, size_t size, int8_t want_hdrs) {
+    size_t cnt = 0;
+
+    while (cnt < size) {
+        rd_kafka_event_t *rkev;
+        glue_ev_t *ev;
+
+        rkev = rd_kafka_queue_poll(rkq, cnt == 0 ? timeoutMs : 0);
+        if (!rkev)
+            break;
+
+        ev = &evs[cnt++];
+        memset(ev, 0, sizeof(*ev));
+        ev->rkev = rkev;
+        ev->evtype = rd_kafka_event_type(rkev);
+
+        if (ev->evtype == RD_KAFKA_EVENT_FETCH) {
+            ev->gMsg.msg = (rd_kafka_message_t *)rd_kafka_event_message_next(rkev);
+            ev->gMsg.ts = rd_kafka_message_timestamp(ev->gMsg.msg, &ev->gMsg.tstype);
+
+            if (want_hdrs == GLUE_HDRS_COPY)
+                chdrs_to_tmphdrs(&ev->gMsg);
+            else if (want_hdrs == GLUE_HDRS_LAZY)
+                rd_kafka_message_detach_headers(ev->gMsg.msg,
+                                                &ev->gMsg.hdrs);
+
+        } else if (ev->evtype != RD_KAFKA_EVENT_DR)
+            break;
     }
 
-    return rkev;
+    return cnt;
 }
 */
 import "C"
@@ -80,6 +101,10 @@ func chdrsToTmphdrs(gMsg *C.glue_msg_t) {
 	C.chdrs_to_tmphdrs(gMsg)
 }
 
+func hdrsToTmphdrs(chdrs *C.rd_kafka_headers_t, gMsg *C.glue_msg_t) {
+	C.hdrs_to_tmphdrs(chdrs, gMsg)
+}
+
 // Event generic interface
 type Event interface {
 	// String returns a human-readable representation of the event
@@ -134,6 +159,22 @@ func (o OffsetsCommitted) String() string {
 	return fmt.Sprintf("OffsetsCommitted (%v, %v)", o.Error, o.Offsets)
 }
 
+// cEventToOffsetsCommitted returns an OffsetsCommitted for the
+// OFFSET_COMMIT event rkev.
+func cEventToOffsetsCommitted(rkev *C.rd_kafka_event_t) OffsetsCommitted {
+	var ev OffsetsCommitted
+
+	if coffsets := C.rd_kafka_event_topic_partition_list(rkev); coffsets != nil {
+		ev.Offsets = newTopicPartitionsFromCparts(coffsets)
+	}
+
+	if cErr := C.rd_kafka_event_error(rkev); cErr != C.RD_KAFKA_RESP_ERR_NO_ERROR {
+		ev.Error = newErrorFromCString(cErr, C.rd_kafka_event_error_string(rkev))
+	}
+
+	return ev
+}
+
 // OAuthBearerTokenRefresh indicates token refresh is required
 type OAuthBearerTokenRefresh struct {
 	// Config is the value of the sasl.oauthbearer.config property
@@ -144,173 +185,392 @@ func (o OAuthBearerTokenRefresh) String() string {
 	return "OAuthBearerTokenRefresh"
 }
 
-// eventPoll polls an event from the handler's C rd_kafka_queue_t,
-// translates it into an Event type and then sends on `channel` if non-nil, else returns the Event.
+// DeliveryTimeout is emitted in place of the delivery report *Message for
+// messages that could not be delivered within `message.timeout.ms`.
+// Needs to be explicitly enabled by setting the `go.delivery.timeout.events`
+// Producer configuration property to true.
+//
+// DeliveryTimeout also implements the error interface.
+type DeliveryTimeout struct {
+	// Message is the failed message as it would otherwise have been
+	// emitted as a delivery report, its .TopicPartition.Error is set
+	// to ErrMsgTimedOut.
+	Message *Message
+	// QueueTime is the time from the Produce() call until the message
+	// timed out, or -1 if not available.
+	QueueTime time.Duration
+	// BrokerID is the id of the broker the message was last sent to,
+	// or -1 if the message was never transmitted.
+	BrokerID int32
+	// PossiblyPersisted is true if the message was transmitted to the
+	// broker but no acknowledgement was received, in which case
+	// the message may or may not have been written to the log.
+	// If false the message was never transmitted and retrying it
+	// will not cause duplicates.
+	PossiblyPersisted bool
+}
+
+// newDeliveryTimeout creates a DeliveryTimeout event for the given
+// delivery report message.
+func newDeliveryTimeout(msg *Message, cmsg *C.rd_kafka_message_t) *DeliveryTimeout {
+	dt := &DeliveryTimeout{
+		Message:   msg,
+		QueueTime: -1,
+		BrokerID:  int32(C.rd_kafka_message_broker_id(cmsg)),
+		PossiblyPersisted: C.rd_kafka_message_status(cmsg) ==
+			C.RD_KAFKA_MSG_STATUS_POSSIBLY_PERSISTED,
+	}
+
+	latency := int64(C.rd_kafka_message_latency(cmsg))
+	if latency >= 0 {
+		dt.QueueTime = time.Duration(latency) * time.Microsecond
+	}
+
+	return dt
+}
+
+// Error returns a human readable representation of a DeliveryTimeout
+func (dt *DeliveryTimeout) Error() string {
+	return dt.String()
+}
+
+func (dt *DeliveryTimeout) String() string {
+	return fmt.Sprintf("DeliveryTimeout for %v after %v (broker %d, possibly persisted: %v)",
+		dt.Message, dt.QueueTime, dt.BrokerID, dt.PossiblyPersisted)
+}
+
+// eventPollBatchSize is the maximum number of events polled from
+// librdkafka per cgo call by eventPoll().
+const eventPollBatchSize = 100
+
+// eventPoll polls events from the handler's C rd_kafka_queue_t,
+// translates them into Event types and then sends them on `channel` if non-nil,
+// else polls a single event and returns it.
 // term_chan is an optional channel to monitor along with producing to channel
 // to indicate that `channel` is being terminated.
 // returns (event Event, terminate Bool) tuple, where Terminate indicates
 // if termChan received a termination event.
+//
+// When forwarding to `channel` up to eventPollBatchSize events are
+// polled per cgo call, when polling a single event for the caller
+// nothing is polled ahead since it could be outdated by the time
+// the caller polls again, e.g., after a Seek().
 func (h *handle) eventPoll(channel chan Event, timeoutMs int, maxEvents int, termChan chan bool) (Event, bool) {
+	return h.eventPollInto(nil, channel, timeoutMs, maxEvents, termChan)
+}
 
-	var prevRkev *C.rd_kafka_event_t
-	term := false
+// eventPollInto is eventPoll() setting up a consumed message in msg,
+// if non-nil, rather than in a new Message.
+func (h *handle) eventPollInto(msg *Message, channel chan Event, timeoutMs int, maxEvents int, termChan chan bool) (Event, bool) {
 
 	var retval Event
 
 	if channel == nil {
 		maxEvents = 1
 	}
-out:
-	for evcnt := 0; evcnt < maxEvents; evcnt++ {
-		var evtype C.rd_kafka_event_type_t
-		var gMsg C.glue_msg_t
-		gMsg.want_hdrs = C.int8_t(bool2cint(h.msgFields.Headers))
-		rkev := C._rk_queue_poll(h.rkq, C.int(timeoutMs), &evtype, &gMsg, prevRkev)
-		prevRkev = rkev
-		timeoutMs = 0
 
-		retval = nil
+	batchSize := maxEvents
+	if batchSize > eventPollBatchSize {
+		batchSize = eventPollBatchSize
+	}
+	batch := make([]C.glue_ev_t, batchSize)
+	wantHdrs := C.int8_t(C.GLUE_HDRS_NONE)
+	if h.msgFields.Headers && h.lazyHeaders {
+		wantHdrs = C.GLUE_HDRS_LAZY
+	} else if h.msgFields.Headers {
+		wantHdrs = C.GLUE_HDRS_COPY
+	}
 
-		switch evtype {
-		case C.RD_KAFKA_EVENT_FETCH:
-			// Consumer fetch event, new message.
-			// Extracted into temporary gMsg for optimization
-			retval = h.newMessageFromGlueMsg(&gMsg)
+	for evcnt := 0; evcnt < maxEvents; {
+		if pev := h.popPendingEvent(); pev != nil {
+			evcnt++
+			if h.invokeCallback(pev) {
+				continue
+			}
+			if channel == nil {
+				return pev, false
+			}
+			if h.sendEvent(channel, pev, termChan) {
+				return nil, true
+			}
+			continue
+		}
 
-		case C.RD_KAFKA_EVENT_REBALANCE:
-			// Consumer rebalance event
-			retval = h.c.handleRebalanceEvent(channel, rkev)
-
-		case C.RD_KAFKA_EVENT_ERROR:
-			// Error event
-			cErr := C.rd_kafka_event_error(rkev)
-			if cErr == C.RD_KAFKA_RESP_ERR__PARTITION_EOF {
-				crktpar := C.rd_kafka_event_topic_partition(rkev)
-				if crktpar == nil {
-					break
-				}
+		size := maxEvents - evcnt
+		if size > batchSize {
+			size = batchSize
+		}
 
-				defer C.rd_kafka_topic_partition_destroy(crktpar)
-				var peof PartitionEOF
-				setupTopicPartitionFromCrktpar((*TopicPartition)(&peof), crktpar)
-
-				retval = peof
-
-			} else if int(C.rd_kafka_event_error_is_fatal(rkev)) != 0 {
-				// A fatal error has been raised.
-				// Extract the actual error from the client
-				// instance and return a new Error with
-				// fatal set to true.
-				cFatalErrstrSize := C.size_t(512)
-				cFatalErrstr := (*C.char)(C.malloc(cFatalErrstrSize))
-				defer C.free(unsafe.Pointer(cFatalErrstr))
-				cFatalErr := C.rd_kafka_fatal_error(h.rk, cFatalErrstr, cFatalErrstrSize)
-				fatalErr := newErrorFromCString(cFatalErr, cFatalErrstr)
-				fatalErr.fatal = true
-				retval = fatalErr
-
-			} else {
-				retval = newErrorFromCString(cErr, C.rd_kafka_event_error_string(rkev))
+		cnt := int(C._rk_queue_poll_batch(h.rkq, C.int(timeoutMs),
+			&batch[0], C.size_t(size), wantHdrs))
+		timeoutMs = 0
+
+		if cnt == 0 {
+			// poll timed out: no events available
+			break
+		}
+		evcnt += cnt
+
+		for i := range batch[:cnt] {
+			var term bool
+			retval, term = h.handleEvent(msg, channel, &batch[i], termChan)
+			if batch[i].rkev != nil {
+				C.rd_kafka_event_destroy(batch[i].rkev)
 			}
 
-		case C.RD_KAFKA_EVENT_STATS:
-			retval = &Stats{C.GoString(C.rd_kafka_event_stats(rkev))}
-
-		case C.RD_KAFKA_EVENT_DR:
-			// Producer Delivery Report event
-			// Each such event contains delivery reports for all
-			// messages in the produced batch.
-			// Forward delivery reports to per-message's response channel
-			// or to the global Producer.Events channel, or none.
-			rkmessages := make([]*C.rd_kafka_message_t, int(C.rd_kafka_event_message_count(rkev)))
-
-			cnt := int(C.rd_kafka_event_message_array(rkev, (**C.rd_kafka_message_t)(unsafe.Pointer(&rkmessages[0])), C.size_t(len(rkmessages))))
-
-			for _, rkmessage := range rkmessages[:cnt] {
-				msg := h.newMessageFromC(rkmessage)
-				var ch *chan Event
-
-				if rkmessage._private != nil {
-					// Find cgoif by id
-					cg, found := h.cgoGet((int)((uintptr)(rkmessage._private)))
-					if found {
-						cdr := cg.(cgoDr)
-
-						if cdr.deliveryChan != nil {
-							ch = &cdr.deliveryChan
-						}
-						msg.Opaque = cdr.opaque
-					}
-				}
+			if term {
+				h.discardEvents(batch[i+1 : cnt])
+				return nil, true
+			}
+		}
+	}
 
-				if ch == nil && h.fwdDr {
-					ch = &channel
-				}
+	return retval, false
+}
 
-				if ch != nil {
-					select {
-					case *ch <- msg:
-					case <-termChan:
-						retval = nil
-						term = true
-						break out
-					}
+// handleEvent translates a polled event into an Event type and then sends
+// it on `channel` if non-nil, else returns the Event.
+// A consumed message is set up in msg, if non-nil.
+// Returns true if termChan received a termination event.
+func (h *handle) handleEvent(msg *Message, channel chan Event, ev *C.glue_ev_t, termChan chan bool) (retval Event, term bool) {
+
+	rkev := ev.rkev
+
+	switch ev.evtype {
+	case C.RD_KAFKA_EVENT_FETCH:
+		// Consumer fetch event, new message.
+		// Extracted into temporary gMsg for optimization
+		var zc *zeroCopyBuf
+		if h.zeroCopy {
+			zc = h.newZeroCopyBuf(ev)
+		}
+		if msg != nil {
+			h.setupMessageFromGlueMsg(msg, &ev.gMsg, zc)
+			retval = msg
+		} else {
+			retval = h.newMessageFromGlueMsg(&ev.gMsg, zc)
+		}
 
-				} else {
-					retval = msg
-					break out
-				}
+	case C.RD_KAFKA_EVENT_REBALANCE:
+		// Consumer rebalance event
+		retval = h.c.handleRebalanceEvent(channel, rkev)
+
+	case C.RD_KAFKA_EVENT_ERROR:
+		// Error event
+		cErr := C.rd_kafka_event_error(rkev)
+		if cErr == C.RD_KAFKA_RESP_ERR__PARTITION_EOF {
+			crktpar := C.rd_kafka_event_topic_partition(rkev)
+			if crktpar == nil {
+				break
 			}
 
-		case C.RD_KAFKA_EVENT_OFFSET_COMMIT:
-			// Offsets committed
-			cErr := C.rd_kafka_event_error(rkev)
-			coffsets := C.rd_kafka_event_topic_partition_list(rkev)
-			var offsets []TopicPartition
-			if coffsets != nil {
-				offsets = newTopicPartitionsFromCparts(coffsets)
+			defer C.rd_kafka_topic_partition_destroy(crktpar)
+			var peof PartitionEOF
+			setupTopicPartitionFromCrktpar((*TopicPartition)(&peof), crktpar)
+
+			retval = peof
+
+		} else if int(C.rd_kafka_event_error_is_fatal(rkev)) != 0 {
+			// A fatal error has been raised.
+			// Extract the actual error from the client
+			// instance and return a new Error with
+			// fatal set to true.
+			cFatalErrstrSize := C.size_t(512)
+			cFatalErrstr := (*C.char)(C.malloc(cFatalErrstrSize))
+			defer C.free(unsafe.Pointer(cFatalErrstr))
+			cFatalErr := C.rd_kafka_fatal_error(h.rk, cFatalErrstr, cFatalErrstrSize)
+			fatalErr := newErrorFromCString(cFatalErr, cFatalErrstr)
+			fatalErr.fatal = true
+			retval = fatalErr
+
+		} else {
+			err := newErrorFromCString(cErr, C.rd_kafka_event_error_string(rkev))
+			switch {
+			case h.brokerStateEvents && cErr == C.RD_KAFKA_RESP_ERR__TRANSPORT:
+				retval = h.newBrokerDown(err)
+			case h.brokerStateEvents && cErr == C.RD_KAFKA_RESP_ERR__ALL_BROKERS_DOWN:
+				retval = h.newAllBrokersDown(err)
+			default:
+				retval = err
 			}
+		}
+
+	case C.RD_KAFKA_EVENT_STATS:
+		// The JSON document is only copied for go.stats.callback or events
+		if h.statisticsCb != nil && h.statsCb == nil {
+			retval = h.serveStatisticsCallback(rkev)
+			break
+		}
 
-			if cErr != C.RD_KAFKA_RESP_ERR_NO_ERROR {
-				retval = OffsetsCommitted{newErrorFromCString(cErr, C.rd_kafka_event_error_string(rkev)), offsets}
-			} else {
-				retval = OffsetsCommitted{nil, offsets}
+		statsJSON := C.GoString(C.rd_kafka_event_stats(rkev))
+		var stats *Statistics
+		var err error
+		if h.parseStats || h.brokerStateEvents || h.throttleEvents || h.throttleBackoff != nil || h.statisticsCb != nil ||
+			h.partitionCountEvents ||
+			(h.c != nil && h.c.prefetch.maxBytes > 0) {
+			stats, err = ParseStatistics(statsJSON)
+		}
+
+		if err != nil {
+			retval = err.(Error)
+		} else if h.parseStats {
+			retval = &StatsEvent{Statistics: stats, JSON: statsJSON}
+		} else {
+			retval = &Stats{statsJSON}
+		}
+
+		if stats != nil {
+			h.updateStatsStates(stats)
+		}
+
+		if stats != nil && h.statisticsCb != nil {
+			h.statisticsCb(stats)
+		}
+
+	case C.RD_KAFKA_EVENT_DR:
+		// Producer Delivery Report event
+		// Each such event contains delivery reports for all
+		// messages in the produced batch.
+		// Forward delivery reports to per-message's response channel
+		// or to the global Producer.Events channel, or none.
+		rkmessages := make([]*C.rd_kafka_message_t, int(C.rd_kafka_event_message_count(rkev)))
+
+		cnt := int(C.rd_kafka_event_message_array(rkev, (**C.rd_kafka_message_t)(unsafe.Pointer(&rkmessages[0])), C.size_t(len(rkmessages))))
+
+		for _, rkmessage := range rkmessages[:cnt] {
+			msg := h.newMessageFromC(rkmessage)
+			setupDrInfoFromC(msg, rkmessage)
+			var dr Event = msg
+			var ch *chan Event
+
+			if h.fwdDeliveryTimeouts &&
+				rkmessage.err == C.RD_KAFKA_RESP_ERR__MSG_TIMED_OUT {
+				dr = newDeliveryTimeout(msg, rkmessage)
 			}
 
-		case C.RD_KAFKA_EVENT_OAUTHBEARER_TOKEN_REFRESH:
-			ev := OAuthBearerTokenRefresh{C.GoString(C.rd_kafka_event_config_string(rkev))}
-			retval = ev
+			if rkmessage._private != nil {
+				// Find cgoif by id
+				cg, found := h.cgoGet((int)((uintptr)(rkmessage._private)))
+				if found {
+					cdr := cg.(*cgoDr)
 
-		case C.RD_KAFKA_EVENT_NONE:
-			// poll timed out: no events available
-			break out
+					if cdr.deliveryChan != nil {
+						deliveryChan := cdr.deliveryChan
+						ch = &deliveryChan
+					}
+					msg.Opaque = cdr.opaque
+					cdr.release()
+				}
+			}
 
-		default:
-			if rkev != nil {
-				fmt.Fprintf(os.Stderr, "Ignored event %s\n",
-					C.GoString(C.rd_kafka_event_name(rkev)))
+			if ch == nil && h.fwdDr {
+				ch = &channel
 			}
 
-		}
+			if ch == &channel {
+				if h.sendEvent(channel, dr, termChan) {
+					return nil, true
+				}
 
-		if retval != nil {
-			if channel != nil {
+			} else if ch != nil {
 				select {
-				case channel <- retval:
+				case *ch <- dr:
 				case <-termChan:
-					retval = nil
-					term = true
-					break out
+					return nil, true
 				}
-			} else {
-				break out
+
+			} else if channel == nil {
+				return dr, false
+			}
+		}
+
+		return nil, false
+
+	case C.RD_KAFKA_EVENT_OFFSET_COMMIT:
+		// Offsets committed
+		ev := cEventToOffsetsCommitted(rkev)
+		if h.c != nil && h.c.commitCb != nil {
+			h.c.commitCb(ev)
+		}
+		retval = ev
+
+	case C.RD_KAFKA_EVENT_OAUTHBEARER_TOKEN_REFRESH:
+		ev := OAuthBearerTokenRefresh{C.GoString(C.rd_kafka_event_config_string(rkev))}
+		retval = ev
+
+	default:
+		fmt.Fprintf(os.Stderr, "Ignored event %s\n",
+			C.GoString(C.rd_kafka_event_name(rkev)))
+	}
+
+	if retval != nil && h.invokeCallback(retval) {
+		retval = nil
+	}
+
+	if retval != nil && channel != nil {
+		if h.sendEvent(channel, retval, termChan) {
+			if m, ok := retval.(*Message); ok {
+				m.Release()
 			}
+			return nil, true
 		}
 	}
 
-	if prevRkev != nil {
-		C.rd_kafka_event_destroy(prevRkev)
+	return retval, false
+}
+
+// updateStatsStates updates the broker, throttle and partition count
+// states derived from the statistics, if enabled.
+func (h *handle) updateStatsStates(stats *Statistics) {
+	if h.brokerStateEvents {
+		h.updateBrokerStates(stats)
 	}
 
-	return retval, term
+	if h.throttleEvents || h.throttleBackoff != nil {
+		h.updateThrottleStates(stats)
+	}
+
+	if h.partitionCountEvents {
+		h.updatePartitionCounts(stats)
+	}
+
+	if h.c != nil && h.c.prefetch.maxBytes > 0 {
+		h.c.prefetch.update(h.c, stats)
+	}
+}
+
+// serveStatisticsCallback parses the statistics of a stats event straight
+// from librdkafka's buffer, without copying the JSON document, and calls
+// the statistics callback. Returns the parse error, if any, else nil.
+func (h *handle) serveStatisticsCallback(rkev *C.rd_kafka_event_t) Event {
+	cstats := C.rd_kafka_event_stats(rkev)
+	stats, err := parseStatistics(zeroCopyBytes(unsafe.Pointer(cstats), C.strlen(cstats)))
+	if err != nil {
+		return err.(Error)
+	}
+
+	h.updateStatsStates(stats)
+	h.statisticsCb(stats)
+
+	return nil
+}
+
+// discardEvents destroys events polled in a batch but not handled
+// since eventPoll() was terminated.
+// Rebalance events are still handled, without forwarding them,
+// for the assignment to be updated, as Consumer.Close() would.
+func (h *handle) discardEvents(batch []C.glue_ev_t) {
+	for i := range batch {
+		ev := &batch[i]
+		switch ev.evtype {
+		case C.RD_KAFKA_EVENT_REBALANCE:
+			h.c.handleRebalanceEvent(nil, ev.rkev)
+		case C.RD_KAFKA_EVENT_FETCH:
+			C.free(unsafe.Pointer(ev.gMsg.tmphdrs))
+			if ev.gMsg.hdrs != nil {
+				C.rd_kafka_headers_destroy(ev.gMsg.hdrs)
+			}
+		}
+		C.rd_kafka_event_destroy(ev.rkev)
+	}
 }
@@ -0,0 +1,181 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"context"
+)
+
+// EventDispatcher dispatches events to handlers registered per event type,
+// sparing the application the type switch over all event types.
+//
+// Handlers must be registered before events are dispatched, and are called
+// from the goroutine calling Dispatch() or Run().
+//
+// E.g.:
+//
+//	d := kafka.NewEventDispatcher()
+//	d.OnMessage(func(m *kafka.Message) { ... })
+//	d.OnError(func(err kafka.Error) { ... })
+//	go d.Run(ctx, p.Events())
+type EventDispatcher struct {
+	onMessage            func(*Message)
+	onError              func(Error)
+	onStats              func(*Statistics)
+	onOffsetsCommitted   func(OffsetsCommitted)
+	onThrottle           func(ThrottleEvent)
+	onAssignedPartitions func(AssignedPartitions)
+	onRevokedPartitions  func(RevokedPartitions)
+	onPartitionEOF       func(PartitionEOF)
+	onOther              func(Event)
+}
+
+// NewEventDispatcher creates an EventDispatcher without any handlers.
+func NewEventDispatcher() *EventDispatcher {
+	return &EventDispatcher{}
+}
+
+// OnMessage registers the handler for consumed messages and
+// producer delivery reports.
+func (d *EventDispatcher) OnMessage(handler func(*Message)) {
+	d.onMessage = handler
+}
+
+// OnError registers the handler for Error events.
+func (d *EventDispatcher) OnError(handler func(Error)) {
+	d.onError = handler
+}
+
+// OnStats registers the handler for statistics, both *Stats and
+// *StatsEvent events are dispatched as parsed Statistics.
+// Statistics that fail to parse are dispatched to the OnError handler.
+func (d *EventDispatcher) OnStats(handler func(*Statistics)) {
+	d.onStats = handler
+}
+
+// OnOffsetsCommitted registers the handler for OffsetsCommitted events.
+func (d *EventDispatcher) OnOffsetsCommitted(handler func(OffsetsCommitted)) {
+	d.onOffsetsCommitted = handler
+}
+
+// OnThrottle registers the handler for ThrottleEvent events.
+func (d *EventDispatcher) OnThrottle(handler func(ThrottleEvent)) {
+	d.onThrottle = handler
+}
+
+// OnAssignedPartitions registers the handler for AssignedPartitions events.
+func (d *EventDispatcher) OnAssignedPartitions(handler func(AssignedPartitions)) {
+	d.onAssignedPartitions = handler
+}
+
+// OnRevokedPartitions registers the handler for RevokedPartitions events.
+func (d *EventDispatcher) OnRevokedPartitions(handler func(RevokedPartitions)) {
+	d.onRevokedPartitions = handler
+}
+
+// OnPartitionEOF registers the handler for PartitionEOF events.
+func (d *EventDispatcher) OnPartitionEOF(handler func(PartitionEOF)) {
+	d.onPartitionEOF = handler
+}
+
+// OnOther registers the handler for events without a specific
+// handler registered.
+func (d *EventDispatcher) OnOther(handler func(Event)) {
+	d.onOther = handler
+}
+
+// Dispatch calls the handler registered for the event's type,
+// or the OnOther handler if there is none.
+// Returns true if a handler was called, nil events are ignored.
+func (d *EventDispatcher) Dispatch(ev Event) bool {
+	switch e := ev.(type) {
+	case nil:
+		return false
+	case *Message:
+		if d.onMessage != nil {
+			d.onMessage(e)
+			return true
+		}
+	case Error:
+		if d.onError != nil {
+			d.onError(e)
+			return true
+		}
+	case *Stats:
+		if d.onStats != nil {
+			stats, err := e.Parse()
+			if err != nil {
+				return d.Dispatch(err.(Error))
+			}
+			d.onStats(stats)
+			return true
+		}
+	case *StatsEvent:
+		if d.onStats != nil {
+			d.onStats(e.Statistics)
+			return true
+		}
+	case OffsetsCommitted:
+		if d.onOffsetsCommitted != nil {
+			d.onOffsetsCommitted(e)
+			return true
+		}
+	case ThrottleEvent:
+		if d.onThrottle != nil {
+			d.onThrottle(e)
+			return true
+		}
+	case AssignedPartitions:
+		if d.onAssignedPartitions != nil {
+			d.onAssignedPartitions(e)
+			return true
+		}
+	case RevokedPartitions:
+		if d.onRevokedPartitions != nil {
+			d.onRevokedPartitions(e)
+			return true
+		}
+	case PartitionEOF:
+		if d.onPartitionEOF != nil {
+			d.onPartitionEOF(e)
+			return true
+		}
+	}
+
+	if d.onOther != nil {
+		d.onOther(ev)
+		return true
+	}
+
+	return false
+}
+
+// Run dispatches events from the events channel, such as
+// Producer.Events(), until the channel is closed or ctx is done.
+func (d *EventDispatcher) Run(ctx context.Context, events <-chan Event) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			d.Dispatch(ev)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestEventDispatcher tests dispatching of events to typed handlers
+func TestEventDispatcher(t *testing.T) {
+	d := NewEventDispatcher()
+
+	var msgs, errs, stats, commits, throttles, others int
+	d.OnMessage(func(*Message) { msgs++ })
+	d.OnError(func(Error) { errs++ })
+	d.OnStats(func(s *Statistics) {
+		if s.Name == "rdkafka#producer-1" {
+			stats++
+		}
+	})
+	d.OnOffsetsCommitted(func(OffsetsCommitted) { commits++ })
+	d.OnThrottle(func(ThrottleEvent) { throttles++ })
+
+	events := []Event{
+		&Message{},
+		newErrorFromString(ErrTransport, "broker down"),
+		&Stats{`{"name": "rdkafka#producer-1"}`},
+		&Stats{`not json`},
+		&StatsEvent{Statistics: &Statistics{Name: "rdkafka#producer-1"}},
+		OffsetsCommitted{},
+		ThrottleEvent{},
+		PartitionEOF{},
+	}
+
+	for _, ev := range events[:len(events)-1] {
+		if !d.Dispatch(ev) {
+			t.Errorf("Expected %v to be dispatched", ev)
+		}
+	}
+
+	if d.Dispatch(PartitionEOF{}) || d.Dispatch(nil) {
+		t.Errorf("Expected unhandled events not to be dispatched")
+	}
+
+	d.OnOther(func(Event) { others++ })
+
+	ch := make(chan Event, len(events))
+	for _, ev := range events {
+		ch <- ev
+	}
+	close(ch)
+	d.Run(context.Background(), ch)
+
+	if msgs != 2 || errs != 4 || stats != 4 || commits != 2 || throttles != 2 || others != 1 {
+		t.Errorf("Unexpected dispatch counts: msgs %d, errs %d, stats %d, commits %d, throttles %d, others %d",
+			msgs, errs, stats, commits, throttles, others)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	d.Run(ctx, make(chan Event))
+}
@@ -17,7 +17,9 @@ package kafka
  */
 
 import (
+	"fmt"
 	"testing"
+	"time"
 )
 
 // TestEventAPIs dry-tests the public event related APIs, no broker is needed.
@@ -44,3 +46,79 @@ func TestEventAPIs(t *testing.T) {
 	oauthBearerTokenRefresh := OAuthBearerTokenRefresh{"some=config"}
 	t.Logf("%s\n", oauthBearerTokenRefresh.String())
 }
+
+// TestEventPollBatch tests that events polled in batches by the
+// channel based producer and consumer are all forwarded, in order.
+func TestEventPollBatch(t *testing.T) {
+	mc, err := NewMockCluster(1)
+	if err != nil {
+		t.Fatalf("Failed to create MockCluster: %v", err)
+	}
+	defer mc.Close()
+
+	topic := "batch"
+	msgcnt := 3 * eventPollBatchSize
+
+	p, err := NewProducer(&ConfigMap{"bootstrap.servers": mc.BootstrapServers()})
+	if err != nil {
+		t.Fatalf("Failed to create Producer: %v", err)
+	}
+	defer p.Close()
+
+	drChan := make(chan Event, msgcnt)
+	for i := 0; i < msgcnt; i++ {
+		err = p.Produce(&Message{
+			TopicPartition: TopicPartition{Topic: &topic, Partition: 0},
+			Value:          []byte(fmt.Sprintf("%d", i)),
+			Headers:        []Header{{Key: "i", Value: []byte(fmt.Sprintf("%d", i))}},
+		}, drChan)
+		if err != nil {
+			t.Fatalf("Produce failed: %v", err)
+		}
+	}
+
+	for i := 0; i < msgcnt; i++ {
+		select {
+		case ev := <-drChan:
+			if m := ev.(*Message); m.TopicPartition.Error != nil {
+				t.Fatalf("Delivery failed: %v", m.TopicPartition)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("Timed out waiting for delivery report %d", i)
+		}
+	}
+
+	c, err := NewConsumer(&ConfigMap{
+		"bootstrap.servers":        mc.BootstrapServers(),
+		"group.id":                 "batch",
+		"enable.auto.commit":       false,
+		"auto.offset.reset":        "earliest",
+		"go.events.channel.enable": true})
+	if err != nil {
+		t.Fatalf("Failed to create Consumer: %v", err)
+	}
+	defer c.Close()
+
+	if err = c.Assign([]TopicPartition{{Topic: &topic, Partition: 0}}); err != nil {
+		t.Fatalf("Assign failed: %v", err)
+	}
+
+	for i := 0; i < msgcnt; {
+		select {
+		case ev := <-c.Events():
+			m, ok := ev.(*Message)
+			if !ok {
+				continue
+			}
+			exp := fmt.Sprintf("%d", i)
+			if string(m.Value) != exp || len(m.Headers) != 1 ||
+				string(m.Headers[0].Value) != exp {
+				t.Fatalf("Expected message %s, got %v with headers %v",
+					exp, m, m.Headers)
+			}
+			i++
+		case <-time.After(10 * time.Second):
+			t.Fatalf("Timed out waiting for message %d", i)
+		}
+	}
+}
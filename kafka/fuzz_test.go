@@ -0,0 +1,170 @@
+//go:build go1.18
+// +build go1.18
+
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Fuzz targets for the parsers of untrusted input: configuration
+// files and strings, and consumed header values and message payloads.
+// Run with, e.g.:
+//
+//	go test -run '^$' -fuzz FuzzParseConfig -fuzztime 1m
+
+// FuzzConfigMapSet fuzzes ConfigMap.Set() key=value parsing
+func FuzzConfigMapSet(f *testing.F) {
+	f.Add("bootstrap.servers=localhost:9092")
+	f.Add("{topic}.acks=all")
+	f.Add("sasl.password=a=b=c")
+	f.Add("novalue")
+
+	f.Fuzz(func(t *testing.T, kv string) {
+		m := ConfigMap{}
+		err := m.Set(kv)
+
+		i := strings.Index(kv, "=")
+		if i == -1 {
+			if err == nil {
+				t.Fatalf("Expected error for %q", kv)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("Set(%q) failed: %v", kv, err)
+		}
+
+		key := kv[:i]
+		v, err := m.Get(key, nil)
+		if err != nil || v != kv[i+1:] {
+			t.Fatalf("Set(%q): expected %s=%q, got %v (%v)",
+				kv, key, kv[i+1:], v, err)
+		}
+	})
+}
+
+// FuzzParseConfig fuzzes the configuration file parsers used by
+// ConfigMap.LoadFromFile()
+func FuzzParseConfig(f *testing.F) {
+	f.Add([]byte("# comment\nbootstrap.servers=localhost:9092\nacks = all\n"))
+	f.Add([]byte(`{"bootstrap": {"servers": "localhost"}, "linger.ms": 5, "go.logs.channel.enable": true}`))
+	f.Add([]byte("bootstrap:\n  servers: \"localhost:9092\"\nlinger.ms: 5 # comment\n"))
+
+	parsers := map[string]func([]byte) ([]string, map[string]ConfigValue, error){
+		"properties": parsePropertiesConfig,
+		"json":       parseJSONConfig,
+		"yaml":       parseYAMLConfig,
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for name, parse := range parsers {
+			keys, values, err := parse(data)
+			if err != nil {
+				continue
+			}
+
+			for _, k := range keys {
+				if _, found := values[k]; !found {
+					t.Fatalf("%s: key %q has no value", name, k)
+				}
+			}
+		}
+	})
+}
+
+// FuzzDecodeConnectHeader fuzzes Kafka Connect header value decoding
+func FuzzDecodeConnectHeader(f *testing.F) {
+	f.Add([]byte("true"))
+	f.Add([]byte("-12.50"))
+	f.Add([]byte("1.2E+3"))
+	f.Add([]byte("AAH+"))
+	f.Add([]byte("2147483648"))
+
+	f.Fuzz(func(t *testing.T, value []byte) {
+		h := Header{"k", value}
+
+		for typ := ConnectHeaderInfer; typ <= ConnectHeaderDecimal; typ++ {
+			v, err := DecodeConnectHeader(h, typ)
+			if err != nil {
+				continue
+			}
+
+			// Exact types must survive an encode/decode round-trip
+			switch typ {
+			case ConnectHeaderString, ConnectHeaderBoolean, ConnectHeaderInt8,
+				ConnectHeaderInt16, ConnectHeaderInt32, ConnectHeaderInt64,
+				ConnectHeaderBytes:
+			default:
+				continue
+			}
+
+			h2, err := NewConnectHeader("k", v)
+			if err != nil {
+				t.Fatalf("Failed to encode %#v decoded from %q as %v: %v",
+					v, value, typ, err)
+			}
+			v2, err := DecodeConnectHeader(h2, typ)
+			if err != nil || !reflect.DeepEqual(v, v2) {
+				t.Fatalf("Round-trip of %q as %v: %#v != %#v (%v)",
+					value, typ, v, v2, err)
+			}
+		}
+	})
+}
+
+// FuzzCloudEventFromMessage fuzzes CloudEvents decoding of consumed messages
+func FuzzCloudEventFromMessage(f *testing.F) {
+	f.Add([]byte(`{"specversion":"1.0","id":"1","source":"s","type":"t","data":{"a":1}}`),
+		"content-type", []byte("application/cloudevents+json"))
+	f.Add([]byte("data"), "ce_specversion", []byte("1.0"))
+	f.Add([]byte(`{"specversion":"1.0","id":"1","source":"s","type":"t","data_base64":"AAE="}`),
+		"content-type", []byte("application/cloudevents+json"))
+
+	f.Fuzz(func(t *testing.T, value []byte, hdrKey string, hdrValue []byte) {
+		msg := &Message{
+			Value: value,
+			Headers: []Header{
+				{hdrKey, hdrValue},
+				{"ce_id", []byte("id")},
+				{"ce_source", []byte("source")},
+				{"ce_type", []byte("type")},
+			},
+		}
+
+		ev, err := CloudEventFromMessage(msg)
+		if err != nil {
+			return
+		}
+
+		// A decoded event must be re-encodable in binary mode,
+		// preserving its data.
+		msg2, err := NewCloudEventMessage(ev, TopicPartition{}, CloudEventsBinary)
+		if err != nil {
+			t.Fatalf("Failed to re-encode %+v: %v", ev, err)
+		}
+		ev2, err := CloudEventFromMessage(msg2)
+		if err != nil || !bytes.Equal(ev.Data, ev2.Data) {
+			t.Fatalf("Round-trip of %+v failed: %+v (%v)", ev, ev2, err)
+		}
+	})
+}
@@ -45,4 +45,24 @@ typedef struct glue_msg_s {
   tmphdr_t *tmphdrs;
   size_t    tmphdrsCnt;
   int8_t    want_hdrs;  /**< If true, copy headers */
+  rd_kafka_headers_t *hdrs; /**< Detached headers, parsed lazily */
 } glue_msg_t;
+
+
+/**
+ * How _rk_queue_poll_batch() extracts the headers of fetched messages.
+ */
+#define GLUE_HDRS_NONE 0  /**< Headers are not extracted */
+#define GLUE_HDRS_COPY 1  /**< Headers are copied to tmphdrs */
+#define GLUE_HDRS_LAZY 2  /**< Headers are detached to hdrs */
+
+
+/**
+ * @struct An event polled by _rk_queue_poll_batch(), with the
+ *         message of fetch events extracted to gMsg.
+ */
+typedef struct glue_ev_s {
+  rd_kafka_event_t *rkev;
+  rd_kafka_event_type_t evtype;
+  glue_msg_t gMsg;
+} glue_ev_t;
@@ -84,8 +84,10 @@ type handle struct {
 	rk  *C.rd_kafka_t
 	rkq *C.rd_kafka_queue_t
 
-	// Forward logs from librdkafka log queue to logs channel.
+	// Forward logs from librdkafka log queue to logs channel,
+	// or to logger if set.
 	logs          chan LogEvent
+	logger        Logger
 	logq          *C.rd_kafka_queue_t
 	closeLogsChan bool
 
@@ -95,10 +97,42 @@ type handle struct {
 	rktCache map[string]*C.rd_kafka_topic_t
 	// rkt -> topic name cache
 	rktNameCache map[*C.rd_kafka_topic_t]string
+	// Topic name pointers shared by the messages of each topic,
+	// if internTopics is enabled.
+	rktTopicCache map[*C.rd_kafka_topic_t]*string
+	internTopics  bool
 
 	// Cached instance name to avoid CGo call in String()
 	name string
 
+	// Emit parsed StatsEvent instead of raw Stats events.
+	parseStats bool
+
+	// Emit BrokerUp, BrokerDown and AllBrokersDown events.
+	brokerStateEvents bool
+	brokerStateLock   sync.Mutex
+	// Broker name -> broker is up
+	brokersUp      map[string]bool
+	allBrokersDown bool
+	// Emit ThrottleEvent events.
+	throttleEvents bool
+	// Broker name -> broker was throttled in the previous statistics
+	brokersThrottled map[string]bool
+	// Emit PartitionCountChange events.
+	partitionCountEvents bool
+	// Topic -> partition count in the previous statistics
+	partitionCounts map[string]int
+	// Back off producing or consuming while throttled, if set.
+	throttleBackoff ThrottleBackoffPolicy
+	throttleUntil   time.Time
+	// Application callbacks for statistics and errors, if set,
+	// called instead of forwarding the events to the application.
+	statsCb      StatsCallback
+	statisticsCb StatisticsCallback
+	errorCb      ErrorCallback
+	// Events generated by the Go client, served before librdkafka events.
+	pendingEvents []Event
+
 	//
 	// cgo map
 	// Maps C callbacks based on cgoid back to its Go object
@@ -114,9 +148,38 @@ type handle struct {
 	// Forward delivery reports on Producer.Events channel
 	fwdDr bool
 
+	// Emit DeliveryTimeout events for timed out messages
+	// instead of *Message delivery reports.
+	fwdDeliveryTimeouts bool
+
 	// Enabled message fields for delivery reports and consumed messages.
 	msgFields *messageFields
 
+	// Consumed message headers are parsed by Message.GetHeaders()
+	lazyHeaders bool
+
+	// Produced messages are stamped with, and consumed messages verified
+	// against, an integrity header.
+	integrityStamp  bool
+	integrityVerify bool
+
+	// Policies applied to events that don't fit in the Events() and
+	// Logs() channels, and the number of events they dropped.
+	eventsOverflow overflowPolicy
+	logsOverflow   overflowPolicy
+	overflowCb     func(Event)
+	droppedEvents  int64
+	droppedLogs    int64
+
+	// Consumed message keys and values refer to librdkafka's buffers,
+	// which are owned by the messages until Message.Release().
+	zeroCopy     bool
+	zeroCopyLock sync.Mutex
+	// Fetch events of unreleased zero-copy messages
+	zeroCopyEvents map[*C.rd_kafka_event_t]bool
+	// Zero-copy messages garbage collected without Message.Release()
+	zeroCopyLeaks int64
+
 	//
 	// consumer
 	//
@@ -133,7 +196,12 @@ func (h *handle) String() string {
 func (h *handle) setup() {
 	h.rktCache = make(map[string]*C.rd_kafka_topic_t)
 	h.rktNameCache = make(map[*C.rd_kafka_topic_t]string)
+	h.rktTopicCache = make(map[*C.rd_kafka_topic_t]*string)
 	h.cgomap = make(map[int]cgoif)
+	h.brokersUp = make(map[string]bool)
+	h.brokersThrottled = make(map[string]bool)
+	h.partitionCounts = make(map[string]int)
+	h.zeroCopyEvents = make(map[*C.rd_kafka_event_t]bool)
 	h.name = C.GoString(C.rd_kafka_name(h.rk))
 	if h.msgFields == nil {
 		h.msgFields = newMessageFields()
@@ -141,9 +209,11 @@ func (h *handle) setup() {
 }
 
 func (h *handle) cleanup() {
-	if h.logs != nil {
+	h.unregister()
+
+	if h.logq != nil {
 		C.rd_kafka_queue_destroy(h.logq)
-		if h.closeLogsChan {
+		if h.logs != nil && h.closeLogsChan {
 			close(h.logs)
 		}
 	}
@@ -157,13 +227,17 @@ func (h *handle) cleanup() {
 	}
 }
 
-func (h *handle) setupLogQueue(logsChan chan LogEvent, termChan chan bool) {
-	if logsChan == nil {
-		logsChan = make(chan LogEvent, 10000)
-		h.closeLogsChan = true
-	}
+func (h *handle) setupLogQueue(logsChan chan LogEvent, logsChanSize int, logger Logger, termChan chan bool) {
+	if logger != nil {
+		h.logger = logger
+	} else {
+		if logsChan == nil {
+			logsChan = make(chan LogEvent, logsChanSize)
+			h.closeLogsChan = true
+		}
 
-	h.logs = logsChan
+		h.logs = logsChan
+	}
 
 	// Let librdkafka forward logs to our log queue instead of the main queue
 	h.logq = C.rd_kafka_queue_new(h.rk)
@@ -231,6 +305,34 @@ func (h *handle) getTopicNameFromRkt(crkt *C.rd_kafka_topic_t) (topic string) {
 	return topic
 }
 
+// getTopicFromRkt returns a topic name pointer for a C topic_t object,
+// shared by all messages of the topic if internTopics is enabled, which
+// avoids an allocation per message, see `go.topic.intern`.
+func (h *handle) getTopicFromRkt(crkt *C.rd_kafka_topic_t) *string {
+	if !h.internTopics {
+		topic := h.getTopicNameFromRkt(crkt)
+		return &topic
+	}
+
+	h.rktCacheLock.Lock()
+	topic, ok := h.rktTopicCache[crkt]
+	h.rktCacheLock.Unlock()
+	if ok {
+		return topic
+	}
+
+	name := h.getTopicNameFromRkt(crkt)
+
+	h.rktCacheLock.Lock()
+	defer h.rktCacheLock.Unlock()
+	if topic, ok = h.rktTopicCache[crkt]; !ok {
+		topic = &name
+		h.rktTopicCache[crkt] = topic
+	}
+
+	return topic
+}
+
 // cgoif is a generic interface for holding Go state passed as opaque
 // value to the C code.
 // Since pointers to complex Go types cannot be passed to C we instead create
@@ -245,6 +347,24 @@ type cgoDr struct {
 	opaque       interface{}
 }
 
+// cgoDrPool pools *cgoDr containers, which are put back once the
+// delivery report is served.
+var cgoDrPool = sync.Pool{New: func() interface{} { return &cgoDr{} }}
+
+// newCgoDr returns a pooled delivery report container
+func newCgoDr(deliveryChan chan Event, opaque interface{}) *cgoDr {
+	cdr := cgoDrPool.Get().(*cgoDr)
+	cdr.deliveryChan = deliveryChan
+	cdr.opaque = opaque
+	return cdr
+}
+
+// release resets cdr and returns it to the pool
+func (cdr *cgoDr) release() {
+	*cdr = cgoDr{}
+	cgoDrPool.Put(cdr)
+}
+
 // cgoPut adds object cg to the handle's cgo map and returns a
 // unique id for the added entry.
 // Thread-safe.
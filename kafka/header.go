@@ -18,10 +18,14 @@ package kafka
 
 import (
 	"fmt"
+	"runtime"
 	"strconv"
+	"sync"
+	"unsafe"
 )
 
 /*
+#include <stdlib.h>
 #include <string.h>
 #include "select_rdkafka.h"
 #include "glue_rdkafka.h"
@@ -47,13 +51,19 @@ type Header struct {
 // String returns the Header Key and data in a human representable possibly truncated form
 // suitable for displaying to the user.
 func (h Header) String() string {
-	if h.Value == nil {
-		return fmt.Sprintf("%s=nil", h.Key)
+	return fmt.Sprintf("%s=%s", h.Key, quoteTruncated(h.Value))
+}
+
+// quoteTruncated returns b as a quoted, possibly truncated, string
+// suitable for displaying to the user.
+func quoteTruncated(b []byte) string {
+	if b == nil {
+		return "nil"
 	}
 
-	valueLen := len(h.Value)
+	valueLen := len(b)
 	if valueLen == 0 {
-		return fmt.Sprintf("%s=<empty>", h.Key)
+		return "<empty>"
 	}
 
 	truncSize := valueLen
@@ -63,5 +73,96 @@ func (h Header) String() string {
 		trunc = fmt.Sprintf("(%d more bytes)", valueLen-truncSize)
 	}
 
-	return fmt.Sprintf("%s=%s%s", h.Key, strconv.Quote(string(h.Value[:truncSize])), trunc)
+	return strconv.Quote(string(b[:truncSize])) + trunc
+}
+
+// lazyHeaders holds the librdkafka headers of a consumed message until
+// they are parsed by Message.GetHeaders(), see `go.headers.lazy`.
+// It is shared by copies of the message, hence the lock.
+type lazyHeaders struct {
+	lock sync.Mutex
+	// Unparsed headers, nil once parsed or released
+	chdrs *C.rd_kafka_headers_t
+	// Parsed headers, owned by the lazyHeaders until released
+	headers  []Header
+	released bool
+}
+
+// newLazyHeaders takes ownership of the detached chdrs, which are
+// destroyed when parsed, released, or garbage collected.
+func newLazyHeaders(chdrs *C.rd_kafka_headers_t) *lazyHeaders {
+	lh := &lazyHeaders{chdrs: chdrs}
+	runtime.SetFinalizer(lh, (*lazyHeaders).destroy)
+	return lh
+}
+
+// get returns the headers, parsing them on the first call.
+// Returns nil once released.
+func (lh *lazyHeaders) get() []Header {
+	lh.lock.Lock()
+	defer lh.lock.Unlock()
+
+	if lh.chdrs != nil {
+		var gMsg C.glue_msg_t
+		hdrsToTmphdrs(lh.chdrs, &gMsg)
+		if gMsg.tmphdrsCnt > 0 {
+			var msg Message
+			setupHeadersFromGlueMsg(&msg, &gMsg)
+			lh.headers = msg.Headers
+		} else {
+			C.free(unsafe.Pointer(gMsg.tmphdrs))
+		}
+		lh.destroyHeaders()
+	}
+
+	return lh.headers
+}
+
+// release destroys the unparsed headers, if any, and, if pool is true,
+// returns the parsed headers to the pool. Only the first call has an
+// effect, subsequent get() calls return nil.
+func (lh *lazyHeaders) release(pool bool) {
+	lh.lock.Lock()
+	defer lh.lock.Unlock()
+
+	if lh.released {
+		return
+	}
+	lh.released = true
+
+	if lh.chdrs != nil {
+		lh.destroyHeaders()
+	}
+	if pool && cap(lh.headers) > 0 {
+		headersPool.Put(&lh.headers)
+	}
+	lh.headers = nil
+}
+
+// destroyHeaders destroys the unparsed headers.
+// Must be called with lh.lock held.
+func (lh *lazyHeaders) destroyHeaders() {
+	runtime.SetFinalizer(lh, nil)
+	C.rd_kafka_headers_destroy(lh.chdrs)
+	lh.chdrs = nil
+}
+
+// destroy is the finalizer of unparsed headers.
+func (lh *lazyHeaders) destroy() {
+	lh.lock.Lock()
+	defer lh.lock.Unlock()
+	if lh.chdrs != nil {
+		lh.destroyHeaders()
+	}
+}
+
+// GetHeaders returns the message headers.
+// The headers of messages consumed with `go.headers.lazy` are parsed
+// on the first call, by this message or a copy of it, Headers is nil
+// until then.
+func (m *Message) GetHeaders() []Header {
+	if m.lazyHeaders != nil && m.Headers == nil {
+		m.Headers = m.lazyHeaders.get()
+	}
+	return m.Headers
 }
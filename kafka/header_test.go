@@ -17,7 +17,10 @@ package kafka
  */
 
 import (
+	"fmt"
+	"reflect"
 	"testing"
+	"time"
 )
 
 // TestHeader tests the Header type
@@ -39,3 +42,144 @@ func TestHeader(t *testing.T) {
 	}
 
 }
+
+// TestLazyHeaders tests consuming messages with go.headers.lazy
+func TestLazyHeaders(t *testing.T) {
+	mc, err := NewMockCluster(1)
+	if err != nil {
+		t.Fatalf("Failed to create MockCluster: %v", err)
+	}
+	defer mc.Close()
+
+	topic := "lazyheaders"
+	msgcnt := 10
+
+	p, err := NewProducer(&ConfigMap{"bootstrap.servers": mc.BootstrapServers()})
+	if err != nil {
+		t.Fatalf("Failed to create Producer: %v", err)
+	}
+	defer p.Close()
+
+	// Odd messages have headers
+	expHeaders := func(i int) []Header {
+		if i%2 == 0 {
+			return nil
+		}
+		return []Header{{"hdr1", []byte(fmt.Sprintf("value%d", i))},
+			{"hdr2", nil}}
+	}
+
+	drChan := make(chan Event, msgcnt)
+	for i := 0; i < msgcnt; i++ {
+		err = p.Produce(&Message{
+			TopicPartition: TopicPartition{Topic: &topic, Partition: 0},
+			Value:          []byte(fmt.Sprintf("value%d", i)),
+			Headers:        expHeaders(i),
+		}, drChan)
+		if err != nil {
+			t.Fatalf("Produce failed: %v", err)
+		}
+	}
+
+	for i := 0; i < msgcnt; i++ {
+		if m := (<-drChan).(*Message); m.TopicPartition.Error != nil {
+			t.Fatalf("Delivery failed: %v", m.TopicPartition)
+		}
+	}
+
+	c, err := NewConsumer(&ConfigMap{
+		"bootstrap.servers":  mc.BootstrapServers(),
+		"group.id":           "lazyheaders",
+		"enable.auto.commit": false,
+		"auto.offset.reset":  "earliest",
+		"go.headers.lazy":    true})
+	if err != nil {
+		t.Fatalf("Failed to create Consumer: %v", err)
+	}
+	defer c.Close()
+
+	if err = c.Assign([]TopicPartition{{Topic: &topic, Partition: 0}}); err != nil {
+		t.Fatalf("Assign failed: %v", err)
+	}
+
+	var reused Message
+	for i := 0; i < msgcnt; {
+		var m *Message
+		if i < msgcnt/2 {
+			m, err = c.ReadMessage(10 * time.Second)
+			if err != nil {
+				t.Fatalf("ReadMessage failed: %v", err)
+			}
+		} else {
+			ev := c.PollMessage(&reused, 100)
+			if ev == nil {
+				continue
+			}
+			if ev != &reused {
+				t.Fatalf("Unexpected event %v", ev)
+			}
+			m = &reused
+		}
+
+		if m.Headers != nil {
+			t.Errorf("Message %d: expected unparsed headers, not %v", i, m.Headers)
+		}
+
+		switch i {
+		case 1:
+			clone := m.Clone()
+			if !reflect.DeepEqual(clone.Headers, expHeaders(i)) {
+				t.Errorf("Clone of message %d: expected headers %v, not %v",
+					i, expHeaders(i), clone.Headers)
+			}
+		case 3:
+			// Released before being parsed
+			m.Release()
+			if m.GetHeaders() != nil {
+				t.Errorf("Expected no headers after Release(), not %v", m.Headers)
+			}
+			i++
+			continue
+		case 5:
+			// Copies share the lazy headers, parsed concurrently
+			cp := *m
+			hdrsChan := make(chan []Header)
+			go func() { hdrsChan <- cp.GetHeaders() }()
+			if hdrs := m.GetHeaders(); !reflect.DeepEqual(hdrs, expHeaders(i)) {
+				t.Errorf("Message %d: expected headers %v, not %v", i, expHeaders(i), hdrs)
+			}
+			if hdrs := <-hdrsChan; !reflect.DeepEqual(hdrs, expHeaders(i)) {
+				t.Errorf("Copy of message %d: expected headers %v, not %v", i, expHeaders(i), hdrs)
+			}
+			cp.Release()
+			m.Release()
+			if m.GetHeaders() != nil || cp.GetHeaders() != nil {
+				t.Errorf("Expected no headers after Release()")
+			}
+			i++
+			continue
+		case 7:
+			// A copy released before being parsed
+			cp := *m
+			cp.Release()
+			if m.GetHeaders() != nil {
+				t.Errorf("Expected no headers after the copy's Release(), not %v", m.Headers)
+			}
+			m.Release()
+			i++
+			continue
+		}
+
+		if hdrs := m.GetHeaders(); !reflect.DeepEqual(hdrs, expHeaders(i)) {
+			t.Errorf("Message %d: expected headers %v, not %v", i, expHeaders(i), hdrs)
+		}
+
+		if i%4 == 1 {
+			m.Release()
+			if m.Headers != nil {
+				t.Errorf("Expected no headers after Release(), not %v", m.Headers)
+			}
+		}
+		i++
+	}
+}
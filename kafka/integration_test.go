@@ -493,6 +493,82 @@ func TestConsumerGetWatermarkOffsets(t *testing.T) {
 
 }
 
+// TestConsumerConsumeRange tests that ConsumeRange() consumes the messages
+// within the time window, and stops on the handler's error.
+func TestConsumerConsumeRange(t *testing.T) {
+	if !testconfRead() {
+		t.Skipf("Missing testconf.json")
+	}
+
+	conf := ConfigMap{"bootstrap.servers": testconf.Brokers,
+		"group.id":           testconf.GroupID,
+		"enable.auto.commit": false}
+	conf.updateFromTestconf()
+
+	p, err := NewProducer(&conf)
+	if err != nil {
+		t.Fatalf("Failed to create Producer: %v", err)
+	}
+	defer p.Close()
+
+	// Timestamps in the past, in a window no other test produces to
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	drChan := make(chan Event, 10)
+	for i := 0; i < 10; i++ {
+		err = p.Produce(&Message{
+			TopicPartition: TopicPartition{Topic: &testconf.Topic, Partition: 0},
+			Value:          []byte(fmt.Sprintf("%s-%d", base, i)),
+			Timestamp:      base.Add(time.Duration(i) * time.Second),
+		}, drChan)
+		if err != nil {
+			t.Fatalf("Produce failed: %v", err)
+		}
+	}
+	for i := 0; i < 10; i++ {
+		if m := (<-drChan).(*Message); m.TopicPartition.Error != nil {
+			t.Fatalf("Delivery failed: %v", m.TopicPartition)
+		}
+	}
+
+	c, err := NewConsumer(&conf)
+	if err != nil {
+		t.Fatalf("Failed to create Consumer: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	start := base.Add(3 * time.Second)
+	end := base.Add(7 * time.Second)
+	consumed := make(map[string]bool)
+	err = c.ConsumeRange(ctx, testconf.Topic, start, end, func(m *Message) error {
+		if m.Timestamp.Before(start) || !m.Timestamp.Before(end) {
+			t.Errorf("Message %v timestamp %v outside of the window", m, m.Timestamp)
+		}
+		consumed[string(m.Value)] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ConsumeRange failed: %v", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		value := fmt.Sprintf("%s-%d", base, i)
+		if consumed[value] != (i >= 3 && i < 7) {
+			t.Errorf("Message %d consumed: %v, expected %v", i, consumed[value], !consumed[value])
+		}
+	}
+
+	stop := newErrorFromString(ErrApplication, "stop")
+	err = c.ConsumeRange(ctx, testconf.Topic, start, end, func(m *Message) error {
+		return stop
+	})
+	if err != stop {
+		t.Errorf("Expected the handler's error, not %v", err)
+	}
+}
+
 //TestConsumerOffsetsForTimes
 func TestConsumerOffsetsForTimes(t *testing.T) {
 	if !testconfRead() {
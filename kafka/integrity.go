@@ -0,0 +1,78 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+)
+
+// IntegrityHeaderKey is the key of the header holding the CRC32C checksum
+// of a message's value, as 4 big-endian bytes, stamped by producers with
+// `go.integrity.checksum` and verified by consumers with
+// `go.integrity.verify`.
+const IntegrityHeaderKey = "kafka-integrity-crc32c"
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// integrityChecksum returns the CRC32C checksum of value.
+func integrityChecksum(value []byte) uint32 {
+	return crc32.Checksum(value, crc32cTable)
+}
+
+// withIntegrityHeader returns msg's headers with an integrity header
+// for msg's value appended, msg's headers are not modified.
+func withIntegrityHeader(msg *Message) []Header {
+	checksum := make([]byte, 4)
+	binary.BigEndian.PutUint32(checksum, integrityChecksum(msg.Value))
+
+	headers := make([]Header, len(msg.Headers), len(msg.Headers)+1)
+	copy(headers, msg.Headers)
+	return append(headers, Header{Key: IntegrityHeaderKey, Value: checksum})
+}
+
+// verifyIntegrity verifies msg's value against its last integrity header,
+// if any, setting msg.TopicPartition.Error to an ErrBadMsg Error on
+// mismatch or malformed header.
+// Messages without an integrity header are not verified.
+func verifyIntegrity(msg *Message) {
+	if msg.TopicPartition.Error != nil {
+		return
+	}
+
+	headers := msg.GetHeaders()
+	for i := len(headers) - 1; i >= 0; i-- {
+		if headers[i].Key != IntegrityHeaderKey {
+			continue
+		}
+
+		if len(headers[i].Value) != 4 {
+			msg.TopicPartition.Error = newErrorFromString(ErrBadMsg,
+				fmt.Sprintf("Malformed %s header", IntegrityHeaderKey))
+			return
+		}
+
+		expected := binary.BigEndian.Uint32(headers[i].Value)
+		if actual := integrityChecksum(msg.Value); actual != expected {
+			msg.TopicPartition.Error = newErrorFromString(ErrBadMsg,
+				fmt.Sprintf("Message value checksum mismatch: %s header %08x, value %08x",
+					IntegrityHeaderKey, expected, actual))
+		}
+		return
+	}
+}
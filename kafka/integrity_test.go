@@ -0,0 +1,137 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestIntegrityHeaders tests that produced messages are stamped with
+// a checksum header, verified when consumed.
+func TestIntegrityHeaders(t *testing.T) {
+	mc, err := NewMockCluster(1)
+	if err != nil {
+		t.Fatalf("Failed to create MockCluster: %v", err)
+	}
+	defer mc.Close()
+
+	topic := "integrity"
+
+	produce := func(checksum bool, msgs ...*Message) {
+		p, err := NewProducer(&ConfigMap{
+			"bootstrap.servers":     mc.BootstrapServers(),
+			"go.integrity.checksum": checksum})
+		if err != nil {
+			t.Fatalf("Failed to create Producer: %v", err)
+		}
+		defer p.Close()
+
+		drChan := make(chan Event, len(msgs))
+		for _, m := range msgs {
+			m.TopicPartition = TopicPartition{Topic: &topic, Partition: 0}
+			if err = p.Produce(m, drChan); err != nil {
+				t.Fatalf("Produce failed: %v", err)
+			}
+		}
+		for range msgs {
+			if m := (<-drChan).(*Message); m.TopicPartition.Error != nil {
+				t.Fatalf("Delivery failed: %v", m.TopicPartition)
+			}
+		}
+	}
+
+	stamped := &Message{Value: []byte("stamped"), Headers: []Header{{"h", []byte("v")}}}
+	produce(true, stamped, &Message{Value: nil})
+	if len(stamped.Headers) != 1 {
+		t.Errorf("Expected the produced message's headers to be unmodified, not %v", stamped.Headers)
+	}
+
+	// Tampered with, malformed and unstamped messages
+	produce(false,
+		&Message{Value: []byte("tampered"),
+			Headers: []Header{{IntegrityHeaderKey, []byte{0, 0, 0, 1}}}},
+		&Message{Value: []byte("malformed"),
+			Headers: []Header{{IntegrityHeaderKey, []byte("x")}}},
+		&Message{Value: []byte("unstamped")})
+
+	c, err := NewConsumer(&ConfigMap{
+		"bootstrap.servers":   mc.BootstrapServers(),
+		"group.id":            "integrity",
+		"enable.auto.commit":  false,
+		"go.headers.lazy":     true,
+		"go.integrity.verify": true})
+	if err != nil {
+		t.Fatalf("Failed to create Consumer: %v", err)
+	}
+	defer c.Close()
+
+	if err = c.Assign([]TopicPartition{{Topic: &topic, Partition: 0, Offset: OffsetBeginning}}); err != nil {
+		t.Fatalf("Assign failed: %v", err)
+	}
+
+	var msgs []*Message
+	for tEnd := time.Now().Add(10 * time.Second); len(msgs) < 5; {
+		if time.Now().After(tEnd) {
+			t.Fatalf("Timed out, consumed %v", msgs)
+		}
+		if m, ok := c.Poll(100).(*Message); ok {
+			msgs = append(msgs, m)
+		}
+	}
+
+	for i, m := range msgs {
+		kerr, isKafkaErr := m.TopicPartition.Error.(Error)
+		switch i {
+		case 0, 1, 4:
+			if m.TopicPartition.Error != nil {
+				t.Errorf("Message %d: unexpected error %v", i, m.TopicPartition.Error)
+			}
+		case 2:
+			expected := fmt.Sprintf("Message value checksum mismatch: %s header 00000001, value %08x",
+				IntegrityHeaderKey, integrityChecksum([]byte("tampered")))
+			if !isKafkaErr || kerr.Code() != ErrBadMsg || kerr.Error() != expected {
+				t.Errorf("Message %d: expected checksum mismatch, not %v", i, m.TopicPartition.Error)
+			}
+		case 3:
+			if !isKafkaErr || kerr.Code() != ErrBadMsg ||
+				kerr.Error() != "Malformed "+IntegrityHeaderKey+" header" {
+				t.Errorf("Message %d: expected malformed header, not %v", i, m.TopicPartition.Error)
+			}
+		}
+	}
+
+	if hdrs := msgs[0].GetHeaders(); len(hdrs) != 2 || hdrs[1].Key != IntegrityHeaderKey {
+		t.Errorf("Expected the integrity header after the message's headers, not %v", hdrs)
+	}
+}
+
+// TestIntegrityBatchProducer tests that go.integrity.checksum can't be
+// combined with go.batch.producer, which wouldn't stamp the messages.
+func TestIntegrityBatchProducer(t *testing.T) {
+	p, err := NewProducer(&ConfigMap{
+		"go.batch.producer":     true,
+		"go.integrity.checksum": true})
+	if err == nil {
+		p.Close()
+		t.Fatalf("Expected go.integrity.checksum with go.batch.producer to fail")
+	}
+	if err.(Error).Code() != ErrInvalidArg {
+		t.Errorf("Expected ErrInvalidArg, not %v", err)
+	}
+}
@@ -0,0 +1,229 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"context"
+	"time"
+)
+
+// ProducerClient is the method set of the Producer.
+//
+// Applications may depend on ProducerClient rather than *Producer
+// to be able to substitute the Producer with a mock or fake
+// implementation in tests, such as the MockProducer, see NewMockProducer().
+type ProducerClient interface {
+	// String returns a human readable name for the Producer instance
+	String() string
+
+	// Produce single message, see Producer.Produce()
+	Produce(msg *Message, deliveryChan chan Event) error
+	// Events returns the Events channel (read)
+	Events() chan Event
+	// Logs returns the Log channel (if enabled), else nil
+	Logs() chan LogEvent
+	// ProduceChannel returns the produce *Message channel (write)
+	ProduceChannel() chan *Message
+	// Len returns the number of messages and requests waiting to be
+	// transmitted to the broker as well as delivery reports queued for
+	// the application.
+	Len() int
+	// Flush and wait for outstanding messages and requests to complete delivery.
+	Flush(timeoutMs int) int
+	// Close the Producer instance.
+	Close()
+	// Purge messages currently handled by this producer instance.
+	Purge(flags int) error
+
+	// GetMetadata queries broker for cluster and topic metadata.
+	GetMetadata(topic *string, allTopics bool, timeoutMs int) (*Metadata, error)
+	// Metadata queries the cluster for the metadata of the selected topics.
+	Metadata(ctx context.Context, opts MetadataOptions) (*Metadata, error)
+	// QueryWatermarkOffsets returns the broker's low and high offsets for
+	// the given topic and partition.
+	QueryWatermarkOffsets(topic string, partition int32, timeoutMs int) (low, high int64, err error)
+	// OffsetsForTimes looks up offsets by timestamp for the given partitions.
+	OffsetsForTimes(times []TopicPartition, timeoutMs int) (offsets []TopicPartition, err error)
+	// GetFatalError returns an Error object if the client instance has
+	// raised a fatal error, else nil.
+	GetFatalError() error
+	// TestFatalError triggers a fatal error in the underlying client.
+	TestFatalError(code ErrorCode, str string) ErrorCode
+
+	// SetOAuthBearerToken sets the data to be transmitted
+	// to a broker during SASL/OAUTHBEARER authentication.
+	SetOAuthBearerToken(oauthBearerToken OAuthBearerToken) error
+	// SetOAuthBearerTokenFailure sets the error message describing why
+	// token retrieval/setting failed.
+	SetOAuthBearerTokenFailure(errstr string) error
+
+	// InitTransactions initializes transactions for the producer instance.
+	InitTransactions(ctx context.Context) error
+	// BeginTransaction starts a new transaction.
+	BeginTransaction() error
+	// SendOffsetsToTransaction sends a list of topic partition offsets to
+	// the consumer group coordinator and marks the offsets as part of the
+	// current transaction.
+	SendOffsetsToTransaction(ctx context.Context, offsets []TopicPartition, consumerMetadata *ConsumerGroupMetadata) error
+	// CommitTransaction commits the current transaction.
+	CommitTransaction(ctx context.Context) error
+	// AbortTransaction aborts the ongoing transaction.
+	AbortTransaction(ctx context.Context) error
+}
+
+// ConsumerClient is the method set of the Consumer.
+//
+// Applications may depend on ConsumerClient rather than *Consumer
+// to be able to substitute the Consumer with a mock or fake
+// implementation in tests, such as the MockConsumer, see NewMockConsumer().
+type ConsumerClient interface {
+	// String returns a human readable name for the Consumer instance
+	String() string
+
+	// Subscribe to a single topic, replacing the current subscription.
+	Subscribe(topic string, rebalanceCb RebalanceCb) error
+	// SubscribeTopics subscribes to the provided list of topics,
+	// replacing the current subscription.
+	SubscribeTopics(topics []string, rebalanceCb RebalanceCb) (err error)
+	// Unsubscribe from the current subscription, if any.
+	Unsubscribe() (err error)
+	// Subscription returns the current subscription.
+	Subscription() (topics []string, err error)
+
+	// Assign an atomic set of partitions to consume.
+	Assign(partitions []TopicPartition) (err error)
+	// Unassign the current set of partitions to consume.
+	Unassign() (err error)
+	// IncrementalAssign adds the specified partitions to the current
+	// set of partitions to consume.
+	IncrementalAssign(partitions []TopicPartition) (err error)
+	// IncrementalUnassign removes the specified partitions from the
+	// current set of partitions to consume.
+	IncrementalUnassign(partitions []TopicPartition) (err error)
+	// Assignment returns the current partition assignments.
+	Assignment() (partitions []TopicPartition, err error)
+	// GetRebalanceProtocol returns the current consumer group rebalance
+	// protocol, which is either "EAGER" or "COOPERATIVE".
+	GetRebalanceProtocol() string
+	// AssignmentLost returns true if current partition assignment has
+	// been lost.
+	AssignmentLost() bool
+
+	// Commit offsets for currently assigned partitions.
+	Commit() ([]TopicPartition, error)
+	// CommitMessage commits offset based on the provided message.
+	CommitMessage(m *Message) ([]TopicPartition, error)
+	// CommitOffsets commits the provided list of offsets.
+	CommitOffsets(offsets []TopicPartition) ([]TopicPartition, error)
+	// StoreOffsets stores the provided list of offsets that will be
+	// committed to the offset store.
+	StoreOffsets(offsets []TopicPartition) (storedOffsets []TopicPartition, err error)
+	// StoreMessage stores offset based on the provided message.
+	StoreMessage(m *Message) (storedOffsets []TopicPartition, err error)
+	// Committed retrieves committed offsets for the given set of partitions.
+	Committed(partitions []TopicPartition, timeoutMs int) (offsets []TopicPartition, err error)
+	// Position returns the current consume position for the given partitions.
+	Position(partitions []TopicPartition) (offsets []TopicPartition, err error)
+	// Seek seeks the given topic partitions using the offset from the
+	// TopicPartition.
+	Seek(partition TopicPartition, timeoutMs int) error
+	// Pause consumption for the provided list of partitions.
+	Pause(partitions []TopicPartition) (err error)
+	// Resume consumption for the provided list of partitions.
+	Resume(partitions []TopicPartition) (err error)
+
+	// Poll the consumer for messages or events.
+	Poll(timeoutMs int) (event Event)
+	// ReadMessage polls the consumer for a message.
+	ReadMessage(timeout time.Duration) (*Message, error)
+	// Events returns the Events channel (if enabled).
+	Events() chan Event
+	// Logs returns the log channel if enabled, or nil otherwise.
+	Logs() chan LogEvent
+	// Close the Consumer instance.
+	Close() (err error)
+
+	// GetMetadata queries broker for cluster and topic metadata.
+	GetMetadata(topic *string, allTopics bool, timeoutMs int) (*Metadata, error)
+	// Metadata queries the cluster for the metadata of the selected topics.
+	Metadata(ctx context.Context, opts MetadataOptions) (*Metadata, error)
+	// QueryWatermarkOffsets queries the broker for the low and high
+	// offsets for the given topic and partition.
+	QueryWatermarkOffsets(topic string, partition int32, timeoutMs int) (low, high int64, err error)
+	// GetWatermarkOffsets returns the cached low and high offsets for the
+	// given topic and partition.
+	GetWatermarkOffsets(topic string, partition int32) (low, high int64, err error)
+	// OffsetsForTimes looks up offsets by timestamp for the given partitions.
+	OffsetsForTimes(times []TopicPartition, timeoutMs int) (offsets []TopicPartition, err error)
+	// GetConsumerGroupMetadata returns the consumer's current group metadata.
+	GetConsumerGroupMetadata() (*ConsumerGroupMetadata, error)
+
+	// SetOAuthBearerToken sets the data to be transmitted
+	// to a broker during SASL/OAUTHBEARER authentication.
+	SetOAuthBearerToken(oauthBearerToken OAuthBearerToken) error
+	// SetOAuthBearerTokenFailure sets the error message describing why
+	// token retrieval/setting failed.
+	SetOAuthBearerTokenFailure(errstr string) error
+}
+
+// AdminAPI is the method set of the AdminClient.
+//
+// Applications may depend on AdminAPI rather than *AdminClient
+// to be able to substitute the AdminClient with a mock or fake
+// implementation in tests.
+type AdminAPI interface {
+	// String returns a human readable name for the AdminClient instance
+	String() string
+
+	// ClusterID returns the cluster ID as reported in broker metadata.
+	ClusterID(ctx context.Context) (clusterID string, err error)
+	// ControllerID returns the broker ID of the current controller as
+	// reported in broker metadata.
+	ControllerID(ctx context.Context) (controllerID int32, err error)
+	// GetMetadata queries broker for cluster and topic metadata.
+	GetMetadata(topic *string, allTopics bool, timeoutMs int) (*Metadata, error)
+	// Metadata queries the cluster for the metadata of the selected topics.
+	Metadata(ctx context.Context, opts MetadataOptions) (*Metadata, error)
+
+	// CreateTopics creates topics in cluster.
+	CreateTopics(ctx context.Context, topics []TopicSpecification, options ...CreateTopicsAdminOption) (result []TopicResult, err error)
+	// DeleteTopics deletes a batch of topics.
+	DeleteTopics(ctx context.Context, topics []string, options ...DeleteTopicsAdminOption) (result []TopicResult, err error)
+	// CreatePartitions creates additional partitions for topics.
+	CreatePartitions(ctx context.Context, partitions []PartitionsSpecification, options ...CreatePartitionsAdminOption) (result []TopicResult, err error)
+	// AlterConfigs alters/updates cluster resource configuration.
+	AlterConfigs(ctx context.Context, resources []ConfigResource, options ...AlterConfigsAdminOption) (result []ConfigResourceResult, err error)
+	// DescribeConfigs retrieves configuration for cluster resources.
+	DescribeConfigs(ctx context.Context, resources []ConfigResource, options ...DescribeConfigsAdminOption) (result []ConfigResourceResult, err error)
+
+	// SetOAuthBearerToken sets the data to be transmitted
+	// to a broker during SASL/OAUTHBEARER authentication.
+	SetOAuthBearerToken(oauthBearerToken OAuthBearerToken) error
+	// SetOAuthBearerTokenFailure sets the error message describing why
+	// token retrieval/setting failed.
+	SetOAuthBearerTokenFailure(errstr string) error
+
+	// Close the AdminClient instance.
+	Close()
+}
+
+// Make sure the concrete client types implement the interfaces.
+var (
+	_ ProducerClient = (*Producer)(nil)
+	_ ConsumerClient = (*Consumer)(nil)
+	_ AdminAPI       = (*AdminClient)(nil)
+)
@@ -221,12 +221,21 @@
 //
 // * `OffsetsCommitted` - Offset commit results (when `enable.auto.commit` is enabled).
 //
+// * `PartitionRangeEnd` - A range assigned with `Consumer.AssignRanges()` was consumed up to its end.
+//
 //
 // Producer events
 //
 // * `*kafka.Message` - delivery report for produced message.
 // Check `.TopicPartition.Error` for delivery result.
 //
+// * `*kafka.DeliveryTimeout` - delivery report for a message that timed out,
+// replaces the `*kafka.Message` delivery report for such messages.
+// Requires `go.delivery.timeout.events`
+//
+// * `PartitionCountChange` - the partition count of a produced-to topic changed.
+// Requires `go.partition.count.events` and `statistics.interval.ms`.
+//
 //
 // Generic events for both Consumer and Producer
 //
@@ -243,6 +252,19 @@
 // the required regular expression); invoking SetOAuthBearerTokenFailure() will
 // schedule a new event for 10 seconds later so another retrieval can be attempted.
 //
+// * `*kafka.Stats` - statistics JSON document, emitted every `statistics.interval.ms`.
+// Use `.Parse()` to parse it into a `*kafka.Statistics` struct.
+//
+// * `*kafka.StatsEvent` - parsed statistics, emitted instead of `*kafka.Stats`.
+// Requires `go.statistics.parse`
+//
+// * `BrokerUp`, `BrokerDown`, `AllBrokersDown` - broker connection state changes,
+// `BrokerDown` and `AllBrokersDown` are emitted instead of the corresponding `KafkaError`.
+// Requires `go.broker.state.events`, and `statistics.interval.ms` for `BrokerUp`.
+//
+// * `ThrottleEvent` - a broker throttled requests due to quota violation.
+// Requires `go.throttle.events` and `statistics.interval.ms`.
+//
 //
 // Hint: If your application registers a signal notification
 // (signal.Notify) makes sure the signals channel is buffered to avoid
@@ -30,6 +30,22 @@ func TestLibraryVersion(t *testing.T) {
 	}
 }
 
+// TestRequireLibrdkafkaVersion tests runtime librdkafka feature detection
+func TestRequireLibrdkafkaVersion(t *testing.T) {
+	ver, _ := LibraryVersion()
+
+	if err := requireLibrdkafkaVersion("Feature", ver); err != nil {
+		t.Errorf("Expected current version to be supported, got %v", err)
+	}
+
+	err := requireLibrdkafkaVersion("Feature", 0x63000000)
+	if err == nil || err.(Error).Code() != ErrUnsupportedFeature {
+		t.Errorf("Expected ErrUnsupportedFeature, got %v", err)
+	} else {
+		t.Logf("%v", err)
+	}
+}
+
 //Test Offset APIs
 func TestOffsetAPIs(t *testing.T) {
 	offsets := []Offset{OffsetBeginning, OffsetEnd, OffsetInvalid, OffsetStored, 1001}
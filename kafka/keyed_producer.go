@@ -0,0 +1,304 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// keyedMsg is a message produced by a KeyedProducer.
+type keyedMsg struct {
+	key          string
+	msg          *Message
+	deliveryChan chan Event
+	opaque       interface{}
+	attempts     int
+	// inFlight is 1 while produced, awaiting its delivery report,
+	// accessed atomically since set by Produce() and the handler.
+	inFlight int32
+}
+
+// KeyedProducer produces messages through a Producer preserving the order
+// of the messages of each key, even with `max.in.flight` > 1 and
+// application-level retries: only one message per key is in flight, the
+// following messages of the key are queued until its delivery report.
+//
+// Failed deliveries are retried up to Retries times before the next
+// message of the key is sent, and queue full errors of queued messages
+// are retried until they fit. Messages without a key are produced as is.
+//
+// Delivery reports must not be disabled with `go.delivery.reports`.
+//
+// This trades throughput for ordering: the messages of a key are sent one
+// per round-trip, while messages of distinct keys are sent concurrently.
+type KeyedProducer struct {
+	// Retries is the number of times a failed delivery is retried
+	// before its delivery report is emitted, 0 by default.
+	// Must be set before producing.
+	Retries int
+
+	p        *Producer
+	drChan   chan Event
+	lock     sync.Mutex
+	queues   map[string][]*keyedMsg
+	queued   int
+	termChan chan bool
+	doneChan chan bool
+}
+
+// NewKeyedProducer creates a KeyedProducer producing through p,
+// which remains owned by the application.
+func NewKeyedProducer(p *Producer) *KeyedProducer {
+	kp := &KeyedProducer{
+		p:        p,
+		drChan:   make(chan Event, 1000),
+		queues:   make(map[string][]*keyedMsg),
+		termChan: make(chan bool),
+		doneChan: make(chan bool),
+	}
+
+	go kp.handleDeliveryReports()
+
+	return kp
+}
+
+// Produce produces msg once the previous messages with the same key were
+// delivered, see Producer.Produce().
+// The delivery report is sent on deliveryChan if specified,
+// or on the Producer's Events() channel if not.
+func (kp *KeyedProducer) Produce(msg *Message, deliveryChan chan Event) error {
+	if msg.Key == nil {
+		return kp.p.Produce(msg, deliveryChan)
+	}
+
+	key := string(msg.Key)
+	km := &keyedMsg{key: key, msg: msg, deliveryChan: deliveryChan, opaque: msg.Opaque}
+
+	kp.lock.Lock()
+	defer kp.lock.Unlock()
+
+	kp.queues[key] = append(kp.queues[key], km)
+	kp.queued++
+	if len(kp.queues[key]) > 1 {
+		// Sent on the delivery of the previous message of the key
+		return nil
+	}
+
+	if err := kp.send(km); err != nil {
+		kp.dequeue(key)
+		return err
+	}
+
+	return nil
+}
+
+// send produces km, the head of its key's queue.
+func (kp *KeyedProducer) send(km *keyedMsg) error {
+	km.attempts++
+	atomic.StoreInt32(&km.inFlight, 1)
+	km.msg.Opaque = km
+	err := kp.p.Produce(km.msg, kp.drChan)
+	if err != nil {
+		km.attempts--
+		atomic.StoreInt32(&km.inFlight, 0)
+		km.msg.Opaque = km.opaque
+	}
+	return err
+}
+
+// dequeue removes the head of key's queue.
+// Returns the next message of key, if any.
+// Must be called with kp.lock held.
+func (kp *KeyedProducer) dequeue(key string) *keyedMsg {
+	q := kp.queues[key]
+	kp.queued--
+	if len(q) == 1 {
+		delete(kp.queues, key)
+		return nil
+	}
+	q[0] = nil
+	kp.queues[key] = q[1:]
+	return q[1]
+}
+
+// report emits km's delivery report, applying the Producer's events
+// overflow policy to the Events() channel.
+// Gives up if the Producer is closed.
+func (kp *KeyedProducer) report(km *keyedMsg) {
+	km.msg.Opaque = km.opaque
+	if km.deliveryChan == nil {
+		kp.p.handle.sendEvent(kp.p.events, km.msg, kp.p.pollerTermChan)
+		return
+	}
+
+	select {
+	case km.deliveryChan <- km.msg:
+	case <-kp.p.pollerTermChan:
+	}
+}
+
+// done reports km, the head of its key's queue, and returns the pending
+// messages with the next message of the key appended, if any.
+func (kp *KeyedProducer) done(km *keyedMsg, pending []*keyedMsg) []*keyedMsg {
+	kp.report(km)
+
+	kp.lock.Lock()
+	next := kp.dequeue(km.key)
+	kp.lock.Unlock()
+
+	if next != nil {
+		pending = append(pending, next)
+	}
+	return pending
+}
+
+// sendPending sends the pending messages, until the queue is full.
+// Returns the messages still pending.
+func (kp *KeyedProducer) sendPending(pending []*keyedMsg) []*keyedMsg {
+	for len(pending) > 0 {
+		km := pending[0]
+		err := kp.send(km)
+		if kerr, ok := err.(Error); ok && kerr.Code() == ErrQueueFull {
+			break
+		}
+
+		pending = pending[1:]
+		if err != nil {
+			km.msg.TopicPartition.Error = err
+			pending = kp.done(km, pending)
+		}
+	}
+
+	return pending
+}
+
+// purge reports the pending messages and the queued messages not in
+// flight as failed.
+func (kp *KeyedProducer) purge(pending []*keyedMsg) {
+	var purged []*keyedMsg
+
+	kp.lock.Lock()
+	for key, q := range kp.queues {
+		inFlight := q[:0]
+		for _, km := range q {
+			if atomic.LoadInt32(&km.inFlight) == 1 {
+				inFlight = append(inFlight, km)
+				continue
+			}
+			purged = append(purged, km)
+			kp.queued--
+		}
+
+		if len(inFlight) == 0 {
+			delete(kp.queues, key)
+		} else {
+			kp.queues[key] = inFlight
+		}
+	}
+	kp.lock.Unlock()
+
+	// Reported without the lock since report() may block
+	for _, km := range purged {
+		km.msg.TopicPartition.Error = newErrorFromString(ErrPurgeQueue,
+			"KeyedProducer closed")
+		kp.report(km)
+	}
+}
+
+// handleDeliveryReports retries failed messages, emits the delivery
+// reports and sends the next messages of the keys.
+// Messages are not sent from the Producer's poller, which would block
+// on full queues.
+// Once closed, the queued messages are purged and the goroutine returns
+// after the delivery of the messages in flight.
+func (kp *KeyedProducer) handleDeliveryReports() {
+	defer close(kp.doneChan)
+
+	// Queue heads to send, once the queue has room for them
+	var pending []*keyedMsg
+	termChan := kp.termChan
+	closed := false
+
+	for !closed || kp.Len() > 0 {
+		var retryChan <-chan time.Time
+		if len(pending) > 0 {
+			retryChan = time.After(10 * time.Millisecond)
+		}
+
+		select {
+		case <-termChan:
+			termChan = nil
+			closed = true
+		case <-retryChan:
+		case ev := <-kp.drChan:
+			m, ok := ev.(*Message)
+			if !ok {
+				continue
+			}
+			km := m.Opaque.(*keyedMsg)
+			atomic.StoreInt32(&km.inFlight, 0)
+
+			if m.TopicPartition.Error != nil && km.attempts <= kp.Retries &&
+				kp.p.GetFatalError() == nil {
+				pending = append(pending, km)
+			} else {
+				km.msg = m
+				pending = kp.done(km, pending)
+			}
+		}
+
+		if closed {
+			kp.purge(pending)
+			pending = nil
+		} else {
+			pending = kp.sendPending(pending)
+		}
+	}
+}
+
+// Len returns the number of keyed messages queued or in flight.
+func (kp *KeyedProducer) Len() int {
+	kp.lock.Lock()
+	defer kp.lock.Unlock()
+	return kp.queued
+}
+
+// Flush waits for the keyed messages to be delivered, for at most
+// timeoutMs milliseconds, or indefinitely if -1.
+// Returns the number of messages queued or in flight.
+func (kp *KeyedProducer) Flush(timeoutMs int) int {
+	tEnd := time.Now().Add(time.Duration(timeoutMs) * time.Millisecond)
+	for {
+		n := kp.Len()
+		if n == 0 || (timeoutMs >= 0 && !time.Now().Before(tEnd)) {
+			return n
+		}
+		kp.p.Flush(10)
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// Close stops sending the queued messages, which are reported as failed
+// with ErrPurgeQueue, and waits for the delivery reports of the messages
+// in flight. The Producer is not closed.
+// Use Flush() first to have all messages sent.
+func (kp *KeyedProducer) Close() {
+	close(kp.termChan)
+	<-kp.doneChan
+}
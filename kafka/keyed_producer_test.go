@@ -0,0 +1,145 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestKeyedProducer tests that the messages of each key are delivered in
+// order, through queue full errors and retried deliveries.
+func TestKeyedProducer(t *testing.T) {
+	mc, err := NewMockCluster(1)
+	if err != nil {
+		t.Fatalf("Failed to create MockCluster: %v", err)
+	}
+	defer mc.Close()
+
+	topic := "keyed"
+	if err = mc.CreateTopic(topic, 4, 1); err != nil {
+		t.Fatalf("CreateTopic failed: %v", err)
+	}
+
+	p, err := NewProducer(&ConfigMap{
+		"bootstrap.servers":                     mc.BootstrapServers(),
+		"queue.buffering.max.messages":          5,
+		"max.in.flight.requests.per.connection": 5,
+		"linger.ms":                             0})
+	if err != nil {
+		t.Fatalf("Failed to create Producer: %v", err)
+	}
+	defer p.Close()
+
+	kp := NewKeyedProducer(p)
+	kp.Retries = 2
+
+	// The first produce requests fail
+	if err = mc.PushRequestErrors(0, ErrMsgSizeTooLarge, ErrMsgSizeTooLarge); err != nil {
+		t.Fatalf("PushRequestErrors failed: %v", err)
+	}
+
+	keys := 4
+	msgcnt := 100
+	drChan := make(chan Event, msgcnt)
+	for i := 0; i < msgcnt; i++ {
+		msg := &Message{
+			TopicPartition: TopicPartition{Topic: &topic, Partition: PartitionAny},
+			Key:            []byte(fmt.Sprintf("key-%d", i%keys)),
+			Value:          []byte(fmt.Sprintf("%d", i)),
+			Opaque:         i,
+		}
+		for {
+			err = kp.Produce(msg, drChan)
+			if err == nil {
+				break
+			}
+			if err.(Error).Code() != ErrQueueFull {
+				t.Fatalf("Produce failed: %v", err)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	if n := kp.Flush(10000); n != 0 {
+		t.Fatalf("%d messages not delivered", n)
+	}
+
+	last := make(map[string]int)
+	offsets := make(map[string]Offset)
+	for i := 0; i < msgcnt; i++ {
+		m := (<-drChan).(*Message)
+		if m.TopicPartition.Error != nil {
+			t.Fatalf("Delivery failed: %v", m.TopicPartition)
+		}
+
+		key := string(m.Key)
+		n := m.Opaque.(int)
+		if prev, found := last[key]; found && (n != prev+keys || m.TopicPartition.Offset <= offsets[key]) {
+			t.Errorf("%s: message %d at %v delivered after message %d at %v",
+				key, n, m.TopicPartition.Offset, prev, offsets[key])
+		}
+		last[key] = n
+		offsets[key] = m.TopicPartition.Offset
+	}
+
+	kp.Close()
+}
+
+// TestKeyedProducerClose tests that the queued messages are purged on Close().
+func TestKeyedProducerClose(t *testing.T) {
+	p, err := NewProducer(&ConfigMap{
+		"bootstrap.servers":  "127.0.0.1:1",
+		"message.timeout.ms": 100})
+	if err != nil {
+		t.Fatalf("Failed to create Producer: %v", err)
+	}
+	defer p.Close()
+
+	kp := NewKeyedProducer(p)
+
+	topic := "keyedclose"
+	drChan := make(chan Event, 3)
+	for i := 0; i < 3; i++ {
+		err = kp.Produce(&Message{
+			TopicPartition: TopicPartition{Topic: &topic, Partition: 0},
+			Key:            []byte("key"),
+		}, drChan)
+		if err != nil {
+			t.Fatalf("Produce failed: %v", err)
+		}
+	}
+
+	if n := kp.Len(); n != 3 {
+		t.Errorf("Expected 3 queued messages, not %d", n)
+	}
+
+	kp.Close()
+
+	codes := make(map[ErrorCode]int)
+	for i := 0; i < 3; i++ {
+		m := (<-drChan).(*Message)
+		codes[m.TopicPartition.Error.(Error).Code()]++
+	}
+	if codes[ErrPurgeQueue] != 2 || codes[ErrMsgTimedOut] != 1 {
+		t.Errorf("Expected 2 purged and 1 timed out messages, not %v", codes)
+	}
+	if n := kp.Len(); n != 0 {
+		t.Errorf("Expected no queued messages, not %d", n)
+	}
+}
@@ -19,6 +19,23 @@ type LogEvent struct {
 	Timestamp time.Time // Log timestamp
 }
 
+// Logger is implemented by loggers that client logs can be forwarded to
+// instead of the Logs() channel, see the `go.logger` configuration property.
+//
+// Log is called from an internal goroutine, one log event at a time,
+// and must not block for long.
+type Logger interface {
+	Log(logEvent LogEvent)
+}
+
+// LoggerFunc is an adapter to allow the use of ordinary functions as Loggers.
+type LoggerFunc func(logEvent LogEvent)
+
+// Log calls f(logEvent).
+func (f LoggerFunc) Log(logEvent LogEvent) {
+	f(logEvent)
+}
+
 // newLogEvent creates a new LogEvent from the given rd_kafka_event_t.
 //
 // This function does not take ownership of the cEvent pointer. You need to
@@ -42,7 +59,7 @@ func (h *handle) newLogEvent(cEvent *C.rd_kafka_event_t) LogEvent {
 }
 
 // pollLogEvents polls log events from librdkafka and pushes them to toChannel,
-// until doneChan is closed.
+// or passes them to the handle's Logger if set, until doneChan is closed.
 //
 // Each call to librdkafka times out after timeoutMs. If a call to librdkafka
 // is ongoing when doneChan is closed, the function will wait until the call
@@ -67,13 +84,14 @@ func (h *handle) pollLogEvents(toChannel chan LogEvent, timeoutMs int, doneChan
 			logEvent := h.newLogEvent(cEvent)
 			C.rd_kafka_event_destroy(cEvent)
 
-			select {
-			case <-doneChan:
-				return
-
-			case toChannel <- logEvent:
+			if h.logger != nil {
+				h.logger.Log(logEvent)
 				continue
 			}
+
+			if h.sendLogEvent(toChannel, logEvent, doneChan) {
+				return
+			}
 		}
 	}
 }
@@ -0,0 +1,77 @@
+//go:build go1.21
+// +build go1.21
+
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"context"
+	"log/slog"
+)
+
+// slogLogger forwards client logs to a slog.Handler.
+type slogLogger struct {
+	handler slog.Handler
+}
+
+// NewSlogLogger returns a Logger forwarding client logs as structured
+// log records to handler, for use with the `go.logger` configuration
+// property.
+//
+// Each record carries the log message, the librdkafka syslog level
+// mapped to a slog.Level, and the attributes "client" (client instance
+// name), "facility" (log tag, e.g., "METADATA") and "syslog_level".
+func NewSlogLogger(handler slog.Handler) Logger {
+	return slogLogger{handler: handler}
+}
+
+// slogLevel maps a syslog level to a slog.Level.
+func slogLevel(syslogLevel int) slog.Level {
+	switch {
+	case syslogLevel <= 3:
+		// LOG_EMERG, LOG_ALERT, LOG_CRIT, LOG_ERR
+		return slog.LevelError
+	case syslogLevel == 4:
+		// LOG_WARNING
+		return slog.LevelWarn
+	case syslogLevel <= 6:
+		// LOG_NOTICE, LOG_INFO
+		return slog.LevelInfo
+	default:
+		// LOG_DEBUG
+		return slog.LevelDebug
+	}
+}
+
+// Log implements Logger
+func (l slogLogger) Log(logEvent LogEvent) {
+	ctx := context.Background()
+	level := slogLevel(logEvent.Level)
+
+	if !l.handler.Enabled(ctx, level) {
+		return
+	}
+
+	record := slog.NewRecord(logEvent.Timestamp, level, logEvent.Message, 0)
+	record.AddAttrs(
+		slog.String("client", logEvent.Name),
+		slog.String("facility", logEvent.Tag),
+		slog.Int("syslog_level", logEvent.Level))
+
+	l.handler.Handle(ctx, record)
+}
@@ -0,0 +1,62 @@
+//go:build go1.21
+// +build go1.21
+
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+// TestSlogLogger tests the slog Logger adapter.
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewSlogLogger(slog.NewJSONHandler(&buf,
+		&slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	logger.Log(LogEvent{Name: "rdkafka#producer-1", Tag: "METADATA",
+		Message: "debug message", Level: 7, Timestamp: time.Now()})
+	if buf.Len() != 0 {
+		t.Errorf("Expected debug log to be filtered, got %s", buf.String())
+	}
+
+	logger.Log(LogEvent{Name: "rdkafka#producer-1", Tag: "FAIL",
+		Message: "connection refused", Level: 3, Timestamp: time.Now()})
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Failed to parse log record %s: %s", buf.String(), err)
+	}
+
+	expected := map[string]interface{}{
+		"level":        "ERROR",
+		"msg":          "connection refused",
+		"client":       "rdkafka#producer-1",
+		"facility":     "FAIL",
+		"syslog_level": float64(3),
+	}
+	for key, value := range expected {
+		if record[key] != value {
+			t.Errorf("Expected %s %v, got %v", key, value, record[key])
+		}
+	}
+}
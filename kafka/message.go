@@ -18,6 +18,7 @@ package kafka
 
 import (
 	"fmt"
+	"sync"
 	"time"
 	"unsafe"
 )
@@ -78,10 +79,20 @@ type Message struct {
 	TimestampType  TimestampType
 	Opaque         interface{}
 	Headers        []Header
+
+	// Delivery report information, see BrokerID() and Latency()
+	brokerID  int32
+	latency   time.Duration
+	hasDrInfo bool
+
+	// Buffers the Key and Value refer to, see Release()
+	zeroCopy *zeroCopyBuf
+	// Unparsed headers, see GetHeaders()
+	lazyHeaders *lazyHeaders
 }
 
 // String returns a human readable representation of a Message.
-// Key and payload are not represented.
+// Key and payload are quoted and truncated, headers are not represented.
 func (m *Message) String() string {
 	var topic string
 	if m.TopicPartition.Topic != nil {
@@ -89,7 +100,87 @@ func (m *Message) String() string {
 	} else {
 		topic = ""
 	}
-	return fmt.Sprintf("%s[%d]@%s", topic, m.TopicPartition.Partition, m.TopicPartition.Offset)
+	return fmt.Sprintf("%s[%d]@%s key=%s value=%s",
+		topic, m.TopicPartition.Partition, m.TopicPartition.Offset,
+		quoteTruncated(m.Key), quoteTruncated(m.Value))
+}
+
+// Clone returns a deep copy of the message: the topic, key, value and
+// headers, which are parsed first if lazily parsed, are copied,
+// while Opaque refers to the same object.
+func (m *Message) Clone() *Message {
+	m.GetHeaders()
+
+	c := *m
+	c.zeroCopy = nil
+	c.lazyHeaders = nil
+
+	if m.TopicPartition.Topic != nil {
+		topic := *m.TopicPartition.Topic
+		c.TopicPartition.Topic = &topic
+	}
+	if m.TopicPartition.Metadata != nil {
+		metadata := *m.TopicPartition.Metadata
+		c.TopicPartition.Metadata = &metadata
+	}
+	if m.Key != nil {
+		c.Key = append([]byte{}, m.Key...)
+	}
+	if m.Value != nil {
+		c.Value = append([]byte{}, m.Value...)
+	}
+	if m.Headers != nil {
+		c.Headers = make([]Header, len(m.Headers))
+		for i, h := range m.Headers {
+			c.Headers[i].Key = h.Key
+			if h.Value != nil {
+				c.Headers[i].Value = append([]byte{}, h.Value...)
+			}
+		}
+	}
+
+	return &c
+}
+
+// Size returns the size of the message in bytes,
+// the sum of the key, value and header key and value sizes.
+func (m *Message) Size() int {
+	size := len(m.Key) + len(m.Value)
+	for _, h := range m.Headers {
+		size += len(h.Key) + len(h.Value)
+	}
+	return size
+}
+
+// BrokerID returns the id of the broker the message was produced to,
+// or -1 if unknown.
+// Only available on producer delivery reports.
+func (m *Message) BrokerID() int32 {
+	if !m.hasDrInfo {
+		return -1
+	}
+	return m.brokerID
+}
+
+// Latency returns the time from the Produce() call until the message
+// was acknowledged by the broker, or -1 if unknown.
+// Only available on producer delivery reports.
+func (m *Message) Latency() time.Duration {
+	if !m.hasDrInfo {
+		return -1
+	}
+	return m.latency
+}
+
+// setupDrInfoFromC sets the delivery report information of msg
+// from the produced C message.
+func setupDrInfoFromC(msg *Message, cmsg *C.rd_kafka_message_t) {
+	msg.hasDrInfo = true
+	msg.brokerID = int32(C.rd_kafka_message_broker_id(cmsg))
+	msg.latency = -1
+	if latency := int64(C.rd_kafka_message_latency(cmsg)); latency >= 0 {
+		msg.latency = time.Duration(latency) * time.Microsecond
+	}
 }
 
 func (h *handle) getRktFromMessage(msg *Message) (crkt *C.rd_kafka_topic_t) {
@@ -100,16 +191,47 @@ func (h *handle) getRktFromMessage(msg *Message) (crkt *C.rd_kafka_topic_t) {
 	return h.getRkt(*msg.TopicPartition.Topic)
 }
 
+// headersPool pools the *[]Header slices of released messages.
+var headersPool sync.Pool
+
+// getHeaders returns a pooled, or else new, slice of cnt headers.
+func getHeaders(cnt int) []Header {
+	if hdrs, ok := headersPool.Get().(*[]Header); ok && cap(*hdrs) >= cnt {
+		return (*hdrs)[:cnt]
+	}
+	return make([]Header, cnt)
+}
+
+// putHeaders returns msg's header slice to the pool.
+func putHeaders(msg *Message) {
+	hdrs := msg.Headers
+	msg.Headers = nil
+	if cap(hdrs) > 0 {
+		headersPool.Put(&hdrs)
+	}
+}
+
 // setupHeadersFromGlueMsg converts the C tmp headers in gMsg to
-// Go Headers in msg.
+// Go Headers in msg, reusing the storage of msg's current headers,
+// if any, where large enough.
 // gMsg.tmphdrs will be freed.
 func setupHeadersFromGlueMsg(msg *Message, gMsg *C.glue_msg_t) {
-	msg.Headers = make([]Header, gMsg.tmphdrsCnt)
+	cnt := int(gMsg.tmphdrsCnt)
+	if cap(msg.Headers) >= cnt {
+		msg.Headers = msg.Headers[:cnt]
+	} else {
+		putHeaders(msg)
+		msg.Headers = getHeaders(cnt)
+	}
 	for n := range msg.Headers {
 		tmphdr := (*[1 << 30]C.tmphdr_t)(unsafe.Pointer(gMsg.tmphdrs))[n]
-		msg.Headers[n].Key = C.GoString(tmphdr.key)
+		key := zeroCopyBytes(unsafe.Pointer(tmphdr.key), C.strlen(tmphdr.key))
+		if msg.Headers[n].Key != string(key) {
+			msg.Headers[n].Key = string(key)
+		}
 		if tmphdr.val != nil {
-			msg.Headers[n].Value = C.GoBytes(unsafe.Pointer(tmphdr.val), C.int(tmphdr.size))
+			msg.Headers[n].Value = reuseBytes(msg.Headers[n].Value,
+				unsafe.Pointer(tmphdr.val), C.size_t(tmphdr.size))
 		} else {
 			msg.Headers[n].Value = nil
 		}
@@ -117,8 +239,37 @@ func setupHeadersFromGlueMsg(msg *Message, gMsg *C.glue_msg_t) {
 	C.free(unsafe.Pointer(gMsg.tmphdrs))
 }
 
-func (h *handle) newMessageFromGlueMsg(gMsg *C.glue_msg_t) (msg *Message) {
+// reuseBytes returns a copy of the C buffer, in dst if large enough.
+func reuseBytes(dst []byte, ptr unsafe.Pointer, size C.size_t) []byte {
+	if dst == nil || cap(dst) < int(size) {
+		return C.GoBytes(ptr, C.int(size))
+	}
+	dst = dst[:size]
+	copy(dst, zeroCopyBytes(ptr, size))
+	return dst
+}
+
+// newMessageFromGlueMsg creates a new message object from a C glue_msg_t,
+// with the key and value referring to zc's buffers if non-nil.
+func (h *handle) newMessageFromGlueMsg(gMsg *C.glue_msg_t, zc *zeroCopyBuf) (msg *Message) {
 	msg = &Message{}
+	h.setupMessageFromGlueMsg(msg, gMsg, zc)
+	return msg
+}
+
+// setupMessageFromGlueMsg sets up msg from a C glue_msg_t, see
+// newMessageFromGlueMsg(). A reused msg is reset, while the storage of
+// its topic, key, value and headers is reused where large enough.
+func (h *handle) setupMessageFromGlueMsg(msg *Message, gMsg *C.glue_msg_t, zc *zeroCopyBuf) {
+	// Release a reused zero-copy message's buffers rather than reusing them
+	msg.releaseBuffers()
+	*msg = Message{
+		TopicPartition: TopicPartition{Topic: msg.TopicPartition.Topic},
+		Key:            msg.Key,
+		Value:          msg.Value,
+		Headers:        msg.Headers,
+		zeroCopy:       zc,
+	}
 
 	if gMsg.ts != -1 {
 		ts := int64(gMsg.ts)
@@ -128,34 +279,44 @@ func (h *handle) newMessageFromGlueMsg(gMsg *C.glue_msg_t) (msg *Message) {
 
 	if gMsg.tmphdrsCnt > 0 {
 		setupHeadersFromGlueMsg(msg, gMsg)
+	} else if gMsg.hdrs != nil {
+		putHeaders(msg)
+		msg.lazyHeaders = newLazyHeaders(gMsg.hdrs)
+	} else {
+		msg.Headers = nil
 	}
 
 	h.setupMessageFromC(msg, gMsg.msg)
 
-	return msg
+	if h.integrityVerify {
+		verifyIntegrity(msg)
+	}
 }
 
-// setupMessageFromC sets up a message object from a C rd_kafka_message_t
+// setupMessageFromC sets up a message object from a C rd_kafka_message_t,
+// reusing the storage of msg's topic, key and value, if any.
 func (h *handle) setupMessageFromC(msg *Message, cmsg *C.rd_kafka_message_t) {
-	if cmsg.rkt != nil {
-		topic := h.getTopicNameFromRkt(cmsg.rkt)
+	if cmsg.rkt == nil {
+		msg.TopicPartition.Topic = nil
+	} else if h.internTopics || msg.TopicPartition.Topic == nil {
+		msg.TopicPartition.Topic = h.getTopicFromRkt(cmsg.rkt)
+	} else if topic := h.getTopicNameFromRkt(cmsg.rkt); *msg.TopicPartition.Topic != topic {
 		msg.TopicPartition.Topic = &topic
 	}
 	msg.TopicPartition.Partition = int32(cmsg.partition)
-	if cmsg.payload != nil && h.msgFields.Value {
-		msg.Value = C.GoBytes(unsafe.Pointer(cmsg.payload), C.int(cmsg.len))
-	}
-	if cmsg.key != nil && h.msgFields.Key {
-		msg.Key = C.GoBytes(unsafe.Pointer(cmsg.key), C.int(cmsg.key_len))
+	if cmsg.payload == nil || !h.msgFields.Value {
+		msg.Value = nil
+	} else if msg.zeroCopy != nil {
+		msg.Value = zeroCopyBytes(cmsg.payload, cmsg.len)
+	} else {
+		msg.Value = reuseBytes(msg.Value, cmsg.payload, cmsg.len)
 	}
-	if h.msgFields.Headers {
-		var gMsg C.glue_msg_t
-		gMsg.msg = cmsg
-		gMsg.want_hdrs = C.int8_t(1)
-		chdrsToTmphdrs(&gMsg)
-		if gMsg.tmphdrsCnt > 0 {
-			setupHeadersFromGlueMsg(msg, &gMsg)
-		}
+	if cmsg.key == nil || !h.msgFields.Key {
+		msg.Key = nil
+	} else if msg.zeroCopy != nil {
+		msg.Key = zeroCopyBytes(cmsg.key, cmsg.key_len)
+	} else {
+		msg.Key = reuseBytes(msg.Key, cmsg.key, cmsg.key_len)
 	}
 	msg.TopicPartition.Offset = Offset(cmsg.offset)
 	if cmsg.err != 0 {
@@ -170,6 +331,16 @@ func (h *handle) newMessageFromC(cmsg *C.rd_kafka_message_t) (msg *Message) {
 
 	h.setupMessageFromC(msg, cmsg)
 
+	if h.msgFields.Headers {
+		var gMsg C.glue_msg_t
+		gMsg.msg = cmsg
+		gMsg.want_hdrs = C.int8_t(1)
+		chdrsToTmphdrs(&gMsg)
+		if gMsg.tmphdrsCnt > 0 {
+			setupHeadersFromGlueMsg(msg, &gMsg)
+		}
+	}
+
 	return msg
 }
 
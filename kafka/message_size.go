@@ -0,0 +1,105 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	// Size of a v2 record batch header
+	recordBatchOverhead = 61
+	// Maximum size of the length, attributes, timestamp and offset deltas
+	// and key and value lengths of a v2 record
+	recordOverhead = 21
+	// Maximum size of the key and value lengths of a v2 record header
+	recordHeaderOverhead = 10
+)
+
+// messageSizeCheck estimates the size of the record batch of a produced
+// message, alone in its batch, to fail messages the broker would reject
+// before they are enqueued.
+type messageSizeCheck struct {
+	limit int
+	// Estimated compressed to uncompressed size ratio of keys and values
+	compressionRatio float64
+	// Size of the integrity header added to the messages, if any
+	integrityHeaderSize int
+}
+
+// newMessageSizeCheck returns the message size check configured by the
+// producer's conf, or nil if disabled.
+func newMessageSizeCheck(conf ConfigMap, integrityStamp bool) (*messageSizeCheck, error) {
+	v, err := conf.extract("go.produce.size.check", false)
+	if err != nil {
+		return nil, err
+	}
+	enabled := v.(bool)
+
+	v, err = conf.extract("go.produce.size.compression.ratio", 1.0)
+	if err != nil {
+		return nil, err
+	}
+	ratio := v.(float64)
+	if ratio <= 0 || ratio > 1 {
+		return nil, newErrorFromString(ErrInvalidArg,
+			fmt.Sprintf("go.produce.size.compression.ratio must be within (0, 1], not %v", ratio))
+	}
+
+	if !enabled {
+		return nil, nil
+	}
+
+	// message.max.bytes is left for librdkafka to validate
+	limit := 1000000
+	if v, _ = conf.get("message.max.bytes", nil); v != nil {
+		s, errstr := value2string(v)
+		if errstr != "" {
+			return nil, newErrorFromString(ErrInvalidArg,
+				fmt.Sprintf("message.max.bytes: %s", errstr))
+		}
+		if limit, err = strconv.Atoi(s); err != nil {
+			return nil, newErrorFromString(ErrInvalidArg,
+				fmt.Sprintf("message.max.bytes: %v", err))
+		}
+	}
+
+	sc := &messageSizeCheck{limit: limit, compressionRatio: ratio}
+	if integrityStamp {
+		sc.integrityHeaderSize = recordHeaderOverhead + len(IntegrityHeaderKey) + 4
+	}
+	return sc, nil
+}
+
+// check returns an ErrMsgSizeTooLarge Error, stating the estimated size
+// and the limit, if msg's estimated size exceeds the limit.
+func (sc *messageSizeCheck) check(msg *Message) error {
+	size := recordBatchOverhead + recordOverhead +
+		int(float64(len(msg.Key)+len(msg.Value))*sc.compressionRatio) +
+		sc.integrityHeaderSize
+	for _, hdr := range msg.Headers {
+		size += recordHeaderOverhead + len(hdr.Key) + len(hdr.Value)
+	}
+
+	if size > sc.limit {
+		return newErrorFromString(ErrMsgSizeTooLarge,
+			fmt.Sprintf("Estimated message size %d exceeds message.max.bytes %d",
+				size, sc.limit))
+	}
+	return nil
+}
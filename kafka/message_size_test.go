@@ -0,0 +1,89 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"testing"
+)
+
+// TestMessageSizeCheck tests that Produce() fails fast for messages
+// exceeding message.max.bytes, no broker is needed.
+func TestMessageSizeCheck(t *testing.T) {
+	topic := "size"
+
+	produce := func(conf ConfigMap, msg *Message) error {
+		p, err := NewProducer(&conf)
+		if err != nil {
+			t.Fatalf("Failed to create Producer: %v", err)
+		}
+		defer p.Close()
+
+		msg.TopicPartition = TopicPartition{Topic: &topic, Partition: 0}
+		return p.Produce(msg, nil)
+	}
+
+	conf := ConfigMap{
+		"message.max.bytes":     "1000",
+		"go.produce.size.check": true,
+		"message.timeout.ms":    10}
+
+	if err := produce(conf, &Message{Value: make([]byte, 900)}); err != nil {
+		t.Errorf("Expected message to fit, got %v", err)
+	}
+
+	// The headers don't fit
+	err := produce(conf, &Message{Value: make([]byte, 900),
+		Headers: []Header{{"h", make([]byte, 10)}}})
+	if kerr, ok := err.(Error); !ok || kerr.Code() != ErrMsgSizeTooLarge ||
+		kerr.Error() != "Estimated message size 1003 exceeds message.max.bytes 1000" {
+		t.Errorf("Expected ErrMsgSizeTooLarge, got %v", err)
+	}
+
+	// Neither does the integrity header
+	conf["go.integrity.checksum"] = true
+	err = produce(conf, &Message{Value: make([]byte, 900)})
+	if kerr, ok := err.(Error); !ok || kerr.Code() != ErrMsgSizeTooLarge {
+		t.Errorf("Expected ErrMsgSizeTooLarge, got %v", err)
+	}
+	delete(conf, "go.integrity.checksum")
+
+	// Compression makes the value fit
+	conf["message.max.bytes"] = 1000
+	err = produce(conf, &Message{Value: make([]byte, 950)})
+	if kerr, ok := err.(Error); !ok || kerr.Code() != ErrMsgSizeTooLarge {
+		t.Errorf("Expected ErrMsgSizeTooLarge, got %v", err)
+	}
+	conf["go.produce.size.compression.ratio"] = 0.5
+	if err = produce(conf, &Message{Value: make([]byte, 950)}); err != nil {
+		t.Errorf("Expected compressed message to fit, got %v", err)
+	}
+
+	conf["go.produce.size.compression.ratio"] = 1.5
+	if _, err = NewProducer(&conf); err == nil {
+		t.Errorf("Expected invalid compression ratio to fail")
+	}
+
+	// Not applied by the batch producer
+	delete(conf, "go.produce.size.compression.ratio")
+	conf["go.batch.producer"] = true
+	if p, err := NewProducer(&conf); err == nil {
+		p.Close()
+		t.Errorf("Expected go.produce.size.check with go.batch.producer to fail")
+	} else if err.(Error).Code() != ErrInvalidArg {
+		t.Errorf("Expected ErrInvalidArg, not %v", err)
+	}
+}
@@ -17,6 +17,7 @@ package kafka
  */
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -31,3 +32,41 @@ func TestTimestampType(t *testing.T) {
 		}
 	}
 }
+
+// TestMessageMethods tests Message Clone, Size and String
+func TestMessageMethods(t *testing.T) {
+	topic := "test"
+	msg := &Message{
+		TopicPartition: TopicPartition{Topic: &topic, Partition: 1, Offset: 5},
+		Key:            []byte("key"),
+		Value:          []byte(strings.Repeat("v", 100)),
+		Headers:        []Header{{Key: "hdr", Value: []byte("val")}},
+		Opaque:         &topic,
+	}
+
+	if msg.Size() != 3+100+3+3 {
+		t.Errorf("Expected size 109, got %d", msg.Size())
+	}
+
+	expected := `test[1]@5 key="key" value="` + strings.Repeat("v", 50) + `"(50 more bytes)`
+	if msg.String() != expected {
+		t.Errorf("Expected %s, got %s", expected, msg.String())
+	}
+
+	clone := msg.Clone()
+	clone.Key[0] = 'K'
+	clone.Headers[0].Value[0] = 'V'
+	*clone.TopicPartition.Topic = "other"
+
+	if string(msg.Key) != "key" || string(msg.Headers[0].Value) != "val" || topic != "test" {
+		t.Errorf("Clone modified original message %v", msg)
+	}
+	if clone.Opaque != msg.Opaque {
+		t.Errorf("Expected clone to retain Opaque")
+	}
+
+	if msg.BrokerID() != -1 || msg.Latency() != -1 {
+		t.Errorf("Expected unknown broker id and latency, got %d, %v",
+			msg.BrokerID(), msg.Latency())
+	}
+}
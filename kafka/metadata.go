@@ -17,6 +17,9 @@
 package kafka
 
 import (
+	"context"
+	"strings"
+	"time"
 	"unsafe"
 )
 
@@ -72,6 +75,37 @@ type Metadata struct {
 	Topics  map[string]TopicMetadata
 
 	OriginatingBroker BrokerMetadata
+
+	// ControllerID is the id of the cluster's controller broker,
+	// or -1 if not known.
+	ControllerID int32
+	// ClusterID is the id of the cluster, or empty if not known.
+	ClusterID string
+}
+
+// MetadataOptions selects the topics returned by Metadata().
+type MetadataOptions struct {
+	// Topics to return the metadata of,
+	// all topics of the cluster if empty.
+	Topics []string
+	// IncludeInternal includes the internal topics, such as
+	// __consumer_offsets, when returning all topics.
+	IncludeInternal bool
+	// Refresh requests the metadata from the cluster rather than from
+	// the client's cache.
+	// This librdkafka version always requests the metadata from the
+	// cluster.
+	Refresh bool
+
+	// allTopics is false to only return the locally used topics when
+	// Topics is empty, see GetMetadata().
+	allTopics bool
+}
+
+// isInternalTopic returns true if topic is an internal topic of the
+// cluster, which librdkafka doesn't flag in metadata.
+func isInternalTopic(topic string) bool {
+	return strings.HasPrefix(topic, "__")
 }
 
 // getMetadata queries broker for cluster and topic metadata.
@@ -79,13 +113,86 @@ type Metadata struct {
 // allTopics is false only information about locally used topics is returned,
 // else information about all topics is returned.
 func getMetadata(H Handle, topic *string, allTopics bool, timeoutMs int) (*Metadata, error) {
+	opts := MetadataOptions{IncludeInternal: true, allTopics: allTopics}
+	if topic != nil {
+		opts.Topics = []string{*topic}
+	}
+	return queryMetadata(H, opts, timeoutMs)
+}
+
+// metadata queries the cluster for the metadata of the topics selected
+// by opts, see Metadata().
+func metadata(H Handle, ctx context.Context, opts MetadataOptions) (*Metadata, error) {
+	opts.allTopics = true
+	m, err := queryMetadata(H, opts, int(cTimeoutFromContext(ctx)))
+	if err != nil {
+		// The request failed at ctx's deadline, within the millisecond
+		// granularity of librdkafka's timeouts
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < 10*time.Millisecond {
+			<-ctx.Done()
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+	return m, err
+}
+
+// queryMetadata queries the cluster for the metadata of the topics
+// selected by opts, within timeoutMs, or indefinitely if -1.
+// Each topic of opts.Topics is queried in turn.
+func queryMetadata(H Handle, opts MetadataOptions, timeoutMs int) (*Metadata, error) {
 	h := H.gethandle()
 
-	var rkt *C.rd_kafka_topic_t
-	if topic != nil {
-		rkt = h.getRkt(*topic)
+	if len(opts.Topics) == 0 {
+		m, err := getMetadataOnce(h, nil, opts.allTopics, timeoutMs)
+		if err != nil {
+			return nil, err
+		}
+		if !opts.IncludeInternal {
+			for topic := range m.Topics {
+				if isInternalTopic(topic) {
+					delete(m.Topics, topic)
+				}
+			}
+		}
+		return m, nil
 	}
 
+	deadline := time.Now().Add(time.Duration(timeoutMs) * time.Millisecond)
+	var m *Metadata
+	for _, topic := range opts.Topics {
+		remainingMs := timeoutMs
+		if timeoutMs > 0 {
+			remainingMs = int(time.Until(deadline) / time.Millisecond)
+			if remainingMs <= 0 {
+				return nil, newError(C.RD_KAFKA_RESP_ERR__TIMED_OUT)
+			}
+		}
+
+		tm, err := getMetadataOnce(h, h.getRkt(topic), false, remainingMs)
+		if err != nil {
+			return nil, err
+		}
+		if m == nil {
+			m = tm
+			continue
+		}
+		// The cluster's metadata are the latest
+		for name, t := range m.Topics {
+			if _, found := tm.Topics[name]; !found {
+				tm.Topics[name] = t
+			}
+		}
+		m = tm
+	}
+
+	return m, nil
+}
+
+// getMetadataOnce requests metadata from a broker: of rkt only if
+// non-nil, else of all topics if allTopics or of the locally used topics.
+func getMetadataOnce(h *handle, rkt *C.rd_kafka_topic_t, allTopics bool, timeoutMs int) (*Metadata, error) {
 	var cMd *C.struct_rd_kafka_metadata
 	cErr := C.rd_kafka_metadata(h.rk, bool2cint(allTopics),
 		rkt, &cMd, C.int(timeoutMs))
@@ -133,6 +240,13 @@ func getMetadata(H Handle, topic *string, allTopics bool, timeoutMs int) (*Metad
 	m.OriginatingBroker = BrokerMetadata{int32(cMd.orig_broker_id),
 		C.GoString(cMd.orig_broker_name), 0}
 
+	// The controller and cluster ids are cached from the metadata response
+	m.ControllerID = int32(C.rd_kafka_controllerid(h.rk, 0))
+	if cClusterID := C.rd_kafka_clusterid(h.rk, 0); cClusterID != nil {
+		m.ClusterID = C.GoString(cClusterID)
+		C.rd_kafka_mem_free(h.rk, unsafe.Pointer(cClusterID))
+	}
+
 	return &m, nil
 }
 
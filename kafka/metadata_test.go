@@ -17,7 +17,9 @@ package kafka
  */
 
 import (
+	"context"
 	"testing"
+	"time"
 )
 
 // TestMetadataAPIs dry-tests the Metadata APIs, no broker is needed.
@@ -62,3 +64,85 @@ func TestMetadataAPIs(t *testing.T) {
 	c.Close()
 
 }
+
+// TestMetadataOptions tests Metadata() topic selection with a MockCluster.
+func TestMetadataOptions(t *testing.T) {
+	mc, err := NewMockCluster(3)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer mc.Close()
+
+	for _, topic := range []string{"topic1", "topic2", "__internal"} {
+		if err = mc.CreateTopic(topic, 2, 1); err != nil {
+			t.Fatalf("%s", err)
+		}
+	}
+
+	a, err := NewAdminClient(&ConfigMap{"bootstrap.servers": mc.BootstrapServers()})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer a.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	md, err := a.Metadata(ctx, MetadataOptions{})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(md.Brokers) != 3 {
+		t.Errorf("Expected 3 brokers, got %v", md.Brokers)
+	}
+	if md.ControllerID < 0 || md.ClusterID == "" {
+		t.Errorf("Expected controller and cluster ids, got %d, %q", md.ControllerID, md.ClusterID)
+	}
+	if _, found := md.Topics["__internal"]; found || len(md.Topics) != 2 {
+		t.Errorf("Expected topic1 and topic2, got %v", md.Topics)
+	}
+
+	md, err = a.Metadata(ctx, MetadataOptions{IncludeInternal: true})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if _, found := md.Topics["__internal"]; !found {
+		t.Errorf("Expected __internal, got %v", md.Topics)
+	}
+
+	md, err = a.Metadata(ctx, MetadataOptions{Topics: []string{"topic1", "topic2"}, Refresh: true})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(md.Topics) != 2 || len(md.Topics["topic1"].Partitions) != 2 ||
+		len(md.Topics["topic2"].Partitions) != 2 {
+		t.Errorf("Expected topic1 and topic2 with 2 partitions, got %v", md.Topics)
+	}
+
+	// The legacy API returns the internal topics
+	md, err = a.GetMetadata(nil, true, 10000)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if _, found := md.Topics["__internal"]; !found {
+		t.Errorf("Expected __internal, got %v", md.Topics)
+	}
+}
+
+// TestMetadataContext tests that Metadata() is bound by the context's
+// deadline, no broker is needed.
+func TestMetadataContext(t *testing.T) {
+	p, err := NewProducer(&ConfigMap{})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer p.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	md, err := p.Metadata(ctx, MetadataOptions{Topics: []string{"gotest"}})
+	if md != nil || err != context.DeadlineExceeded {
+		t.Errorf("Expected DeadlineExceeded, got %v, %v", md, err)
+	}
+}
@@ -0,0 +1,1072 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// errMockNotSupported is returned by mock client methods that require
+// a real cluster.
+func errMockNotSupported(what string) error {
+	return newErrorFromString(ErrNotImplemented,
+		fmt.Sprintf("%s is not supported by the mock client", what))
+}
+
+// mockTopicPartition is a comparable Topic+Partition map key.
+type mockTopicPartition struct {
+	topic     string
+	partition int32
+}
+
+func newMockTopicPartition(tp TopicPartition) mockTopicPartition {
+	var topic string
+	if tp.Topic != nil {
+		topic = *tp.Topic
+	}
+	return mockTopicPartition{topic, tp.Partition}
+}
+
+// mockDr is a not yet completed delivery report.
+type mockDr struct {
+	msg          *Message
+	deliveryChan chan Event
+}
+
+// MockProducer is an in-memory ProducerClient implementation for use in
+// unit tests, no broker is needed.
+//
+// Produced messages are recorded and can be retrieved with Messages().
+// If the MockProducer was created with autoComplete set to true
+// the delivery report for each produced message is emitted immediately,
+// else delivery reports are held until completed by the test through
+// CompleteNext(), ErrorNext() or Flush().
+//
+// Delivery reports are emitted asynchronously, in completion order, on
+// the per-message delivery channel, if provided to Produce(), else on the
+// Events() channel, as the Producer does.
+//
+// The MockProducer is safe for concurrent use.
+type MockProducer struct {
+	lock           sync.Mutex
+	autoComplete   bool
+	events         chan Event
+	produceChannel chan *Message
+	waitGroup      sync.WaitGroup
+	closed         bool
+	termChan       chan bool
+	// Signals the reporter of newly completed delivery reports
+	reportChan chan bool
+
+	// All messages produced since creation or the last Clear()
+	history []*Message
+	// Messages awaiting delivery report completion
+	pending []mockDr
+	// Completed delivery reports awaiting emission by the reporter
+	reports []mockDr
+	// Error returned from Produce(), if set
+	produceErr error
+	// Next offset per partition
+	nextOffsets map[mockTopicPartition]Offset
+
+	// Transactional state
+	txnInitialized bool
+	txnInFlight    bool
+	txnCommitCnt   int
+	txnAbortCnt    int
+	txnOffsets     []TopicPartition
+}
+
+// NewMockProducer creates a new in-memory MockProducer.
+//
+// If autoComplete is true delivery reports are emitted as soon as a
+// message is produced, else they are held until completed by
+// CompleteNext(), ErrorNext() or Flush().
+func NewMockProducer(autoComplete bool) *MockProducer {
+	mp := &MockProducer{
+		autoComplete:   autoComplete,
+		events:         make(chan Event, 10000),
+		produceChannel: make(chan *Message, 10000),
+		nextOffsets:    make(map[mockTopicPartition]Offset),
+		termChan:       make(chan bool),
+		reportChan:     make(chan bool, 1),
+	}
+
+	mp.waitGroup.Add(2)
+	go func() {
+		defer mp.waitGroup.Done()
+		for m := range mp.produceChannel {
+			err := mp.Produce(m, nil)
+			if err != nil {
+				mp.deliver(mockDr{msg: m}, err)
+			}
+		}
+	}()
+	go mp.reporter()
+
+	return mp
+}
+
+// String returns a human readable name for the MockProducer instance
+func (mp *MockProducer) String() string {
+	return "MockProducer"
+}
+
+// Produce records the message and emits (or holds, see NewMockProducer())
+// its delivery report.
+//
+// Returns the error set with SetProduceError(), if any.
+func (mp *MockProducer) Produce(msg *Message, deliveryChan chan Event) error {
+	if msg == nil || msg.TopicPartition.Topic == nil || len(*msg.TopicPartition.Topic) == 0 {
+		return newErrorFromString(ErrInvalidArg, "")
+	}
+
+	mp.lock.Lock()
+
+	if mp.closed {
+		mp.lock.Unlock()
+		return newErrorFromString(ErrState, "Producer is closed")
+	}
+
+	if mp.produceErr != nil {
+		err := mp.produceErr
+		mp.lock.Unlock()
+		return err
+	}
+
+	m := msg.Clone()
+	if m.TopicPartition.Partition == PartitionAny {
+		m.TopicPartition.Partition = 0
+	}
+	tp := newMockTopicPartition(m.TopicPartition)
+	m.TopicPartition.Offset = mp.nextOffsets[tp]
+	mp.nextOffsets[tp]++
+
+	mp.history = append(mp.history, m)
+
+	dr := mockDr{msg: m.Clone(), deliveryChan: deliveryChan}
+	if !mp.autoComplete {
+		mp.pending = append(mp.pending, dr)
+		mp.lock.Unlock()
+		return nil
+	}
+	mp.lock.Unlock()
+
+	mp.deliver(dr, nil)
+
+	return nil
+}
+
+// deliver queues the delivery report for dr with the given error for
+// emission by the reporter.
+// Delivery reports are discarded once the MockProducer is closed.
+func (mp *MockProducer) deliver(dr mockDr, err error) {
+	if err != nil {
+		dr.msg.TopicPartition.Error = err
+		dr.msg.TopicPartition.Offset = OffsetInvalid
+	}
+
+	mp.lock.Lock()
+	if mp.closed {
+		mp.lock.Unlock()
+		return
+	}
+	mp.reports = append(mp.reports, dr)
+	mp.lock.Unlock()
+
+	select {
+	case mp.reportChan <- true:
+	default:
+	}
+}
+
+// reporter emits the queued delivery reports, until closed.
+// The delivery report being emitted is kept queued, for Len().
+func (mp *MockProducer) reporter() {
+	defer mp.waitGroup.Done()
+
+	for {
+		mp.lock.Lock()
+		if len(mp.reports) == 0 {
+			mp.lock.Unlock()
+			select {
+			case <-mp.reportChan:
+				continue
+			case <-mp.termChan:
+				return
+			}
+		}
+		dr := mp.reports[0]
+		mp.lock.Unlock()
+
+		ch := dr.deliveryChan
+		if ch == nil {
+			ch = mp.events
+		}
+		select {
+		case ch <- dr.msg:
+		case <-mp.termChan:
+			return
+		}
+
+		mp.lock.Lock()
+		if len(mp.reports) > 0 {
+			mp.reports[0] = mockDr{}
+			mp.reports = mp.reports[1:]
+		}
+		mp.lock.Unlock()
+	}
+}
+
+// completeNext completes the oldest pending delivery report, if any.
+func (mp *MockProducer) completeNext(err error) bool {
+	mp.lock.Lock()
+	if len(mp.pending) == 0 {
+		mp.lock.Unlock()
+		return false
+	}
+	dr := mp.pending[0]
+	mp.pending = mp.pending[1:]
+	mp.lock.Unlock()
+
+	mp.deliver(dr, err)
+	return true
+}
+
+// CompleteNext successfully completes the oldest pending delivery report.
+// Returns false if there were no pending delivery reports.
+func (mp *MockProducer) CompleteNext() bool {
+	return mp.completeNext(nil)
+}
+
+// ErrorNext fails the oldest pending delivery report with err,
+// e.g., NewError(ErrMsgTimedOut, "", false).
+// Returns false if there were no pending delivery reports.
+func (mp *MockProducer) ErrorNext(err error) bool {
+	return mp.completeNext(err)
+}
+
+// SetProduceError makes all subsequent Produce() calls fail with err,
+// e.g., NewError(ErrQueueFull, "", false), until reset with a nil err.
+func (mp *MockProducer) SetProduceError(err error) {
+	mp.lock.Lock()
+	defer mp.lock.Unlock()
+	mp.produceErr = err
+}
+
+// Messages returns the messages produced since the MockProducer was
+// created or Clear() was last called, in produce order.
+// Each message's TopicPartition.Offset is set to the offset assigned
+// by the MockProducer.
+func (mp *MockProducer) Messages() []*Message {
+	mp.lock.Lock()
+	defer mp.lock.Unlock()
+	return append([]*Message{}, mp.history...)
+}
+
+// Clear the recorded history of produced messages.
+func (mp *MockProducer) Clear() {
+	mp.lock.Lock()
+	defer mp.lock.Unlock()
+	mp.history = nil
+}
+
+// Events returns the Events channel (read)
+func (mp *MockProducer) Events() chan Event {
+	return mp.events
+}
+
+// Logs returns nil since the MockProducer does not emit logs.
+func (mp *MockProducer) Logs() chan LogEvent {
+	return nil
+}
+
+// ProduceChannel returns the produce *Message channel (write)
+func (mp *MockProducer) ProduceChannel() chan *Message {
+	return mp.produceChannel
+}
+
+// Len returns the number of pending delivery reports
+// as well as delivery reports queued for the application.
+// Includes messages on ProduceChannel.
+func (mp *MockProducer) Len() int {
+	mp.lock.Lock()
+	defer mp.lock.Unlock()
+	return len(mp.produceChannel) + len(mp.events) + len(mp.pending) + len(mp.reports)
+}
+
+// Flush successfully completes all pending delivery reports.
+// Returns the number of events still queued for the application.
+func (mp *MockProducer) Flush(timeoutMs int) int {
+	tEnd := time.Now().Add(time.Duration(timeoutMs) * time.Millisecond)
+	for mp.Len() > 0 {
+		if !mp.CompleteNext() {
+			if time.Now().After(tEnd) {
+				return mp.Len()
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	return 0
+}
+
+// Close the MockProducer.
+// Pending and not yet emitted delivery reports are discarded.
+func (mp *MockProducer) Close() {
+	mp.lock.Lock()
+	mp.closed = true
+	mp.pending = nil
+	mp.reports = nil
+	mp.lock.Unlock()
+
+	close(mp.termChan)
+	close(mp.produceChannel)
+	mp.waitGroup.Wait()
+	close(mp.events)
+}
+
+// Purge discards all pending delivery reports, failing them with
+// ErrPurgeQueue if flags include PurgeQueue.
+func (mp *MockProducer) Purge(flags int) error {
+	mp.lock.Lock()
+	pending := mp.pending
+	mp.pending = nil
+	mp.lock.Unlock()
+
+	if flags&PurgeQueue != 0 {
+		for _, dr := range pending {
+			mp.deliver(dr, newErrorFromString(ErrPurgeQueue, ""))
+		}
+	}
+
+	return nil
+}
+
+// GetMetadata is not supported by the MockProducer.
+func (mp *MockProducer) GetMetadata(topic *string, allTopics bool, timeoutMs int) (*Metadata, error) {
+	return nil, errMockNotSupported("GetMetadata")
+}
+
+// Metadata is not supported by the MockProducer.
+func (mp *MockProducer) Metadata(ctx context.Context, opts MetadataOptions) (*Metadata, error) {
+	return nil, errMockNotSupported("Metadata")
+}
+
+// QueryWatermarkOffsets returns 0 as the low offset and the next offset
+// to be assigned by the MockProducer as the high offset.
+func (mp *MockProducer) QueryWatermarkOffsets(topic string, partition int32, timeoutMs int) (low, high int64, err error) {
+	mp.lock.Lock()
+	defer mp.lock.Unlock()
+	return 0, int64(mp.nextOffsets[mockTopicPartition{topic, partition}]), nil
+}
+
+// OffsetsForTimes is not supported by the MockProducer.
+func (mp *MockProducer) OffsetsForTimes(times []TopicPartition, timeoutMs int) (offsets []TopicPartition, err error) {
+	return nil, errMockNotSupported("OffsetsForTimes")
+}
+
+// GetFatalError always returns nil.
+func (mp *MockProducer) GetFatalError() error {
+	return nil
+}
+
+// TestFatalError is not supported by the MockProducer.
+func (mp *MockProducer) TestFatalError(code ErrorCode, str string) ErrorCode {
+	return ErrNotImplemented
+}
+
+// SetOAuthBearerToken is a no-op on the MockProducer.
+func (mp *MockProducer) SetOAuthBearerToken(oauthBearerToken OAuthBearerToken) error {
+	return nil
+}
+
+// SetOAuthBearerTokenFailure is a no-op on the MockProducer.
+func (mp *MockProducer) SetOAuthBearerTokenFailure(errstr string) error {
+	return nil
+}
+
+// InitTransactions initializes the mock transactional state.
+func (mp *MockProducer) InitTransactions(ctx context.Context) error {
+	mp.lock.Lock()
+	defer mp.lock.Unlock()
+	if mp.txnInitialized {
+		return newErrorFromString(ErrState, "Transactions already initialized")
+	}
+	mp.txnInitialized = true
+	return nil
+}
+
+// BeginTransaction starts a new mock transaction.
+func (mp *MockProducer) BeginTransaction() error {
+	mp.lock.Lock()
+	defer mp.lock.Unlock()
+	if !mp.txnInitialized || mp.txnInFlight {
+		return newErrorFromString(ErrState, "Transactions not initialized or transaction already in progress")
+	}
+	mp.txnInFlight = true
+	return nil
+}
+
+// SendOffsetsToTransaction records the offsets as part of the current
+// transaction, see TransactionOffsets().
+func (mp *MockProducer) SendOffsetsToTransaction(ctx context.Context, offsets []TopicPartition, consumerMetadata *ConsumerGroupMetadata) error {
+	mp.lock.Lock()
+	defer mp.lock.Unlock()
+	if !mp.txnInFlight {
+		return newErrorFromString(ErrState, "No transaction in progress")
+	}
+	mp.txnOffsets = append(mp.txnOffsets, offsets...)
+	return nil
+}
+
+// endTransaction ends the current transaction, committing it if commit is true.
+func (mp *MockProducer) endTransaction(commit bool) error {
+	mp.lock.Lock()
+	if !mp.txnInFlight {
+		mp.lock.Unlock()
+		return newErrorFromString(ErrState, "No transaction in progress")
+	}
+	mp.txnInFlight = false
+	if commit {
+		mp.txnCommitCnt++
+	} else {
+		mp.txnAbortCnt++
+		mp.txnOffsets = nil
+	}
+	mp.lock.Unlock()
+
+	if commit {
+		mp.Flush(0)
+	} else {
+		mp.Purge(PurgeQueue)
+	}
+	return nil
+}
+
+// CommitTransaction completes all pending delivery reports and commits
+// the current mock transaction.
+func (mp *MockProducer) CommitTransaction(ctx context.Context) error {
+	return mp.endTransaction(true)
+}
+
+// AbortTransaction fails all pending delivery reports with ErrPurgeQueue
+// and aborts the current mock transaction.
+func (mp *MockProducer) AbortTransaction(ctx context.Context) error {
+	return mp.endTransaction(false)
+}
+
+// TransactionCounts returns the number of committed and aborted
+// transactions.
+func (mp *MockProducer) TransactionCounts() (committed int, aborted int) {
+	mp.lock.Lock()
+	defer mp.lock.Unlock()
+	return mp.txnCommitCnt, mp.txnAbortCnt
+}
+
+// TransactionOffsets returns the offsets sent with
+// SendOffsetsToTransaction() for committed and in-progress transactions.
+func (mp *MockProducer) TransactionOffsets() []TopicPartition {
+	mp.lock.Lock()
+	defer mp.lock.Unlock()
+	return append([]TopicPartition{}, mp.txnOffsets...)
+}
+
+// MockConsumer is an in-memory ConsumerClient implementation for use in
+// unit tests, no broker is needed.
+//
+// The test feeds messages, errors and other events to the consumer
+// with AddMessage() and AddEvent(), and triggers rebalances with
+// Rebalance().
+// Messages are only returned by Poll() once their partition is assigned
+// (and not paused), either directly through Assign() or through
+// a Rebalance().
+//
+// The MockConsumer does not support the Events() channel, use Poll()
+// or ReadMessage().
+//
+// The MockConsumer is safe for concurrent use.
+type MockConsumer struct {
+	lock   sync.Mutex
+	wakeup chan bool
+	closed bool
+
+	// Queued messages and events
+	queue []Event
+
+	subscription  []string
+	rebalanceCb   RebalanceCb
+	appReassigned bool
+	assignment    map[mockTopicPartition]bool
+	paused        map[mockTopicPartition]bool
+
+	position  map[mockTopicPartition]Offset
+	stored    map[mockTopicPartition]Offset
+	committed map[mockTopicPartition]Offset
+	low       map[mockTopicPartition]Offset
+	high      map[mockTopicPartition]Offset
+}
+
+// NewMockConsumer creates a new in-memory MockConsumer.
+func NewMockConsumer() *MockConsumer {
+	return &MockConsumer{
+		wakeup:     make(chan bool, 1),
+		assignment: make(map[mockTopicPartition]bool),
+		paused:     make(map[mockTopicPartition]bool),
+		position:   make(map[mockTopicPartition]Offset),
+		stored:     make(map[mockTopicPartition]Offset),
+		committed:  make(map[mockTopicPartition]Offset),
+		low:        make(map[mockTopicPartition]Offset),
+		high:       make(map[mockTopicPartition]Offset),
+	}
+}
+
+// String returns a human readable name for the MockConsumer instance
+func (mc *MockConsumer) String() string {
+	return "MockConsumer"
+}
+
+// enqueue adds ev to the queue of events to be returned by Poll().
+func (mc *MockConsumer) enqueue(ev Event) {
+	mc.lock.Lock()
+	mc.queue = append(mc.queue, ev)
+	mc.lock.Unlock()
+
+	select {
+	case mc.wakeup <- true:
+	default:
+	}
+}
+
+// AddMessage queues a copy of msg to be consumed.
+// If msg.TopicPartition.Offset is a logical offset the next offset
+// of the partition is assigned.
+// The partition's high watermark is updated accordingly.
+func (mc *MockConsumer) AddMessage(msg *Message) {
+	m := msg.Clone()
+	tp := newMockTopicPartition(m.TopicPartition)
+
+	mc.lock.Lock()
+	if m.TopicPartition.Offset < 0 {
+		m.TopicPartition.Offset = mc.high[tp]
+	}
+	if m.TopicPartition.Offset >= mc.high[tp] {
+		mc.high[tp] = m.TopicPartition.Offset + 1
+	}
+	mc.lock.Unlock()
+
+	mc.enqueue(m)
+}
+
+// AddEvent queues an arbitrary event, such as an Error or PartitionEOF,
+// to be returned by Poll().
+func (mc *MockConsumer) AddEvent(ev Event) {
+	mc.enqueue(ev)
+}
+
+// Rebalance queues a rebalance that revokes the current assignment,
+// if any, and assigns the given partitions.
+// The rebalance is served by Poll() like a real rebalance: the
+// rebalanceCb passed to Subscribe*() is called, if any, and if the
+// callback does not (un)assign partitions the (un)assignment is performed
+// automatically.
+//
+// The *Consumer argument passed to the rebalanceCb is nil,
+// the callback should use the MockConsumer directly.
+func (mc *MockConsumer) Rebalance(assigned []TopicPartition) {
+	current, _ := mc.Assignment()
+	if len(current) > 0 {
+		mc.enqueue(RevokedPartitions{Partitions: current})
+	}
+	mc.enqueue(AssignedPartitions{Partitions: assigned})
+}
+
+// SetWatermarkOffsets sets the low and high watermark offsets of a
+// partition, as returned by QueryWatermarkOffsets() and
+// GetWatermarkOffsets().
+func (mc *MockConsumer) SetWatermarkOffsets(topic string, partition int32, low, high int64) {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+	tp := mockTopicPartition{topic, partition}
+	mc.low[tp] = Offset(low)
+	mc.high[tp] = Offset(high)
+}
+
+// Subscribe to a single topic, replacing the current subscription.
+func (mc *MockConsumer) Subscribe(topic string, rebalanceCb RebalanceCb) error {
+	return mc.SubscribeTopics([]string{topic}, rebalanceCb)
+}
+
+// SubscribeTopics records the subscription, replacing the current
+// subscription. Use Rebalance() to assign partitions.
+func (mc *MockConsumer) SubscribeTopics(topics []string, rebalanceCb RebalanceCb) (err error) {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+	mc.subscription = append([]string{}, topics...)
+	mc.rebalanceCb = rebalanceCb
+	return nil
+}
+
+// Unsubscribe from the current subscription, if any.
+func (mc *MockConsumer) Unsubscribe() (err error) {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+	mc.subscription = nil
+	return nil
+}
+
+// Subscription returns the current subscription.
+func (mc *MockConsumer) Subscription() (topics []string, err error) {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+	return append([]string{}, mc.subscription...), nil
+}
+
+// assign adds partitions to the assignment, initializing their position
+// from the partition's .Offset, else the committed offset.
+// Must be called with the lock held.
+func (mc *MockConsumer) assign(partitions []TopicPartition) {
+	mc.appReassigned = true
+	for _, p := range partitions {
+		tp := newMockTopicPartition(p)
+		mc.assignment[tp] = true
+		switch {
+		case p.Offset >= 0:
+			mc.position[tp] = p.Offset
+		case p.Offset == OffsetBeginning:
+			mc.position[tp] = mc.low[tp]
+		case p.Offset == OffsetEnd:
+			mc.position[tp] = mc.high[tp]
+		default:
+			if committed, ok := mc.committed[tp]; ok {
+				mc.position[tp] = committed
+			} else {
+				delete(mc.position, tp)
+			}
+		}
+	}
+}
+
+// unassign removes partitions from the assignment.
+// Must be called with the lock held.
+func (mc *MockConsumer) unassign(partitions []TopicPartition) {
+	mc.appReassigned = true
+	for _, p := range partitions {
+		tp := newMockTopicPartition(p)
+		delete(mc.assignment, tp)
+		delete(mc.paused, tp)
+	}
+}
+
+// Assign an atomic set of partitions to consume,
+// replacing the current assignment.
+func (mc *MockConsumer) Assign(partitions []TopicPartition) (err error) {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+	mc.assignment = make(map[mockTopicPartition]bool)
+	mc.paused = make(map[mockTopicPartition]bool)
+	mc.assign(partitions)
+	return nil
+}
+
+// Unassign the current set of partitions to consume.
+func (mc *MockConsumer) Unassign() (err error) {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+	mc.appReassigned = true
+	mc.assignment = make(map[mockTopicPartition]bool)
+	mc.paused = make(map[mockTopicPartition]bool)
+	return nil
+}
+
+// IncrementalAssign adds the specified partitions to the current
+// set of partitions to consume.
+func (mc *MockConsumer) IncrementalAssign(partitions []TopicPartition) (err error) {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+	mc.assign(partitions)
+	return nil
+}
+
+// IncrementalUnassign removes the specified partitions from the
+// current set of partitions to consume.
+func (mc *MockConsumer) IncrementalUnassign(partitions []TopicPartition) (err error) {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+	mc.unassign(partitions)
+	return nil
+}
+
+// Assignment returns the current partition assignments.
+func (mc *MockConsumer) Assignment() (partitions []TopicPartition, err error) {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+	for tp := range mc.assignment {
+		topic := tp.topic
+		partitions = append(partitions, TopicPartition{Topic: &topic, Partition: tp.partition})
+	}
+	return partitions, nil
+}
+
+// GetRebalanceProtocol always returns "EAGER".
+func (mc *MockConsumer) GetRebalanceProtocol() string {
+	return "EAGER"
+}
+
+// AssignmentLost always returns false.
+func (mc *MockConsumer) AssignmentLost() bool {
+	return false
+}
+
+// commit commits offsets, or the current positions of the assigned
+// partitions (or their stored offsets) if offsets is nil.
+func (mc *MockConsumer) commit(offsets []TopicPartition) ([]TopicPartition, error) {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+
+	if offsets == nil {
+		for tp := range mc.assignment {
+			offset, ok := mc.stored[tp]
+			if !ok {
+				offset, ok = mc.position[tp]
+			}
+			if !ok {
+				continue
+			}
+			topic := tp.topic
+			offsets = append(offsets, TopicPartition{Topic: &topic, Partition: tp.partition, Offset: offset})
+		}
+		if len(offsets) == 0 {
+			return nil, newErrorFromString(ErrNoOffset, "")
+		}
+	}
+
+	committed := make([]TopicPartition, len(offsets))
+	for i, p := range offsets {
+		mc.committed[newMockTopicPartition(p)] = p.Offset
+		committed[i] = p
+	}
+	return committed, nil
+}
+
+// Commit the current positions (or stored offsets) of the assigned
+// partitions.
+func (mc *MockConsumer) Commit() ([]TopicPartition, error) {
+	return mc.commit(nil)
+}
+
+// CommitMessage commits offset based on the provided message.
+func (mc *MockConsumer) CommitMessage(m *Message) ([]TopicPartition, error) {
+	if m.TopicPartition.Error != nil {
+		return nil, newErrorFromString(ErrInvalidArg, "Can't commit errored message")
+	}
+	offsets := []TopicPartition{m.TopicPartition}
+	offsets[0].Offset++
+	return mc.commit(offsets)
+}
+
+// CommitOffsets commits the provided list of offsets.
+func (mc *MockConsumer) CommitOffsets(offsets []TopicPartition) ([]TopicPartition, error) {
+	return mc.commit(offsets)
+}
+
+// StoreOffsets stores the provided list of offsets to be committed by
+// the next Commit().
+func (mc *MockConsumer) StoreOffsets(offsets []TopicPartition) (storedOffsets []TopicPartition, err error) {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+	for _, p := range offsets {
+		mc.stored[newMockTopicPartition(p)] = p.Offset
+	}
+	return append([]TopicPartition{}, offsets...), nil
+}
+
+// StoreMessage stores offset based on the provided message.
+func (mc *MockConsumer) StoreMessage(m *Message) (storedOffsets []TopicPartition, err error) {
+	if m.TopicPartition.Error != nil {
+		return nil, newErrorFromString(ErrInvalidArg, "Can't store errored message")
+	}
+	if m.TopicPartition.Offset < 0 {
+		return nil, newErrorFromString(ErrInvalidArg, "Can't store message with offset less than 0")
+	}
+	offsets := []TopicPartition{m.TopicPartition}
+	offsets[0].Offset++
+	return mc.StoreOffsets(offsets)
+}
+
+// lookupOffsets returns a copy of partitions with the .Offset set from
+// the offsets map, or OffsetInvalid if not found.
+func (mc *MockConsumer) lookupOffsets(partitions []TopicPartition, offsets map[mockTopicPartition]Offset) []TopicPartition {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+	result := append([]TopicPartition{}, partitions...)
+	for i := range result {
+		offset, ok := offsets[newMockTopicPartition(result[i])]
+		if !ok {
+			offset = OffsetInvalid
+		}
+		result[i].Offset = offset
+	}
+	return result
+}
+
+// Committed returns the committed offsets for the given set of partitions.
+func (mc *MockConsumer) Committed(partitions []TopicPartition, timeoutMs int) (offsets []TopicPartition, err error) {
+	return mc.lookupOffsets(partitions, mc.committed), nil
+}
+
+// Position returns the current consume position for the given partitions.
+func (mc *MockConsumer) Position(partitions []TopicPartition) (offsets []TopicPartition, err error) {
+	return mc.lookupOffsets(partitions, mc.position), nil
+}
+
+// Seek sets the consume position of an assigned partition, queued
+// messages prior to the new position will be skipped.
+func (mc *MockConsumer) Seek(partition TopicPartition, timeoutMs int) error {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+	tp := newMockTopicPartition(partition)
+	if !mc.assignment[tp] {
+		return newErrorFromString(ErrState, "")
+	}
+	mc.position[tp] = partition.Offset
+	return nil
+}
+
+// Pause consumption for the provided list of partitions.
+func (mc *MockConsumer) Pause(partitions []TopicPartition) (err error) {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+	for _, p := range partitions {
+		mc.paused[newMockTopicPartition(p)] = true
+	}
+	return nil
+}
+
+// Resume consumption for the provided list of partitions.
+func (mc *MockConsumer) Resume(partitions []TopicPartition) (err error) {
+	mc.lock.Lock()
+	for _, p := range partitions {
+		delete(mc.paused, newMockTopicPartition(p))
+	}
+	mc.lock.Unlock()
+
+	select {
+	case mc.wakeup <- true:
+	default:
+	}
+	return nil
+}
+
+// next removes and returns the next consumable event from the queue,
+// or nil if there is none.
+// Messages for unassigned or paused partitions are retained in the queue,
+// messages prior to the partition's current position are discarded.
+// Must be called with the lock held.
+func (mc *MockConsumer) next() Event {
+	for i := 0; i < len(mc.queue); i++ {
+		ev := mc.queue[i]
+
+		if m, ok := ev.(*Message); ok {
+			tp := newMockTopicPartition(m.TopicPartition)
+			if !mc.assignment[tp] || mc.paused[tp] {
+				continue
+			}
+
+			if pos, ok := mc.position[tp]; ok && m.TopicPartition.Offset < pos {
+				mc.queue = append(mc.queue[:i], mc.queue[i+1:]...)
+				i--
+				continue
+			}
+
+			mc.position[tp] = m.TopicPartition.Offset + 1
+		}
+
+		mc.queue = append(mc.queue[:i], mc.queue[i+1:]...)
+		return ev
+	}
+
+	return nil
+}
+
+// handleRebalance serves a rebalance event, see Rebalance().
+func (mc *MockConsumer) handleRebalance(ev Event) {
+	mc.lock.Lock()
+	rebalanceCb := mc.rebalanceCb
+	mc.appReassigned = false
+	mc.lock.Unlock()
+
+	if rebalanceCb != nil {
+		rebalanceCb(nil, ev)
+	}
+
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+	if mc.appReassigned {
+		return
+	}
+
+	switch e := ev.(type) {
+	case AssignedPartitions:
+		mc.assign(e.Partitions)
+	case RevokedPartitions:
+		mc.unassign(e.Partitions)
+	}
+}
+
+// Poll the consumer for queued messages or events.
+//
+// Will block for at most timeoutMs milliseconds, -1 for indefinite wait.
+//
+// Returns nil on timeout, else an Event
+func (mc *MockConsumer) Poll(timeoutMs int) (event Event) {
+	var timer <-chan time.Time
+	if timeoutMs >= 0 {
+		timer = time.After(time.Duration(timeoutMs) * time.Millisecond)
+	}
+
+	for {
+		mc.lock.Lock()
+		if mc.closed {
+			mc.lock.Unlock()
+			return nil
+		}
+		ev := mc.next()
+		mc.lock.Unlock()
+
+		switch ev.(type) {
+		case nil:
+			select {
+			case <-mc.wakeup:
+				continue
+			case <-timer:
+				return nil
+			}
+		case AssignedPartitions, RevokedPartitions:
+			mc.handleRebalance(ev)
+			continue
+		}
+
+		return ev
+	}
+}
+
+// ReadMessage polls the consumer for a message, see Consumer.ReadMessage().
+func (mc *MockConsumer) ReadMessage(timeout time.Duration) (*Message, error) {
+	tEnd := time.Now().Add(timeout)
+
+	for {
+		timeoutMs := -1
+		if timeout >= 0 {
+			timeoutMs = int(time.Until(tEnd) / time.Millisecond)
+			if timeoutMs < 0 {
+				timeoutMs = 0
+			}
+		}
+
+		ev := mc.Poll(timeoutMs)
+
+		switch e := ev.(type) {
+		case *Message:
+			if e.TopicPartition.Error != nil {
+				return e, e.TopicPartition.Error
+			}
+			return e, nil
+		case Error:
+			return nil, e
+		case nil:
+			return nil, newErrorFromString(ErrTimedOut, "")
+		}
+	}
+}
+
+// Events returns nil since the MockConsumer does not support the
+// Events() channel.
+func (mc *MockConsumer) Events() chan Event {
+	return nil
+}
+
+// Logs returns nil since the MockConsumer does not emit logs.
+func (mc *MockConsumer) Logs() chan LogEvent {
+	return nil
+}
+
+// Close the MockConsumer, any blocking Poll() call will return nil.
+func (mc *MockConsumer) Close() (err error) {
+	mc.lock.Lock()
+	mc.closed = true
+	mc.lock.Unlock()
+
+	select {
+	case mc.wakeup <- true:
+	default:
+	}
+	return nil
+}
+
+// GetMetadata is not supported by the MockConsumer.
+func (mc *MockConsumer) GetMetadata(topic *string, allTopics bool, timeoutMs int) (*Metadata, error) {
+	return nil, errMockNotSupported("GetMetadata")
+}
+
+// Metadata is not supported by the MockConsumer.
+func (mc *MockConsumer) Metadata(ctx context.Context, opts MetadataOptions) (*Metadata, error) {
+	return nil, errMockNotSupported("Metadata")
+}
+
+// QueryWatermarkOffsets returns the low and high watermark offsets of the
+// partition, see SetWatermarkOffsets().
+func (mc *MockConsumer) QueryWatermarkOffsets(topic string, partition int32, timeoutMs int) (low, high int64, err error) {
+	return mc.GetWatermarkOffsets(topic, partition)
+}
+
+// GetWatermarkOffsets returns the low and high watermark offsets of the
+// partition, see SetWatermarkOffsets().
+func (mc *MockConsumer) GetWatermarkOffsets(topic string, partition int32) (low, high int64, err error) {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+	tp := mockTopicPartition{topic, partition}
+	return int64(mc.low[tp]), int64(mc.high[tp]), nil
+}
+
+// OffsetsForTimes is not supported by the MockConsumer.
+func (mc *MockConsumer) OffsetsForTimes(times []TopicPartition, timeoutMs int) (offsets []TopicPartition, err error) {
+	return nil, errMockNotSupported("OffsetsForTimes")
+}
+
+// GetConsumerGroupMetadata returns test consumer group metadata.
+func (mc *MockConsumer) GetConsumerGroupMetadata() (*ConsumerGroupMetadata, error) {
+	return NewTestConsumerGroupMetadata("mock")
+}
+
+// SetOAuthBearerToken is a no-op on the MockConsumer.
+func (mc *MockConsumer) SetOAuthBearerToken(oauthBearerToken OAuthBearerToken) error {
+	return nil
+}
+
+// SetOAuthBearerTokenFailure is a no-op on the MockConsumer.
+func (mc *MockConsumer) SetOAuthBearerTokenFailure(errstr string) error {
+	return nil
+}
+
+// Make sure the mock clients implement the client interfaces.
+var (
+	_ ProducerClient = (*MockProducer)(nil)
+	_ ConsumerClient = (*MockConsumer)(nil)
+)
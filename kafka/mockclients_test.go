@@ -0,0 +1,264 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestMockProducer tests produce, delivery report completion and errors
+// of the MockProducer.
+func TestMockProducer(t *testing.T) {
+	mp := NewMockProducer(false)
+	defer mp.Close()
+
+	topic := "mock"
+	drChan := make(chan Event, 10)
+
+	for i := 0; i < 3; i++ {
+		err := mp.Produce(&Message{
+			TopicPartition: TopicPartition{Topic: &topic, Partition: PartitionAny},
+			Value:          []byte("hi"),
+			Opaque:         i}, drChan)
+		if err != nil {
+			t.Fatalf("Produce failed: %v", err)
+		}
+	}
+
+	msgs := mp.Messages()
+	if len(msgs) != 3 {
+		t.Fatalf("Expected 3 recorded messages, got %d", len(msgs))
+	}
+	for i, m := range msgs {
+		if m.TopicPartition.Offset != Offset(i) {
+			t.Errorf("Expected offset %d, got %v", i, m.TopicPartition.Offset)
+		}
+	}
+
+	if len(drChan) != 0 {
+		t.Fatalf("Expected no delivery reports before completion")
+	}
+
+	if !mp.CompleteNext() {
+		t.Fatalf("Expected a pending delivery report")
+	}
+	if !mp.ErrorNext(NewError(ErrMsgTimedOut, "", false)) {
+		t.Fatalf("Expected a pending delivery report")
+	}
+
+	m := (<-drChan).(*Message)
+	if m.TopicPartition.Error != nil || m.Opaque.(int) != 0 {
+		t.Errorf("Expected successful delivery of message 0, got %v", m)
+	}
+	m = (<-drChan).(*Message)
+	if m.TopicPartition.Error.(Error).Code() != ErrMsgTimedOut || m.Opaque.(int) != 1 {
+		t.Errorf("Expected failed delivery of message 1, got %v", m)
+	}
+
+	if mp.Flush(100) != 0 {
+		t.Errorf("Expected Flush to complete all delivery reports")
+	}
+	m = (<-drChan).(*Message)
+	if m.TopicPartition.Error != nil || m.Opaque.(int) != 2 {
+		t.Errorf("Expected successful delivery of message 2, got %v", m)
+	}
+
+	mp.SetProduceError(NewError(ErrQueueFull, "", false))
+	err := mp.Produce(&Message{TopicPartition: TopicPartition{Topic: &topic}}, nil)
+	if err == nil || err.(Error).Code() != ErrQueueFull {
+		t.Errorf("Expected ErrQueueFull, got %v", err)
+	}
+
+	mp.Clear()
+	if len(mp.Messages()) != 0 {
+		t.Errorf("Expected Clear to remove recorded messages")
+	}
+}
+
+// TestMockProducerAsyncDelivery tests that delivery reports don't block
+// Produce() and are discarded once the MockProducer is closed.
+func TestMockProducerAsyncDelivery(t *testing.T) {
+	mp := NewMockProducer(true)
+
+	topic := "mock"
+	drChan := make(chan Event)
+
+	done := make(chan error)
+	go func() {
+		done <- mp.Produce(&Message{TopicPartition: TopicPartition{Topic: &topic}}, drChan)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Produce failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Produce blocked on the unread delivery channel")
+	}
+
+	if m := (<-drChan).(*Message); m.TopicPartition.Error != nil {
+		t.Errorf("Expected successful delivery, got %v", m)
+	}
+	mp.Close()
+
+	mp = NewMockProducer(false)
+	if err := mp.Produce(&Message{TopicPartition: TopicPartition{Topic: &topic}}, nil); err != nil {
+		t.Fatalf("Produce failed: %v", err)
+	}
+	mp.Close()
+
+	if mp.CompleteNext() || mp.ErrorNext(NewError(ErrMsgTimedOut, "", false)) {
+		t.Errorf("Expected no pending delivery reports after Close")
+	}
+	mp.deliver(mockDr{msg: &Message{}}, nil)
+	if _, ok := <-mp.Events(); ok {
+		t.Errorf("Expected Events() to be closed")
+	}
+}
+
+// TestMockProducerTransactions tests the MockProducer transactional
+// state machine.
+func TestMockProducerTransactions(t *testing.T) {
+	mp := NewMockProducer(false)
+	defer mp.Close()
+
+	ctx := context.Background()
+
+	if err := mp.BeginTransaction(); err == nil {
+		t.Errorf("Expected BeginTransaction to fail before InitTransactions")
+	}
+
+	if err := mp.InitTransactions(ctx); err != nil {
+		t.Fatalf("InitTransactions failed: %v", err)
+	}
+
+	topic := "mock"
+	for _, commit := range []bool{true, false} {
+		if err := mp.BeginTransaction(); err != nil {
+			t.Fatalf("BeginTransaction failed: %v", err)
+		}
+		mp.Produce(&Message{TopicPartition: TopicPartition{Topic: &topic}}, nil)
+
+		var err error
+		if commit {
+			err = mp.CommitTransaction(ctx)
+		} else {
+			err = mp.AbortTransaction(ctx)
+		}
+		if err != nil {
+			t.Fatalf("Ending transaction failed: %v", err)
+		}
+
+		m := (<-mp.Events()).(*Message)
+		if commit && m.TopicPartition.Error != nil {
+			t.Errorf("Expected committed message to be delivered, got %v", m)
+		} else if !commit && m.TopicPartition.Error.(Error).Code() != ErrPurgeQueue {
+			t.Errorf("Expected aborted message to be purged, got %v", m)
+		}
+	}
+
+	committed, aborted := mp.TransactionCounts()
+	if committed != 1 || aborted != 1 {
+		t.Errorf("Expected 1 committed and 1 aborted transaction, got %d and %d",
+			committed, aborted)
+	}
+}
+
+// TestMockConsumer tests rebalancing, consuming, seeking and committing
+// with the MockConsumer.
+func TestMockConsumer(t *testing.T) {
+	mc := NewMockConsumer()
+	defer mc.Close()
+
+	topic := "mock"
+	for p := int32(0); p < 2; p++ {
+		for i := 0; i < 3; i++ {
+			mc.AddMessage(&Message{
+				TopicPartition: TopicPartition{Topic: &topic, Partition: p,
+					Offset: OffsetInvalid},
+				Value: []byte("hi")})
+		}
+	}
+
+	rebalanceCnt := 0
+	err := mc.Subscribe(topic, func(c *Consumer, ev Event) error {
+		rebalanceCnt++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	if ev := mc.Poll(10); ev != nil {
+		t.Fatalf("Expected no event prior to assignment, got %v", ev)
+	}
+
+	mc.Rebalance([]TopicPartition{{Topic: &topic, Partition: 1}})
+
+	for i := 0; i < 3; i++ {
+		m, err := mc.ReadMessage(time.Second)
+		if err != nil {
+			t.Fatalf("ReadMessage failed: %v", err)
+		}
+		if m.TopicPartition.Partition != 1 || m.TopicPartition.Offset != Offset(i) {
+			t.Errorf("Unexpected message %v", m)
+		}
+	}
+
+	if rebalanceCnt != 1 {
+		t.Errorf("Expected rebalanceCb to be called once, not %d", rebalanceCnt)
+	}
+
+	if _, err := mc.ReadMessage(10 * time.Millisecond); err.(Error).Code() != ErrTimedOut {
+		t.Errorf("Expected ErrTimedOut, got %v", err)
+	}
+
+	committed, err := mc.Commit()
+	if err != nil || len(committed) != 1 || committed[0].Offset != 3 {
+		t.Errorf("Expected commit of offset 3, got %v: %v", committed, err)
+	}
+
+	// Move the assignment to partition 0 and skip the first message.
+	mc.Rebalance([]TopicPartition{{Topic: &topic, Partition: 0, Offset: 1}})
+
+	m, err := mc.ReadMessage(time.Second)
+	if err != nil {
+		t.Fatalf("ReadMessage failed: %v", err)
+	}
+	if m.TopicPartition.Partition != 0 || m.TopicPartition.Offset != 1 {
+		t.Errorf("Unexpected message %v", m)
+	}
+
+	if rebalanceCnt != 3 {
+		t.Errorf("Expected rebalanceCb to be called 3 times, not %d", rebalanceCnt)
+	}
+
+	low, high, _ := mc.GetWatermarkOffsets(topic, 0)
+	if low != 0 || high != 3 {
+		t.Errorf("Expected watermarks 0..3, got %d..%d", low, high)
+	}
+
+	mc.AddEvent(NewError(ErrAllBrokersDown, "mock", false))
+	if m, err = mc.ReadMessage(time.Second); err != nil || m.TopicPartition.Offset != 2 {
+		t.Errorf("Expected message at offset 2, got %v: %v", m, err)
+	}
+	if _, err := mc.ReadMessage(time.Second); err.(Error).Code() != ErrAllBrokersDown {
+		t.Errorf("Expected ErrAllBrokersDown, got %v", err)
+	}
+}
@@ -16,7 +16,10 @@ package kafka
  * limitations under the License.
  */
 
-import "unsafe"
+import (
+	"time"
+	"unsafe"
+)
 
 /*
 #include <stdlib.h>
@@ -82,3 +85,114 @@ func (mc *MockCluster) Close() {
 	C.rd_kafka_mock_cluster_destroy(mc.mcluster)
 	C.rd_kafka_destroy(mc.rk)
 }
+
+// CreateTopic creates a topic without having to use a Producer or
+// topic auto creation.
+//
+// The Admin API CreateTopics() is not supported by the MockCluster.
+func (mc *MockCluster) CreateTopic(topic string, partitions, replicationFactor int) error {
+	cTopic := C.CString(topic)
+	defer C.free(unsafe.Pointer(cTopic))
+
+	return mockErr(C.rd_kafka_mock_topic_create(mc.mcluster, cTopic,
+		C.int(partitions), C.int(replicationFactor)))
+}
+
+// SetTopicError sets the topic error to return in Metadata and
+// AddPartitionsToTxn protocol requests, use ErrNoError to clear it.
+func (mc *MockCluster) SetTopicError(topic string, code ErrorCode) {
+	cTopic := C.CString(topic)
+	defer C.free(unsafe.Pointer(cTopic))
+
+	C.rd_kafka_mock_topic_set_error(mc.mcluster, cTopic,
+		C.rd_kafka_resp_err_t(code))
+}
+
+// PushRequestErrors pushes errors onto the cluster's error stack for the
+// given apiKey, the Kafka protocol request type, e.g., 0 for ProduceRequest.
+//
+// The following len(errors) protocol requests matching apiKey will fail
+// with the provided error codes, in order.
+//
+// Passing ErrTransport makes the mock broker disconnect the client,
+// which is useful to trigger a disconnect on certain requests.
+//
+// Requires librdkafka v1.7.0 or later.
+func (mc *MockCluster) PushRequestErrors(apiKey int16, errors ...ErrorCode) error {
+	if err := requireLibrdkafkaVersion("MockCluster.PushRequestErrors", 0x01070000); err != nil {
+		return err
+	}
+
+	if len(errors) == 0 {
+		return nil
+	}
+
+	cErrors := make([]C.rd_kafka_resp_err_t, len(errors))
+	for i, code := range errors {
+		cErrors[i] = C.rd_kafka_resp_err_t(code)
+	}
+
+	C.rd_kafka_mock_push_request_errors_array(mc.mcluster, C.int16_t(apiKey),
+		C.size_t(len(cErrors)), &cErrors[0])
+
+	return nil
+}
+
+// ClearRequestErrors clears the cluster's error stack for the given apiKey.
+func (mc *MockCluster) ClearRequestErrors(apiKey int16) {
+	C.rd_kafka_mock_clear_request_errors(mc.mcluster, C.int16_t(apiKey))
+}
+
+// SetRoundtripDuration sets the broker's round-trip-time delay.
+func (mc *MockCluster) SetRoundtripDuration(brokerID int32, duration time.Duration) error {
+	return mockErr(C.rd_kafka_mock_broker_set_rtt(mc.mcluster,
+		C.int32_t(brokerID), C.int(durationToMilliseconds(duration))))
+}
+
+// SetBrokerDown disconnects the broker and disallows any new connections.
+// This does NOT trigger a leader change, see SetPartitionLeader().
+func (mc *MockCluster) SetBrokerDown(brokerID int32) error {
+	return mockErr(C.rd_kafka_mock_broker_set_down(mc.mcluster,
+		C.int32_t(brokerID)))
+}
+
+// SetBrokerUp makes the broker accept connections again.
+// This does NOT trigger a leader change, see SetPartitionLeader().
+func (mc *MockCluster) SetBrokerUp(brokerID int32) error {
+	return mockErr(C.rd_kafka_mock_broker_set_up(mc.mcluster,
+		C.int32_t(brokerID)))
+}
+
+// SetPartitionLeader sets the partition leader, the topic is created
+// if it does not exist.
+//
+// brokerID needs to be an existing broker, or -1 to make the
+// partition leader-less.
+func (mc *MockCluster) SetPartitionLeader(topic string, partition int32, brokerID int32) error {
+	cTopic := C.CString(topic)
+	defer C.free(unsafe.Pointer(cTopic))
+
+	return mockErr(C.rd_kafka_mock_partition_set_leader(mc.mcluster, cTopic,
+		C.int32_t(partition), C.int32_t(brokerID)))
+}
+
+// SetCoordinator explicitly sets the coordinator for the given
+// group.id (keyType "group") or transactional.id (keyType "transaction").
+// The brokerID does not have to be a valid broker.
+func (mc *MockCluster) SetCoordinator(keyType string, key string, brokerID int32) error {
+	cKeyType := C.CString(keyType)
+	defer C.free(unsafe.Pointer(cKeyType))
+	cKey := C.CString(key)
+	defer C.free(unsafe.Pointer(cKey))
+
+	return mockErr(C.rd_kafka_mock_coordinator_set(mc.mcluster, cKeyType, cKey,
+		C.int32_t(brokerID)))
+}
+
+// mockErr converts a mock cluster API return value to an error, or nil.
+func mockErr(cErr C.rd_kafka_resp_err_t) error {
+	if cErr != C.RD_KAFKA_RESP_ERR_NO_ERROR {
+		return newError(cErr)
+	}
+	return nil
+}
@@ -0,0 +1,268 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"testing"
+	"time"
+)
+
+// TestMockCluster produces to and consumes from a MockCluster
+// using real client instances, relying on topic auto creation.
+func TestMockCluster(t *testing.T) {
+	mc, err := NewMockCluster(3)
+	if err != nil {
+		t.Fatalf("Failed to create MockCluster: %v", err)
+	}
+	defer mc.Close()
+
+	bootstrapServers := mc.BootstrapServers()
+	if bootstrapServers == "" {
+		t.Fatalf("Expected non-empty bootstrap.servers")
+	}
+
+	topic := "mockTopic"
+
+	a, err := NewAdminClient(&ConfigMap{"bootstrap.servers": bootstrapServers})
+	if err != nil {
+		t.Fatalf("Failed to create AdminClient: %v", err)
+	}
+	defer a.Close()
+
+	md, err := a.GetMetadata(nil, true, 10*1000)
+	if err != nil {
+		t.Fatalf("GetMetadata failed: %v", err)
+	}
+	if len(md.Brokers) != 3 {
+		t.Errorf("Expected 3 brokers, got %d", len(md.Brokers))
+	}
+
+	p, err := NewProducer(&ConfigMap{"bootstrap.servers": bootstrapServers})
+	if err != nil {
+		t.Fatalf("Failed to create Producer: %v", err)
+	}
+	defer p.Close()
+
+	msgCnt := 10
+	for i := 0; i < msgCnt; i++ {
+		err = p.Produce(&Message{
+			TopicPartition: TopicPartition{Topic: &topic, Partition: PartitionAny},
+			Value:          []byte("mock")}, nil)
+		if err != nil {
+			t.Fatalf("Produce failed: %v", err)
+		}
+	}
+
+	for i := 0; i < msgCnt; i++ {
+		m := (<-p.Events()).(*Message)
+		if m.TopicPartition.Error != nil {
+			t.Fatalf("Delivery failed: %v", m.TopicPartition)
+		}
+		if m.BrokerID() < 1 || m.Latency() < 0 {
+			t.Errorf("Expected delivery report broker id and latency, got %d, %v",
+				m.BrokerID(), m.Latency())
+		}
+	}
+
+	c, err := NewConsumer(&ConfigMap{
+		"bootstrap.servers": bootstrapServers,
+		"group.id":          "mockGroup",
+		"auto.offset.reset": "earliest"})
+	if err != nil {
+		t.Fatalf("Failed to create Consumer: %v", err)
+	}
+	defer c.Close()
+
+	err = c.Subscribe(topic, nil)
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	for i := 0; i < msgCnt; i++ {
+		m, err := c.ReadMessage(10 * time.Second)
+		if err != nil {
+			t.Fatalf("ReadMessage failed after %d/%d messages: %v", i, msgCnt, err)
+		}
+		if string(m.Value) != "mock" {
+			t.Errorf("Unexpected message value %s", m.Value)
+		}
+	}
+}
+
+// TestMockClusterFaultInjection tests MockCluster error injection and
+// partition leader changes.
+func TestMockClusterFaultInjection(t *testing.T) {
+	mc, err := NewMockCluster(3)
+	if err != nil {
+		t.Fatalf("Failed to create MockCluster: %v", err)
+	}
+	defer mc.Close()
+
+	topic := "mockTopic"
+
+	if err = mc.CreateTopic(topic, 2, 1); err != nil {
+		t.Fatalf("CreateTopic failed: %v", err)
+	}
+
+	if err = mc.SetPartitionLeader(topic, 0, 3); err != nil {
+		t.Fatalf("SetPartitionLeader failed: %v", err)
+	}
+
+	if err = mc.SetPartitionLeader(topic, 0, 99); err == nil {
+		t.Errorf("Expected SetPartitionLeader to fail for unknown broker")
+	}
+
+	if err = mc.SetRoundtripDuration(1, 10*time.Millisecond); err != nil {
+		t.Errorf("SetRoundtripDuration failed: %v", err)
+	}
+
+	p, err := NewProducer(&ConfigMap{"bootstrap.servers": mc.BootstrapServers()})
+	if err != nil {
+		t.Fatalf("Failed to create Producer: %v", err)
+	}
+	defer p.Close()
+
+	md, err := p.GetMetadata(&topic, false, 10*1000)
+	if err != nil {
+		t.Fatalf("GetMetadata failed: %v", err)
+	}
+	partitions := md.Topics[topic].Partitions
+	if len(partitions) != 2 {
+		t.Fatalf("Expected 2 partitions, got %v", partitions)
+	}
+	for _, partition := range partitions {
+		if partition.ID == 0 && partition.Leader != 3 {
+			t.Errorf("Expected partition 0 leader 3, got %d", partition.Leader)
+		}
+	}
+
+	// Fail the next ProduceRequest (ApiKey 0) with a permanent error.
+	if err = mc.PushRequestErrors(0, ErrMsgSizeTooLarge); err != nil {
+		t.Fatalf("PushRequestErrors failed: %v", err)
+	}
+
+	err = p.Produce(&Message{
+		TopicPartition: TopicPartition{Topic: &topic, Partition: 0}}, nil)
+	if err != nil {
+		t.Fatalf("Produce failed: %v", err)
+	}
+
+	m := (<-p.Events()).(*Message)
+	if m.TopicPartition.Error == nil ||
+		m.TopicPartition.Error.(Error).Code() != ErrMsgSizeTooLarge {
+		t.Errorf("Expected ErrMsgSizeTooLarge, got %v", m.TopicPartition)
+	}
+
+	// The error stack is now empty, so the next produce must succeed.
+	err = p.Produce(&Message{
+		TopicPartition: TopicPartition{Topic: &topic, Partition: 0}}, nil)
+	if err != nil {
+		t.Fatalf("Produce failed: %v", err)
+	}
+
+	m = (<-p.Events()).(*Message)
+	if m.TopicPartition.Error != nil {
+		t.Errorf("Expected successful delivery, got %v", m.TopicPartition)
+	}
+
+	if err = mc.SetBrokerDown(2); err != nil {
+		t.Errorf("SetBrokerDown failed: %v", err)
+	}
+	if err = mc.SetBrokerUp(2); err != nil {
+		t.Errorf("SetBrokerUp failed: %v", err)
+	}
+	if err = mc.SetCoordinator("group", "mockGroup", 1); err != nil {
+		t.Errorf("SetCoordinator failed: %v", err)
+	}
+}
+
+// TestBrokerStateEvents tests BrokerUp, BrokerDown and AllBrokersDown
+// events by taking a MockCluster broker down and up.
+func TestBrokerStateEvents(t *testing.T) {
+	mc, err := NewMockCluster(1)
+	if err != nil {
+		t.Fatalf("Failed to create MockCluster: %v", err)
+	}
+	defer mc.Close()
+
+	p, err := NewProducer(&ConfigMap{
+		"bootstrap.servers":      mc.BootstrapServers(),
+		"statistics.interval.ms": 100,
+		"go.broker.state.events": true})
+	if err != nil {
+		t.Fatalf("Failed to create Producer: %v", err)
+	}
+	defer p.Close()
+
+	// Trigger a connection
+	if _, err = p.GetMetadata(nil, true, 10*1000); err != nil {
+		t.Fatalf("GetMetadata failed: %v", err)
+	}
+
+	waitEvent := func(match func(ev Event) bool) Event {
+		tmout := time.After(10 * time.Second)
+		for {
+			select {
+			case ev := <-p.Events():
+				if match(ev) {
+					return ev
+				}
+			case <-tmout:
+				t.Fatalf("Timed out waiting for broker state event")
+			}
+		}
+	}
+
+	up := waitEvent(func(ev Event) bool { _, ok := ev.(BrokerUp); return ok }).(BrokerUp)
+	if up.BrokerID != 1 || up.Recovered {
+		t.Errorf("Unexpected BrokerUp event %v", up)
+	}
+
+	mc.SetBrokerDown(1)
+
+	waitEvent(func(ev Event) bool {
+		switch e := ev.(type) {
+		case Error:
+			if e.Code() == ErrTransport || e.Code() == ErrAllBrokersDown {
+				t.Errorf("Expected typed broker state event, got %v", e)
+			}
+		case AllBrokersDown:
+			return true
+		}
+		return false
+	})
+
+	mc.SetBrokerUp(1)
+
+	up = waitEvent(func(ev Event) bool { _, ok := ev.(BrokerUp); return ok }).(BrokerUp)
+	if up.BrokerID != 1 || !up.Recovered {
+		t.Errorf("Expected recovered BrokerUp event, got %v", up)
+	}
+}
+
+// TestBrokerIDFromName tests broker id extraction from broker names.
+func TestBrokerIDFromName(t *testing.T) {
+	for name, id := range map[string]int32{
+		"localhost:9092/3":         3,
+		"localhost:9092/bootstrap": -1,
+		"GroupCoordinator":         -1,
+	} {
+		if brokerIDFromName(name) != id {
+			t.Errorf("Expected %s broker id %d, got %d", name, id, brokerIDFromName(name))
+		}
+	}
+}
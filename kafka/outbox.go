@@ -0,0 +1,294 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// outboxRetryBackoff is the backoff between commit retries
+	outboxRetryBackoff = 100 * time.Millisecond
+	// outboxAbortTimeout bounds the abort of a failed transaction
+	outboxAbortTimeout = 30 * time.Second
+)
+
+// OutboxRow is a row of an outbox table, the message to publish and
+// its id, which increases with each row.
+type OutboxRow struct {
+	ID      int64
+	Message *Message
+}
+
+// OutboxSource is the application's outbox table, see OutboxRelay.
+type OutboxSource interface {
+	// Fetch returns up to max rows with an id greater than afterID,
+	// in increasing id order.
+	Fetch(ctx context.Context, afterID int64, max int) ([]OutboxRow, error)
+	// Ack marks the rows up to and including id as published, e.g.,
+	// to delete them. Ack may be called again with the same id.
+	Ack(ctx context.Context, id int64) error
+}
+
+// OutboxRelay publishes the rows of an outbox table through a
+// transactional Producer.
+//
+// Each batch of rows is produced in a transaction together with a
+// checkpoint message, keyed by the `transactional.id` and holding the id
+// of the batch's last row, to the checkpoint topic, which should be
+// compacted. The relay resumes from the last committed checkpoint, so
+// that each row is published exactly once even if the relay fails
+// before acknowledging the rows to the source, which are then
+// acknowledged again.
+type OutboxRelay struct {
+	// BatchSize is the maximum number of rows published per transaction,
+	// 100 by default.
+	BatchSize int
+	// PollInterval is the interval at which the source is polled once
+	// all rows were published, 1s by default.
+	PollInterval time.Duration
+
+	conf            ConfigMap
+	source          OutboxSource
+	checkpointTopic string
+	relayID         string
+	p               *Producer
+	// Id of the last published row
+	checkpoint int64
+}
+
+// NewOutboxRelay creates an OutboxRelay publishing the rows of source,
+// checkpointing to checkpointTopic.
+// conf is the configuration of the relay's Producer and of the Consumer
+// reading the checkpoint, it must hold a `transactional.id`, which
+// identifies the relay and must be stable across restarts.
+func NewOutboxRelay(conf *ConfigMap, source OutboxSource, checkpointTopic string) (*OutboxRelay, error) {
+	v, err := conf.Get("transactional.id", nil)
+	if err != nil {
+		return nil, err
+	}
+	relayID, ok := v.(string)
+	if !ok || relayID == "" {
+		return nil, newErrorFromString(ErrInvalidArg,
+			"OutboxRelay requires a transactional.id")
+	}
+
+	p, err := NewProducer(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OutboxRelay{
+		BatchSize:       100,
+		PollInterval:    time.Second,
+		conf:            conf.clone(),
+		source:          source,
+		checkpointTopic: checkpointTopic,
+		relayID:         relayID,
+		p:               p,
+		checkpoint:      -1,
+	}, nil
+}
+
+// Checkpoint returns the id of the last published row,
+// or -1 if none was published.
+func (r *OutboxRelay) Checkpoint() int64 {
+	return atomic.LoadInt64(&r.checkpoint)
+}
+
+// readCheckpoint reads the relay's last committed checkpoint.
+func (r *OutboxRelay) readCheckpoint(ctx context.Context) (int64, error) {
+	// The consumer is configured as the producer, without the
+	// producer's transactional and Go properties
+	conf := ConfigMap{}
+	for k, v := range r.conf {
+		if k == "transactional.id" || k == "enable.idempotence" || strings.HasPrefix(k, "go.") {
+			continue
+		}
+		conf[k] = v
+	}
+	conf["isolation.level"] = "read_committed"
+	conf["group.id"] = "kafka-go-outbox-" + r.relayID
+
+	table, err := NewTable(&conf, r.checkpointTopic, nil)
+	if err != nil {
+		return -1, err
+	}
+	defer table.Close()
+
+	if err = table.WaitCaughtUp(ctx); err != nil {
+		return -1, err
+	}
+
+	value, found := table.Get(r.relayID)
+	if !found {
+		return -1, nil
+	}
+
+	checkpoint, err := strconv.ParseInt(string(value), 10, 64)
+	if err != nil {
+		return -1, newErrorFromString(ErrInvalidArg,
+			fmt.Sprintf("Invalid checkpoint %q in %s: %v", value, r.checkpointTopic, err))
+	}
+	return checkpoint, nil
+}
+
+// publish produces rows and their checkpoint in a transaction.
+func (r *OutboxRelay) publish(ctx context.Context, rows []OutboxRow) error {
+	if err := r.p.BeginTransaction(); err != nil {
+		return err
+	}
+
+	drChan := make(chan Event, len(rows)+1)
+	last := rows[len(rows)-1].ID
+	msgs := make([]*Message, 0, len(rows)+1)
+	for _, row := range rows {
+		msgs = append(msgs, row.Message)
+	}
+	msgs = append(msgs, &Message{
+		TopicPartition: TopicPartition{Topic: &r.checkpointTopic, Partition: PartitionAny},
+		Key:            []byte(r.relayID),
+		Value:          []byte(strconv.FormatInt(last, 10)),
+	})
+
+	err := func() error {
+		for _, m := range msgs {
+			for {
+				err := r.p.Produce(m, drChan)
+				if err == nil {
+					break
+				}
+				if kerr, ok := err.(Error); !ok || kerr.Code() != ErrQueueFull {
+					return err
+				}
+				r.p.Flush(100)
+			}
+		}
+
+		for range msgs {
+			select {
+			case ev := <-drChan:
+				if m := ev.(*Message); m.TopicPartition.Error != nil {
+					return m.TopicPartition.Error
+				}
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		return r.commit(ctx)
+	}()
+
+	if err != nil {
+		if kerr, ok := err.(Error); ok && kerr.IsFatal() {
+			return err
+		}
+		// ctx may be done already, the abort gets its own timeout
+		abortCtx, cancel := context.WithTimeout(context.Background(), outboxAbortTimeout)
+		defer cancel()
+		if aerr := r.p.AbortTransaction(abortCtx); aerr != nil {
+			return aerr
+		}
+		return err
+	}
+
+	return nil
+}
+
+// commit commits the current transaction, retrying retriable errors
+// after outboxRetryBackoff until ctx is done.
+func (r *OutboxRelay) commit(ctx context.Context) error {
+	for {
+		err := r.p.CommitTransaction(ctx)
+		if err == nil {
+			return nil
+		}
+		if kerr, ok := err.(Error); !ok || !kerr.IsRetriable() {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(outboxRetryBackoff):
+		}
+	}
+}
+
+// Run publishes the rows of the source until ctx is done, or a fatal
+// error is raised.
+// The transactions are initialized and the last checkpoint read first.
+// Errors of a batch abort its transaction and the batch is fetched again
+// after PollInterval, errors that aren't Kafka errors, such as errors of
+// the source, are returned.
+func (r *OutboxRelay) Run(ctx context.Context) error {
+	if err := r.p.InitTransactions(ctx); err != nil {
+		return err
+	}
+
+	checkpoint, err := r.readCheckpoint(ctx)
+	if err != nil {
+		return err
+	}
+	atomic.StoreInt64(&r.checkpoint, checkpoint)
+
+	acked := checkpoint < 0
+	for {
+		if !acked {
+			// Rows published before a failure are acknowledged again
+			if err = r.source.Ack(ctx, checkpoint); err != nil {
+				return err
+			}
+			acked = true
+		}
+
+		rows, err := r.source.Fetch(ctx, checkpoint, r.BatchSize)
+		if err != nil {
+			return err
+		}
+
+		if len(rows) > 0 {
+			err = r.publish(ctx, rows)
+			if err == nil {
+				checkpoint = rows[len(rows)-1].ID
+				atomic.StoreInt64(&r.checkpoint, checkpoint)
+				acked = false
+				continue
+			}
+
+			if kerr, ok := err.(Error); !ok || kerr.IsFatal() {
+				return err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(r.PollInterval):
+		}
+	}
+}
+
+// Close closes the relay's Producer, once Run() returned.
+func (r *OutboxRelay) Close() {
+	r.p.Close()
+}
@@ -0,0 +1,211 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testOutbox is an in-memory OutboxSource.
+type testOutbox struct {
+	lock  sync.Mutex
+	topic string
+	rows  []OutboxRow
+	acked int64
+}
+
+func (o *testOutbox) add(cnt int) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	for i := 0; i < cnt; i++ {
+		id := int64(len(o.rows))
+		o.rows = append(o.rows, OutboxRow{ID: id, Message: &Message{
+			TopicPartition: TopicPartition{Topic: &o.topic, Partition: PartitionAny},
+			Value:          []byte(fmt.Sprintf("%d", id)),
+		}})
+	}
+}
+
+func (o *testOutbox) Fetch(ctx context.Context, afterID int64, max int) ([]OutboxRow, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	rows := o.rows[afterID+1:]
+	if len(rows) > max {
+		rows = rows[:max]
+	}
+	return rows, nil
+}
+
+func (o *testOutbox) Ack(ctx context.Context, id int64) error {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.acked = id
+	return nil
+}
+
+// TestOutboxRelay tests that rows are published once, across relay restarts.
+func TestOutboxRelay(t *testing.T) {
+	mc, err := NewMockCluster(3)
+	if err != nil {
+		t.Fatalf("Failed to create MockCluster: %v", err)
+	}
+	defer mc.Close()
+
+	outbox := &testOutbox{topic: "outbox", acked: -1}
+	checkpointTopic := "outbox-checkpoints"
+	for _, topic := range []string{outbox.topic, checkpointTopic} {
+		if err = mc.CreateTopic(topic, 2, 3); err != nil {
+			t.Fatalf("CreateTopic failed: %v", err)
+		}
+	}
+
+	conf := ConfigMap{
+		"bootstrap.servers": mc.BootstrapServers(),
+		"transactional.id":  "outbox-relay"}
+
+	// run runs a relay until it published the rows up to id.
+	run := func(id int64) {
+		relay, err := NewOutboxRelay(&conf, outbox, checkpointTopic)
+		if err != nil {
+			t.Fatalf("NewOutboxRelay failed: %v", err)
+		}
+		defer relay.Close()
+		relay.PollInterval = 10 * time.Millisecond
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		errChan := make(chan error, 1)
+		go func() { errChan <- relay.Run(ctx) }()
+
+		for relay.Checkpoint() < id {
+			select {
+			case err = <-errChan:
+				t.Fatalf("Run failed at checkpoint %d: %v", relay.Checkpoint(), err)
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+
+		cancel()
+		if err = <-errChan; err != context.Canceled {
+			t.Errorf("Expected context.Canceled, not %v", err)
+		}
+	}
+
+	outbox.add(250)
+	run(249)
+
+	// Rows published but not acknowledged are acknowledged on restart
+	outbox.lock.Lock()
+	outbox.acked = 100
+	outbox.lock.Unlock()
+
+	outbox.add(10)
+	run(259)
+
+	if outbox.acked != 259 {
+		t.Errorf("Expected the rows to be acknowledged up to 259, not %d", outbox.acked)
+	}
+
+	c, err := NewConsumer(&ConfigMap{
+		"bootstrap.servers":  mc.BootstrapServers(),
+		"group.id":           "outbox",
+		"enable.auto.commit": false,
+		"isolation.level":    "read_committed"})
+	if err != nil {
+		t.Fatalf("Failed to create Consumer: %v", err)
+	}
+	defer c.Close()
+
+	published := make(map[string]int)
+	err = c.Assign([]TopicPartition{
+		{Topic: &outbox.topic, Partition: 0, Offset: OffsetBeginning},
+		{Topic: &outbox.topic, Partition: 1, Offset: OffsetBeginning}})
+	if err != nil {
+		t.Fatalf("Assign failed: %v", err)
+	}
+	for tEnd := time.Now().Add(10 * time.Second); len(published) < 260 && time.Now().Before(tEnd); {
+		if m, ok := c.Poll(100).(*Message); ok && m.TopicPartition.Error == nil {
+			published[string(m.Value)]++
+		}
+	}
+
+	for i := 0; i < 260; i++ {
+		if n := published[fmt.Sprintf("%d", i)]; n != 1 {
+			t.Errorf("Row %d published %d times", i, n)
+		}
+	}
+}
+
+// TestOutboxRelayCommitDeadline tests that commit retries stop once the
+// context is done.
+func TestOutboxRelayCommitDeadline(t *testing.T) {
+	mc, err := NewMockCluster(3)
+	if err != nil {
+		t.Fatalf("Failed to create MockCluster: %v", err)
+	}
+	defer mc.Close()
+
+	topic := "outbox"
+	if err = mc.CreateTopic(topic, 1, 3); err != nil {
+		t.Fatalf("CreateTopic failed: %v", err)
+	}
+
+	relay, err := NewOutboxRelay(&ConfigMap{
+		"bootstrap.servers": mc.BootstrapServers(),
+		"transactional.id":  "outbox-relay"}, &testOutbox{}, "outbox-checkpoints")
+	if err != nil {
+		t.Fatalf("NewOutboxRelay failed: %v", err)
+	}
+	defer relay.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err = relay.p.InitTransactions(ctx); err != nil {
+		t.Fatalf("InitTransactions failed: %v", err)
+	}
+	if err = relay.p.BeginTransaction(); err != nil {
+		t.Fatalf("BeginTransaction failed: %v", err)
+	}
+	err = relay.p.Produce(&Message{
+		TopicPartition: TopicPartition{Topic: &topic, Partition: 0}}, nil)
+	if err != nil {
+		t.Fatalf("Produce failed: %v", err)
+	}
+
+	expired, expiredCancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer expiredCancel()
+	<-expired.Done()
+
+	errChan := make(chan error, 1)
+	go func() { errChan <- relay.commit(expired) }()
+
+	select {
+	case err = <-errChan:
+		if err == nil {
+			t.Errorf("Expected commit to fail with an expired context")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected commit to return once the context is done")
+	}
+
+	relay.p.AbortTransaction(ctx)
+}
@@ -0,0 +1,231 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// overflowPolicy is applied to events that don't fit in the Events()
+// or Logs() channel, see `go.events.channel.overflow`.
+type overflowPolicy int
+
+const (
+	// overflowBlock blocks the poller until the application reads
+	// the channel.
+	overflowBlock overflowPolicy = iota
+	// overflowDropOldest drops the oldest event in the channel.
+	overflowDropOldest
+	// overflowCallback passes the event to the overflow callback.
+	overflowCallback
+)
+
+// newOverflowPolicy returns the policy named by the value of the
+// configuration property name.
+func newOverflowPolicy(name string, v ConfigValue) (overflowPolicy, error) {
+	switch v {
+	case "block":
+		return overflowBlock, nil
+	case "drop-oldest":
+		return overflowDropOldest, nil
+	case "callback":
+		return overflowCallback, nil
+	default:
+		return overflowBlock, newErrorFromString(ErrInvalidArg,
+			fmt.Sprintf("%s expects \"block\", \"drop-oldest\" or \"callback\", not %v", name, v))
+	}
+}
+
+// extractOverflowConfig extracts generic go.*.channel.overflow and
+// go.overflow.callback configuration properties.
+func (m ConfigMap) extractOverflowConfig() (eventsOverflow overflowPolicy, logsOverflow overflowPolicy, overflowCb func(Event), err error) {
+	v, err := m.extract("go.events.channel.overflow", "block")
+	if err != nil {
+		return
+	}
+
+	eventsOverflow, err = newOverflowPolicy("go.events.channel.overflow", v)
+	if err != nil {
+		return
+	}
+
+	v, err = m.extract("go.logs.channel.overflow", "block")
+	if err != nil {
+		return
+	}
+
+	logsOverflow, err = newOverflowPolicy("go.logs.channel.overflow", v)
+	if err != nil {
+		return
+	}
+
+	v, err = m.extract("go.overflow.callback", nil)
+	if err != nil {
+		return
+	}
+
+	if v != nil {
+		var ok bool
+		overflowCb, ok = v.(func(Event))
+		if !ok {
+			err = newErrorFromString(ErrInvalidArg,
+				fmt.Sprintf("go.overflow.callback expects a func(kafka.Event), not %T", v))
+			return
+		}
+	}
+
+	if overflowCb == nil && (eventsOverflow == overflowCallback || logsOverflow == overflowCallback) {
+		err = newErrorFromString(ErrInvalidArg,
+			"The callback overflow policy requires go.overflow.callback")
+	}
+
+	return
+}
+
+// checkDropOldest returns an error if the drop-oldest overflow policy
+// is configured for an unbuffered channel, which there is no oldest
+// event to drop from.
+func (h *handle) checkDropOldest(eventsChanSize int, logsChanEnable bool, logsChan chan LogEvent, logsChanSize int) error {
+	if h.eventsOverflow == overflowDropOldest && eventsChanSize <= 0 {
+		return newErrorFromString(ErrInvalidArg,
+			"go.events.channel.overflow=drop-oldest requires a buffered channel (go.events.channel.size > 0)")
+	}
+
+	if logsChan != nil {
+		logsChanSize = cap(logsChan)
+	}
+	if logsChanEnable && h.logsOverflow == overflowDropOldest && logsChanSize <= 0 {
+		return newErrorFromString(ErrInvalidArg,
+			"go.logs.channel.overflow=drop-oldest requires a buffered channel (go.logs.channel.size > 0)")
+	}
+
+	return nil
+}
+
+// sendEvent sends ev on the Events() channel, applying the events
+// overflow policy if the channel is full.
+// Returns true if termChan received a termination event while blocked.
+func (h *handle) sendEvent(channel chan Event, ev Event, termChan chan bool) bool {
+	switch h.eventsOverflow {
+	case overflowDropOldest:
+		for {
+			select {
+			case channel <- ev:
+				return false
+			case <-termChan:
+				return true
+			default:
+			}
+
+			select {
+			case old := <-channel:
+				atomic.AddInt64(&h.droppedEvents, 1)
+				// The application never saw the message: free its
+				// librdkafka resources without recycling its headers.
+				if m, ok := old.(*Message); ok {
+					m.releaseBuffers()
+				}
+			case <-termChan:
+				return true
+			default:
+			}
+		}
+
+	case overflowCallback:
+		select {
+		case channel <- ev:
+		default:
+			h.overflowCb(ev)
+		}
+		return false
+
+	default:
+		select {
+		case channel <- ev:
+			return false
+		case <-termChan:
+			return true
+		}
+	}
+}
+
+// sendLogEvent sends logEvent on the Logs() channel, applying the logs
+// overflow policy if the channel is full, see sendEvent().
+func (h *handle) sendLogEvent(channel chan LogEvent, logEvent LogEvent, termChan chan bool) bool {
+	switch h.logsOverflow {
+	case overflowDropOldest:
+		for {
+			select {
+			case channel <- logEvent:
+				return false
+			case <-termChan:
+				return true
+			default:
+			}
+
+			select {
+			case <-channel:
+				atomic.AddInt64(&h.droppedLogs, 1)
+			case <-termChan:
+				return true
+			default:
+			}
+		}
+
+	case overflowCallback:
+		select {
+		case channel <- logEvent:
+		default:
+			h.overflowCb(logEvent)
+		}
+		return false
+
+	default:
+		select {
+		case channel <- logEvent:
+			return false
+		case <-termChan:
+			return true
+		}
+	}
+}
+
+// DroppedEvents returns the number of events dropped from the full
+// Events() channel with `go.events.channel.overflow=drop-oldest`.
+func (c *Consumer) DroppedEvents() int {
+	return int(atomic.LoadInt64(&c.handle.droppedEvents))
+}
+
+// DroppedLogs returns the number of log events dropped from the full
+// Logs() channel with `go.logs.channel.overflow=drop-oldest`.
+func (c *Consumer) DroppedLogs() int {
+	return int(atomic.LoadInt64(&c.handle.droppedLogs))
+}
+
+// DroppedEvents returns the number of events, including delivery reports,
+// dropped from the full Events() channel with
+// `go.events.channel.overflow=drop-oldest`.
+func (p *Producer) DroppedEvents() int {
+	return int(atomic.LoadInt64(&p.handle.droppedEvents))
+}
+
+// DroppedLogs returns the number of log events dropped from the full
+// Logs() channel with `go.logs.channel.overflow=drop-oldest`.
+func (p *Producer) DroppedLogs() int {
+	return int(atomic.LoadInt64(&p.handle.droppedLogs))
+}
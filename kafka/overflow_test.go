@@ -0,0 +1,151 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestOverflowConfig tests the overflow configuration properties
+func TestOverflowConfig(t *testing.T) {
+	for _, conf := range []ConfigMap{
+		{"go.events.channel.overflow": "drop-newest"},
+		{"go.logs.channel.overflow": true},
+		{"go.events.channel.overflow": "callback"},
+		{"go.overflow.callback": func(LogEvent) {}},
+		{"go.events.channel.overflow": "drop-oldest", "go.events.channel.size": 0},
+		{"go.logs.channel.enable": true, "go.logs.channel.overflow": "drop-oldest",
+			"go.logs.channel": make(chan LogEvent)},
+	} {
+		p, err := NewProducer(&conf)
+		if err == nil {
+			p.Close()
+			t.Errorf("Expected %v to fail", conf)
+		} else if err.(Error).Code() != ErrInvalidArg {
+			t.Errorf("Expected ErrInvalidArg for %v, not %v", conf, err)
+		}
+	}
+
+	_, err := NewConsumer(&ConfigMap{
+		"group.id":                        "test",
+		"go.events.channel.enable":        true,
+		"go.application.rebalance.enable": true,
+		"go.events.channel.overflow":      "drop-oldest"})
+	if err == nil {
+		t.Errorf("Expected drop-oldest with go.application.rebalance.enable to fail")
+	}
+}
+
+// produceTimedOut produces msgcnt messages that time out, no broker
+// is needed, and waits until done() returns true.
+func produceTimedOut(t *testing.T, p *Producer, msgcnt int, done func() bool) {
+	topic := "overflow"
+	for i := 0; i < msgcnt; i++ {
+		err := p.Produce(&Message{
+			TopicPartition: TopicPartition{Topic: &topic, Partition: 0}}, nil)
+		if err != nil {
+			t.Fatalf("Produce failed: %v", err)
+		}
+	}
+
+	tEnd := time.Now().Add(10 * time.Second)
+	for !done() && time.Now().Before(tEnd) {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestOverflowDropOldest tests that the oldest events are dropped
+// from the full Events() and Logs() channels.
+func TestOverflowDropOldest(t *testing.T) {
+	p, err := NewProducer(&ConfigMap{
+		"message.timeout.ms":         10,
+		"debug":                      "generic",
+		"go.events.channel.size":     1,
+		"go.events.channel.overflow": "drop-oldest",
+		"go.logs.channel.enable":     true,
+		"go.logs.channel.size":       1,
+		"go.logs.channel.overflow":   "drop-oldest"})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer p.Close()
+
+	msgcnt := 10
+	produceTimedOut(t, p, msgcnt, func() bool {
+		return p.DroppedEvents() == msgcnt-1
+	})
+
+	if p.DroppedEvents() != msgcnt-1 {
+		t.Errorf("Expected %d dropped events, not %d", msgcnt-1, p.DroppedEvents())
+	}
+
+	if len(p.Events()) != 1 {
+		t.Errorf("Expected the last delivery report in Events(), not %d events",
+			len(p.Events()))
+	}
+
+	if p.DroppedLogs() == 0 {
+		t.Errorf("Expected log events to be dropped")
+	}
+}
+
+// TestOverflowCallback tests that events that don't fit in the full
+// Events() channel are passed to the overflow callback.
+func TestOverflowCallback(t *testing.T) {
+	var lock sync.Mutex
+	var overflowed []Event
+
+	p, err := NewProducer(&ConfigMap{
+		"message.timeout.ms":         10,
+		"go.events.channel.size":     2,
+		"go.events.channel.overflow": "callback",
+		"go.overflow.callback": func(ev Event) {
+			lock.Lock()
+			overflowed = append(overflowed, ev)
+			lock.Unlock()
+		}})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer p.Close()
+
+	msgcnt := 10
+	produceTimedOut(t, p, msgcnt, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(overflowed) == msgcnt-2
+	})
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if len(overflowed) != msgcnt-2 {
+		t.Errorf("Expected %d overflowed events, not %d", msgcnt-2, len(overflowed))
+	}
+
+	for _, ev := range overflowed {
+		if m, ok := ev.(*Message); !ok || m.TopicPartition.Error == nil {
+			t.Errorf("Expected failed delivery report, not %v", ev)
+		}
+	}
+
+	if p.DroppedEvents() != 0 {
+		t.Errorf("Expected no dropped events, not %d", p.DroppedEvents())
+	}
+}
@@ -0,0 +1,84 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// PartitionCountChange is emitted when the partition count of a topic
+// the producer produces to changes, e.g., when partitions are added,
+// which changes the partition of keys hashed by the partitioner.
+//
+// Partition count events are enabled by setting the
+// `go.partition.count.events` producer configuration property to true.
+// Since the partition counts are taken from the client statistics, which
+// reflect the latest metadata refresh (see `topic.metadata.refresh.interval.ms`),
+// partition count events also require `statistics.interval.ms` to be set.
+type PartitionCountChange struct {
+	// Topic whose partition count changed
+	Topic string
+	// Previous partition count
+	Previous int
+	// Count is the new partition count
+	Count int
+}
+
+func (e PartitionCountChange) String() string {
+	return fmt.Sprintf("PartitionCountChange: %s: %d -> %d partitions",
+		e.Topic, e.Previous, e.Count)
+}
+
+// topicPartitionCount returns the number of partitions of t known from
+// metadata, excluding the UA partition and the desired partitions not
+// seen in metadata.
+func topicPartitionCount(t TopicStatistics) int {
+	cnt := 0
+	for id, p := range t.Partitions {
+		if partition, err := strconv.Atoi(id); err != nil || partition < 0 || p.Unknown {
+			continue
+		}
+		cnt++
+	}
+	return cnt
+}
+
+// updatePartitionCounts enqueues PartitionCountChange events for the
+// topics whose partition count changed since the previous statistics.
+// Topics not yet in metadata are ignored.
+func (h *handle) updatePartitionCounts(stats *Statistics) {
+	h.brokerStateLock.Lock()
+	defer h.brokerStateLock.Unlock()
+
+	for name, t := range stats.Topics {
+		cnt := topicPartitionCount(t)
+		if cnt == 0 {
+			continue
+		}
+
+		prev, found := h.partitionCounts[name]
+		if found && prev != cnt {
+			h.pendingEvents = append(h.pendingEvents, PartitionCountChange{
+				Topic:    name,
+				Previous: prev,
+				Count:    cnt,
+			})
+		}
+		h.partitionCounts[name] = cnt
+	}
+}
@@ -0,0 +1,56 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"strconv"
+	"testing"
+)
+
+// TestPartitionCountEvents tests PartitionCountChange generation from
+// statistics
+func TestPartitionCountEvents(t *testing.T) {
+	h := &handle{partitionCountEvents: true, partitionCounts: make(map[string]int)}
+
+	stats := func(cnt int, unknown int) *Statistics {
+		partitions := map[string]PartitionStatistics{
+			"-1": {Partition: -1},
+		}
+		for p := 0; p < cnt+unknown; p++ {
+			partitions[strconv.Itoa(p)] = PartitionStatistics{
+				Partition: int32(p), Unknown: p >= cnt}
+		}
+		return &Statistics{Topics: map[string]TopicStatistics{
+			"topic": {Topic: "topic", Partitions: partitions},
+		}}
+	}
+
+	// Not in metadata yet, then in metadata, unchanged with a partition
+	// desired but unknown, then increased.
+	for _, s := range []*Statistics{stats(0, 0), stats(3, 0), stats(3, 1), stats(6, 0)} {
+		h.updatePartitionCounts(s)
+	}
+
+	ev, ok := h.popPendingEvent().(PartitionCountChange)
+	if !ok || ev.Topic != "topic" || ev.Previous != 3 || ev.Count != 6 {
+		t.Errorf("Expected PartitionCountChange from 3 to 6 partitions, got %v", ev)
+	}
+
+	if ev := h.popPendingEvent(); ev != nil {
+		t.Errorf("Unexpected event %v", ev)
+	}
+}
@@ -0,0 +1,502 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+#include "select_rdkafka.h"
+*/
+import "C"
+
+// PartitionRange is a partition to consume from Start up to, but not
+// including, End, see Consumer.AssignRanges().
+type PartitionRange struct {
+	Topic     string
+	Partition int32
+	// Start is the offset to start consuming from: an absolute offset,
+	// OffsetBeginning, OffsetEnd, OffsetStored or OffsetTail(n)
+	Start Offset
+	// End is the offset to stop consuming at: an absolute offset,
+	// OffsetEnd for the partition's high watermark when assigned,
+	// or OffsetInvalid to consume without an end
+	End Offset
+}
+
+func (r PartitionRange) String() string {
+	return fmt.Sprintf("%s[%d]@%v..%v", r.Topic, r.Partition, r.Start, r.End)
+}
+
+// PartitionRangeEnd is emitted when a PartitionRange assigned with
+// Consumer.AssignRanges() was consumed up to its End.
+// The partition is paused.
+type PartitionRangeEnd struct {
+	PartitionRange
+	// Remaining is the number of assigned ranges not yet consumed
+	// up to their End, or without an End.
+	Remaining int
+}
+
+func (e PartitionRangeEnd) String() string {
+	return fmt.Sprintf("PartitionRangeEnd(%v, %d remaining)", e.PartitionRange, e.Remaining)
+}
+
+// rangeState is the consumption state of an assigned PartitionRange.
+type rangeState struct {
+	PartitionRange
+	atEnd bool
+}
+
+// rangeAssignment tracks the ranges assigned with Consumer.AssignRanges().
+type rangeAssignment struct {
+	// Non-zero while ranges are assigned, read atomically by Poll()
+	active    int32
+	lock      sync.Mutex
+	ranges    map[partitionKey]*rangeState
+	remaining int
+	// PartitionRangeEnd events not yet returned by Poll()
+	pending []Event
+}
+
+// reset forgets the assigned ranges.
+func (ra *rangeAssignment) reset() {
+	if atomic.LoadInt32(&ra.active) == 0 {
+		return
+	}
+
+	ra.lock.Lock()
+	defer ra.lock.Unlock()
+
+	atomic.StoreInt32(&ra.active, 0)
+	ra.ranges = nil
+	ra.remaining = 0
+	ra.pending = nil
+}
+
+// nextPending returns the next PartitionRangeEnd event not yet returned
+// by Poll(), if any.
+func (ra *rangeAssignment) nextPending() Event {
+	if atomic.LoadInt32(&ra.active) == 0 {
+		return nil
+	}
+
+	ra.lock.Lock()
+	defer ra.lock.Unlock()
+
+	if len(ra.pending) == 0 {
+		return nil
+	}
+
+	ev := ra.pending[0]
+	ra.pending = ra.pending[1:]
+	return ev
+}
+
+// reachEnd marks st as consumed up to its End.
+// Must be called with ra.lock held.
+func (ra *rangeAssignment) reachEnd(c *Consumer, st *rangeState) {
+	st.atEnd = true
+	ra.remaining--
+	ra.pending = append(ra.pending, PartitionRangeEnd{st.PartitionRange, ra.remaining})
+
+	topic := st.Topic
+	c.Pause([]TopicPartition{{Topic: &topic, Partition: st.Partition}})
+}
+
+// checkPositions marks the ranges whose partition's position reached
+// their End as consumed, for Ends that aren't message offsets, e.g.,
+// transaction markers, without `enable.partition.eof`.
+func (ra *rangeAssignment) checkPositions(c *Consumer) {
+	if atomic.LoadInt32(&ra.active) == 0 {
+		return
+	}
+
+	ra.lock.Lock()
+	defer ra.lock.Unlock()
+
+	var states []*rangeState
+	var partitions []TopicPartition
+	for _, st := range ra.ranges {
+		if !st.atEnd && st.End >= 0 {
+			states = append(states, st)
+			partitions = append(partitions,
+				TopicPartition{Topic: &st.Topic, Partition: st.Partition})
+		}
+	}
+	if len(partitions) == 0 {
+		return
+	}
+
+	positions, err := c.Position(partitions)
+	if err != nil {
+		return
+	}
+	for i, tp := range positions {
+		if tp.Error == nil && tp.Offset >= states[i].End {
+			ra.reachEnd(c, states[i])
+		}
+	}
+}
+
+// filter filters the event ev polled by Poll() against the assigned
+// ranges: messages at or beyond their range's End are discarded, and
+// the consumption of the last message of a range, or the partition's EOF,
+// queues a PartitionRangeEnd event.
+// Returns the event to return from Poll() instead of ev, which may be nil.
+func (ra *rangeAssignment) filter(c *Consumer, ev Event) Event {
+	if atomic.LoadInt32(&ra.active) == 0 {
+		return ev
+	}
+
+	var tp *TopicPartition
+	switch e := ev.(type) {
+	case *Message:
+		if e.TopicPartition.Error != nil {
+			return ev
+		}
+		tp = &e.TopicPartition
+	case PartitionEOF:
+		ptp := TopicPartition(e)
+		tp = &ptp
+	default:
+		return ev
+	}
+
+	ra.lock.Lock()
+	defer ra.lock.Unlock()
+
+	st := ra.ranges[partitionKey{*tp.Topic, tp.Partition}]
+	if st == nil || st.End == OffsetInvalid {
+		return ev
+	}
+
+	if _, isMsg := ev.(*Message); !isMsg {
+		// PartitionEOF: the range's End may not be a message offset,
+		// e.g., with compacted topics or transaction markers.
+		if !st.atEnd && tp.Offset >= st.End {
+			ra.reachEnd(c, st)
+		}
+		return ev
+	}
+
+	if st.atEnd || tp.Offset >= st.End {
+		// Prefetched before the partition was paused
+		if !st.atEnd {
+			ra.reachEnd(c, st)
+		}
+		ev.(*Message).releaseBuffers()
+
+		if len(ra.pending) == 0 {
+			return nil
+		}
+		pending := ra.pending[0]
+		ra.pending = ra.pending[1:]
+		return pending
+	}
+
+	if tp.Offset == st.End-1 {
+		ra.reachEnd(c, st)
+	}
+
+	return ev
+}
+
+// resolveRanges resolves the ranges' logical Start and End offsets
+// to absolute offsets where possible: OffsetStored is resolved to the
+// committed offset, if any, and the other logical offsets to the
+// partition's watermarks.
+func (c *Consumer) resolveRanges(ranges []PartitionRange, timeoutMs int) ([]PartitionRange, error) {
+	resolved := make([]PartitionRange, len(ranges))
+	copy(resolved, ranges)
+
+	var stored []TopicPartition
+	for i := range resolved {
+		r := &resolved[i]
+		if r.Start == OffsetStored {
+			stored = append(stored, TopicPartition{Topic: &r.Topic, Partition: r.Partition})
+		}
+	}
+
+	if len(stored) > 0 {
+		committed, err := c.Committed(stored, timeoutMs)
+		if err != nil {
+			return nil, err
+		}
+		for _, tp := range committed {
+			for i := range resolved {
+				r := &resolved[i]
+				if r.Start == OffsetStored && r.Topic == *tp.Topic &&
+					r.Partition == tp.Partition && tp.Offset >= 0 {
+					r.Start = tp.Offset
+				}
+			}
+		}
+	}
+
+	for i := range resolved {
+		r := &resolved[i]
+		if r.Start >= 0 && r.End != OffsetEnd {
+			continue
+		}
+		if r.Start == OffsetStored && r.End != OffsetEnd {
+			// Not committed: auto.offset.reset applies
+			continue
+		}
+
+		low, high, err := c.QueryWatermarkOffsets(r.Topic, r.Partition, timeoutMs)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case r.Start == OffsetBeginning:
+			r.Start = Offset(low)
+		case r.Start == OffsetEnd:
+			r.Start = Offset(high)
+		case r.Start <= OffsetTail(0):
+			r.Start = Offset(high) - (OffsetTail(0) - r.Start)
+			if r.Start < Offset(low) {
+				r.Start = Offset(low)
+			}
+		}
+
+		if r.End == OffsetEnd {
+			r.End = Offset(high)
+		}
+	}
+
+	return resolved, nil
+}
+
+// AssignRanges assigns the partitions of ranges to consume each from its
+// Start offset up to, but not including, its End offset, replacing the
+// current assignment, for backfill and bootstrap jobs that don't need
+// group management. The consumer should not be subscribed.
+//
+// The logical Start and End offsets are resolved to absolute offsets,
+// using the committed offsets for OffsetStored and the partitions'
+// watermarks otherwise, blocking for at most timeoutMs milliseconds for
+// each lookup. Returns the resolved ranges.
+//
+// Once a range was consumed up to its End, its partition is paused,
+// any message at or beyond End is discarded, and Poll() returns a
+// PartitionRangeEnd event, which ReadMessage() discards: use
+// AssignedRanges() to check for the remaining ranges instead.
+// Ranges that are empty once resolved are not assigned, and their
+// PartitionRangeEnd is returned by the next Poll().
+// When End is not the offset of a message, e.g., with compacted topics
+// or transactional producers, the end is only detected when a message
+// beyond End is consumed or, with `enable.partition.eof`, on PartitionEOF.
+//
+// Ranges are only enforced with Poll(), PollMessage() and ReadMessage(),
+// not on the deprecated Events() channel.
+// Assign() or Unassign() forget the ranges.
+//
+// Seeking by leader epoch is not supported by this librdkafka version.
+func (c *Consumer) AssignRanges(ranges []PartitionRange, timeoutMs int) ([]PartitionRange, error) {
+	resolved, err := c.resolveRanges(ranges, timeoutMs)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make(map[partitionKey]*rangeState, len(resolved))
+	var partitions []TopicPartition
+	var pending []Event
+	remaining := len(resolved)
+	for i := range resolved {
+		r := resolved[i]
+		key := partitionKey{r.Topic, r.Partition}
+		if _, dup := states[key]; dup {
+			return nil, newErrorFromString(ErrInvalidArg,
+				fmt.Sprintf("Duplicate range for %s [%d]", r.Topic, r.Partition))
+		}
+
+		st := &rangeState{PartitionRange: r}
+		states[key] = st
+
+		if r.End >= 0 && r.Start >= r.End {
+			st.atEnd = true
+			remaining--
+			pending = append(pending, PartitionRangeEnd{r, remaining})
+			continue
+		}
+
+		partitions = append(partitions,
+			TopicPartition{Topic: &st.Topic, Partition: r.Partition, Offset: r.Start})
+	}
+
+	if err = c.Assign(partitions); err != nil {
+		return nil, err
+	}
+
+	ra := &c.ranges
+	ra.lock.Lock()
+	ra.ranges = states
+	ra.remaining = remaining
+	ra.pending = pending
+	atomic.StoreInt32(&ra.active, 1)
+	ra.lock.Unlock()
+
+	return resolved, nil
+}
+
+// AssignedRanges returns the ranges assigned with AssignRanges() whose
+// End was not yet reached.
+func (c *Consumer) AssignedRanges() []PartitionRange {
+	ra := &c.ranges
+	ra.lock.Lock()
+	defer ra.lock.Unlock()
+
+	var ranges []PartitionRange
+	for _, st := range ra.ranges {
+		if !st.atEnd {
+			ranges = append(ranges, st.PartitionRange)
+		}
+	}
+	return ranges
+}
+
+// ConsumeRange consumes the messages of all partitions of topic whose
+// timestamps fall within [startTime, endTime), calling handler for each,
+// and returns once all partitions were consumed up to endTime.
+//
+// The partitions' offset ranges are looked up with OffsetsForTimes() and
+// assigned with AssignRanges(), replacing the current assignment, and
+// messages within the ranges with out-of-window timestamps are skipped.
+// Partitions where no message has a timestamp at or after endTime are
+// consumed up to their high watermark at the time of the call.
+// Partitions whose End is not a message offset, e.g., a transaction
+// marker, are consumed up to their End once their position reaches it,
+// `enable.partition.eof` is not required.
+//
+// Returns ctx.Err() if ctx is done first, the handler's error if it
+// returns one, or the first message or fatal error consumed.
+// Returns an ErrUnknownTopic or ErrUnknownPartition error if the topic
+// is not known or has no partitions.
+// The context's deadline, if any, also bounds the offset lookups.
+//
+// Events other than messages and errors are discarded, the consumer
+// should not be subscribed nor used by other goroutines meanwhile.
+func (c *Consumer) ConsumeRange(ctx context.Context, topic string, startTime, endTime time.Time, handler func(*Message) error) error {
+	if !startTime.Before(endTime) {
+		return newErrorFromString(ErrInvalidArg, "startTime must be before endTime")
+	}
+
+	timeoutMs := -1
+	if d, ok := timeout(ctx); ok {
+		if d <= 0 {
+			return ctx.Err()
+		}
+		timeoutMs = int(d / time.Millisecond)
+	}
+
+	md, err := c.GetMetadata(&topic, false, timeoutMs)
+	if err != nil {
+		return err
+	}
+	t, ok := md.Topics[topic]
+	if !ok {
+		return newError(C.RD_KAFKA_RESP_ERR__UNKNOWN_TOPIC)
+	}
+	if t.Error.Code() != ErrNoError {
+		return t.Error
+	}
+	if len(t.Partitions) == 0 {
+		return newErrorFromString(ErrUnknownPartition,
+			fmt.Sprintf("Topic %s has no partitions", topic))
+	}
+
+	startMs := startTime.UnixNano() / int64(time.Millisecond)
+	endMs := endTime.UnixNano() / int64(time.Millisecond)
+
+	starts := make([]TopicPartition, len(t.Partitions))
+	ends := make([]TopicPartition, len(t.Partitions))
+	for i, p := range t.Partitions {
+		starts[i] = TopicPartition{Topic: &topic, Partition: p.ID, Offset: Offset(startMs)}
+		ends[i] = TopicPartition{Topic: &topic, Partition: p.ID, Offset: Offset(endMs)}
+	}
+
+	if starts, err = c.OffsetsForTimes(starts, timeoutMs); err != nil {
+		return err
+	}
+	if ends, err = c.OffsetsForTimes(ends, timeoutMs); err != nil {
+		return err
+	}
+
+	ranges := make([]PartitionRange, len(starts))
+	for i := range starts {
+		if starts[i].Error != nil {
+			return starts[i].Error
+		}
+		if ends[i].Error != nil {
+			return ends[i].Error
+		}
+
+		ranges[i] = PartitionRange{Topic: topic, Partition: starts[i].Partition,
+			Start: starts[i].Offset, End: ends[i].Offset}
+		// No message at or after the timestamp
+		if ranges[i].Start < 0 {
+			ranges[i].Start = OffsetEnd
+		}
+		if ranges[i].End < 0 {
+			ranges[i].End = OffsetEnd
+		}
+	}
+
+	if _, err = c.AssignRanges(ranges, timeoutMs); err != nil {
+		return err
+	}
+	defer c.Unassign()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		switch e := c.Poll(100).(type) {
+		case nil:
+			// Idle: the End may not be a message offset
+			c.ranges.checkPositions(c)
+		case *Message:
+			if e.TopicPartition.Error != nil {
+				return e.TopicPartition.Error
+			}
+			if e.Timestamp.Before(startTime) || !e.Timestamp.Before(endTime) {
+				continue
+			}
+			if err = handler(e); err != nil {
+				return err
+			}
+		case PartitionRangeEnd:
+			if e.Remaining == 0 {
+				return nil
+			}
+		case Error:
+			if e.IsFatal() {
+				return e
+			}
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestAssignRanges tests that assigned ranges are resolved, consumed
+// up to their end, and notified with PartitionRangeEnd events.
+func TestAssignRanges(t *testing.T) {
+	mc, err := NewMockCluster(1)
+	if err != nil {
+		t.Fatalf("Failed to create MockCluster: %v", err)
+	}
+	defer mc.Close()
+
+	topic := "ranges"
+	if err = mc.CreateTopic(topic, 3, 1); err != nil {
+		t.Fatalf("CreateTopic failed: %v", err)
+	}
+
+	p, err := NewProducer(&ConfigMap{"bootstrap.servers": mc.BootstrapServers()})
+	if err != nil {
+		t.Fatalf("Failed to create Producer: %v", err)
+	}
+	defer p.Close()
+
+	drChan := make(chan Event, 30)
+	for i := 0; i < 30; i++ {
+		err = p.Produce(&Message{
+			TopicPartition: TopicPartition{Topic: &topic, Partition: int32(i % 3)},
+			Value:          []byte("value"),
+		}, drChan)
+		if err != nil {
+			t.Fatalf("Produce failed: %v", err)
+		}
+	}
+	for i := 0; i < 30; i++ {
+		if m := (<-drChan).(*Message); m.TopicPartition.Error != nil {
+			t.Fatalf("Delivery failed: %v", m.TopicPartition)
+		}
+	}
+
+	c, err := NewConsumer(&ConfigMap{
+		"bootstrap.servers":  mc.BootstrapServers(),
+		"group.id":           "ranges",
+		"enable.auto.commit": false})
+	if err != nil {
+		t.Fatalf("Failed to create Consumer: %v", err)
+	}
+	defer c.Close()
+
+	resolved, err := c.AssignRanges([]PartitionRange{
+		{Topic: topic, Partition: 0, Start: OffsetBeginning, End: 5},
+		{Topic: topic, Partition: 1, Start: OffsetTail(3), End: OffsetEnd},
+		{Topic: topic, Partition: 2, Start: 4, End: 4},
+	}, 10000)
+	if err != nil {
+		t.Fatalf("AssignRanges failed: %v", err)
+	}
+
+	expected := []PartitionRange{
+		{Topic: topic, Partition: 0, Start: 0, End: 5},
+		{Topic: topic, Partition: 1, Start: 7, End: 10},
+		{Topic: topic, Partition: 2, Start: 4, End: 4},
+	}
+	if !reflect.DeepEqual(resolved, expected) {
+		t.Fatalf("Expected resolved ranges %v, not %v", expected, resolved)
+	}
+
+	consumed := make(map[int32][]Offset)
+	var ends []PartitionRangeEnd
+	for tEnd := time.Now().Add(10 * time.Second); len(ends) < 3; {
+		if time.Now().After(tEnd) {
+			t.Fatalf("Timed out, consumed %v, ends %v", consumed, ends)
+		}
+
+		switch e := c.Poll(100).(type) {
+		case *Message:
+			if e.TopicPartition.Error != nil {
+				t.Fatalf("Consume failed: %v", e.TopicPartition)
+			}
+			consumed[e.TopicPartition.Partition] = append(
+				consumed[e.TopicPartition.Partition], e.TopicPartition.Offset)
+		case PartitionRangeEnd:
+			ends = append(ends, e)
+		}
+	}
+
+	// The empty range ends first
+	if ends[0].Partition != 2 || ends[0].Remaining != 2 || ends[2].Remaining != 0 {
+		t.Errorf("Unexpected range ends %v", ends)
+	}
+	if !reflect.DeepEqual(consumed[0], []Offset{0, 1, 2, 3, 4}) ||
+		!reflect.DeepEqual(consumed[1], []Offset{7, 8, 9}) ||
+		len(consumed[2]) != 0 {
+		t.Errorf("Unexpected consumed offsets %v", consumed)
+	}
+
+	// Nothing beyond the ends
+	for i := 0; i < 5; i++ {
+		if ev := c.Poll(100); ev != nil {
+			if _, isMsg := ev.(*Message); isMsg {
+				t.Errorf("Unexpected message %v", ev)
+			}
+		}
+	}
+
+	if ranges := c.AssignedRanges(); len(ranges) != 0 {
+		t.Errorf("Expected no remaining ranges, not %v", ranges)
+	}
+
+	if _, err = c.AssignRanges([]PartitionRange{
+		{Topic: topic, Partition: 0, Start: 0, End: 1},
+		{Topic: topic, Partition: 0, Start: 1, End: 2},
+	}, 10000); err == nil || err.(Error).Code() != ErrInvalidArg {
+		t.Errorf("Expected ErrInvalidArg for duplicate ranges, not %v", err)
+	}
+}
+
+// TestConsumeRange tests ConsumeRange()'s argument checks and its return
+// on partitions without messages in the window, see the integration tests
+// for actual time windows, which the mock cluster can't look up.
+func TestConsumeRange(t *testing.T) {
+	mc, err := NewMockCluster(1)
+	if err != nil {
+		t.Fatalf("Failed to create MockCluster: %v", err)
+	}
+	defer mc.Close()
+
+	topic := "consumerange"
+	if err = mc.CreateTopic(topic, 2, 1); err != nil {
+		t.Fatalf("CreateTopic failed: %v", err)
+	}
+
+	c, err := NewConsumer(&ConfigMap{
+		"bootstrap.servers":  mc.BootstrapServers(),
+		"group.id":           "consumerange",
+		"enable.auto.commit": false})
+	if err != nil {
+		t.Fatalf("Failed to create Consumer: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	err = c.ConsumeRange(ctx, topic, now, now.Add(time.Minute),
+		func(m *Message) error {
+			t.Errorf("Unexpected message %v", m)
+			return nil
+		})
+	if err != nil {
+		t.Errorf("ConsumeRange failed: %v", err)
+	}
+	if ranges := c.AssignedRanges(); len(ranges) != 0 {
+		t.Errorf("Expected the ranges to be unassigned, not %v", ranges)
+	}
+
+	if err = c.ConsumeRange(ctx, topic, now, now, nil); err == nil || err.(Error).Code() != ErrInvalidArg {
+		t.Errorf("Expected ErrInvalidArg for an empty window, not %v", err)
+	}
+
+	cancel()
+	if err = c.ConsumeRange(ctx, topic, now, now.Add(time.Minute), nil); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, not %v", err)
+	}
+}
+
+// TestRangePositionEnd tests that ranges end once their partition's
+// position reaches End, for Ends that aren't message offsets.
+func TestRangePositionEnd(t *testing.T) {
+	mc, err := NewMockCluster(1)
+	if err != nil {
+		t.Fatalf("Failed to create MockCluster: %v", err)
+	}
+	defer mc.Close()
+
+	topic := "rangeposition"
+	if err = mc.CreateTopic(topic, 1, 1); err != nil {
+		t.Fatalf("CreateTopic failed: %v", err)
+	}
+
+	p, err := NewProducer(&ConfigMap{"bootstrap.servers": mc.BootstrapServers()})
+	if err != nil {
+		t.Fatalf("Failed to create Producer: %v", err)
+	}
+	defer p.Close()
+
+	drChan := make(chan Event, 5)
+	for i := 0; i < 5; i++ {
+		err = p.Produce(&Message{
+			TopicPartition: TopicPartition{Topic: &topic, Partition: 0},
+			Value:          []byte("value"),
+		}, drChan)
+		if err != nil {
+			t.Fatalf("Produce failed: %v", err)
+		}
+	}
+	for i := 0; i < 5; i++ {
+		if m := (<-drChan).(*Message); m.TopicPartition.Error != nil {
+			t.Fatalf("Delivery failed: %v", m.TopicPartition)
+		}
+	}
+
+	c, err := NewConsumer(&ConfigMap{
+		"bootstrap.servers":  mc.BootstrapServers(),
+		"group.id":           "rangeposition",
+		"enable.auto.commit": false})
+	if err != nil {
+		t.Fatalf("Failed to create Consumer: %v", err)
+	}
+	defer c.Close()
+
+	// Range 0..6, as if offset 5 was a transaction marker: messages
+	// consumed without the range, which would end on offset 5
+	if err = c.Assign([]TopicPartition{{Topic: &topic, Partition: 0, Offset: 0}}); err != nil {
+		t.Fatalf("Assign failed: %v", err)
+	}
+	for cnt, tEnd := 0, time.Now().Add(10*time.Second); cnt < 5; {
+		if time.Now().After(tEnd) {
+			t.Fatalf("Timed out after %d messages", cnt)
+		}
+		if _, ok := c.Poll(100).(*Message); ok {
+			cnt++
+		}
+	}
+
+	r := PartitionRange{Topic: topic, Partition: 0, Start: 0, End: 6}
+	c.ranges.ranges = map[partitionKey]*rangeState{{topic, 0}: {PartitionRange: r}}
+	c.ranges.remaining = 1
+	c.ranges.active = 1
+
+	c.ranges.checkPositions(c)
+	if ev := c.Poll(100); ev != nil {
+		t.Fatalf("Unexpected event %v before reaching the range's end", ev)
+	}
+
+	r.End = 5
+	c.ranges.ranges[partitionKey{topic, 0}].End = 5
+	c.ranges.checkPositions(c)
+	if ev, ok := c.Poll(100).(PartitionRangeEnd); !ok || ev.PartitionRange != r || ev.Remaining != 0 {
+		t.Errorf("Expected PartitionRangeEnd for %v, not %v", r, ev)
+	}
+
+	// Nothing beyond the end
+	for i := 0; i < 5; i++ {
+		if ev, isMsg := c.Poll(100).(*Message); isMsg {
+			t.Errorf("Unexpected message %v after the range's end", ev)
+		}
+	}
+}
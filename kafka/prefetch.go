@@ -0,0 +1,196 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"sort"
+	"sync"
+)
+
+/*
+#include "select_rdkafka.h"
+*/
+import "C"
+
+// prefetchBudget bounds the bytes of the messages prefetched by
+// librdkafka but not yet consumed by the application across all
+// assigned partitions, see `go.prefetch.max.bytes`.
+//
+// The backlog is derived from the client statistics: all partitions'
+// fetch queues are forwarded to the consumer queue, the size of which
+// each partition reports as its fetchq_size, and each partition's share
+// of the backlog is estimated from its fetched but unconsumed messages.
+// When the backlog exceeds the budget the partitions with the largest
+// backlog are paused, which discards their prefetched messages, until
+// the remaining backlog fits, and they are resumed once the backlog has
+// dropped below half the budget. Partitions paused by the application
+// are neither paused nor resumed by the budget.
+type prefetchBudget struct {
+	maxBytes int64
+
+	lock sync.Mutex
+	// Partitions paused by the budget, rather than the application
+	paused map[partitionKey]TopicPartition
+	// Partitions paused by the application, left alone by the budget
+	appPaused map[partitionKey]bool
+}
+
+// partitionKey identifies a topic partition in maps.
+type partitionKey struct {
+	topic     string
+	partition int32
+}
+
+// partitionBacklog is a partition's fetched but unconsumed messages.
+type partitionBacklog struct {
+	tp   TopicPartition
+	msgs int64
+}
+
+// update pauses or resumes partitions according to the backlog in stats.
+func (pb *prefetchBudget) update(c *Consumer, stats *Statistics) {
+	var size, msgs int64
+	var backlogs []partitionBacklog
+	assigned := make(map[partitionKey]bool)
+
+	for topic, t := range stats.Topics {
+		for _, p := range t.Partitions {
+			if p.Partition < 0 || !p.Desired {
+				continue
+			}
+
+			assigned[partitionKey{topic, p.Partition}] = true
+
+			if p.FetchqSize > size {
+				size = p.FetchqSize
+			}
+
+			// Messages are consumed from the committed offset
+			// until the application consumed the first one
+			consumed := p.AppOffset
+			if consumed < 0 {
+				consumed = p.CommittedOffset
+			}
+			if consumed < 0 || p.NextOffset <= consumed {
+				continue
+			}
+
+			topic := topic
+			backlogs = append(backlogs, partitionBacklog{
+				tp:   TopicPartition{Topic: &topic, Partition: p.Partition},
+				msgs: p.NextOffset - consumed})
+			msgs += p.NextOffset - consumed
+		}
+	}
+
+	pb.lock.Lock()
+	defer pb.lock.Unlock()
+
+	// Forget revoked partitions
+	for key := range pb.paused {
+		if !assigned[key] {
+			delete(pb.paused, key)
+		}
+	}
+	for key := range pb.appPaused {
+		if !assigned[key] {
+			delete(pb.appPaused, key)
+		}
+	}
+
+	switch {
+	case size > pb.maxBytes && msgs > 0:
+		total := size
+		sort.Slice(backlogs, func(i, j int) bool {
+			return backlogs[i].msgs > backlogs[j].msgs
+		})
+
+		var pause []TopicPartition
+		for _, b := range backlogs {
+			if size <= pb.maxBytes {
+				break
+			}
+			key := partitionKey{*b.tp.Topic, b.tp.Partition}
+			if _, paused := pb.paused[key]; paused || pb.appPaused[key] {
+				continue
+			}
+			pause = append(pause, b.tp)
+			pb.paused[key] = b.tp
+			size -= total * b.msgs / msgs
+		}
+
+		if len(pause) > 0 {
+			cparts := newCPartsFromTopicPartitions(pause)
+			C.rd_kafka_pause_partitions(c.handle.rk, cparts)
+			C.rd_kafka_topic_partition_list_destroy(cparts)
+		}
+
+	case size < pb.maxBytes/2 && len(pb.paused) > 0:
+		resume := make([]TopicPartition, 0, len(pb.paused))
+		for key, tp := range pb.paused {
+			if !pb.appPaused[key] {
+				resume = append(resume, tp)
+			}
+			delete(pb.paused, key)
+		}
+		cparts := newCPartsFromTopicPartitions(resume)
+		C.rd_kafka_resume_partitions(c.handle.rk, cparts)
+		C.rd_kafka_topic_partition_list_destroy(cparts)
+	}
+}
+
+// appPause records partitions paused, or resumed if paused is false,
+// by the application: the budget no longer pauses or resumes the
+// partitions paused by the application.
+func (pb *prefetchBudget) appPause(partitions []TopicPartition, paused bool) {
+	pb.lock.Lock()
+	defer pb.lock.Unlock()
+
+	for _, tp := range partitions {
+		if tp.Topic == nil {
+			continue
+		}
+		key := partitionKey{*tp.Topic, tp.Partition}
+		delete(pb.paused, key)
+		if paused {
+			pb.appPaused[key] = true
+		} else {
+			delete(pb.appPaused, key)
+		}
+	}
+}
+
+// PrefetchPaused returns the partitions currently paused by the
+// prefetch budget, see `go.prefetch.max.bytes`.
+func (c *Consumer) PrefetchPaused() []TopicPartition {
+	c.prefetch.lock.Lock()
+	defer c.prefetch.lock.Unlock()
+
+	partitions := make([]TopicPartition, 0, len(c.prefetch.paused))
+	for _, tp := range c.prefetch.paused {
+		partitions = append(partitions, tp)
+	}
+
+	sort.Slice(partitions, func(i, j int) bool {
+		if *partitions[i].Topic != *partitions[j].Topic {
+			return *partitions[i].Topic < *partitions[j].Topic
+		}
+		return partitions[i].Partition < partitions[j].Partition
+	})
+
+	return partitions
+}
@@ -0,0 +1,216 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"testing"
+	"time"
+)
+
+// TestPrefetchBudget tests that partitions are paused while the prefetched
+// backlog exceeds go.prefetch.max.bytes, and that no messages are lost.
+func TestPrefetchBudget(t *testing.T) {
+	mc, err := NewMockCluster(1)
+	if err != nil {
+		t.Fatalf("Failed to create MockCluster: %v", err)
+	}
+	defer mc.Close()
+
+	topic := "prefetch"
+	partitions := 4
+	msgcnt := 2000
+	if err = mc.CreateTopic(topic, partitions, 1); err != nil {
+		t.Fatalf("CreateTopic failed: %v", err)
+	}
+
+	p, err := NewProducer(&ConfigMap{"bootstrap.servers": mc.BootstrapServers()})
+	if err != nil {
+		t.Fatalf("Failed to create Producer: %v", err)
+	}
+	defer p.Close()
+
+	drChan := make(chan Event, msgcnt)
+	value := make([]byte, 1000)
+	for i := 0; i < msgcnt; i++ {
+		err = p.Produce(&Message{
+			TopicPartition: TopicPartition{Topic: &topic, Partition: int32(i % partitions)},
+			Value:          value,
+		}, drChan)
+		if err != nil {
+			t.Fatalf("Produce failed: %v", err)
+		}
+	}
+
+	for i := 0; i < msgcnt; i++ {
+		if m := (<-drChan).(*Message); m.TopicPartition.Error != nil {
+			t.Fatalf("Delivery failed: %v", m.TopicPartition)
+		}
+	}
+
+	c, err := NewConsumer(&ConfigMap{
+		"bootstrap.servers":      mc.BootstrapServers(),
+		"group.id":               "prefetch",
+		"enable.auto.commit":     false,
+		"auto.offset.reset":      "earliest",
+		"statistics.interval.ms": 100,
+		"go.prefetch.max.bytes":  100000})
+	if err != nil {
+		t.Fatalf("Failed to create Consumer: %v", err)
+	}
+	defer c.Close()
+
+	var assignment []TopicPartition
+	for i := 0; i < partitions; i++ {
+		assignment = append(assignment, TopicPartition{Topic: &topic, Partition: int32(i)})
+	}
+	if err = c.Assign(assignment); err != nil {
+		t.Fatalf("Assign failed: %v", err)
+	}
+
+	// Consume slowly until partitions are paused, the statistics are
+	// served after the messages prefetched before them.
+	consumed := make(map[int32]int)
+	tEnd := time.Now().Add(10 * time.Second)
+	for len(c.PrefetchPaused()) == 0 && time.Now().Before(tEnd) {
+		if m, ok := c.Poll(100).(*Message); ok {
+			consumed[m.TopicPartition.Partition]++
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	if len(c.PrefetchPaused()) == 0 {
+		t.Fatalf("Expected partitions to be paused")
+	}
+
+	// Consume the remaining messages, resuming the paused partitions
+	cnt := 0
+	for _, n := range consumed {
+		cnt += n
+	}
+	for cnt < msgcnt && time.Now().Before(tEnd.Add(20*time.Second)) {
+		if m, ok := c.Poll(100).(*Message); ok {
+			if m.TopicPartition.Error != nil {
+				t.Fatalf("Consume failed: %v", m.TopicPartition)
+			}
+			consumed[m.TopicPartition.Partition]++
+			cnt++
+		}
+	}
+
+	for i := 0; i < partitions; i++ {
+		if consumed[int32(i)] != msgcnt/partitions {
+			t.Errorf("Partition %d: expected %d messages, consumed %d",
+				i, msgcnt/partitions, consumed[int32(i)])
+		}
+	}
+
+	if paused := c.PrefetchPaused(); len(paused) != 0 {
+		t.Errorf("Expected no paused partitions once consumed, not %v", paused)
+	}
+}
+
+// TestPrefetchBudgetAppPaused tests that the budget neither pauses nor
+// resumes partitions paused by the application.
+func TestPrefetchBudgetAppPaused(t *testing.T) {
+	mc, err := NewMockCluster(1)
+	if err != nil {
+		t.Fatalf("Failed to create MockCluster: %v", err)
+	}
+	defer mc.Close()
+
+	topic := "prefetchapp"
+	if err = mc.CreateTopic(topic, 2, 1); err != nil {
+		t.Fatalf("CreateTopic failed: %v", err)
+	}
+
+	p, err := NewProducer(&ConfigMap{"bootstrap.servers": mc.BootstrapServers()})
+	if err != nil {
+		t.Fatalf("Failed to create Producer: %v", err)
+	}
+	defer p.Close()
+
+	drChan := make(chan Event, 20)
+	for i := 0; i < 20; i++ {
+		err = p.Produce(&Message{
+			TopicPartition: TopicPartition{Topic: &topic, Partition: int32(i % 2)},
+		}, drChan)
+		if err != nil {
+			t.Fatalf("Produce failed: %v", err)
+		}
+	}
+	for i := 0; i < 20; i++ {
+		if m := (<-drChan).(*Message); m.TopicPartition.Error != nil {
+			t.Fatalf("Delivery failed: %v", m.TopicPartition)
+		}
+	}
+
+	c, err := NewConsumer(&ConfigMap{
+		"bootstrap.servers":     mc.BootstrapServers(),
+		"group.id":              "prefetchapp",
+		"enable.auto.commit":    false,
+		"auto.offset.reset":     "earliest",
+		"go.prefetch.max.bytes": 1000})
+	if err != nil {
+		t.Fatalf("Failed to create Consumer: %v", err)
+	}
+	defer c.Close()
+
+	tp0 := TopicPartition{Topic: &topic, Partition: 0}
+	tp1 := TopicPartition{Topic: &topic, Partition: 1}
+	if err = c.Assign([]TopicPartition{tp0, tp1}); err != nil {
+		t.Fatalf("Assign failed: %v", err)
+	}
+	if err = c.Pause([]TopicPartition{tp0}); err != nil {
+		t.Fatalf("Pause failed: %v", err)
+	}
+
+	// Partition 0 has the largest backlog
+	stats := func(fetchqSize int64) *Statistics {
+		return &Statistics{Topics: map[string]TopicStatistics{
+			topic: {Partitions: map[string]PartitionStatistics{
+				"0": {Partition: 0, Desired: true, FetchqSize: fetchqSize,
+					AppOffset: 0, NextOffset: 100},
+				"1": {Partition: 1, Desired: true, FetchqSize: fetchqSize,
+					AppOffset: 0, NextOffset: 10},
+			}}}}
+	}
+
+	c.prefetch.update(c, stats(100000))
+	if paused := c.PrefetchPaused(); len(paused) != 1 || paused[0].Partition != 1 {
+		t.Errorf("Expected the budget to pause partition 1 only, not %v", paused)
+	}
+
+	c.prefetch.update(c, stats(0))
+	if paused := c.PrefetchPaused(); len(paused) != 0 {
+		t.Errorf("Expected the budget to resume its paused partitions, not %v", paused)
+	}
+
+	// Partition 0 remains paused by the application
+	tEnd := time.Now().Add(3 * time.Second)
+	cnt := 0
+	for time.Now().Before(tEnd) && cnt < 10 {
+		if m, ok := c.Poll(100).(*Message); ok {
+			if m.TopicPartition.Partition == 0 {
+				t.Fatalf("Consumed from partition 0, paused by the application")
+			}
+			cnt++
+		}
+	}
+	if cnt != 10 {
+		t.Errorf("Expected 10 messages from partition 1, consumed %d", cnt)
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"sync"
 	"time"
 	"unsafe"
 )
@@ -128,11 +129,30 @@ rd_kafka_resp_err_t err;
 */
 import "C"
 
+// produceOneByte is pointed to for null and empty keys and values,
+// see produce().
+var produceOneByte = []byte{0}
+
+// tmphdrsPool pools the *[]C.tmphdr_t header conversion slices of produce().
+var tmphdrsPool = sync.Pool{New: func() interface{} { return new([]C.tmphdr_t) }}
+
+// getTmphdrs returns a pooled tmphdrs slice of cnt headers
+func getTmphdrs(cnt int) *[]C.tmphdr_t {
+	tmphdrs := tmphdrsPool.Get().(*[]C.tmphdr_t)
+	if cap(*tmphdrs) < cnt {
+		*tmphdrs = make([]C.tmphdr_t, cnt)
+	}
+	*tmphdrs = (*tmphdrs)[:cnt]
+	return tmphdrs
+}
+
 // Producer implements a High-level Apache Kafka Producer instance
 type Producer struct {
 	events         chan Event
 	produceChannel chan *Message
 	handle         handle
+	// Message size check, nil if disabled
+	sizeCheck *messageSizeCheck
 
 	// Terminates the poller() goroutine
 	pollerTermChan chan bool
@@ -153,6 +173,12 @@ func (p *Producer) produce(msg *Message, msgFlags int, deliveryChan chan Event)
 		return newErrorFromString(ErrInvalidArg, "")
 	}
 
+	if p.sizeCheck != nil {
+		if err := p.sizeCheck.check(msg); err != nil {
+			return err
+		}
+	}
+
 	crkt := p.handle.getRkt(*msg.TopicPartition.Topic)
 
 	// Three problems:
@@ -172,7 +198,6 @@ func (p *Producer) produce(msg *Message, msgFlags int, deliveryChan chan Event)
 	//
 	var valp []byte
 	var keyp []byte
-	oneByte := []byte{0}
 	var valIsNull C.int
 	var keyIsNull C.int
 	var valLen int
@@ -181,30 +206,31 @@ func (p *Producer) produce(msg *Message, msgFlags int, deliveryChan chan Event)
 	if msg.Value == nil {
 		valIsNull = 1
 		valLen = 0
-		valp = oneByte
+		valp = produceOneByte
 	} else {
 		valLen = len(msg.Value)
 		if valLen > 0 {
 			valp = msg.Value
 		} else {
-			valp = oneByte
+			valp = produceOneByte
 		}
 	}
 
 	if msg.Key == nil {
 		keyIsNull = 1
 		keyLen = 0
-		keyp = oneByte
+		keyp = produceOneByte
 	} else {
 		keyLen = len(msg.Key)
 		if keyLen > 0 {
 			keyp = msg.Key
 		} else {
-			keyp = oneByte
+			keyp = produceOneByte
 		}
 	}
 
 	var cgoid int
+	var cdr *cgoDr
 
 	// Per-message state that needs to be retained through the C code:
 	//   delivery channel (if specified)
@@ -212,8 +238,10 @@ func (p *Producer) produce(msg *Message, msgFlags int, deliveryChan chan Event)
 	// Since these cant be passed as opaque pointers to the C code,
 	// due to cgo constraints, we add them to a per-producer map for lookup
 	// when the C code triggers the callbacks or events.
+	// Messages with neither take the allocation-free fast path.
 	if deliveryChan != nil || msg.Opaque != nil {
-		cgoid = p.handle.cgoPut(cgoDr{deliveryChan: deliveryChan, opaque: msg.Opaque})
+		cdr = newCgoDr(deliveryChan, msg.Opaque)
+		cgoid = p.handle.cgoPut(cdr)
 	}
 
 	var timestamp int64
@@ -221,36 +249,41 @@ func (p *Producer) produce(msg *Message, msgFlags int, deliveryChan chan Event)
 		timestamp = msg.Timestamp.UnixNano() / 1000000
 	}
 
-	// Convert headers to C-friendly tmphdrs
-	var tmphdrs []C.tmphdr_t
-	tmphdrsCnt := len(msg.Headers)
+	// Convert headers to C-friendly tmphdrs,
+	// which are freed by do_produce().
+	var tmphdrs *[]C.tmphdr_t
+	var tmphdrsp *C.tmphdr_t
+	headers := msg.Headers
+	if p.handle.integrityStamp {
+		headers = withIntegrityHeader(msg)
+	}
+	tmphdrsCnt := len(headers)
 
 	if tmphdrsCnt > 0 {
-		tmphdrs = make([]C.tmphdr_t, tmphdrsCnt)
+		tmphdrs = getTmphdrs(tmphdrsCnt)
+		defer tmphdrsPool.Put(tmphdrs)
 
-		for n, hdr := range msg.Headers {
+		for n, hdr := range headers {
+			tmphdr := &(*tmphdrs)[n]
 			// Make a copy of the key
 			// to avoid runtime panic with
 			// foreign Go pointers in cgo.
-			tmphdrs[n].key = C.CString(hdr.Key)
+			tmphdr.key = C.CString(hdr.Key)
+			tmphdr.val = nil
 			if hdr.Value != nil {
-				tmphdrs[n].size = C.ssize_t(len(hdr.Value))
-				if tmphdrs[n].size > 0 {
+				tmphdr.size = C.ssize_t(len(hdr.Value))
+				if tmphdr.size > 0 {
 					// Make a copy of the value
 					// to avoid runtime panic with
 					// foreign Go pointers in cgo.
-					tmphdrs[n].val = C.CBytes(hdr.Value)
+					tmphdr.val = C.CBytes(hdr.Value)
 				}
 			} else {
 				// null value
-				tmphdrs[n].size = C.ssize_t(-1)
+				tmphdr.size = C.ssize_t(-1)
 			}
 		}
-	} else {
-		// no headers, need a dummy tmphdrs of size 1 to avoid index
-		// out of bounds panic in do_produce() call below.
-		// tmphdrsCnt will be 0.
-		tmphdrs = []C.tmphdr_t{{nil, nil, 0}}
+		tmphdrsp = &(*tmphdrs)[0]
 	}
 
 	cErr := C.do_produce(p.handle.rk, crkt,
@@ -259,11 +292,12 @@ func (p *Producer) produce(msg *Message, msgFlags int, deliveryChan chan Event)
 		valIsNull, unsafe.Pointer(&valp[0]), C.size_t(valLen),
 		keyIsNull, unsafe.Pointer(&keyp[0]), C.size_t(keyLen),
 		C.int64_t(timestamp),
-		(*C.tmphdr_t)(unsafe.Pointer(&tmphdrs[0])), C.size_t(tmphdrsCnt),
+		tmphdrsp, C.size_t(tmphdrsCnt),
 		(C.uintptr_t)(cgoid))
 	if cErr != C.RD_KAFKA_RESP_ERR_NO_ERROR {
 		if cgoid != 0 {
 			p.handle.cgoGet(cgoid)
+			cdr.release()
 		}
 		return newError(cErr)
 	}
@@ -282,6 +316,7 @@ func (p *Producer) produce(msg *Message, msgFlags int, deliveryChan chan Event)
 // api.version.request=true, and broker >= 0.11.0.0.
 // Returns an error if message could not be enqueued.
 func (p *Producer) Produce(msg *Message, deliveryChan chan Event) error {
+	p.handle.waitThrottleBackoff(-1, p.pollerTermChan)
 	return p.produce(msg, 0, deliveryChan)
 }
 
@@ -426,10 +461,46 @@ func (p *Producer) Purge(flags int) error {
 //   go.delivery.report.fields (string, "key,value") - Comma separated list of fields to enable for delivery reports.
 //                                       Allowed values: all, none (or empty string), key, value, headers
 //                                       Warning: There is a performance penalty to include headers in the delivery report.
+//   go.delivery.timeout.events (bool, false) - Emit a *DeliveryTimeout event instead of the *Message delivery report
+//                                              for messages that failed with ErrMsgTimedOut.
 //   go.events.channel.size (int, 1000000) - Events().
+//   go.events.channel.overflow (string, "block") - Policy for events, including delivery reports, that don't fit in
+//                                        the full Events() channel: "block" blocks until the application reads the channel,
+//                                        "drop-oldest" drops the oldest event in the channel, counted by DroppedEvents(),
+//                                        "callback" passes the event to go.overflow.callback. "drop-oldest" requires
+//                                        a buffered channel.
+//   go.overflow.callback (func(kafka.Event), nil) - Called from the poller for events and log events that don't fit
+//                                        in their channel with the "callback" overflow policy.
 //   go.produce.channel.size (int, 1000000) - ProduceChannel() buffer size (in number of messages)
+//   go.integrity.checksum (bool, false) - Add a header with the CRC32C checksum of the value to produced messages,
+//                                         see IntegrityHeaderKey. Can't be combined with go.batch.producer.
+//   go.produce.size.check (bool, false) - Fail Produce() with an ErrMsgSizeTooLarge error for messages whose estimated record
+//                                         batch size, including headers and protocol overhead, exceeds message.max.bytes, rather
+//                                         than with a delayed delivery report. Can't be combined with go.batch.producer.
+//   go.produce.size.compression.ratio (float64, 1.0) - Estimated ratio of the compressed to uncompressed size of keys
+//                                         and values applied by go.produce.size.check, e.g., 0.5 for a codec halving their size.
+//   go.topic.intern (bool, false) - If enabled, delivery reports of the same topic share the TopicPartition.Topic string pointer,
+//                                   which must then not be modified by the application.
+//   go.statistics.parse (bool, false) - Emit parsed *StatsEvent events instead of raw JSON *Stats events.
+//   go.broker.state.events (bool, false) - Emit BrokerDown and AllBrokersDown events instead of the corresponding
+//                                          Errors, and BrokerUp events (requires statistics.interval.ms).
+//   go.throttle.events (bool, false) - Emit ThrottleEvent events for brokers throttling the client (requires statistics.interval.ms).
+//   go.partition.count.events (bool, false) - Emit PartitionCountChange events when the partition count of a produced-to
+//                                             topic changes (requires statistics.interval.ms).
+//   go.throttle.backoff (bool or kafka.ThrottleBackoffPolicy, nil) - Delay Produce() calls and ProduceChannel() messages while brokers
+//                                          throttle the client, true backs off for the broker's throttle time (requires statistics.interval.ms).
+//                                          This adds to librdkafka's own throttling of the requests to the throttling brokers.
 //   go.logs.channel.enable (bool, false) - Forward log to Logs() channel.
 //   go.logs.channel (chan kafka.LogEvent, nil) - Forward logs to application-provided channel instead of Logs(). Requires go.logs.channel.enable=true.
+//   go.logs.channel.size (int, 10000) - Logs() channel size
+//   go.logs.channel.overflow (string, "block") - Policy for log events that don't fit in the full Logs() or go.logs.channel
+//                                        channel, see go.events.channel.overflow. Dropped log events are counted by DroppedLogs().
+//   go.logger (kafka.Logger, nil) - Forward logs to the application-provided Logger instead of Logs(), see NewSlogLogger().
+//   go.log.callback (func(kafka.LogEvent), nil) - Call the application-provided function for each log instead of forwarding logs to Logs().
+//   go.stats.callback (kafka.StatsCallback, nil) - Call the application-provided function with the statistics JSON instead of emitting Stats events on Events().
+//   go.statistics.callback (kafka.StatisticsCallback, nil) - Call the application-provided function with the parsed statistics,
+//                                          parsed without copying the JSON document, instead of emitting statistics events.
+//   go.error.callback (kafka.ErrorCallback, nil) - Call the application-provided function for each client Error instead of emitting Error events on Events().
 //
 func NewProducer(conf *ConfigMap) (*Producer, error) {
 
@@ -464,6 +535,12 @@ func NewProducer(conf *ConfigMap) (*Producer, error) {
 	}
 	p.handle.fwdDr = v.(bool)
 
+	v, err = confCopy.extract("go.delivery.timeout.events", false)
+	if err != nil {
+		return nil, err
+	}
+	p.handle.fwdDeliveryTimeouts = v.(bool)
+
 	v, err = confCopy.extract("go.delivery.report.fields", "key,value")
 	if err != nil {
 		return nil, err
@@ -474,23 +551,91 @@ func NewProducer(conf *ConfigMap) (*Producer, error) {
 		return nil, err
 	}
 
+	v, err = confCopy.extract("go.topic.intern", false)
+	if err != nil {
+		return nil, err
+	}
+	p.handle.internTopics = v.(bool)
+
+	v, err = confCopy.extract("go.integrity.checksum", false)
+	if err != nil {
+		return nil, err
+	}
+	p.handle.integrityStamp = v.(bool)
+	if p.handle.integrityStamp && batchProducer {
+		return nil, newErrorFromString(ErrInvalidArg,
+			"go.integrity.checksum is not supported with go.batch.producer")
+	}
+
+	p.sizeCheck, err = newMessageSizeCheck(confCopy, p.handle.integrityStamp)
+	if err != nil {
+		return nil, err
+	}
+	if p.sizeCheck != nil && batchProducer {
+		return nil, newErrorFromString(ErrInvalidArg,
+			"go.produce.size.check is not supported with go.batch.producer")
+	}
+
 	v, err = confCopy.extract("go.events.channel.size", 1000000)
 	if err != nil {
 		return nil, err
 	}
 	eventsChanSize := v.(int)
 
+	v, err = confCopy.extract("go.logs.channel.size", 10000)
+	if err != nil {
+		return nil, err
+	}
+	logsChanSize := v.(int)
+
+	p.handle.eventsOverflow, p.handle.logsOverflow, p.handle.overflowCb, err = confCopy.extractOverflowConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	v, err = confCopy.extract("go.statistics.parse", false)
+	if err != nil {
+		return nil, err
+	}
+	p.handle.parseStats = v.(bool)
+
+	v, err = confCopy.extract("go.broker.state.events", false)
+	if err != nil {
+		return nil, err
+	}
+	p.handle.brokerStateEvents = v.(bool)
+
+	v, err = confCopy.extract("go.partition.count.events", false)
+	if err != nil {
+		return nil, err
+	}
+	p.handle.partitionCountEvents = v.(bool)
+
+	p.handle.throttleEvents, p.handle.throttleBackoff, err = confCopy.extractThrottleConfig()
+	if err != nil {
+		return nil, err
+	}
+
 	v, err = confCopy.extract("go.produce.channel.size", 1000000)
 	if err != nil {
 		return nil, err
 	}
 	produceChannelSize := v.(int)
 
-	logsChanEnable, logsChan, err := confCopy.extractLogConfig()
+	p.handle.statsCb, p.handle.statisticsCb, p.handle.errorCb, err = confCopy.extractCallbackConfig()
 	if err != nil {
 		return nil, err
 	}
 
+	logsChanEnable, logsChan, logger, err := confCopy.extractLogConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = p.handle.checkDropOldest(eventsChanSize, logsChanEnable, logsChan, logsChanSize); err != nil {
+		return nil, err
+	}
+
 	if int(C.rd_kafka_version()) < 0x01000000 {
 		// produce.offset.report is no longer used in librdkafka >= v1.0.0
 		v, _ = confCopy.extract("{topic}.produce.offset.report", nil)
@@ -519,13 +664,14 @@ func NewProducer(conf *ConfigMap) (*Producer, error) {
 
 	p.handle.p = p
 	p.handle.setup()
+	p.handle.register("producer", conf)
 	p.handle.rkq = C.rd_kafka_queue_get_main(p.handle.rk)
 	p.events = make(chan Event, eventsChanSize)
 	p.produceChannel = make(chan *Message, produceChannelSize)
 	p.pollerTermChan = make(chan bool)
 
 	if logsChanEnable {
-		p.handle.setupLogQueue(logsChan, p.pollerTermChan)
+		p.handle.setupLogQueue(logsChan, logsChanSize, logger, p.pollerTermChan)
 	}
 
 	p.handle.waitGroup.Add(1)
@@ -554,10 +700,11 @@ func NewProducer(conf *ConfigMap) (*Producer, error) {
 // channel_producer serves the ProduceChannel channel
 func channelProducer(p *Producer) {
 	for m := range p.produceChannel {
+		p.handle.waitThrottleBackoff(-1, p.pollerTermChan)
 		err := p.produce(m, C.RD_KAFKA_MSG_F_BLOCK, nil)
 		if err != nil {
 			m.TopicPartition.Error = err
-			p.events <- m
+			p.handle.sendEvent(p.events, m, nil)
 		}
 	}
 }
@@ -606,7 +753,7 @@ func channelBatchProducer(p *Producer) {
 			if err != nil {
 				for _, m = range buffered2 {
 					m.TopicPartition.Error = err
-					p.events <- m
+					p.handle.sendEvent(p.events, m, nil)
 				}
 			}
 		}
@@ -642,6 +789,17 @@ func (p *Producer) GetMetadata(topic *string, allTopics bool, timeoutMs int) (*M
 	return getMetadata(p, topic, allTopics, timeoutMs)
 }
 
+// Metadata queries the cluster for the metadata of the topics selected
+// by opts, until ctx is done.
+// The metadata includes the controller and cluster ids, the brokers'
+// racks and the topic ids are not provided by this librdkafka version.
+//
+// Note on cancellation: the request is bound by ctx's deadline, if any,
+// but it currently cannot be manually cancelled.
+func (p *Producer) Metadata(ctx context.Context, opts MetadataOptions) (*Metadata, error) {
+	return metadata(p, ctx, opts)
+}
+
 // QueryWatermarkOffsets returns the broker's low and high offsets for the given topic
 // and partition.
 func (p *Producer) QueryWatermarkOffsets(topic string, partition int32, timeoutMs int) (low, high int64, err error) {
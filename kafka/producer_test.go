@@ -721,3 +721,237 @@ func runProducerDeliveryReportFieldTest(t *testing.T, config *ConfigMap, fn func
 		t.Errorf("Expected empty queue after Flush, still has %d", r)
 	}
 }
+
+// TestProducerDeliveryTimeout tests the `go.delivery.timeout.events` config setting
+func TestProducerDeliveryTimeout(t *testing.T) {
+	p, err := NewProducer(&ConfigMap{
+		"socket.timeout.ms":          10,
+		"message.timeout.ms":         10,
+		"go.delivery.timeout.events": true})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer p.Close()
+
+	topic := "gotest"
+	myOpq := "My opaque"
+	drChan := make(chan Event, 1)
+
+	err = p.Produce(&Message{TopicPartition: TopicPartition{Topic: &topic, Partition: 0},
+		Opaque: &myOpq,
+		Value:  []byte("timeout")}, drChan)
+	if err != nil {
+		t.Fatalf("Produce failed: %s", err)
+	}
+
+	ev := <-drChan
+	dt, ok := ev.(*DeliveryTimeout)
+	if !ok {
+		t.Fatalf("Expected *DeliveryTimeout, got %T: %v", ev, ev)
+	}
+	t.Logf("%v", dt)
+
+	if dt.Message.TopicPartition.Error == nil ||
+		dt.Message.TopicPartition.Error.(Error).Code() != ErrMsgTimedOut {
+		t.Errorf("Expected ErrMsgTimedOut, not %v", dt.Message.TopicPartition.Error)
+	}
+	if dt.Message.Opaque != &myOpq {
+		t.Errorf("Opaque should be %v, not %v", &myOpq, dt.Message.Opaque)
+	}
+	if dt.QueueTime < 10*time.Millisecond {
+		t.Errorf("Expected QueueTime >= 10ms, not %v", dt.QueueTime)
+	}
+	if dt.BrokerID != -1 {
+		t.Errorf("Expected BrokerID -1 for never transmitted message, not %d", dt.BrokerID)
+	}
+	if dt.PossiblyPersisted {
+		t.Errorf("Expected never transmitted message to not be possibly persisted")
+	}
+
+	var dtErr error = dt
+	if dtErr.Error() == "" {
+		t.Errorf("Expected non-empty error string")
+	}
+}
+
+// TestProducerLogger tests forwarding of logs to a go.logger Logger.
+func TestProducerLogger(t *testing.T) {
+	logs := make(chan LogEvent, 10000)
+
+	p, err := NewProducer(&ConfigMap{
+		"debug":             "all",
+		"socket.timeout.ms": 10,
+		"go.logger": LoggerFunc(func(logEvent LogEvent) {
+			select {
+			case logs <- logEvent:
+			default:
+			}
+		})})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer p.Close()
+
+	if p.Logs() != nil {
+		t.Errorf("Expected no Logs() channel when go.logger is set")
+	}
+
+	select {
+	case logEvent := <-logs:
+		if logEvent.Name != p.String() || logEvent.Tag == "" {
+			t.Errorf("Unexpected log event %v", logEvent)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for log event")
+	}
+
+	_, err = NewProducer(&ConfigMap{"go.logger": "notALogger"})
+	if err == nil || err.(Error).Code() != ErrInvalidArg {
+		t.Errorf("Expected ErrInvalidArg for invalid go.logger, got %v", err)
+	}
+}
+
+// TestProducerCallbacks tests the go.log.callback, go.stats.callback and
+// go.error.callback configuration properties
+func TestProducerCallbacks(t *testing.T) {
+	logs := make(chan LogEvent, 10000)
+	stats := make(chan string, 100)
+	errs := make(chan Error, 100)
+
+	p, err := NewProducer(&ConfigMap{
+		"bootstrap.servers":      "127.0.0.1:1",
+		"debug":                  "broker",
+		"statistics.interval.ms": 100,
+		"go.log.callback": func(logEvent LogEvent) {
+			select {
+			case logs <- logEvent:
+			default:
+			}
+		},
+		"go.stats.callback": StatsCallback(func(statsJSON string) {
+			select {
+			case stats <- statsJSON:
+			default:
+			}
+		}),
+		"go.error.callback": func(err Error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		}})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	select {
+	case <-logs:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Timed out waiting for log callback")
+	}
+
+	select {
+	case <-stats:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Timed out waiting for stats callback")
+	}
+
+	select {
+	case <-errs:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Timed out waiting for error callback")
+	}
+
+	// No stats or error events are forwarded to Events() when callbacks are set
+	p.Close()
+	for ev := range p.Events() {
+		switch ev.(type) {
+		case *Stats, Error:
+			t.Errorf("Unexpected event %v", ev)
+		}
+	}
+
+	_, err = NewProducer(&ConfigMap{"go.stats.callback": func(int) {}})
+	if err == nil || err.(Error).Code() != ErrInvalidArg {
+		t.Errorf("Expected ErrInvalidArg for invalid go.stats.callback, got %v", err)
+	}
+}
+
+// TestProducerTopicIntern tests that delivery reports share the topic
+// name pointer only if go.topic.intern is enabled.
+func TestProducerTopicIntern(t *testing.T) {
+	mc, err := NewMockCluster(1)
+	if err != nil {
+		t.Fatalf("Failed to create MockCluster: %v", err)
+	}
+	defer mc.Close()
+
+	topic := "intern"
+
+	for _, intern := range []bool{true, false} {
+		p, err := NewProducer(&ConfigMap{
+			"bootstrap.servers": mc.BootstrapServers(),
+			"go.topic.intern":   intern})
+		if err != nil {
+			t.Fatalf("Failed to create Producer: %v", err)
+		}
+
+		drChan := make(chan Event, 2)
+		var drs []*Message
+		for i := 0; i < 2; i++ {
+			err = p.Produce(&Message{
+				TopicPartition: TopicPartition{Topic: &topic, Partition: 0}}, drChan)
+			if err != nil {
+				t.Fatalf("Produce failed: %v", err)
+			}
+			drs = append(drs, (<-drChan).(*Message))
+		}
+		p.Close()
+
+		for _, dr := range drs {
+			if dr.TopicPartition.Error != nil || *dr.TopicPartition.Topic != topic {
+				t.Errorf("Unexpected delivery report %v", dr.TopicPartition)
+			}
+		}
+
+		shared := drs[0].TopicPartition.Topic == drs[1].TopicPartition.Topic
+		if shared != intern {
+			t.Errorf("go.topic.intern=%v: expected shared topic pointer %v, got %v",
+				intern, intern, shared)
+		}
+	}
+}
+
+// TestProducerAllocs tests that Produce() doesn't allocate for messages
+// without delivery channel or Opaque.
+func TestProducerAllocs(t *testing.T) {
+	// No brokers: messages are only enqueued, without delivery reports
+	// being created while measuring.
+	p, err := NewProducer(&ConfigMap{
+		"message.timeout.ms": 60 * 1000})
+	if err != nil {
+		t.Fatalf("Failed to create Producer: %v", err)
+	}
+	defer p.Close()
+
+	topic := "allocs"
+	for _, msg := range []*Message{
+		{TopicPartition: TopicPartition{Topic: &topic, Partition: PartitionAny},
+			Value: []byte("value"), Key: []byte("key")},
+		{TopicPartition: TopicPartition{Topic: &topic, Partition: PartitionAny}},
+		{TopicPartition: TopicPartition{Topic: &topic, Partition: PartitionAny},
+			Value:   []byte("value"),
+			Headers: []Header{{"hdr", []byte("value")}, {"null", nil}}},
+	} {
+		allocs := testing.AllocsPerRun(1000, func() {
+			if err := p.Produce(msg, nil); err != nil {
+				t.Fatalf("Produce failed: %v", err)
+			}
+		})
+		if allocs > 0 {
+			t.Errorf("Expected no allocations, got %v for %v", allocs, msg)
+		}
+	}
+
+	p.Purge(PurgeQueue)
+}
@@ -0,0 +1,161 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+/*
+#include "select_rdkafka.h"
+*/
+import "C"
+
+// RebalanceRecord describes a consumer group rebalance event,
+// see Consumer.RebalanceHistory().
+type RebalanceRecord struct {
+	// Timestamp is the time the rebalance event was received
+	Timestamp time.Time
+	// Protocol is the rebalance protocol, "EAGER" or "COOPERATIVE"
+	Protocol string
+	// GenerationID is the group generation once the assignment was
+	// updated, or -1 if not known
+	GenerationID int32
+	// Added holds the partitions assigned by the rebalance
+	Added []TopicPartition
+	// Removed holds the partitions revoked by the rebalance
+	Removed []TopicPartition
+	// Lost is true if the revoked partitions were lost, see
+	// Consumer.AssignmentLost()
+	Lost bool
+	// Duration is the time from receiving the rebalance event to the
+	// assignment being updated, including the rebalance callback or
+	// the application handling the event, or 0 if not yet updated
+	Duration time.Duration
+}
+
+func (r RebalanceRecord) String() string {
+	return fmt.Sprintf("%s rebalance at %s, generation %d: added %v, removed %v (lost %v) in %v",
+		r.Protocol, r.Timestamp.Format(time.RFC3339Nano), r.GenerationID,
+		r.Added, r.Removed, r.Lost, r.Duration)
+}
+
+// rebalanceHistory records the last rebalances in a ring buffer,
+// see `go.rebalance.history.size`.
+type rebalanceHistory struct {
+	lock    sync.Mutex
+	records []RebalanceRecord
+	// Index of the oldest record once the buffer is full
+	next int
+	// Record waiting for the assignment to be updated, if any
+	pending *RebalanceRecord
+}
+
+// record records the rebalance event rkev.
+func (rh *rebalanceHistory) record(c *Consumer, rkev *C.rd_kafka_event_t) {
+	if cap(rh.records) == 0 {
+		return
+	}
+
+	r := RebalanceRecord{
+		Timestamp:    time.Now(),
+		Protocol:     c.GetRebalanceProtocol(),
+		GenerationID: -1,
+	}
+
+	partitions := newTopicPartitionsFromCparts(C.rd_kafka_event_topic_partition_list(rkev))
+	if C.rd_kafka_event_error(rkev) == C.RD_KAFKA_RESP_ERR__ASSIGN_PARTITIONS {
+		r.Added = partitions
+	} else {
+		r.Removed = partitions
+		r.Lost = c.AssignmentLost()
+	}
+
+	rh.lock.Lock()
+	defer rh.lock.Unlock()
+
+	if len(rh.records) < cap(rh.records) {
+		rh.records = append(rh.records, r)
+		rh.pending = &rh.records[len(rh.records)-1]
+	} else {
+		rh.records[rh.next] = r
+		rh.pending = &rh.records[rh.next]
+		rh.next = (rh.next + 1) % len(rh.records)
+	}
+}
+
+// assigned completes the pending record, if any, once the assignment
+// was updated.
+func (rh *rebalanceHistory) assigned(c *Consumer) {
+	if cap(rh.records) == 0 {
+		return
+	}
+
+	rh.lock.Lock()
+	pending := rh.pending
+	rh.pending = nil
+	rh.lock.Unlock()
+
+	if pending == nil {
+		return
+	}
+
+	generationID := c.groupGenerationID()
+
+	rh.lock.Lock()
+	pending.Duration = time.Since(pending.Timestamp)
+	pending.GenerationID = generationID
+	rh.lock.Unlock()
+}
+
+// groupGenerationID returns the consumer's group generation id,
+// or -1 if not known.
+// librdkafka doesn't expose the generation id, which is read from
+// the serialized consumer group metadata:
+// "CGMDv2:" followed by the generation id in host byte order.
+func (c *Consumer) groupGenerationID() int32 {
+	cgmd, err := c.GetConsumerGroupMetadata()
+	if err != nil {
+		return -1
+	}
+
+	magic := []byte("CGMDv2:")
+	if len(cgmd.serialized) < len(magic)+4 || !bytes.HasPrefix(cgmd.serialized, magic) {
+		return -1
+	}
+
+	var generationID int32
+	copy((*[4]byte)(unsafe.Pointer(&generationID))[:], cgmd.serialized[len(magic):])
+	return generationID
+}
+
+// RebalanceHistory returns the last rebalances, oldest first,
+// recorded with `go.rebalance.history.size`.
+func (c *Consumer) RebalanceHistory() []RebalanceRecord {
+	rh := &c.rebalanceHistory
+	rh.lock.Lock()
+	defer rh.lock.Unlock()
+
+	history := make([]RebalanceRecord, 0, len(rh.records))
+	history = append(history, rh.records[rh.next:]...)
+	history = append(history, rh.records[:rh.next]...)
+	return history
+}
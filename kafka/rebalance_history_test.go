@@ -0,0 +1,82 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"testing"
+	"time"
+)
+
+// TestRebalanceHistory tests that rebalances are recorded, and that only
+// the last go.rebalance.history.size ones are kept.
+func TestRebalanceHistory(t *testing.T) {
+	mc, err := NewMockCluster(1)
+	if err != nil {
+		t.Fatalf("Failed to create MockCluster: %v", err)
+	}
+	defer mc.Close()
+
+	topic := "rebalance-history"
+	if err = mc.CreateTopic(topic, 2, 1); err != nil {
+		t.Fatalf("CreateTopic failed: %v", err)
+	}
+
+	c, err := NewConsumer(&ConfigMap{
+		"bootstrap.servers":         mc.BootstrapServers(),
+		"group.id":                  "rebalance-history",
+		"enable.auto.commit":        false,
+		"go.rebalance.history.size": 1})
+	if err != nil {
+		t.Fatalf("Failed to create Consumer: %v", err)
+	}
+	defer c.Close()
+
+	if h := c.RebalanceHistory(); len(h) != 0 {
+		t.Fatalf("Expected empty history, not %v", h)
+	}
+
+	if err = c.Subscribe(topic, nil); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	var h []RebalanceRecord
+	for i := 0; i < 300 && len(h) == 0; i++ {
+		c.Poll(100)
+		h = c.RebalanceHistory()
+	}
+	if len(h) != 1 {
+		t.Fatalf("Expected 1 record, not %v", h)
+	}
+	if len(h[0].Added) != 2 || len(h[0].Removed) != 0 {
+		t.Errorf("Expected 2 partitions added, not %v", h[0])
+	}
+	if h[0].Protocol != "EAGER" || h[0].GenerationID <= 0 || h[0].Duration <= 0 {
+		t.Errorf("Unexpected record %v", h[0])
+	}
+
+	// The revocation overwrites the assignment
+	if err = c.Unsubscribe(); err != nil {
+		t.Fatalf("Unsubscribe failed: %v", err)
+	}
+	deadline := time.Now().Add(30 * time.Second)
+	for time.Now().Before(deadline) && len(h[0].Removed) == 0 {
+		c.Poll(100)
+		h = c.RebalanceHistory()
+	}
+	if len(h) != 1 || len(h[0].Added) != 0 || len(h[0].Removed) != 2 || h[0].Lost {
+		t.Errorf("Expected 2 partitions removed, not %v", h)
+	}
+}
@@ -0,0 +1,138 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ClientInfo describes a live client instance, see Clients().
+type ClientInfo struct {
+	// Type is the client type: "producer", "consumer" or "admin"
+	Type string `json:"type"`
+	// Name is the client instance name, e.g., "rdkafka#producer-1"
+	Name string `json:"name"`
+	// ConfigFingerprint is a hash of the client configuration,
+	// clients created with the same configuration have the same fingerprint.
+	ConfigFingerprint string `json:"config_fingerprint"`
+	// Created is the time the client was created
+	Created time.Time `json:"created"`
+}
+
+func (ci ClientInfo) String() string {
+	return fmt.Sprintf("%s %s (config %s, created %v)",
+		ci.Type, ci.Name, ci.ConfigFingerprint, ci.Created)
+}
+
+// clientRegistry tracks the live client instances of the process
+var clientRegistry = struct {
+	lock    sync.Mutex
+	clients map[*handle]ClientInfo
+}{clients: make(map[*handle]ClientInfo)}
+
+// configFingerprint returns a hash of the configuration properties,
+// values that are not strings, bools or numbers, such as channels and
+// callbacks, are represented by their type.
+func configFingerprint(conf *ConfigMap) string {
+	if conf == nil {
+		return ""
+	}
+
+	keys := make([]string, 0, len(*conf))
+	for k := range *conf {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	hash := sha256.New()
+	for _, k := range keys {
+		var s string
+		switch v := (*conf)[k].(type) {
+		case string, bool, int, int32, int64, float64, fmt.Stringer:
+			s = fmt.Sprintf("%v", v)
+		default:
+			s = fmt.Sprintf("%T", v)
+		}
+		fmt.Fprintf(hash, "%s=%s\n", k, s)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// register adds the client instance to the client registry.
+func (h *handle) register(clientType string, conf *ConfigMap) {
+	info := ClientInfo{
+		Type:              clientType,
+		Name:              h.name,
+		ConfigFingerprint: configFingerprint(conf),
+		Created:           time.Now(),
+	}
+
+	clientRegistry.lock.Lock()
+	clientRegistry.clients[h] = info
+	clientRegistry.lock.Unlock()
+}
+
+// unregister removes the client instance from the client registry.
+func (h *handle) unregister() {
+	clientRegistry.lock.Lock()
+	delete(clientRegistry.clients, h)
+	clientRegistry.lock.Unlock()
+}
+
+// Clients returns the live (not yet closed) Producer, Consumer and
+// AdminClient instances of the process, in creation order.
+// AdminClients created from a Producer or Consumer are not included.
+//
+// Clients that are never closed leak memory, threads and broker
+// connections, Clients() helps to diagnose such leaks.
+func Clients() []ClientInfo {
+	clientRegistry.lock.Lock()
+	clients := make([]ClientInfo, 0, len(clientRegistry.clients))
+	for _, info := range clientRegistry.clients {
+		clients = append(clients, info)
+	}
+	clientRegistry.lock.Unlock()
+
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].Created.Before(clients[j].Created)
+	})
+
+	return clients
+}
+
+// ClientsHandler returns an http.Handler serving the live client
+// instances, as returned by Clients(), as a JSON array.
+//
+// E.g.:
+//
+//	http.Handle("/debug/kafka/clients", kafka.ClientsHandler())
+func ClientsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(Clients())
+	})
+}
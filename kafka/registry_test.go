@@ -0,0 +1,92 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+)
+
+// findClient returns the registered client with the given name, if any
+func findClient(name string) (ClientInfo, bool) {
+	for _, info := range Clients() {
+		if info.Name == name {
+			return info, true
+		}
+	}
+	return ClientInfo{}, false
+}
+
+// TestClientRegistry tests registration of live clients
+func TestClientRegistry(t *testing.T) {
+	conf := ConfigMap{"socket.timeout.ms": 10, "go.logs.channel": make(chan LogEvent)}
+
+	p, err := NewProducer(&conf)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	a, err := NewAdminClient(&ConfigMap{"socket.timeout.ms": 10})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	c, err := NewConsumer(&ConfigMap{"group.id": "registry", "socket.timeout.ms": 10})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	pInfo, found := findClient(p.String())
+	if !found || pInfo.Type != "producer" || pInfo.Created.IsZero() {
+		t.Errorf("Expected producer %s to be registered, got %v", p, Clients())
+	}
+
+	aInfo, found := findClient(a.handle.name)
+	if !found || aInfo.Type != "admin" {
+		t.Errorf("Expected admin client %s to be registered, got %v", a, Clients())
+	}
+
+	cInfo, found := findClient(c.String())
+	if !found || cInfo.Type != "consumer" {
+		t.Errorf("Expected consumer %s to be registered, got %v", c, Clients())
+	}
+
+	if pInfo.ConfigFingerprint != configFingerprint(&ConfigMap{
+		"socket.timeout.ms": 10, "go.logs.channel": make(chan LogEvent)}) ||
+		pInfo.ConfigFingerprint == aInfo.ConfigFingerprint {
+		t.Errorf("Unexpected config fingerprints %s and %s",
+			pInfo.ConfigFingerprint, aInfo.ConfigFingerprint)
+	}
+
+	rec := httptest.NewRecorder()
+	ClientsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	var served []ClientInfo
+	if err = json.Unmarshal(rec.Body.Bytes(), &served); err != nil || len(served) < 3 {
+		t.Errorf("Unexpected handler response %s: %v", rec.Body.String(), err)
+	}
+
+	p.Close()
+	a.Close()
+	c.Close()
+
+	for _, name := range []string{p.String(), aInfo.Name, c.String()} {
+		if _, found = findClient(name); found {
+			t.Errorf("Expected %s to be unregistered after Close()", name)
+		}
+	}
+}
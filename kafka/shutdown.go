@@ -0,0 +1,193 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ShutdownGroup performs an ordered shutdown of a set of producers and
+// consumers, typically on application termination, see Shutdown().
+type ShutdownGroup struct {
+	lock      sync.Mutex
+	stopFuncs []func()
+	consumers []ConsumerClient
+	producers []ProducerClient
+	done      bool
+}
+
+// ShutdownResult reports the outcome of ShutdownGroup.Shutdown().
+type ShutdownResult struct {
+	// Unflushed is the number of messages and delivery reports per
+	// producer, in the order the producers were added, still outstanding
+	// when the shutdown deadline was reached.
+	Unflushed []int
+	// Errors are the errors returned when committing offsets or
+	// closing consumers.
+	Errors []error
+	// Duration of the shutdown
+	Duration time.Duration
+}
+
+// TotalUnflushed returns the total number of outstanding messages
+// and delivery reports for all producers.
+func (r *ShutdownResult) TotalUnflushed() int {
+	total := 0
+	for _, cnt := range r.Unflushed {
+		total += cnt
+	}
+	return total
+}
+
+func (r *ShutdownResult) String() string {
+	return fmt.Sprintf("Shutdown in %v: %d unflushed, %d error(s)",
+		r.Duration, r.TotalUnflushed(), len(r.Errors))
+}
+
+// NewShutdownGroup creates an empty ShutdownGroup.
+func NewShutdownGroup() *ShutdownGroup {
+	return &ShutdownGroup{}
+}
+
+// OnStop registers a function that stops the application from consuming
+// and producing further messages, e.g., by stopping its poll loops.
+// The function should return once messages already consumed have been
+// processed, it is called before any client is shut down.
+// Functions are called in the order they were registered.
+func (g *ShutdownGroup) OnStop(stop func()) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.stopFuncs = append(g.stopFuncs, stop)
+}
+
+// AddConsumer adds a consumer to the group.
+func (g *ShutdownGroup) AddConsumer(c ConsumerClient) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.consumers = append(g.consumers, c)
+}
+
+// AddProducer adds a producer to the group.
+func (g *ShutdownGroup) AddProducer(p ProducerClient) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.producers = append(g.producers, p)
+}
+
+// Shutdown shuts down the group's clients in order:
+//
+//  1. the OnStop functions are called to stop the application intake.
+//  2. the consumers' assigned partitions are paused to stop fetching.
+//  3. the consumers' stored offsets are committed.
+//  4. the producers are flushed until all messages are delivered
+//     or ctx is done.
+//  5. the consumers, then the producers, are closed.
+//
+// The returned result reports the number of messages per producer left
+// unflushed and any errors encountered. Subsequent calls return nil.
+func (g *ShutdownGroup) Shutdown(ctx context.Context) *ShutdownResult {
+	g.lock.Lock()
+	if g.done {
+		g.lock.Unlock()
+		return nil
+	}
+	g.done = true
+	stopFuncs, consumers, producers := g.stopFuncs, g.consumers, g.producers
+	g.lock.Unlock()
+
+	start := time.Now()
+	result := &ShutdownResult{Unflushed: make([]int, len(producers))}
+
+	for _, stop := range stopFuncs {
+		stop()
+	}
+
+	for _, c := range consumers {
+		if partitions, err := c.Assignment(); err == nil && len(partitions) > 0 {
+			c.Pause(partitions)
+		}
+
+		if _, err := c.Commit(); err != nil {
+			if kerr, ok := err.(Error); !ok || kerr.Code() != ErrNoOffset {
+				result.Errors = append(result.Errors,
+					fmt.Errorf("%s: commit failed: %w", c, err))
+			}
+		}
+	}
+
+	for i, p := range producers {
+		result.Unflushed[i] = flushUntil(ctx, p)
+	}
+
+	for _, c := range consumers {
+		if err := c.Close(); err != nil {
+			result.Errors = append(result.Errors,
+				fmt.Errorf("%s: close failed: %w", c, err))
+		}
+	}
+
+	for _, p := range producers {
+		p.Close()
+	}
+
+	result.Duration = time.Since(start)
+
+	return result
+}
+
+// ShutdownOnDone calls Shutdown() once ctx is done, allowing at most
+// timeout for the shutdown, and sends the result on the returned channel.
+func (g *ShutdownGroup) ShutdownOnDone(ctx context.Context, timeout time.Duration) <-chan *ShutdownResult {
+	resultChan := make(chan *ShutdownResult, 1)
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		resultChan <- g.Shutdown(shutdownCtx)
+		close(resultChan)
+	}()
+
+	return resultChan
+}
+
+// flushUntil flushes p until all messages are delivered or ctx is done,
+// returning the number of messages and events still outstanding.
+func flushUntil(ctx context.Context, p ProducerClient) int {
+	for {
+		remaining := 100
+		if deadline, ok := ctx.Deadline(); ok {
+			remaining = int(time.Until(deadline) / time.Millisecond)
+			if remaining > 100 {
+				remaining = 100
+			}
+		}
+
+		if remaining <= 0 || ctx.Err() != nil {
+			return p.Len()
+		}
+
+		if p.Flush(remaining) == 0 {
+			return 0
+		}
+	}
+}
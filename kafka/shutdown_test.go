@@ -0,0 +1,86 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestShutdownGroup tests the ordered shutdown of mock clients
+func TestShutdownGroup(t *testing.T) {
+	topic := "shutdown"
+
+	mc := NewMockConsumer()
+	mc.Assign([]TopicPartition{{Topic: &topic, Partition: 0}})
+	mc.AddMessage(&Message{TopicPartition: TopicPartition{Topic: &topic, Partition: 0, Offset: 0}})
+	if _, err := mc.ReadMessage(time.Second); err != nil {
+		t.Fatalf("ReadMessage failed: %v", err)
+	}
+
+	// drained's delivery reports are read by the application,
+	// undrained's are not and will be reported as unflushed.
+	drained := NewMockProducer(false)
+	undrained := NewMockProducer(false)
+	go func() {
+		for range drained.Events() {
+		}
+	}()
+
+	for _, p := range []*MockProducer{drained, undrained} {
+		for i := 0; i < 3; i++ {
+			p.Produce(&Message{TopicPartition: TopicPartition{Topic: &topic}}, nil)
+		}
+	}
+
+	g := NewShutdownGroup()
+	var order []string
+	g.OnStop(func() { order = append(order, "stop") })
+	g.AddConsumer(mc)
+	g.AddProducer(drained)
+	g.AddProducer(undrained)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	resultChan := g.ShutdownOnDone(ctx, 500*time.Millisecond)
+	cancel()
+
+	result := <-resultChan
+	if result == nil {
+		t.Fatalf("Expected shutdown result")
+	}
+	t.Logf("%v", result)
+
+	if len(order) != 1 {
+		t.Errorf("Expected stop function to be called once, got %v", order)
+	}
+	if len(result.Errors) != 0 {
+		t.Errorf("Unexpected errors %v", result.Errors)
+	}
+	if result.Unflushed[0] != 0 || result.Unflushed[1] != 3 || result.TotalUnflushed() != 3 {
+		t.Errorf("Expected 0 and 3 unflushed, got %v", result.Unflushed)
+	}
+
+	committed, _ := mc.Committed([]TopicPartition{{Topic: &topic, Partition: 0}}, 0)
+	if len(committed) != 1 || committed[0].Offset != 1 {
+		t.Errorf("Expected offset 1 to be committed, got %v", committed)
+	}
+
+	if g.Shutdown(context.Background()) != nil {
+		t.Errorf("Expected subsequent Shutdown to return nil")
+	}
+}
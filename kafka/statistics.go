@@ -0,0 +1,284 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// WindowStatistics contains rolling window statistics, such as latencies.
+// All values are in microseconds unless noted otherwise.
+type WindowStatistics struct {
+	Min        int64 `json:"min"`
+	Max        int64 `json:"max"`
+	Avg        int64 `json:"avg"`
+	Sum        int64 `json:"sum"`
+	Stddev     int64 `json:"stddev"`
+	P50        int64 `json:"p50"`
+	P75        int64 `json:"p75"`
+	P90        int64 `json:"p90"`
+	P95        int64 `json:"p95"`
+	P99        int64 `json:"p99"`
+	P99_99     int64 `json:"p99_99"`
+	OutOfRange int64 `json:"outofrange"`
+	HdrSize    int64 `json:"hdrsize"`
+	// Number of values sampled
+	Cnt int64 `json:"cnt"`
+}
+
+// BrokerTopicPartition identifies a partition handled by a broker.
+type BrokerTopicPartition struct {
+	Topic     string `json:"topic"`
+	Partition int32  `json:"partition"`
+}
+
+// BrokerStatistics contains per-broker statistics.
+type BrokerStatistics struct {
+	// Broker hostname, port and broker id
+	Name string `json:"name"`
+	// Broker id (-1 for bootstraps)
+	NodeID int32 `json:"nodeid"`
+	// Broker hostname and port
+	NodeName string `json:"nodename"`
+	// Broker source (learned, configured, internal, logical)
+	Source string `json:"source"`
+	// Broker state (INIT, DOWN, CONNECT, AUTH, APIVERSION_QUERY,
+	// AUTH_HANDSHAKE, UP, UPDATE)
+	State string `json:"state"`
+	// Time since last broker state change (microseconds)
+	StateAge int64 `json:"stateage"`
+
+	OutbufCnt      int64 `json:"outbuf_cnt"`
+	OutbufMsgCnt   int64 `json:"outbuf_msg_cnt"`
+	WaitrespCnt    int64 `json:"waitresp_cnt"`
+	WaitrespMsgCnt int64 `json:"waitresp_msg_cnt"`
+
+	Tx          int64 `json:"tx"`
+	TxBytes     int64 `json:"txbytes"`
+	TxErrs      int64 `json:"txerrs"`
+	TxRetries   int64 `json:"txretries"`
+	TxIdle      int64 `json:"txidle"`
+	ReqTimeouts int64 `json:"req_timeouts"`
+
+	Rx           int64 `json:"rx"`
+	RxBytes      int64 `json:"rxbytes"`
+	RxErrs       int64 `json:"rxerrs"`
+	RxCorrIDErrs int64 `json:"rxcorriderrs"`
+	RxPartial    int64 `json:"rxpartial"`
+	RxIdle       int64 `json:"rxidle"`
+
+	ZbufGrow    int64 `json:"zbuf_grow"`
+	BufGrow     int64 `json:"buf_grow"`
+	Wakeups     int64 `json:"wakeups"`
+	Connects    int64 `json:"connects"`
+	Disconnects int64 `json:"disconnects"`
+
+	// Internal producer queue latency
+	IntLatency WindowStatistics `json:"int_latency"`
+	// Internal request queue latency
+	OutbufLatency WindowStatistics `json:"outbuf_latency"`
+	// Broker round-trip time
+	Rtt WindowStatistics `json:"rtt"`
+	// Broker throttling time (milliseconds)
+	Throttle WindowStatistics `json:"throttle"`
+
+	// Request type counters, keyed by request name
+	Req map[string]int64 `json:"req"`
+	// Partitions handled by this broker
+	TopPars map[string]BrokerTopicPartition `json:"toppars"`
+}
+
+// PartitionStatistics contains per-partition statistics.
+type PartitionStatistics struct {
+	Partition int32 `json:"partition"`
+	// The id of the broker that messages are currently being fetched from
+	Broker int32 `json:"broker"`
+	// Current leader broker id
+	Leader int32 `json:"leader"`
+	// Partition is explicitly desired by application
+	Desired bool `json:"desired"`
+	// Partition not seen in topic metadata from broker
+	Unknown bool `json:"unknown"`
+
+	MsgqCnt       int64 `json:"msgq_cnt"`
+	MsgqBytes     int64 `json:"msgq_bytes"`
+	XmitMsgqCnt   int64 `json:"xmit_msgq_cnt"`
+	XmitMsgqBytes int64 `json:"xmit_msgq_bytes"`
+	FetchqCnt     int64 `json:"fetchq_cnt"`
+	FetchqSize    int64 `json:"fetchq_size"`
+	// Consumer fetch state (none, stopping, stopped, offset-query,
+	// offset-wait, active)
+	FetchState string `json:"fetch_state"`
+
+	QueryOffset       int64 `json:"query_offset"`
+	NextOffset        int64 `json:"next_offset"`
+	AppOffset         int64 `json:"app_offset"`
+	StoredOffset      int64 `json:"stored_offset"`
+	CommittedOffset   int64 `json:"committed_offset"`
+	EOFOffset         int64 `json:"eof_offset"`
+	LoOffset          int64 `json:"lo_offset"`
+	HiOffset          int64 `json:"hi_offset"`
+	LsOffset          int64 `json:"ls_offset"`
+	ConsumerLag       int64 `json:"consumer_lag"`
+	ConsumerLagStored int64 `json:"consumer_lag_stored"`
+
+	TxMsgs       int64 `json:"txmsgs"`
+	TxBytes      int64 `json:"txbytes"`
+	RxMsgs       int64 `json:"rxmsgs"`
+	RxBytes      int64 `json:"rxbytes"`
+	Msgs         int64 `json:"msgs"`
+	RxVerDrops   int64 `json:"rx_ver_drops"`
+	MsgsInflight int64 `json:"msgs_inflight"`
+	NextAckSeq   int64 `json:"next_ack_seq"`
+	NextErrSeq   int64 `json:"next_err_seq"`
+	AckedMsgID   int64 `json:"acked_msgid"`
+}
+
+// TopicStatistics contains per-topic statistics.
+type TopicStatistics struct {
+	Topic string `json:"topic"`
+	// Age of client's topic object (milliseconds)
+	Age int64 `json:"age"`
+	// Age of metadata from broker for this topic (milliseconds)
+	MetadataAge int64 `json:"metadata_age"`
+	// Batch sizes in bytes
+	BatchSize WindowStatistics `json:"batchsize"`
+	// Batch message counts
+	BatchCnt WindowStatistics `json:"batchcnt"`
+	// Partitions keyed by partition id, including the internal
+	// UA/UnAssigned partition -1.
+	Partitions map[string]PartitionStatistics `json:"partitions"`
+}
+
+// ConsumerGroupStatistics contains consumer group statistics.
+type ConsumerGroupStatistics struct {
+	// Local consumer group handler's state
+	State string `json:"state"`
+	// Time elapsed since last state change (milliseconds)
+	StateAge int64 `json:"stateage"`
+	// Local consumer group handler's join state
+	JoinState string `json:"join_state"`
+	// Time elapsed since last rebalance (milliseconds)
+	RebalanceAge int64 `json:"rebalance_age"`
+	// Total number of rebalances
+	RebalanceCnt int64 `json:"rebalance_cnt"`
+	// Last rebalance reason, or empty string
+	RebalanceReason string `json:"rebalance_reason"`
+	// Current assignment's partition count
+	AssignmentSize int64 `json:"assignment_size"`
+}
+
+// EOSStatistics contains idempotent and transactional producer statistics.
+type EOSStatistics struct {
+	// Current idempotent producer id state
+	IdempState string `json:"idemp_state"`
+	// Time elapsed since last idemp_state change (milliseconds)
+	IdempStateAge int64 `json:"idemp_stateage"`
+	// Current transactional producer state
+	TxnState string `json:"txn_state"`
+	// Time elapsed since last txn_state change (milliseconds)
+	TxnStateAge int64 `json:"txn_stateage"`
+	// Transactional state allows enqueuing (producing) new messages
+	TxnMayEnq bool `json:"txn_may_enq"`
+	// The currently assigned Producer ID (or -1)
+	ProducerID int64 `json:"producer_id"`
+	// The current epoch (or -1)
+	ProducerEpoch int64 `json:"producer_epoch"`
+	// The number of Producer ID assignments since start
+	EpochCnt int64 `json:"epoch_cnt"`
+}
+
+// Statistics contains the parsed librdkafka statistics as emitted
+// every `statistics.interval.ms`, see Stats.Parse() and StatsEvent.
+//
+// See https://github.com/edenhill/librdkafka/blob/master/STATISTICS.md
+// for a description of all fields.
+type Statistics struct {
+	// Handle instance name
+	Name string `json:"name"`
+	// The configured client.id
+	ClientID string `json:"client_id"`
+	// Instance type (producer or consumer)
+	Type string `json:"type"`
+	// librdkafka's internal monotonic clock (microseconds)
+	Ts int64 `json:"ts"`
+	// Wall clock time in seconds since the epoch
+	Time int64 `json:"time"`
+	// Time since this client instance was created (microseconds)
+	Age int64 `json:"age"`
+
+	ReplyQ           int64 `json:"replyq"`
+	MsgCnt           int64 `json:"msg_cnt"`
+	MsgSize          int64 `json:"msg_size"`
+	MsgMax           int64 `json:"msg_max"`
+	MsgSizeMax       int64 `json:"msg_size_max"`
+	SimpleCnt        int64 `json:"simple_cnt"`
+	MetadataCacheCnt int64 `json:"metadata_cache_cnt"`
+
+	Tx         int64 `json:"tx"`
+	TxBytes    int64 `json:"tx_bytes"`
+	Rx         int64 `json:"rx"`
+	RxBytes    int64 `json:"rx_bytes"`
+	TxMsgs     int64 `json:"txmsgs"`
+	TxMsgBytes int64 `json:"txmsg_bytes"`
+	RxMsgs     int64 `json:"rxmsgs"`
+	RxMsgBytes int64 `json:"rxmsg_bytes"`
+
+	// Brokers keyed by broker name
+	Brokers map[string]BrokerStatistics `json:"brokers"`
+	// Topics keyed by topic name
+	Topics map[string]TopicStatistics `json:"topics"`
+	// Consumer group statistics, only set for consumers in a group
+	ConsumerGroup *ConsumerGroupStatistics `json:"cgrp,omitempty"`
+	// Idempotent/transactional producer statistics, only set for
+	// idempotent and transactional producers
+	EOS *EOSStatistics `json:"eos,omitempty"`
+}
+
+// ParseStatistics parses a librdkafka statistics JSON document.
+func ParseStatistics(statsJSON string) (*Statistics, error) {
+	return parseStatistics([]byte(statsJSON))
+}
+
+// parseStatistics parses a librdkafka statistics JSON document.
+func parseStatistics(statsJSON []byte) (*Statistics, error) {
+	stats := &Statistics{}
+	err := json.Unmarshal(statsJSON, stats)
+	if err != nil {
+		return nil, newErrorFromString(ErrBadMsg,
+			fmt.Sprintf("Failed to parse statistics: %s", err))
+	}
+	return stats, nil
+}
+
+// Parse the statistics JSON document into a Statistics struct.
+func (e Stats) Parse() (*Statistics, error) {
+	return ParseStatistics(e.statsJSON)
+}
+
+// StatsEvent is a statistics event carrying the parsed statistics,
+// emitted instead of Stats if `go.statistics.parse` is set to true.
+type StatsEvent struct {
+	Statistics *Statistics
+	// The original statistics JSON document
+	JSON string
+}
+
+func (e *StatsEvent) String() string {
+	return fmt.Sprintf("StatsEvent: %s (%s)", e.Statistics.Name, e.Statistics.Type)
+}
@@ -129,3 +129,120 @@ func TestStatsEventConsumerChannel(t *testing.T) {
 	}
 
 }
+
+// TestStatsEventParsed dry-tests the parsed statistics event,
+// no broker is needed.
+func TestStatsEventParsed(t *testing.T) {
+	p, err := NewProducer(&ConfigMap{
+		"statistics.interval.ms": 50,
+		"go.statistics.parse":    true})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer p.Close()
+
+	tmout := time.After(5 * time.Second)
+	for {
+		select {
+		case ev := <-p.Events():
+			e, ok := ev.(*StatsEvent)
+			if !ok {
+				t.Logf("Ignored event: %v", ev)
+				continue
+			}
+			if e.Statistics.Name != p.String() || e.Statistics.Type != "producer" {
+				t.Errorf("Unexpected statistics: %+v", e.Statistics)
+			}
+			if e.Statistics.Brokers == nil || e.Statistics.Topics == nil {
+				t.Errorf("Expected brokers and topics to be parsed: %+v", e.Statistics)
+			}
+			return
+		case <-tmout:
+			t.Fatalf("Timed out waiting for StatsEvent")
+		}
+	}
+}
+
+// TestStatisticsCallback dry-tests the go.statistics.callback configuration
+// property, no broker is needed.
+func TestStatisticsCallback(t *testing.T) {
+	statsChan := make(chan *Statistics, 100)
+
+	p, err := NewProducer(&ConfigMap{
+		"statistics.interval.ms": 50,
+		"go.statistics.parse":    true,
+		"go.statistics.callback": func(stats *Statistics) {
+			select {
+			case statsChan <- stats:
+			default:
+			}
+		}})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	select {
+	case stats := <-statsChan:
+		if stats.Name != p.String() || stats.Type != "producer" || stats.Brokers == nil {
+			t.Errorf("Unexpected statistics: %+v", stats)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for statistics callback")
+	}
+
+	// No statistics events are emitted with the callback set
+	p.Close()
+	for ev := range p.Events() {
+		switch ev.(type) {
+		case *Stats, *StatsEvent:
+			t.Errorf("Unexpected event %v", ev)
+		}
+	}
+
+	_, err = NewProducer(&ConfigMap{"go.statistics.callback": func(string) {}})
+	if err == nil || err.(Error).Code() != ErrInvalidArg {
+		t.Errorf("Expected ErrInvalidArg for invalid go.statistics.callback, got %v", err)
+	}
+}
+
+// TestParseStatistics tests parsing of a statistics JSON document.
+func TestParseStatistics(t *testing.T) {
+	statsJSON := `{"name": "rdkafka#consumer-1", "type": "consumer", "msg_cnt": 3,
+"brokers": {"localhost:9092/1": {"name": "localhost:9092/1", "nodeid": 1,
+ "state": "UP", "rtt": {"avg": 1500, "p99": 3000, "cnt": 4},
+ "req": {"Fetch": 12}, "toppars": {"test-0": {"topic": "test", "partition": 0}}}},
+"topics": {"test": {"topic": "test", "partitions": {"0": {"partition": 0,
+ "leader": 1, "desired": true, "fetch_state": "active", "consumer_lag": 42}}}},
+"cgrp": {"state": "up", "join_state": "steady", "rebalance_cnt": 2,
+ "assignment_size": 1}}`
+
+	s, err := Stats{statsJSON}.Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse statistics: %s", err)
+	}
+
+	b := s.Brokers["localhost:9092/1"]
+	if s.MsgCnt != 3 || b.NodeID != 1 || b.State != "UP" ||
+		b.Rtt.P99 != 3000 || b.Req["Fetch"] != 12 ||
+		b.TopPars["test-0"].Topic != "test" {
+		t.Errorf("Unexpected broker statistics: %+v", b)
+	}
+
+	tp := s.Topics["test"].Partitions["0"]
+	if !tp.Desired || tp.FetchState != "active" || tp.ConsumerLag != 42 {
+		t.Errorf("Unexpected partition statistics: %+v", tp)
+	}
+
+	if s.ConsumerGroup == nil || s.ConsumerGroup.RebalanceCnt != 2 {
+		t.Errorf("Unexpected consumer group statistics: %+v", s.ConsumerGroup)
+	}
+
+	if s.EOS != nil {
+		t.Errorf("Expected no EOS statistics, got %+v", s.EOS)
+	}
+
+	_, err = ParseStatistics("{not json")
+	if err == nil || err.(Error).Code() != ErrBadMsg {
+		t.Errorf("Expected ErrBadMsg, got %v", err)
+	}
+}
@@ -0,0 +1,277 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// TableStore stores the latest value of each key of a Table.
+// Put() and Delete() are called from the Table's goroutine,
+// Get() from the application's goroutines.
+type TableStore interface {
+	// Put sets the value of key
+	Put(key string, value []byte) error
+	// Delete removes key
+	Delete(key string) error
+	// Get returns the value of key, and whether it was found
+	Get(key string) ([]byte, bool)
+}
+
+// memoryTableStore is the default in-memory TableStore.
+type memoryTableStore struct {
+	lock   sync.RWMutex
+	values map[string][]byte
+}
+
+// NewMemoryTableStore returns a TableStore keeping the values in memory.
+func NewMemoryTableStore() TableStore {
+	return &memoryTableStore{values: make(map[string][]byte)}
+}
+
+func (s *memoryTableStore) Put(key string, value []byte) error {
+	s.lock.Lock()
+	s.values[key] = value
+	s.lock.Unlock()
+	return nil
+}
+
+func (s *memoryTableStore) Delete(key string) error {
+	s.lock.Lock()
+	delete(s.values, key)
+	s.lock.Unlock()
+	return nil
+}
+
+func (s *memoryTableStore) Get(key string) ([]byte, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	value, found := s.values[key]
+	return value, found
+}
+
+// Table materializes a compacted topic as a table of the latest value of
+// each key: the topic is consumed from the beginning of all its partitions
+// into a TableStore, and the table is then kept updated until Close().
+//
+// Messages without a key are ignored, messages with a nil value
+// (tombstones) delete their key.
+type Table struct {
+	topic    string
+	c        *Consumer
+	store    TableStore
+	caughtUp chan struct{}
+	termChan chan bool
+	doneChan chan bool
+	// Fatal error that stopped the table, if any
+	err error
+}
+
+// NewTable creates a Table materializing topic into store, or into
+// memory if store is nil.
+//
+// conf is the configuration of the table's Consumer, which is assigned
+// all partitions of the topic without joining the consumer group,
+// group.id defaults to "kafka-go-table-<topic>".
+// Offsets are not committed, the table is rebuilt from the beginning of
+// the topic when created.
+//
+// The table is caught up once the messages up to the high watermarks of
+// the partitions at the time of the call were consumed, see CaughtUp().
+func NewTable(conf *ConfigMap, topic string, store TableStore) (*Table, error) {
+	confCopy := conf.clone()
+	if v, _ := confCopy.get("group.id", nil); v == nil {
+		confCopy.SetKey("group.id", "kafka-go-table-"+topic)
+	}
+	// Partition EOFs tell when compacted partitions are caught up
+	confCopy.SetKey("enable.partition.eof", true)
+	confCopy.SetKey("enable.auto.commit", false)
+	confCopy.SetKey("auto.offset.reset", "earliest")
+	// The store keeps the values
+	confCopy.SetKey("go.zerocopy.enable", false)
+
+	if store == nil {
+		store = NewMemoryTableStore()
+	}
+
+	c, err := NewConsumer(&confCopy)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Table{
+		topic:    topic,
+		c:        c,
+		store:    store,
+		caughtUp: make(chan struct{}),
+		termChan: make(chan bool),
+		doneChan: make(chan bool),
+	}
+
+	highs, err := t.assign()
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	go t.run(highs)
+
+	return t, nil
+}
+
+// assign assigns all partitions of the topic from the beginning.
+// Returns the partitions' high watermarks to catch up with.
+func (t *Table) assign() (map[int32]Offset, error) {
+	md, err := t.c.GetMetadata(&t.topic, false, 10000)
+	if err != nil {
+		return nil, err
+	}
+
+	tmd, ok := md.Topics[t.topic]
+	if !ok {
+		return nil, newErrorFromString(ErrUnknownTopic,
+			fmt.Sprintf("Topic %s not found", t.topic))
+	}
+	if tmd.Error.Code() != ErrNoError {
+		return nil, tmd.Error
+	}
+
+	highs := make(map[int32]Offset, len(tmd.Partitions))
+	partitions := make([]TopicPartition, 0, len(tmd.Partitions))
+	for _, p := range tmd.Partitions {
+		low, high, err := t.c.QueryWatermarkOffsets(t.topic, p.ID, 10000)
+		if err != nil {
+			return nil, err
+		}
+		if high > low {
+			highs[p.ID] = Offset(high)
+		}
+		partitions = append(partitions,
+			TopicPartition{Topic: &t.topic, Partition: p.ID, Offset: OffsetBeginning})
+	}
+
+	if err = t.c.Assign(partitions); err != nil {
+		return nil, err
+	}
+
+	return highs, nil
+}
+
+// run consumes the topic into the store until Close() or a fatal error.
+// highs holds the high watermarks of the partitions not yet caught up.
+func (t *Table) run(highs map[int32]Offset) {
+	defer close(t.doneChan)
+
+	if len(highs) == 0 {
+		close(t.caughtUp)
+	}
+
+	// caughtUpTo marks partition as caught up if offset reached its
+	// high watermark.
+	caughtUpTo := func(partition int32, offset Offset) {
+		high, found := highs[partition]
+		if !found || offset < high {
+			return
+		}
+		delete(highs, partition)
+		if len(highs) == 0 {
+			close(t.caughtUp)
+		}
+	}
+
+	for {
+		select {
+		case <-t.termChan:
+			return
+		default:
+		}
+
+		switch e := t.c.Poll(100).(type) {
+		case *Message:
+			if e.TopicPartition.Error != nil {
+				continue
+			}
+			if e.Key != nil {
+				var err error
+				if e.Value == nil {
+					err = t.store.Delete(string(e.Key))
+				} else {
+					err = t.store.Put(string(e.Key), e.Value)
+				}
+				if err != nil {
+					t.err = err
+					return
+				}
+			}
+			caughtUpTo(e.TopicPartition.Partition, e.TopicPartition.Offset+1)
+
+		case PartitionEOF:
+			caughtUpTo(e.Partition, e.Offset)
+
+		case Error:
+			if e.IsFatal() {
+				t.err = e
+				return
+			}
+		}
+	}
+}
+
+// Get returns the latest value of key, and whether it was found.
+func (t *Table) Get(key string) ([]byte, bool) {
+	return t.store.Get(key)
+}
+
+// CaughtUp returns a channel closed once the table is caught up with the
+// high watermarks of the topic's partitions at the time of NewTable().
+func (t *Table) CaughtUp() <-chan struct{} {
+	return t.caughtUp
+}
+
+// WaitCaughtUp blocks until the table is caught up, see CaughtUp().
+// Returns ctx.Err() if ctx is done first, or the fatal error that
+// stopped the table.
+func (t *Table) WaitCaughtUp(ctx context.Context) error {
+	select {
+	case <-t.caughtUp:
+		return nil
+	default:
+	}
+
+	select {
+	case <-t.caughtUp:
+		return nil
+	case <-t.doneChan:
+		if t.err != nil {
+			return t.err
+		}
+		return newErrorFromString(ErrDestroy, "Table closed")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops updating the table and closes its Consumer.
+// Returns the fatal error that stopped the table, if any.
+func (t *Table) Close() error {
+	close(t.termChan)
+	<-t.doneChan
+	t.c.Close()
+	return t.err
+}
@@ -0,0 +1,132 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestTable tests that a Table catches up with the latest value of each
+// key, and is then kept updated.
+func TestTable(t *testing.T) {
+	mc, err := NewMockCluster(1)
+	if err != nil {
+		t.Fatalf("Failed to create MockCluster: %v", err)
+	}
+	defer mc.Close()
+
+	topic := "table"
+	if err = mc.CreateTopic(topic, 3, 1); err != nil {
+		t.Fatalf("CreateTopic failed: %v", err)
+	}
+
+	p, err := NewProducer(&ConfigMap{"bootstrap.servers": mc.BootstrapServers()})
+	if err != nil {
+		t.Fatalf("Failed to create Producer: %v", err)
+	}
+	defer p.Close()
+
+	produce := func(key string, value []byte) {
+		drChan := make(chan Event, 1)
+		err := p.Produce(&Message{
+			TopicPartition: TopicPartition{Topic: &topic, Partition: PartitionAny},
+			Key:            []byte(key),
+			Value:          value,
+		}, drChan)
+		if err != nil {
+			t.Fatalf("Produce failed: %v", err)
+		}
+		if m := (<-drChan).(*Message); m.TopicPartition.Error != nil {
+			t.Fatalf("Delivery failed: %v", m.TopicPartition)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		for k := 0; k < 10; k++ {
+			produce(fmt.Sprintf("key-%d", k), []byte(fmt.Sprintf("value-%d-%d", k, i)))
+		}
+	}
+	produce("key-0", nil)
+
+	table, err := NewTable(&ConfigMap{"bootstrap.servers": mc.BootstrapServers()}, topic, nil)
+	if err != nil {
+		t.Fatalf("NewTable failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err = table.WaitCaughtUp(ctx); err != nil {
+		t.Fatalf("WaitCaughtUp failed: %v", err)
+	}
+
+	if value, found := table.Get("key-0"); found {
+		t.Errorf("Expected key-0 to be deleted, not %s", value)
+	}
+	for k := 1; k < 10; k++ {
+		expected := fmt.Sprintf("value-%d-2", k)
+		if value, found := table.Get(fmt.Sprintf("key-%d", k)); !found || string(value) != expected {
+			t.Errorf("Expected key-%d value %s, not %s (%v)", k, expected, value, found)
+		}
+	}
+
+	// Updates after catching up
+	produce("key-1", []byte("updated"))
+	for tEnd := time.Now().Add(10 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if value, _ := table.Get("key-1"); string(value) == "updated" {
+			break
+		}
+		if time.Now().After(tEnd) {
+			t.Fatalf("Timed out waiting for the update")
+		}
+	}
+
+	if err = table.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	if err = table.WaitCaughtUp(ctx); err != nil {
+		t.Errorf("Expected the table to remain caught up, not %v", err)
+	}
+}
+
+// TestTableEmpty tests that a Table of an empty topic is caught up at once.
+func TestTableEmpty(t *testing.T) {
+	mc, err := NewMockCluster(1)
+	if err != nil {
+		t.Fatalf("Failed to create MockCluster: %v", err)
+	}
+	defer mc.Close()
+
+	topic := "emptytable"
+	if err = mc.CreateTopic(topic, 2, 1); err != nil {
+		t.Fatalf("CreateTopic failed: %v", err)
+	}
+
+	table, err := NewTable(&ConfigMap{"bootstrap.servers": mc.BootstrapServers()}, topic, NewMemoryTableStore())
+	if err != nil {
+		t.Fatalf("NewTable failed: %v", err)
+	}
+	defer table.Close()
+
+	select {
+	case <-table.CaughtUp():
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Expected the empty table to be caught up")
+	}
+}
@@ -0,0 +1,182 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"fmt"
+	"time"
+)
+
+// ThrottleQuota is the type of broker quota that caused throttling
+type ThrottleQuota int
+
+const (
+	// ThrottleQuotaUnknown - the quota type is not known
+	ThrottleQuotaUnknown ThrottleQuota = iota
+	// ThrottleQuotaProduce - producer byte-rate quota
+	ThrottleQuotaProduce
+	// ThrottleQuotaFetch - consumer byte-rate quota
+	ThrottleQuotaFetch
+)
+
+func (q ThrottleQuota) String() string {
+	switch q {
+	case ThrottleQuotaProduce:
+		return "produce"
+	case ThrottleQuotaFetch:
+		return "fetch"
+	default:
+		return "unknown"
+	}
+}
+
+// ThrottleEvent is emitted when a broker throttles the client's requests
+// due to a quota violation, and once more, with a zero ThrottleTime,
+// when the broker stops throttling.
+//
+// Throttle events are enabled by setting the `go.throttle.events`
+// configuration property to true. Since throttling is detected from the
+// client statistics, throttle events also require `statistics.interval.ms`
+// to be set, and are emitted at most once per broker and statistics interval.
+type ThrottleEvent struct {
+	// Broker name, "host:port/id"
+	Broker string
+	// Broker id
+	BrokerID int32
+	// ThrottleTime is the maximum throttle time reported by the broker
+	// during the last statistics interval
+	ThrottleTime time.Duration
+	// Quota is the type of quota violated.
+	// The broker does not report the quota type, it is derived from the
+	// client type: producers are subject to the produce quota and consumers
+	// to the fetch quota. Request rate quota violations are reported as
+	// the same quota type.
+	Quota ThrottleQuota
+}
+
+func (e ThrottleEvent) String() string {
+	return fmt.Sprintf("ThrottleEvent: %s throttled for %v (%s quota)",
+		e.Broker, e.ThrottleTime, e.Quota)
+}
+
+// ThrottleBackoffPolicy returns the time to back off producing or consuming
+// for when a broker throttles the client, see `go.throttle.backoff`.
+//
+// While backing off, Producer.Produce() and the ProduceChannel() producer
+// block and Consumer.Poll() returns no events until the backoff time has
+// passed, or the client is closed, slowing the producer and consumer down
+// to the rate allowed by the quota.
+//
+// The backoff adds to librdkafka's own handling of throttling, which
+// already delays the requests to the throttling broker: it limits the
+// messages queued in the meantime, at the cost of a slower client.
+type ThrottleBackoffPolicy func(ev ThrottleEvent) time.Duration
+
+// ThrottleBackoffTime is the default ThrottleBackoffPolicy, backing off
+// for the throttle time reported by the broker.
+func ThrottleBackoffTime(ev ThrottleEvent) time.Duration {
+	return ev.ThrottleTime
+}
+
+// throttleQuota returns the quota the client is subject to.
+func (h *handle) throttleQuota() ThrottleQuota {
+	switch {
+	case h.p != nil:
+		return ThrottleQuotaProduce
+	case h.c != nil:
+		return ThrottleQuotaFetch
+	default:
+		return ThrottleQuotaUnknown
+	}
+}
+
+// updateThrottleStates enqueues ThrottleEvent events for brokers that
+// throttled the client in stats, or stopped throttling since the
+// previous statistics, and applies the throttle backoff policy.
+func (h *handle) updateThrottleStates(stats *Statistics) {
+	h.brokerStateLock.Lock()
+	defer h.brokerStateLock.Unlock()
+
+	for _, b := range stats.Brokers {
+		if b.Source == "logical" || b.Source == "internal" {
+			continue
+		}
+
+		throttled := b.Throttle.Max > 0
+		if throttled || h.brokersThrottled[b.Name] {
+			ev := ThrottleEvent{
+				Broker:       b.Name,
+				BrokerID:     b.NodeID,
+				ThrottleTime: time.Duration(b.Throttle.Max) * time.Millisecond,
+				Quota:        h.throttleQuota(),
+			}
+
+			if h.throttleEvents {
+				h.pendingEvents = append(h.pendingEvents, ev)
+			}
+
+			if h.throttleBackoff != nil {
+				until := time.Now().Add(h.throttleBackoff(ev))
+				if until.After(h.throttleUntil) {
+					h.throttleUntil = until
+				}
+			}
+		}
+		h.brokersThrottled[b.Name] = throttled
+	}
+}
+
+// waitThrottleBackoff blocks while the client is backing off due to
+// throttling, for at most timeoutMs (-1 for indefinitely), or until
+// termChan is closed.
+// Returns the remaining timeout, and false if the timeout expired or
+// termChan was closed while backing off.
+func (h *handle) waitThrottleBackoff(timeoutMs int, termChan chan bool) (int, bool) {
+	if h.throttleBackoff == nil {
+		return timeoutMs, true
+	}
+
+	h.brokerStateLock.Lock()
+	backoff := time.Until(h.throttleUntil)
+	h.brokerStateLock.Unlock()
+
+	if backoff <= 0 {
+		return timeoutMs, true
+	}
+
+	expired := false
+	if timeoutMs >= 0 {
+		timeout := time.Duration(timeoutMs) * time.Millisecond
+		if backoff >= timeout {
+			backoff = timeout
+			expired = true
+			timeoutMs = 0
+		} else {
+			timeoutMs -= int(backoff / time.Millisecond)
+		}
+	}
+
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return timeoutMs, !expired
+	case <-termChan:
+		return 0, false
+	}
+}
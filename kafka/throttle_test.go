@@ -0,0 +1,115 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"testing"
+	"time"
+)
+
+// TestThrottleEvents tests ThrottleEvent generation from statistics
+func TestThrottleEvents(t *testing.T) {
+	h := &handle{throttleEvents: true, brokersThrottled: make(map[string]bool)}
+
+	stats := func(maxThrottle int64) *Statistics {
+		return &Statistics{Brokers: map[string]BrokerStatistics{
+			"b1": {Name: "localhost:9092/1", NodeID: 1, Source: "learned",
+				Throttle: WindowStatistics{Max: maxThrottle}},
+			"internal": {Name: ":0/internal", NodeID: -1, Source: "internal",
+				Throttle: WindowStatistics{Max: maxThrottle}},
+		}}
+	}
+
+	for _, maxThrottle := range []int64{0, 150, 20, 0, 0} {
+		h.updateThrottleStates(stats(maxThrottle))
+	}
+
+	expected := []time.Duration{150 * time.Millisecond, 20 * time.Millisecond, 0}
+	for _, exp := range expected {
+		ev, ok := h.popPendingEvent().(ThrottleEvent)
+		if !ok || ev.BrokerID != 1 || ev.ThrottleTime != exp {
+			t.Errorf("Expected ThrottleEvent for broker 1 with %v, got %v", exp, ev)
+		}
+	}
+
+	if ev := h.popPendingEvent(); ev != nil {
+		t.Errorf("Unexpected event %v", ev)
+	}
+
+	// Backoff without events
+	h = &handle{p: &Producer{}, brokersThrottled: make(map[string]bool)}
+	var quota ThrottleQuota
+	h.throttleBackoff = func(ev ThrottleEvent) time.Duration {
+		quota = ev.Quota
+		return 2 * ev.ThrottleTime
+	}
+	h.updateThrottleStates(stats(100))
+
+	if quota != ThrottleQuotaProduce {
+		t.Errorf("Expected produce quota, got %v", quota)
+	}
+	if ev := h.popPendingEvent(); ev != nil {
+		t.Errorf("Unexpected event %v", ev)
+	}
+
+	if remaining, ok := h.waitThrottleBackoff(50, nil); ok || remaining != 0 {
+		t.Errorf("Expected timeout while backing off, got %d, %v", remaining, ok)
+	}
+
+	start := time.Now()
+	if _, ok := h.waitThrottleBackoff(-1, nil); !ok {
+		t.Errorf("Expected backoff to complete")
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("Expected to back off for ~150ms, backed off %v", elapsed)
+	}
+
+	if remaining, ok := h.waitThrottleBackoff(10, nil); !ok || remaining != 10 {
+		t.Errorf("Expected no backoff, got %d, %v", remaining, ok)
+	}
+
+	// Closing termChan interrupts the backoff
+	h.updateThrottleStates(stats(5000))
+	termChan := make(chan bool)
+	time.AfterFunc(10*time.Millisecond, func() { close(termChan) })
+	start = time.Now()
+	if _, ok := h.waitThrottleBackoff(-1, termChan); ok {
+		t.Errorf("Expected backoff to be interrupted")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected backoff to be interrupted after ~10ms, backed off %v", elapsed)
+	}
+}
+
+// TestThrottleConfig tests go.throttle.* configuration properties
+func TestThrottleConfig(t *testing.T) {
+	events, backoff, err := (ConfigMap{"go.throttle.events": true, "go.throttle.backoff": true}).extractThrottleConfig()
+	if err != nil || !events || backoff == nil {
+		t.Errorf("Expected events and default backoff, got %v, %v, %v", events, backoff, err)
+	}
+
+	policy := func(ev ThrottleEvent) time.Duration { return time.Second }
+	_, backoff, err = (ConfigMap{"go.throttle.backoff": policy}).extractThrottleConfig()
+	if err != nil || backoff == nil || backoff(ThrottleEvent{}) != time.Second {
+		t.Errorf("Expected custom backoff policy, got %v", err)
+	}
+
+	_, _, err = (ConfigMap{"go.throttle.backoff": "yes"}).extractThrottleConfig()
+	if err == nil || err.(Error).Code() != ErrInvalidArg {
+		t.Errorf("Expected ErrInvalidArg, got %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"sync/atomic"
+	"unsafe"
+)
+
+/*
+#include "select_rdkafka.h"
+#include "glue_rdkafka.h"
+*/
+import "C"
+
+// zeroCopyBuf refers to the librdkafka fetch event owning the key and
+// value buffers of a zero-copy Message, see `go.zerocopy.enable`.
+type zeroCopyBuf struct {
+	h    *handle
+	rkev *C.rd_kafka_event_t
+	// Message position for leak reports
+	topic     string
+	partition int32
+	offset    Offset
+}
+
+// newZeroCopyBuf takes ownership of the fetch event until the buffer is
+// released, or the consumer closed.
+func (h *handle) newZeroCopyBuf(ev *C.glue_ev_t) *zeroCopyBuf {
+	rkev := ev.rkev
+	ev.rkev = nil
+
+	cmsg := ev.gMsg.msg
+	zc := &zeroCopyBuf{h: h, rkev: rkev,
+		topic:     h.getTopicNameFromRkt(cmsg.rkt),
+		partition: int32(cmsg.partition),
+		offset:    Offset(cmsg.offset)}
+
+	h.zeroCopyLock.Lock()
+	h.zeroCopyEvents[rkev] = true
+	h.zeroCopyLock.Unlock()
+
+	runtime.SetFinalizer(zc, (*zeroCopyBuf).leaked)
+
+	return zc
+}
+
+// release destroys the fetch event, unless already destroyed
+// by the consumer closing.
+func (zc *zeroCopyBuf) release() {
+	runtime.SetFinalizer(zc, nil)
+
+	zc.h.zeroCopyLock.Lock()
+	if zc.h.zeroCopyEvents[zc.rkev] {
+		delete(zc.h.zeroCopyEvents, zc.rkev)
+		C.rd_kafka_event_destroy(zc.rkev)
+	}
+	zc.h.zeroCopyLock.Unlock()
+}
+
+// leaked is the finalizer of buffers garbage collected without being
+// released. The fetch event is not destroyed, since the key or value
+// may still be referenced, but is left for the consumer to destroy
+// when closed.
+func (zc *zeroCopyBuf) leaked() {
+	zc.h.zeroCopyLock.Lock()
+	unreleased := zc.h.zeroCopyEvents[zc.rkev]
+	zc.h.zeroCopyLock.Unlock()
+
+	if !unreleased {
+		return
+	}
+
+	if atomic.AddInt64(&zc.h.zeroCopyLeaks, 1) == 1 {
+		fmt.Fprintf(os.Stderr, "%s: zero-copy message %s[%d]@%s was not released, "+
+			"further leaks are counted by ZeroCopyLeaks()\n",
+			zc.h.name, zc.topic, zc.partition, zc.offset)
+	}
+}
+
+// releaseZeroCopyEvents destroys the fetch events of all unreleased
+// zero-copy messages, the client instance can't be destroyed until they are.
+func (h *handle) releaseZeroCopyEvents() {
+	h.zeroCopyLock.Lock()
+	for rkev := range h.zeroCopyEvents {
+		C.rd_kafka_event_destroy(rkev)
+	}
+	h.zeroCopyEvents = make(map[*C.rd_kafka_event_t]bool)
+	h.zeroCopyLock.Unlock()
+}
+
+// zeroCopyBytes returns a slice referring to, not copying, the C buffer.
+func zeroCopyBytes(ptr unsafe.Pointer, size C.size_t) []byte {
+	return (*[1 << 30]byte)(ptr)[:size:size]
+}
+
+// Release releases the librdkafka buffers the Key and Value of a message
+// consumed with `go.zerocopy.enable` refer to.
+// Key and Value are set to nil, and neither they nor any slice of them may
+// be used after Release(). The Key and Value of messages that are not
+// zero-copy are not affected.
+//
+// Headers is set to nil. The headers of messages consumed with
+// `go.headers.lazy` are pooled for reuse by subsequently consumed messages:
+// every Header, and Header.Value, previously returned by GetHeaders() for
+// the message or any copy of it becomes invalid and may be overwritten.
+// Other messages' headers are not pooled and remain valid.
+func (m *Message) Release() {
+	if m.lazyHeaders != nil {
+		m.lazyHeaders.release(true)
+		m.lazyHeaders = nil
+	}
+	m.releaseBuffers()
+	m.Headers = nil
+}
+
+// releaseBuffers releases the zero-copy buffers and unparsed headers.
+// Parsed lazy headers are not pooled.
+func (m *Message) releaseBuffers() {
+	if m.lazyHeaders != nil {
+		m.lazyHeaders.release(false)
+		m.lazyHeaders = nil
+	}
+
+	if m.zeroCopy == nil {
+		return
+	}
+
+	m.zeroCopy.release()
+	m.zeroCopy = nil
+	m.Key = nil
+	m.Value = nil
+}
+
+// ZeroCopyLeaks returns the number of zero-copy messages that were
+// garbage collected without being released, see `go.zerocopy.enable`.
+func (c *Consumer) ZeroCopyLeaks() int {
+	return int(atomic.LoadInt64(&c.handle.zeroCopyLeaks))
+}
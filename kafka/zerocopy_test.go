@@ -0,0 +1,117 @@
+package kafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// TestZeroCopy tests consuming zero-copy messages, their release
+// and leak detection.
+func TestZeroCopy(t *testing.T) {
+	mc, err := NewMockCluster(1)
+	if err != nil {
+		t.Fatalf("Failed to create MockCluster: %v", err)
+	}
+	defer mc.Close()
+
+	topic := "zerocopy"
+	msgcnt := 10
+
+	p, err := NewProducer(&ConfigMap{"bootstrap.servers": mc.BootstrapServers()})
+	if err != nil {
+		t.Fatalf("Failed to create Producer: %v", err)
+	}
+	defer p.Close()
+
+	drChan := make(chan Event, msgcnt)
+	for i := 0; i < msgcnt; i++ {
+		err = p.Produce(&Message{
+			TopicPartition: TopicPartition{Topic: &topic, Partition: 0},
+			Key:            []byte(fmt.Sprintf("key%d", i)),
+			Value:          []byte(fmt.Sprintf("value%d", i)),
+		}, drChan)
+		if err != nil {
+			t.Fatalf("Produce failed: %v", err)
+		}
+	}
+
+	for i := 0; i < msgcnt; i++ {
+		if m := (<-drChan).(*Message); m.TopicPartition.Error != nil {
+			t.Fatalf("Delivery failed: %v", m.TopicPartition)
+		}
+	}
+
+	c, err := NewConsumer(&ConfigMap{
+		"bootstrap.servers":  mc.BootstrapServers(),
+		"group.id":           "zerocopy",
+		"enable.auto.commit": false,
+		"auto.offset.reset":  "earliest",
+		"go.zerocopy.enable": true})
+	if err != nil {
+		t.Fatalf("Failed to create Consumer: %v", err)
+	}
+
+	if err = c.Assign([]TopicPartition{{Topic: &topic, Partition: 0}}); err != nil {
+		t.Fatalf("Assign failed: %v", err)
+	}
+
+	for i := 0; i < msgcnt; i++ {
+		m, err := c.ReadMessage(10 * time.Second)
+		if err != nil {
+			t.Fatalf("ReadMessage failed: %v", err)
+		}
+
+		if string(m.Key) != fmt.Sprintf("key%d", i) ||
+			string(m.Value) != fmt.Sprintf("value%d", i) {
+			t.Fatalf("Unexpected message %v", m)
+		}
+
+		if i == 0 {
+			clone := m.Clone()
+			m.Release()
+			if m.Key != nil || m.Value != nil {
+				t.Errorf("Expected Key and Value to be nil after Release()")
+			}
+			if string(clone.Value) != "value0" {
+				t.Errorf("Expected clone to not be released, got %v", clone)
+			}
+			// Releasing twice and releasing a clone are no-ops
+			m.Release()
+			clone.Release()
+		} else if i%2 == 0 {
+			m.Release()
+		}
+	}
+
+	// The odd messages are leaked
+	tEnd := time.Now().Add(10 * time.Second)
+	for c.ZeroCopyLeaks() < msgcnt/2 && time.Now().Before(tEnd) {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if c.ZeroCopyLeaks() != msgcnt/2 {
+		t.Errorf("Expected %d leaks, got %d", msgcnt/2, c.ZeroCopyLeaks())
+	}
+
+	// Close destroys the leaked messages' buffers
+	c.Close()
+}
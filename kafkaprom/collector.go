@@ -0,0 +1,346 @@
+// Package kafkaprom provides a Prometheus collector exporting
+// confluent-kafka-go client statistics.
+//
+// The application enables statistics on its clients by setting
+// `statistics.interval.ms`, and passes the statistics events it receives
+// to the Collector:
+//
+//	collector := kafkaprom.NewCollector("")
+//	prometheus.MustRegister(collector)
+//
+//	p, err := kafka.NewProducer(&kafka.ConfigMap{
+//		"bootstrap.servers":      broker,
+//		"statistics.interval.ms": 5000})
+//	...
+//	for ev := range p.Events() {
+//		if collector.HandleEvent(ev) {
+//			continue
+//		}
+//		...
+//	}
+package kafkaprom
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Label names
+var (
+	clientLabels    = []string{"client", "client_id", "type"}
+	brokerLabels    = append(append([]string{}, clientLabels...), "broker", "broker_id")
+	partitionLabels = append(append([]string{}, clientLabels...), "topic", "partition")
+)
+
+// Latency quantiles exported from librdkafka's window statistics
+var windowQuantiles = []float64{0.5, 0.75, 0.9, 0.95, 0.99, 0.9999}
+
+// Collector is a prometheus.Collector exporting the most recent
+// statistics of one or more Kafka clients, see NewCollector().
+//
+// The Collector is safe for concurrent use.
+type Collector struct {
+	lock sync.Mutex
+	// Most recent statistics per client instance name
+	stats map[string]*kafka.Statistics
+
+	// Client metrics
+	msgCnt     *prometheus.Desc
+	msgSize    *prometheus.Desc
+	replyQ     *prometheus.Desc
+	txRequests *prometheus.Desc
+	txBytes    *prometheus.Desc
+	rxRequests *prometheus.Desc
+	rxBytes    *prometheus.Desc
+	txMsgs     *prometheus.Desc
+	txMsgBytes *prometheus.Desc
+	rxMsgs     *prometheus.Desc
+	rxMsgBytes *prometheus.Desc
+
+	// Broker metrics
+	brokerUp            *prometheus.Desc
+	brokerOutbufCnt     *prometheus.Desc
+	brokerWaitrespCnt   *prometheus.Desc
+	brokerTxErrs        *prometheus.Desc
+	brokerTxRetries     *prometheus.Desc
+	brokerRxErrs        *prometheus.Desc
+	brokerReqTimeouts   *prometheus.Desc
+	brokerConnects      *prometheus.Desc
+	brokerDisconnects   *prometheus.Desc
+	brokerRtt           *prometheus.Desc
+	brokerIntLatency    *prometheus.Desc
+	brokerOutbufLatency *prometheus.Desc
+	brokerThrottle      *prometheus.Desc
+
+	// Partition metrics
+	partitionMsgqCnt     *prometheus.Desc
+	partitionXmitMsgqCnt *prometheus.Desc
+	partitionConsumerLag *prometheus.Desc
+	partitionTxMsgs      *prometheus.Desc
+	partitionRxMsgs      *prometheus.Desc
+
+	// Consumer group metrics
+	cgrpRebalanceCnt   *prometheus.Desc
+	cgrpAssignmentSize *prometheus.Desc
+}
+
+// NewCollector creates a new Collector with metric names prefixed by
+// namespace, which defaults to "kafka" if empty.
+func NewCollector(namespace string) *Collector {
+	if namespace == "" {
+		namespace = "kafka"
+	}
+
+	desc := func(subsystem, name, help string, labels []string) *prometheus.Desc {
+		return prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, name),
+			help, labels, nil)
+	}
+
+	return &Collector{
+		stats: make(map[string]*kafka.Statistics),
+
+		msgCnt: desc("client", "queue_messages",
+			"Number of messages currently in the producer queues.", clientLabels),
+		msgSize: desc("client", "queue_bytes",
+			"Total size of messages currently in the producer queues.", clientLabels),
+		replyQ: desc("client", "reply_queue_events",
+			"Number of ops (callbacks, events, etc) waiting in the queue for the application to serve.", clientLabels),
+		txRequests: desc("client", "tx_requests_total",
+			"Total number of requests sent to brokers.", clientLabels),
+		txBytes: desc("client", "tx_bytes_total",
+			"Total number of bytes transmitted to brokers.", clientLabels),
+		rxRequests: desc("client", "rx_responses_total",
+			"Total number of responses received from brokers.", clientLabels),
+		rxBytes: desc("client", "rx_bytes_total",
+			"Total number of bytes received from brokers.", clientLabels),
+		txMsgs: desc("client", "tx_messages_total",
+			"Total number of messages transmitted (produced) to brokers.", clientLabels),
+		txMsgBytes: desc("client", "tx_message_bytes_total",
+			"Total number of message bytes (including framing) transmitted to brokers.", clientLabels),
+		rxMsgs: desc("client", "rx_messages_total",
+			"Total number of messages consumed, not including ignored messages, from brokers.", clientLabels),
+		rxMsgBytes: desc("client", "rx_message_bytes_total",
+			"Total number of message bytes (including framing) received from brokers.", clientLabels),
+
+		brokerUp: desc("broker", "up",
+			"Whether the broker connection is up (1) or not (0).", brokerLabels),
+		brokerOutbufCnt: desc("broker", "outbuf_requests",
+			"Number of requests awaiting transmission to the broker.", brokerLabels),
+		brokerWaitrespCnt: desc("broker", "waitresp_requests",
+			"Number of requests in-flight to the broker awaiting response.", brokerLabels),
+		brokerTxErrs: desc("broker", "tx_errors_total",
+			"Total number of transmission errors.", brokerLabels),
+		brokerTxRetries: desc("broker", "tx_retries_total",
+			"Total number of request retries.", brokerLabels),
+		brokerRxErrs: desc("broker", "rx_errors_total",
+			"Total number of receive errors.", brokerLabels),
+		brokerReqTimeouts: desc("broker", "request_timeouts_total",
+			"Total number of requests timed out.", brokerLabels),
+		brokerConnects: desc("broker", "connects_total",
+			"Total number of connection attempts.", brokerLabels),
+		brokerDisconnects: desc("broker", "disconnects_total",
+			"Total number of disconnects.", brokerLabels),
+		brokerRtt: desc("broker", "rtt_seconds",
+			"Broker round-trip time.", brokerLabels),
+		brokerIntLatency: desc("broker", "internal_latency_seconds",
+			"Internal producer queue latency.", brokerLabels),
+		brokerOutbufLatency: desc("broker", "outbuf_latency_seconds",
+			"Internal request queue latency.", brokerLabels),
+		brokerThrottle: desc("broker", "throttle_seconds",
+			"Broker throttling time.", brokerLabels),
+
+		partitionMsgqCnt: desc("partition", "queue_messages",
+			"Number of messages waiting to be produced in the first-level queue.", partitionLabels),
+		partitionXmitMsgqCnt: desc("partition", "xmit_queue_messages",
+			"Number of messages ready to be produced in the transmit queue.", partitionLabels),
+		partitionConsumerLag: desc("partition", "consumer_lag",
+			"Difference between the partition's high watermark offset and the committed offset.", partitionLabels),
+		partitionTxMsgs: desc("partition", "tx_messages_total",
+			"Total number of messages transmitted (produced).", partitionLabels),
+		partitionRxMsgs: desc("partition", "rx_messages_total",
+			"Total number of messages consumed, not including ignored messages.", partitionLabels),
+
+		cgrpRebalanceCnt: desc("consumer_group", "rebalances_total",
+			"Total number of rebalances (assign or revoke).", clientLabels),
+		cgrpAssignmentSize: desc("consumer_group", "assigned_partitions",
+			"Current assignment's partition count.", clientLabels),
+	}
+}
+
+// Update sets the most recent statistics of a client instance,
+// replacing the previous statistics of that instance.
+func (c *Collector) Update(stats *kafka.Statistics) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.stats[stats.Name] = stats
+}
+
+// Remove the statistics of a client instance, e.g., after the client
+// has been closed.
+func (c *Collector) Remove(name string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.stats, name)
+}
+
+// HandleEvent updates the Collector from *kafka.Stats and
+// *kafka.StatsEvent events and returns true, or returns false
+// for any other event.
+func (c *Collector) HandleEvent(ev kafka.Event) bool {
+	switch e := ev.(type) {
+	case *kafka.StatsEvent:
+		c.Update(e.Statistics)
+	case *kafka.Stats:
+		stats, err := e.Parse()
+		if err == nil {
+			c.Update(stats)
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		c.msgCnt, c.msgSize, c.replyQ,
+		c.txRequests, c.txBytes, c.rxRequests, c.rxBytes,
+		c.txMsgs, c.txMsgBytes, c.rxMsgs, c.rxMsgBytes,
+		c.brokerUp, c.brokerOutbufCnt, c.brokerWaitrespCnt,
+		c.brokerTxErrs, c.brokerTxRetries, c.brokerRxErrs,
+		c.brokerReqTimeouts, c.brokerConnects, c.brokerDisconnects,
+		c.brokerRtt, c.brokerIntLatency, c.brokerOutbufLatency,
+		c.brokerThrottle,
+		c.partitionMsgqCnt, c.partitionXmitMsgqCnt,
+		c.partitionConsumerLag, c.partitionTxMsgs, c.partitionRxMsgs,
+		c.cgrpRebalanceCnt, c.cgrpAssignmentSize,
+	} {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, s := range c.stats {
+		c.collectClient(ch, s)
+	}
+}
+
+// collectClient emits the metrics of a single client instance.
+func (c *Collector) collectClient(ch chan<- prometheus.Metric, s *kafka.Statistics) {
+	labels := []string{s.Name, s.ClientID, s.Type}
+
+	gauge := func(desc *prometheus.Desc, v int64, labels []string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue,
+			float64(v), labels...)
+	}
+	counter := func(desc *prometheus.Desc, v int64, labels []string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue,
+			float64(v), labels...)
+	}
+
+	gauge(c.msgCnt, s.MsgCnt, labels)
+	gauge(c.msgSize, s.MsgSize, labels)
+	gauge(c.replyQ, s.ReplyQ, labels)
+	counter(c.txRequests, s.Tx, labels)
+	counter(c.txBytes, s.TxBytes, labels)
+	counter(c.rxRequests, s.Rx, labels)
+	counter(c.rxBytes, s.RxBytes, labels)
+	counter(c.txMsgs, s.TxMsgs, labels)
+	counter(c.txMsgBytes, s.TxMsgBytes, labels)
+	counter(c.rxMsgs, s.RxMsgs, labels)
+	counter(c.rxMsgBytes, s.RxMsgBytes, labels)
+
+	for _, b := range s.Brokers {
+		// Skip logical brokers, such as the GroupCoordinator,
+		// since they duplicate the metrics of a real broker.
+		if b.Source == "logical" || b.Source == "internal" {
+			continue
+		}
+
+		bLabels := append(append([]string{}, labels...),
+			b.NodeName, strconv.Itoa(int(b.NodeID)))
+
+		up := int64(0)
+		if b.State == "UP" {
+			up = 1
+		}
+		gauge(c.brokerUp, up, bLabels)
+		gauge(c.brokerOutbufCnt, b.OutbufCnt, bLabels)
+		gauge(c.brokerWaitrespCnt, b.WaitrespCnt, bLabels)
+		counter(c.brokerTxErrs, b.TxErrs, bLabels)
+		counter(c.brokerTxRetries, b.TxRetries, bLabels)
+		counter(c.brokerRxErrs, b.RxErrs, bLabels)
+		counter(c.brokerReqTimeouts, b.ReqTimeouts, bLabels)
+		counter(c.brokerConnects, b.Connects, bLabels)
+		counter(c.brokerDisconnects, b.Disconnects, bLabels)
+
+		ch <- windowSummary(c.brokerRtt, b.Rtt, 1e-6, bLabels)
+		ch <- windowSummary(c.brokerIntLatency, b.IntLatency, 1e-6, bLabels)
+		ch <- windowSummary(c.brokerOutbufLatency, b.OutbufLatency, 1e-6, bLabels)
+		ch <- windowSummary(c.brokerThrottle, b.Throttle, 1e-3, bLabels)
+	}
+
+	for _, t := range s.Topics {
+		for _, p := range t.Partitions {
+			// Skip the internal UnAssigned partition
+			if p.Partition < 0 {
+				continue
+			}
+
+			pLabels := append(append([]string{}, labels...),
+				t.Topic, strconv.Itoa(int(p.Partition)))
+
+			gauge(c.partitionMsgqCnt, p.MsgqCnt, pLabels)
+			gauge(c.partitionXmitMsgqCnt, p.XmitMsgqCnt, pLabels)
+			counter(c.partitionTxMsgs, p.TxMsgs, pLabels)
+			counter(c.partitionRxMsgs, p.RxMsgs, pLabels)
+			// The consumer lag is -1 when unknown
+			if p.ConsumerLag >= 0 {
+				gauge(c.partitionConsumerLag, p.ConsumerLag, pLabels)
+			}
+		}
+	}
+
+	if s.ConsumerGroup != nil {
+		counter(c.cgrpRebalanceCnt, s.ConsumerGroup.RebalanceCnt, labels)
+		gauge(c.cgrpAssignmentSize, s.ConsumerGroup.AssignmentSize, labels)
+	}
+}
+
+// windowSummary converts window statistics to a summary metric,
+// scaling values by scale to seconds.
+func windowSummary(desc *prometheus.Desc, w kafka.WindowStatistics, scale float64, labels []string) prometheus.Metric {
+	values := []int64{w.P50, w.P75, w.P90, w.P95, w.P99, w.P99_99}
+	quantiles := make(map[float64]float64, len(values))
+	for i, v := range values {
+		quantiles[windowQuantiles[i]] = float64(v) * scale
+	}
+
+	return prometheus.MustNewConstSummary(desc, uint64(w.Cnt),
+		float64(w.Sum)*scale, quantiles, labels...)
+}
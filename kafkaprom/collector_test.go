@@ -0,0 +1,107 @@
+package kafkaprom
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const testStatsJSON = `{"name": "rdkafka#consumer-1", "client_id": "rdkafka",
+"type": "consumer", "rx": 10, "rxmsgs": 5,
+"brokers": {"localhost:9092/1": {"name": "localhost:9092/1", "nodeid": 1,
+ "nodename": "localhost:9092", "source": "configured", "state": "UP",
+ "rtt": {"p50": 1000, "p99": 3000, "sum": 8000, "cnt": 4}},
+ "GroupCoordinator": {"name": "GroupCoordinator", "nodeid": 1,
+ "source": "logical", "state": "UP"}},
+"topics": {"test": {"topic": "test", "partitions": {
+ "0": {"partition": 0, "consumer_lag": 42, "rxmsgs": 5},
+ "-1": {"partition": -1, "consumer_lag": -1}}}},
+"cgrp": {"state": "up", "rebalance_cnt": 2, "assignment_size": 1}}`
+
+// TestCollector tests that statistics are exported as metrics.
+func TestCollector(t *testing.T) {
+	collector := NewCollector("")
+
+	if collector.HandleEvent(kafka.NewError(kafka.ErrTransport, "", false)) {
+		t.Errorf("Expected non-stats event to not be handled")
+	}
+
+	stats, err := kafka.ParseStatistics(testStatsJSON)
+	if err != nil {
+		t.Fatalf("Failed to parse statistics: %s", err)
+	}
+
+	if !collector.HandleEvent(&kafka.StatsEvent{Statistics: stats}) {
+		t.Fatalf("Expected stats event to be handled")
+	}
+
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(collector)
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather failed: %s", err)
+	}
+
+	metrics := make(map[string]int)
+	values := make(map[string]float64)
+	for _, family := range families {
+		metrics[family.GetName()] = len(family.GetMetric())
+		m := family.GetMetric()[0]
+		switch {
+		case m.Gauge != nil:
+			values[family.GetName()] = m.Gauge.GetValue()
+		case m.Counter != nil:
+			values[family.GetName()] = m.Counter.GetValue()
+		case m.Summary != nil:
+			values[family.GetName()] = m.Summary.GetSampleSum()
+		}
+	}
+
+	expected := map[string]float64{
+		"kafka_client_rx_responses_total":       10,
+		"kafka_client_rx_messages_total":        5,
+		"kafka_broker_up":                       1,
+		"kafka_broker_rtt_seconds":              0.008,
+		"kafka_partition_consumer_lag":          42,
+		"kafka_partition_rx_messages_total":     5,
+		"kafka_consumer_group_rebalances_total": 2,
+	}
+	for name, value := range expected {
+		if values[name] != value {
+			t.Errorf("Expected %s to be %v, got %v", name, value, values[name])
+		}
+	}
+
+	// The logical GroupCoordinator broker and the UnAssigned partition
+	// must not be exported.
+	if metrics["kafka_broker_up"] != 1 {
+		t.Errorf("Expected 1 broker, got %d", metrics["kafka_broker_up"])
+	}
+	if metrics["kafka_partition_consumer_lag"] != 1 {
+		t.Errorf("Expected 1 partition, got %d", metrics["kafka_partition_consumer_lag"])
+	}
+
+	collector.Remove(stats.Name)
+	families, _ = registry.Gather()
+	if len(families) != 0 {
+		t.Errorf("Expected no metrics after Remove, got %d families", len(families))
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/confluentinc/confluent-kafka-go/kafkaprom
+
+go 1.13
+
+replace github.com/confluentinc/confluent-kafka-go => ../
+
+require (
+	github.com/confluentinc/confluent-kafka-go v0.0.0-00010101000000-000000000000
+	github.com/prometheus/client_golang v1.12.2
+)
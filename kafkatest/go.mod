@@ -6,8 +6,8 @@ replace github.com/confluentinc/confluent-kafka-go => ../
 
 require (
 	github.com/alecthomas/kingpin v2.2.6+incompatible
-	github.com/alecthomas/template v0.0.0-20190718012654-fb15b899a751 // indirect
 	github.com/alecthomas/units v0.0.0-20211218093645-b94a6e3cc137 // indirect
 	github.com/confluentinc/confluent-kafka-go v0.0.0-00010101000000-000000000000
 	github.com/stretchr/testify v1.7.1 // indirect
+	github.com/testcontainers/testcontainers-go v0.13.0
 )
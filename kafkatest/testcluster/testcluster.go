@@ -0,0 +1,390 @@
+// Package testcluster starts a single-node Kafka cluster, and optionally
+// a Schema Registry, in Docker containers using testcontainers-go, for
+// writing integration tests against a real broker:
+//
+//	func TestOrders(t *testing.T) {
+//		cluster := testcluster.New(t, nil)
+//		topic := cluster.CreateTopic(t, 3)
+//
+//		p, err := kafka.NewProducer(cluster.ConfigMap())
+//		...
+//	}
+//
+// Docker must be available to the test process.
+package testcluster
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+const (
+	// DefaultKafkaImage is the Kafka image used unless Options.KafkaImage is set
+	DefaultKafkaImage = "confluentinc/cp-kafka:7.1.1"
+	// DefaultSchemaRegistryImage is the Schema Registry image used unless
+	// Options.SchemaRegistryImage is set
+	DefaultSchemaRegistryImage = "confluentinc/cp-schema-registry:7.1.1"
+
+	// kafkaPort is the listener advertised with the mapped host port
+	kafkaPort = "9093/tcp"
+	// brokerAlias is the broker's host name on the cluster network,
+	// used by the Schema Registry through the internal listener
+	brokerAlias = "kafka"
+	// schemaRegistryPort is the Schema Registry HTTP port
+	schemaRegistryPort = "8081/tcp"
+	// startScript is the script starting ZooKeeper and Kafka, copied to
+	// the container once the mapped port to advertise is known
+	startScript = "/testcluster_start.sh"
+)
+
+// Options configures the cluster, the zero value (or nil) starts
+// a single broker with the default image.
+type Options struct {
+	// KafkaImage is the cp-kafka compatible image, DefaultKafkaImage if empty
+	KafkaImage string
+	// SchemaRegistry starts a Schema Registry container along with Kafka
+	SchemaRegistry bool
+	// SchemaRegistryImage is the Schema Registry image,
+	// DefaultSchemaRegistryImage if empty
+	SchemaRegistryImage string
+	// StartupTimeout is the maximum time to wait for each container
+	// to be ready, default 2 minutes
+	StartupTimeout time.Duration
+}
+
+// Cluster is a running test cluster, see Start() and New()
+type Cluster struct {
+	// BootstrapServers is the bootstrap.servers to connect to the broker
+	BootstrapServers string
+	// SchemaRegistryURL is the Schema Registry URL, empty unless
+	// Options.SchemaRegistry was set
+	SchemaRegistryURL string
+
+	network        testcontainers.Network
+	kafka          testcontainers.Container
+	schemaRegistry testcontainers.Container
+}
+
+// topicCnt makes topic names created by CreateTopic() unique
+var topicCnt uint64
+
+// New starts a cluster for the test, failing the test if the cluster can't
+// be started. The cluster is terminated when the test and its subtests
+// complete.
+func New(t testing.TB, opts *Options) *Cluster {
+	t.Helper()
+
+	ctx := context.Background()
+	c, err := Start(ctx, opts)
+	if err != nil {
+		t.Fatalf("Failed to start test cluster: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := c.Terminate(ctx); err != nil {
+			t.Logf("Failed to terminate test cluster: %v", err)
+		}
+	})
+
+	return c
+}
+
+// Start starts a cluster, the caller must call Terminate() when done.
+func Start(ctx context.Context, opts *Options) (cluster *Cluster, err error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	kafkaImage := opts.KafkaImage
+	if kafkaImage == "" {
+		kafkaImage = DefaultKafkaImage
+	}
+	timeout := opts.StartupTimeout
+	if timeout == 0 {
+		timeout = 2 * time.Minute
+	}
+
+	c := &Cluster{}
+	defer func() {
+		if err != nil {
+			c.Terminate(context.Background())
+		}
+	}()
+
+	networkName := fmt.Sprintf("testcluster-%d", time.Now().UnixNano())
+	c.network, err = testcontainers.GenericNetwork(ctx, testcontainers.GenericNetworkRequest{
+		NetworkRequest: testcontainers.NetworkRequest{
+			Name:           networkName,
+			CheckDuplicate: true,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create network: %w", err)
+	}
+
+	// The advertised listener must contain the port mapped on the host,
+	// which is only known once the container is created: the container
+	// waits for the start script, which is copied once the port is known.
+	c.kafka, err = testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:          kafkaImage,
+			ExposedPorts:   []string{kafkaPort},
+			Networks:       []string{networkName},
+			NetworkAliases: map[string][]string{networkName: {brokerAlias}},
+			Env: map[string]string{
+				"KAFKA_LISTENERS":                                "PLAINTEXT://0.0.0.0:9093,BROKER://0.0.0.0:9092",
+				"KAFKA_LISTENER_SECURITY_PROTOCOL_MAP":           "BROKER:PLAINTEXT,PLAINTEXT:PLAINTEXT",
+				"KAFKA_INTER_BROKER_LISTENER_NAME":               "BROKER",
+				"KAFKA_BROKER_ID":                                "1",
+				"KAFKA_ZOOKEEPER_CONNECT":                        "localhost:2181",
+				"KAFKA_OFFSETS_TOPIC_REPLICATION_FACTOR":         "1",
+				"KAFKA_OFFSETS_TOPIC_NUM_PARTITIONS":             "1",
+				"KAFKA_TRANSACTION_STATE_LOG_MIN_ISR":            "1",
+				"KAFKA_TRANSACTION_STATE_LOG_REPLICATION_FACTOR": "1",
+				"KAFKA_GROUP_INITIAL_REBALANCE_DELAY_MS":         "0",
+			},
+			Entrypoint: []string{"sh"},
+			Cmd: []string{"-c", fmt.Sprintf(
+				"while [ ! -f %s ]; do sleep 0.1; done; sh %s", startScript, startScript)},
+		},
+		Started: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start Kafka container: %w", err)
+	}
+
+	host, err := c.kafka.Host(ctx)
+	if err != nil {
+		return nil, err
+	}
+	port, err := c.kafka.MappedPort(ctx, kafkaPort)
+	if err != nil {
+		return nil, err
+	}
+	c.BootstrapServers = fmt.Sprintf("%s:%s", host, port.Port())
+
+	script := fmt.Sprintf(`#!/bin/sh
+printf 'clientPort=2181\ndataDir=/var/lib/zookeeper/data\ndataLogDir=/var/lib/zookeeper/log\n' > /tmp/zookeeper.properties
+zookeeper-server-start /tmp/zookeeper.properties &
+export KAFKA_ADVERTISED_LISTENERS=PLAINTEXT://%s,BROKER://%s:9092
+exec /etc/confluent/docker/run
+`, c.BootstrapServers, brokerAlias)
+
+	err = c.kafka.CopyToContainer(ctx, []byte(script), startScript, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy Kafka start script: %w", err)
+	}
+
+	err = waitForBroker(ctx, c.BootstrapServers, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.SchemaRegistry {
+		err = c.startSchemaRegistry(ctx, opts, networkName, timeout)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// waitForBroker waits until the broker responds to metadata requests
+func waitForBroker(ctx context.Context, bootstrapServers string, timeout time.Duration) error {
+	a, err := kafka.NewAdminClient(&kafka.ConfigMap{
+		"bootstrap.servers": bootstrapServers,
+	})
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	deadline := time.Now().Add(timeout)
+	for {
+		md, err := a.GetMetadata(nil, true, 1000)
+		if err == nil && len(md.Brokers) > 0 {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("broker %s not ready after %v: %v",
+				bootstrapServers, timeout, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+// startSchemaRegistry starts the Schema Registry container, connected to
+// the broker's internal listener.
+func (c *Cluster) startSchemaRegistry(ctx context.Context, opts *Options, networkName string, timeout time.Duration) (err error) {
+	image := opts.SchemaRegistryImage
+	if image == "" {
+		image = DefaultSchemaRegistryImage
+	}
+
+	c.schemaRegistry, err = testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        image,
+			ExposedPorts: []string{schemaRegistryPort},
+			Networks:     []string{networkName},
+			Env: map[string]string{
+				"SCHEMA_REGISTRY_HOST_NAME":                    "schema-registry",
+				"SCHEMA_REGISTRY_LISTENERS":                    "http://0.0.0.0:8081",
+				"SCHEMA_REGISTRY_KAFKASTORE_BOOTSTRAP_SERVERS": fmt.Sprintf("PLAINTEXT://%s:9092", brokerAlias),
+			},
+			WaitingFor: wait.ForHTTP("/subjects").
+				WithPort(schemaRegistryPort).
+				WithStartupTimeout(timeout),
+		},
+		Started: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start Schema Registry container: %w", err)
+	}
+
+	host, err := c.schemaRegistry.Host(ctx)
+	if err != nil {
+		return err
+	}
+	port, err := c.schemaRegistry.MappedPort(ctx, schemaRegistryPort)
+	if err != nil {
+		return err
+	}
+	c.SchemaRegistryURL = fmt.Sprintf("http://%s:%s", host, port.Port())
+
+	return nil
+}
+
+// Terminate stops and removes the cluster's containers and network
+func (c *Cluster) Terminate(ctx context.Context) error {
+	var errs []string
+
+	for _, container := range []testcontainers.Container{c.schemaRegistry, c.kafka} {
+		if container == nil {
+			continue
+		}
+		if err := container.Terminate(ctx); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	c.schemaRegistry = nil
+	c.kafka = nil
+
+	if c.network != nil {
+		if err := c.network.Remove(ctx); err != nil {
+			errs = append(errs, err.Error())
+		}
+		c.network = nil
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to terminate test cluster: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// ConfigMap returns a new ConfigMap with bootstrap.servers set,
+// suitable for producers and admin clients.
+func (c *Cluster) ConfigMap() *kafka.ConfigMap {
+	return &kafka.ConfigMap{"bootstrap.servers": c.BootstrapServers}
+}
+
+// ConsumerConfigMap returns a new ConfigMap for a consumer in group groupID,
+// reading topics from the beginning.
+func (c *Cluster) ConsumerConfigMap(groupID string) *kafka.ConfigMap {
+	return &kafka.ConfigMap{
+		"bootstrap.servers": c.BootstrapServers,
+		"group.id":          groupID,
+		"auto.offset.reset": "earliest",
+	}
+}
+
+// CreateTopic creates a topic with a unique name and the given number of
+// partitions, and deletes it when the test completes.
+// Returns the topic name.
+func (c *Cluster) CreateTopic(t testing.TB, partitions int) string {
+	t.Helper()
+
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	topic := fmt.Sprintf("%s-%d", name, atomic.AddUint64(&topicCnt, 1))
+
+	a, err := kafka.NewAdminClient(c.ConfigMap())
+	if err != nil {
+		t.Fatalf("Failed to create admin client: %v", err)
+	}
+	defer a.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	res, err := a.CreateTopics(ctx, []kafka.TopicSpecification{{
+		Topic:             topic,
+		NumPartitions:     partitions,
+		ReplicationFactor: 1,
+	}})
+	if err == nil && res[0].Error.Code() != kafka.ErrNoError {
+		err = res[0].Error
+	}
+	if err != nil {
+		t.Fatalf("Failed to create topic %s: %v", topic, err)
+	}
+
+	t.Cleanup(func() {
+		c.DeleteTopic(t, topic)
+	})
+
+	return topic
+}
+
+// DeleteTopic deletes topic, logging failures.
+func (c *Cluster) DeleteTopic(t testing.TB, topic string) {
+	t.Helper()
+
+	a, err := kafka.NewAdminClient(c.ConfigMap())
+	if err != nil {
+		t.Logf("Failed to create admin client: %v", err)
+		return
+	}
+	defer a.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	res, err := a.DeleteTopics(ctx, []string{topic})
+	if err == nil && res[0].Error.Code() != kafka.ErrNoError &&
+		res[0].Error.Code() != kafka.ErrUnknownTopicOrPart {
+		err = res[0].Error
+	}
+	if err != nil {
+		t.Logf("Failed to delete topic %s: %v", topic, err)
+	}
+}
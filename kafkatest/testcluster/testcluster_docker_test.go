@@ -0,0 +1,70 @@
+//go:build docker
+// +build docker
+
+package testcluster
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// TestCluster produces and consumes a message on a test cluster,
+// Docker is needed: go test -tags docker
+func TestCluster(t *testing.T) {
+	cluster := New(t, nil)
+	topic := cluster.CreateTopic(t, 1)
+
+	p, err := kafka.NewProducer(cluster.ConfigMap())
+	if err != nil {
+		t.Fatalf("Failed to create producer: %v", err)
+	}
+	defer p.Close()
+
+	drChan := make(chan kafka.Event, 1)
+	err = p.Produce(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Value:          []byte("hello"),
+	}, drChan)
+	if err != nil {
+		t.Fatalf("Produce failed: %v", err)
+	}
+	if m := (<-drChan).(*kafka.Message); m.TopicPartition.Error != nil {
+		t.Fatalf("Delivery failed: %v", m.TopicPartition)
+	}
+
+	c, err := kafka.NewConsumer(cluster.ConsumerConfigMap(t.Name()))
+	if err != nil {
+		t.Fatalf("Failed to create consumer: %v", err)
+	}
+	defer c.Close()
+
+	if err = c.Subscribe(topic, nil); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	m, err := c.ReadMessage(30 * time.Second)
+	if err != nil {
+		t.Fatalf("ReadMessage failed: %v", err)
+	}
+	if string(m.Value) != "hello" {
+		t.Errorf("Expected hello, got %s", m.Value)
+	}
+}
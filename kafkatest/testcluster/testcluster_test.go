@@ -0,0 +1,52 @@
+package testcluster
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"context"
+	"testing"
+)
+
+// TestConfigMaps tests the client configurations of a cluster,
+// no Docker is needed.
+func TestConfigMaps(t *testing.T) {
+	c := &Cluster{BootstrapServers: "localhost:12345"}
+
+	if v, _ := c.ConfigMap().Get("bootstrap.servers", nil); v != c.BootstrapServers {
+		t.Errorf("Expected bootstrap.servers %s, got %v", c.BootstrapServers, v)
+	}
+
+	conf := c.ConsumerConfigMap("group")
+	for key, exp := range map[string]string{
+		"bootstrap.servers": c.BootstrapServers,
+		"group.id":          "group",
+		"auto.offset.reset": "earliest",
+	} {
+		if v, _ := conf.Get(key, nil); v != exp {
+			t.Errorf("Expected %s %s, got %v", key, exp, v)
+		}
+	}
+}
+
+// TestTerminateNotStarted tests that terminating a cluster whose
+// containers were not started is a no-op, as done when Start() fails.
+func TestTerminateNotStarted(t *testing.T) {
+	c := &Cluster{}
+	if err := c.Terminate(context.Background()); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
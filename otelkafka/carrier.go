@@ -0,0 +1,68 @@
+package otelkafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// MessageCarrier is a propagation.TextMapCarrier for the headers of a
+// kafka.Message.
+type MessageCarrier struct {
+	msg *kafka.Message
+}
+
+// NewMessageCarrier returns a MessageCarrier for the headers of msg.
+func NewMessageCarrier(msg *kafka.Message) MessageCarrier {
+	return MessageCarrier{msg: msg}
+}
+
+// Get returns the value of the last header with the given key,
+// or an empty string.
+func (c MessageCarrier) Get(key string) string {
+	headers := c.msg.GetHeaders()
+	for i := len(headers) - 1; i >= 0; i-- {
+		if headers[i].Key == key {
+			return string(headers[i].Value)
+		}
+	}
+	return ""
+}
+
+// Set sets the header key to value, replacing any existing headers
+// with the same key.
+func (c MessageCarrier) Set(key string, value string) {
+	headers := make([]kafka.Header, 0, len(c.msg.GetHeaders())+1)
+	for _, h := range c.msg.Headers {
+		if h.Key != key {
+			headers = append(headers, h)
+		}
+	}
+	c.msg.Headers = append(headers, kafka.Header{Key: key, Value: []byte(value)})
+}
+
+// Keys returns the keys of all headers.
+func (c MessageCarrier) Keys() []string {
+	keys := make([]string, len(c.msg.GetHeaders()))
+	for i, h := range c.msg.Headers {
+		keys[i] = h.Key
+	}
+	return keys
+}
+
+var _ propagation.TextMapCarrier = MessageCarrier{}
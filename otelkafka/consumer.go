@@ -0,0 +1,94 @@
+package otelkafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"context"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Consumer is a traced kafka.ConsumerClient, see NewConsumer().
+//
+// Messages returned by Poll() and ReadMessage() are traced,
+// messages read from the Events() channel are not.
+type Consumer struct {
+	kafka.ConsumerClient
+
+	cfg *config
+}
+
+// NewConsumer wraps consumer in a traced Consumer.
+func NewConsumer(consumer kafka.ConsumerClient, opts ...Option) *Consumer {
+	return &Consumer{
+		ConsumerClient: consumer,
+		cfg:            newConfig(opts),
+	}
+}
+
+// traceMessage records a consumer span for msg, as a child of the
+// span context propagated in the message headers, if any.
+func (c *Consumer) traceMessage(msg *kafka.Message) {
+	ctx := c.cfg.propagator.Extract(context.Background(), NewMessageCarrier(msg))
+
+	_, span := c.cfg.tracer.Start(ctx, spanName(msg, "receive"),
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(messageAttributes(msg, "receive")...))
+
+	if msg.TopicPartition.Error != nil {
+		span.RecordError(msg.TopicPartition.Error)
+		span.SetStatus(codes.Error, msg.TopicPartition.Error.Error())
+	}
+
+	span.End()
+}
+
+// Poll the wrapped consumer for messages or events,
+// recording a consumer span for each message.
+func (c *Consumer) Poll(timeoutMs int) (event kafka.Event) {
+	ev := c.ConsumerClient.Poll(timeoutMs)
+	if msg, ok := ev.(*kafka.Message); ok {
+		c.traceMessage(msg)
+	}
+	return ev
+}
+
+// ReadMessage polls the wrapped consumer for a message,
+// recording a consumer span for the message.
+func (c *Consumer) ReadMessage(timeout time.Duration) (*kafka.Message, error) {
+	msg, err := c.ConsumerClient.ReadMessage(timeout)
+	if msg != nil {
+		c.traceMessage(msg)
+	}
+	return msg, err
+}
+
+// StartProcessSpan starts a span for processing msg by the application,
+// as a child of the span context propagated in the message headers.
+// The caller must end the returned span.
+func (c *Consumer) StartProcessSpan(ctx context.Context, msg *kafka.Message) (context.Context, trace.Span) {
+	ctx = c.cfg.propagator.Extract(ctx, NewMessageCarrier(msg))
+
+	return c.cfg.tracer.Start(ctx, spanName(msg, "process"),
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(messageAttributes(msg, "process")...))
+}
+
+var _ kafka.ConsumerClient = (*Consumer)(nil)
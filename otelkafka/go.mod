@@ -0,0 +1,12 @@
+module github.com/confluentinc/confluent-kafka-go/otelkafka
+
+go 1.16
+
+replace github.com/confluentinc/confluent-kafka-go => ../
+
+require (
+	github.com/confluentinc/confluent-kafka-go v0.0.0-00010101000000-000000000000
+	go.opentelemetry.io/otel v1.7.0
+	go.opentelemetry.io/otel/sdk v1.7.0
+	go.opentelemetry.io/otel/trace v1.7.0
+)
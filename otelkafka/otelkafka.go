@@ -0,0 +1,136 @@
+// Package otelkafka provides OpenTelemetry tracing instrumentation for
+// confluent-kafka-go producers and consumers.
+//
+// The Producer wrapper starts a span for each produced message, injects
+// the span context into the message headers using the configured
+// propagator (W3C trace context by default) and ends the span when
+// the message's delivery report is received.
+//
+// The Consumer wrapper extracts the producer's span context from the
+// headers of each consumed message and records a consume span as its child.
+package otelkafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"context"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName is the name of the Tracer used by this package
+const instrumentationName = "github.com/confluentinc/confluent-kafka-go/otelkafka"
+
+// Messaging span attribute keys, as defined by the OpenTelemetry
+// semantic conventions.
+const (
+	attrMessagingSystem          = attribute.Key("messaging.system")
+	attrMessagingDestination     = attribute.Key("messaging.destination")
+	attrMessagingDestinationKind = attribute.Key("messaging.destination_kind")
+	attrMessagingOperation       = attribute.Key("messaging.operation")
+	attrMessagingKafkaPartition  = attribute.Key("messaging.kafka.partition")
+	attrMessagingKafkaMessageKey = attribute.Key("messaging.kafka.message_key")
+	attrMessagingKafkaOffset     = attribute.Key("messaging.kafka.offset")
+)
+
+// config is the instrumentation configuration, see Option.
+type config struct {
+	tracerProvider trace.TracerProvider
+	propagator     propagation.TextMapPropagator
+	tracer         trace.Tracer
+}
+
+// Option configures the instrumentation.
+type Option func(cfg *config)
+
+// WithTracerProvider sets the TracerProvider used to create spans,
+// the global TracerProvider is used by default.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(cfg *config) {
+		cfg.tracerProvider = provider
+	}
+}
+
+// WithPropagator sets the propagator used to inject and extract span
+// context to and from message headers, the W3C trace context propagator
+// is used by default.
+func WithPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(cfg *config) {
+		cfg.propagator = propagator
+	}
+}
+
+func newConfig(opts []Option) *config {
+	cfg := &config{
+		tracerProvider: otel.GetTracerProvider(),
+		propagator:     propagation.TraceContext{},
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	cfg.tracer = cfg.tracerProvider.Tracer(instrumentationName)
+	return cfg
+}
+
+// messageAttributes returns the span attributes of msg.
+func messageAttributes(msg *kafka.Message, operation string) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attrMessagingSystem.String("kafka"),
+		attrMessagingDestinationKind.String("topic"),
+	}
+	if operation != "" {
+		attrs = append(attrs, attrMessagingOperation.String(operation))
+	}
+	if msg.TopicPartition.Topic != nil {
+		attrs = append(attrs, attrMessagingDestination.String(*msg.TopicPartition.Topic))
+	}
+	if msg.TopicPartition.Partition >= 0 {
+		attrs = append(attrs, attrMessagingKafkaPartition.Int64(int64(msg.TopicPartition.Partition)))
+	}
+	if msg.TopicPartition.Offset >= 0 {
+		attrs = append(attrs, attrMessagingKafkaOffset.Int64(int64(msg.TopicPartition.Offset)))
+	}
+	if msg.Key != nil {
+		attrs = append(attrs, attrMessagingKafkaMessageKey.String(string(msg.Key)))
+	}
+	return attrs
+}
+
+// spanName returns the span name for an operation on msg's topic.
+func spanName(msg *kafka.Message, operation string) string {
+	if msg.TopicPartition.Topic == nil {
+		return operation
+	}
+	return *msg.TopicPartition.Topic + " " + operation
+}
+
+// InjectContext injects the span context of ctx into the headers of msg
+// using the W3C trace context propagator, or the propagator set with
+// WithPropagator().
+func InjectContext(ctx context.Context, msg *kafka.Message, opts ...Option) {
+	newConfig(opts).propagator.Inject(ctx, NewMessageCarrier(msg))
+}
+
+// ExtractContext returns a copy of ctx with the span context
+// extracted from the headers of msg.
+func ExtractContext(ctx context.Context, msg *kafka.Message, opts ...Option) context.Context {
+	return newConfig(opts).propagator.Extract(ctx, NewMessageCarrier(msg))
+}
@@ -0,0 +1,134 @@
+package otelkafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TestMessageCarrier tests header get, set and replace.
+func TestMessageCarrier(t *testing.T) {
+	msg := &kafka.Message{Headers: []kafka.Header{{Key: "a", Value: []byte("1")}}}
+	carrier := NewMessageCarrier(msg)
+
+	carrier.Set("traceparent", "x")
+	carrier.Set("traceparent", "y")
+
+	if carrier.Get("traceparent") != "y" || carrier.Get("a") != "1" {
+		t.Errorf("Unexpected headers %v", msg.Headers)
+	}
+	if len(carrier.Keys()) != 2 {
+		t.Errorf("Expected 2 keys, got %v", carrier.Keys())
+	}
+}
+
+// TestTracing tests span propagation from a traced producer to a traced
+// consumer using the mock clients.
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	mp := kafka.NewMockProducer(true)
+	p := NewProducer(mp, WithTracerProvider(provider))
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "parent")
+
+	topic := "traced"
+	drChan := make(chan kafka.Event, 1)
+	err := p.ProduceWithContext(ctx, &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 0},
+		Value:          []byte("hello"),
+		Opaque:         "myOpaque"}, drChan)
+	if err != nil {
+		t.Fatalf("Produce failed: %v", err)
+	}
+	parent.End()
+
+	select {
+	case ev := <-drChan:
+		m := ev.(*kafka.Message)
+		if m.Opaque != "myOpaque" {
+			t.Errorf("Expected original opaque, got %v", m.Opaque)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for delivery report")
+	}
+
+	// Fail the next produce to verify error status is recorded.
+	mp.SetProduceError(kafka.NewError(kafka.ErrQueueFull, "", false))
+	if err = p.Produce(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic}}, nil); err == nil {
+		t.Errorf("Expected produce to fail")
+	}
+
+	p.Close()
+
+	produced := mp.Messages()[0]
+
+	mc := kafka.NewMockConsumer()
+	c := NewConsumer(mc, WithTracerProvider(provider))
+	defer c.Close()
+
+	mc.AddMessage(produced)
+	c.Assign([]kafka.TopicPartition{{Topic: &topic, Partition: 0}})
+
+	if _, err = c.ReadMessage(5 * time.Second); err != nil {
+		t.Fatalf("ReadMessage failed: %v", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 4 {
+		t.Fatalf("Expected 4 spans, got %d", len(spans))
+	}
+
+	var parentSpan, send, failed, receive sdktrace.ReadOnlySpan
+	for _, span := range spans {
+		switch {
+		case span.Name() == "parent":
+			parentSpan = span
+		case span.Name() == "traced receive":
+			receive = span
+		case span.Status().Code == codes.Error:
+			failed = span
+		default:
+			send = span
+		}
+	}
+
+	if parentSpan == nil || send == nil || failed == nil || receive == nil {
+		t.Fatalf("Missing spans: %v", spans)
+	}
+
+	if send.SpanKind() != trace.SpanKindProducer ||
+		send.Parent().SpanID() != parentSpan.SpanContext().SpanID() {
+		t.Errorf("Expected producer span child of parent, got %v", send)
+	}
+
+	if receive.SpanKind() != trace.SpanKindConsumer ||
+		receive.Parent().SpanID() != send.SpanContext().SpanID() ||
+		receive.SpanContext().TraceID() != send.SpanContext().TraceID() {
+		t.Errorf("Expected consumer span child of producer span, got %v", receive)
+	}
+}
@@ -0,0 +1,159 @@
+package otelkafka
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"context"
+	"sync"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracedOpaque replaces the Opaque of a traced message until its
+// delivery report is received.
+type tracedOpaque struct {
+	span         trace.Span
+	opaque       interface{}
+	deliveryChan chan kafka.Event
+}
+
+// Producer is a traced kafka.ProducerClient, see NewProducer().
+//
+// Messages produced with Produce() or ProduceWithContext() are traced,
+// messages produced on the ProduceChannel() are not.
+type Producer struct {
+	kafka.ProducerClient
+
+	cfg       *config
+	events    chan kafka.Event
+	waitGroup sync.WaitGroup
+}
+
+// NewProducer wraps producer in a traced Producer.
+//
+// The producer must be configured with go.delivery.reports=true (default)
+// for spans to be ended on delivery. The wrapped producer's Events()
+// channel must not be read by the application, use the returned
+// Producer's Events() channel instead.
+func NewProducer(producer kafka.ProducerClient, opts ...Option) *Producer {
+	p := &Producer{
+		ProducerClient: producer,
+		cfg:            newConfig(opts),
+		events:         make(chan kafka.Event, cap(producer.Events())),
+	}
+
+	p.waitGroup.Add(1)
+	go p.deliveryReporter()
+
+	return p
+}
+
+// deliveryReporter forwards events from the wrapped producer,
+// ending the span of traced messages' delivery reports.
+func (p *Producer) deliveryReporter() {
+	defer p.waitGroup.Done()
+
+	for ev := range p.ProducerClient.Events() {
+		m, ok := ev.(*kafka.Message)
+		if !ok {
+			p.events <- ev
+			continue
+		}
+
+		to, ok := m.Opaque.(*tracedOpaque)
+		if !ok {
+			p.events <- ev
+			continue
+		}
+
+		m.Opaque = to.opaque
+		endDeliverySpan(to.span, m)
+
+		if to.deliveryChan != nil {
+			to.deliveryChan <- m
+		} else {
+			p.events <- m
+		}
+	}
+
+	close(p.events)
+}
+
+// endDeliverySpan records the delivery result of m on span and ends it.
+func endDeliverySpan(span trace.Span, m *kafka.Message) {
+	if m.TopicPartition.Error != nil {
+		span.RecordError(m.TopicPartition.Error)
+		span.SetStatus(codes.Error, m.TopicPartition.Error.Error())
+	} else {
+		span.SetAttributes(attrMessagingKafkaPartition.Int64(int64(m.TopicPartition.Partition)),
+			attrMessagingKafkaOffset.Int64(int64(m.TopicPartition.Offset)))
+	}
+	span.End()
+}
+
+// Produce a traced message, see ProduceWithContext().
+func (p *Producer) Produce(msg *kafka.Message, deliveryChan chan kafka.Event) error {
+	return p.ProduceWithContext(context.Background(), msg, deliveryChan)
+}
+
+// ProduceWithContext produces a single message with a new producer span
+// that is a child of any span in ctx.
+// The span context is injected in the message headers and the span
+// is ended when the delivery report is received.
+//
+// Returns an error, and ends the span, if the message could not be
+// enqueued, see kafka.Producer.Produce().
+func (p *Producer) ProduceWithContext(ctx context.Context, msg *kafka.Message, deliveryChan chan kafka.Event) error {
+	ctx, span := p.cfg.tracer.Start(ctx, spanName(msg, "send"),
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(messageAttributes(msg, "")...))
+
+	p.cfg.propagator.Inject(ctx, NewMessageCarrier(msg))
+
+	opaque := msg.Opaque
+	msg.Opaque = &tracedOpaque{span: span, opaque: opaque, deliveryChan: deliveryChan}
+
+	// Delivery reports are always routed through the wrapped producer's
+	// Events() channel so that the span can be ended.
+	err := p.ProducerClient.Produce(msg, nil)
+	msg.Opaque = opaque
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.End()
+	}
+
+	return err
+}
+
+// Events returns the Events channel (read), carrying the delivery reports
+// of messages without a per-message deliveryChan and all other events of
+// the wrapped producer.
+func (p *Producer) Events() chan kafka.Event {
+	return p.events
+}
+
+// Close the wrapped producer and wait for remaining delivery reports
+// to be forwarded.
+func (p *Producer) Close() {
+	p.ProducerClient.Close()
+	p.waitGroup.Wait()
+}
+
+var _ kafka.ProducerClient = (*Producer)(nil)
@@ -0,0 +1,465 @@
+// Package harness provides a scenario-driven load test harness for
+// running capacity and soak tests against a Kafka cluster.
+//
+// A Scenario declares the produce rate, message size distribution,
+// number of producers, consumer groups and chaos (fault injection)
+// settings. Run() executes the scenario and returns a Report with
+// delivery and end-to-end latency histograms:
+//
+//	report, err := harness.Run(ctx, harness.Scenario{
+//		Name:             "baseline",
+//		BootstrapServers: "localhost:9092",
+//		Topic:            "loadtest",
+//		Duration:         5 * time.Minute,
+//		Rate:             10000,
+//		MessageSize:      harness.UniformSize{Min: 100, Max: 2000},
+//		ConsumerGroups:   []harness.ConsumerGroup{{Consumers: 3}},
+//		Chaos:            harness.Chaos{RestartConsumerInterval: time.Minute},
+//	})
+package harness
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// runHeader is the header identifying the messages produced by a run,
+// consumers ignore messages produced by other runs.
+const runHeader = "harness.run"
+
+// Report holds the results of a scenario run
+type Report struct {
+	Scenario string        `json:"scenario"`
+	Duration time.Duration `json:"duration_ns"`
+	// Produced is the number of messages successfully enqueued
+	Produced uint64 `json:"produced"`
+	// Delivered is the number of messages acknowledged by the cluster
+	Delivered uint64 `json:"delivered"`
+	// DeliveryErrors is the number of failed deliveries
+	DeliveryErrors uint64 `json:"delivery_errors"`
+	// ProduceErrors is the number of failed Produce() calls,
+	// not counting retried queue full errors
+	ProduceErrors uint64 `json:"produce_errors"`
+	// Consumed is the number of messages consumed per consumer group.
+	// Messages are redelivered after consumer restarts, so Consumed may
+	// exceed Delivered.
+	Consumed map[string]uint64 `json:"consumed"`
+	// ConsumerErrors is the number of consumer errors
+	ConsumerErrors uint64 `json:"consumer_errors"`
+	// ProducerRestarts and ConsumerRestarts count the chaos restarts
+	ProducerRestarts uint64 `json:"producer_restarts"`
+	ConsumerRestarts uint64 `json:"consumer_restarts"`
+	// DeliveryLatency is the Produce() to delivery report latency
+	DeliveryLatency HistogramSnapshot `json:"delivery_latency"`
+	// EndToEndLatency is the Produce() to consumption latency
+	EndToEndLatency HistogramSnapshot `json:"end_to_end_latency"`
+}
+
+func (r *Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Scenario %s ran for %v\n", r.Scenario, r.Duration)
+	fmt.Fprintf(&b, "  produced %d, delivered %d, %d delivery errors, %d produce errors\n",
+		r.Produced, r.Delivered, r.DeliveryErrors, r.ProduceErrors)
+	for group, cnt := range r.Consumed {
+		fmt.Fprintf(&b, "  group %s consumed %d\n", group, cnt)
+	}
+	fmt.Fprintf(&b, "  %d consumer errors, %d producer restarts, %d consumer restarts\n",
+		r.ConsumerErrors, r.ProducerRestarts, r.ConsumerRestarts)
+	fmt.Fprintf(&b, "  delivery latency: %v\n", r.DeliveryLatency)
+	fmt.Fprintf(&b, "  end-to-end latency: %v\n", r.EndToEndLatency)
+	return b.String()
+}
+
+// run holds the state of a running scenario
+type run struct {
+	s  Scenario
+	id []byte
+
+	produced         uint64
+	delivered        uint64
+	deliveryErrors   uint64
+	produceErrors    uint64
+	consumerErrors   uint64
+	producerRestarts uint64
+	consumerRestarts uint64
+	consumed         []uint64
+
+	drLatency  *Histogram
+	e2eLatency *Histogram
+}
+
+// Run executes the scenario: consumers are started, the producers
+// produce for the scenario Duration, then the consumers are given up
+// to DrainTimeout to consume the delivered messages.
+//
+// Run returns early if ctx is cancelled. A Report is returned even if
+// a client fails, together with the first client error.
+func Run(ctx context.Context, s Scenario) (*Report, error) {
+	s, err := s.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &run{
+		s:          s,
+		id:         []byte(fmt.Sprintf("%s-%d", s.Name, time.Now().UnixNano())),
+		consumed:   make([]uint64, len(s.ConsumerGroups)),
+		drLatency:  NewHistogram(),
+		e2eLatency: NewHistogram(),
+	}
+
+	start := time.Now()
+	errs := make(chan error, 1)
+	reportErr := func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	}
+
+	consumeCtx, stopConsuming := context.WithCancel(ctx)
+	defer stopConsuming()
+	var consumers sync.WaitGroup
+	var consumerRestarts []chan struct{}
+	for group, g := range s.ConsumerGroups {
+		for i := 0; i < g.Consumers; i++ {
+			restart := make(chan struct{}, 1)
+			consumerRestarts = append(consumerRestarts, restart)
+			consumers.Add(1)
+			go func(group int) {
+				defer consumers.Done()
+				if err := r.consume(consumeCtx, group, restart); err != nil {
+					reportErr(err)
+				}
+			}(group)
+		}
+	}
+
+	produceCtx, stopProducing := context.WithTimeout(ctx, s.Duration)
+	defer stopProducing()
+	var producers sync.WaitGroup
+	var producerRestarts []chan struct{}
+	rate := float64(s.Rate) / float64(s.Producers)
+	for i := 0; i < s.Producers; i++ {
+		restart := make(chan struct{}, 1)
+		producerRestarts = append(producerRestarts, restart)
+		producers.Add(1)
+		go func() {
+			defer producers.Done()
+			if err := r.produce(produceCtx, rate, restart); err != nil {
+				reportErr(err)
+			}
+		}()
+	}
+
+	go chaos(produceCtx, s.Chaos.RestartProducerInterval, producerRestarts)
+	go chaos(produceCtx, s.Chaos.RestartConsumerInterval, consumerRestarts)
+
+	producers.Wait()
+	r.drain(ctx)
+	stopConsuming()
+	consumers.Wait()
+
+	report := r.report(time.Since(start))
+
+	select {
+	case err = <-errs:
+		return report, err
+	default:
+		return report, nil
+	}
+}
+
+// report returns the Report for the run
+func (r *run) report(duration time.Duration) *Report {
+	report := &Report{
+		Scenario:         r.s.Name,
+		Duration:         duration,
+		Produced:         atomic.LoadUint64(&r.produced),
+		Delivered:        atomic.LoadUint64(&r.delivered),
+		DeliveryErrors:   atomic.LoadUint64(&r.deliveryErrors),
+		ProduceErrors:    atomic.LoadUint64(&r.produceErrors),
+		Consumed:         make(map[string]uint64),
+		ConsumerErrors:   atomic.LoadUint64(&r.consumerErrors),
+		ProducerRestarts: atomic.LoadUint64(&r.producerRestarts),
+		ConsumerRestarts: atomic.LoadUint64(&r.consumerRestarts),
+		DeliveryLatency:  r.drLatency.Snapshot(),
+		EndToEndLatency:  r.e2eLatency.Snapshot(),
+	}
+
+	for i, g := range r.s.ConsumerGroups {
+		report.Consumed[g.GroupID] = atomic.LoadUint64(&r.consumed[i])
+	}
+
+	return report
+}
+
+// chaos signals a randomly chosen restart channel every interval
+// until ctx is done.
+func chaos(ctx context.Context, interval time.Duration, restarts []chan struct{}) {
+	if interval <= 0 || len(restarts) == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			select {
+			case restarts[rand.Intn(len(restarts))] <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// drain waits until all consumer groups consumed the delivered messages,
+// for at most DrainTimeout.
+func (r *run) drain(ctx context.Context) {
+	if len(r.s.ConsumerGroups) == 0 {
+		return
+	}
+
+	deadline := time.Now().Add(r.s.DrainTimeout)
+	for time.Now().Before(deadline) && ctx.Err() == nil {
+		done := true
+		delivered := atomic.LoadUint64(&r.delivered)
+		for i := range r.consumed {
+			if atomic.LoadUint64(&r.consumed[i]) < delivered {
+				done = false
+			}
+		}
+		if done {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// config returns a copy of extra with the bootstrap servers set
+func (r *run) config(extra kafka.ConfigMap) *kafka.ConfigMap {
+	conf := kafka.ConfigMap{}
+	for k, v := range extra {
+		conf[k] = v
+	}
+	conf["bootstrap.servers"] = r.s.BootstrapServers
+	return &conf
+}
+
+// sendTime returns the send time embedded in a message value
+func sendTime(value []byte) (time.Time, bool) {
+	if len(value) < 8 {
+		return time.Time{}, false
+	}
+	return time.Unix(0, int64(binary.BigEndian.Uint64(value))), true
+}
+
+// produce runs a producer until ctx is done, re-creating it when
+// signalled on restart.
+func (r *run) produce(ctx context.Context, rate float64, restart <-chan struct{}) error {
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	var tick <-chan time.Time
+	if rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / rate))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		p, err := kafka.NewProducer(r.config(r.s.ProducerConfig))
+		if err != nil {
+			return err
+		}
+
+		done := make(chan struct{})
+		go r.deliveryReports(p, done)
+
+		restarting := r.produceUntil(ctx, p, rnd, tick, restart)
+
+		p.Flush(int(r.s.DrainTimeout / time.Millisecond))
+		p.Close()
+		<-done
+
+		if !restarting {
+			return nil
+		}
+		atomic.AddUint64(&r.producerRestarts, 1)
+	}
+}
+
+// produceUntil produces messages until ctx is done, returning false,
+// or a restart is signalled, returning true.
+func (r *run) produceUntil(ctx context.Context, p *kafka.Producer, rnd *rand.Rand,
+	tick <-chan time.Time, restart <-chan struct{}) bool {
+	for {
+		if tick != nil {
+			select {
+			case <-ctx.Done():
+				return false
+			case <-restart:
+				return true
+			case <-tick:
+			}
+		} else {
+			select {
+			case <-ctx.Done():
+				return false
+			case <-restart:
+				return true
+			default:
+			}
+		}
+
+		size := r.s.MessageSize.Size(rnd)
+		if size < 8 {
+			size = 8
+		}
+		value := make([]byte, size)
+		binary.BigEndian.PutUint64(value, uint64(time.Now().UnixNano()))
+
+		err := p.Produce(&kafka.Message{
+			TopicPartition: kafka.TopicPartition{Topic: &r.s.Topic, Partition: kafka.PartitionAny},
+			Value:          value,
+			Headers:        []kafka.Header{{Key: runHeader, Value: r.id}},
+		}, nil)
+		if err != nil {
+			if kerr, ok := err.(kafka.Error); ok && kerr.Code() == kafka.ErrQueueFull {
+				// Let the delivery reports catch up
+				time.Sleep(10 * time.Millisecond)
+			} else {
+				atomic.AddUint64(&r.produceErrors, 1)
+			}
+			continue
+		}
+
+		atomic.AddUint64(&r.produced, 1)
+	}
+}
+
+// deliveryReports handles the producer's delivery reports until its
+// events channel is closed.
+func (r *run) deliveryReports(p *kafka.Producer, done chan<- struct{}) {
+	defer close(done)
+
+	for ev := range p.Events() {
+		m, ok := ev.(*kafka.Message)
+		if !ok {
+			continue
+		}
+
+		if m.TopicPartition.Error != nil {
+			atomic.AddUint64(&r.deliveryErrors, 1)
+			continue
+		}
+		atomic.AddUint64(&r.delivered, 1)
+
+		latency := m.Latency()
+		if latency < 0 {
+			if sent, ok := sendTime(m.Value); ok {
+				latency = time.Since(sent)
+			}
+		}
+		r.drLatency.Record(latency)
+	}
+}
+
+// consume runs a consumer of the given group until ctx is done,
+// re-creating it when signalled on restart.
+func (r *run) consume(ctx context.Context, group int, restart <-chan struct{}) error {
+	for {
+		conf := r.config(r.s.ConsumerConfig)
+		(*conf)["group.id"] = r.s.ConsumerGroups[group].GroupID
+		if _, found := (*conf)["auto.offset.reset"]; !found {
+			(*conf)["auto.offset.reset"] = "earliest"
+		}
+
+		c, err := kafka.NewConsumer(conf)
+		if err != nil {
+			return err
+		}
+
+		restarting := false
+		err = c.Subscribe(r.s.Topic, nil)
+		if err == nil {
+			restarting = r.consumeUntil(ctx, c, group, restart)
+		}
+		c.Close()
+
+		if err != nil || !restarting {
+			return err
+		}
+		atomic.AddUint64(&r.consumerRestarts, 1)
+	}
+}
+
+// consumeUntil consumes messages until ctx is done, returning false,
+// or a restart is signalled, returning true.
+func (r *run) consumeUntil(ctx context.Context, c *kafka.Consumer, group int,
+	restart <-chan struct{}) bool {
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-restart:
+			return true
+		default:
+		}
+
+		switch e := c.Poll(100).(type) {
+		case *kafka.Message:
+			if e.TopicPartition.Error != nil {
+				atomic.AddUint64(&r.consumerErrors, 1)
+				continue
+			}
+			if !r.isOwnMessage(e) {
+				continue
+			}
+			atomic.AddUint64(&r.consumed[group], 1)
+			if sent, ok := sendTime(e.Value); ok {
+				r.e2eLatency.Record(time.Since(sent))
+			}
+		case kafka.Error:
+			atomic.AddUint64(&r.consumerErrors, 1)
+		}
+	}
+}
+
+// isOwnMessage returns true if m was produced by this run
+func (r *run) isOwnMessage(m *kafka.Message) bool {
+	for _, h := range m.Headers {
+		if h.Key == runHeader {
+			return bytes.Equal(h.Value, r.id)
+		}
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+package harness
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// histogramGrowth is the relative width of each histogram bucket,
+	// bounding the percentile error to 5%.
+	histogramGrowth = 1.05
+	// histogramBuckets covers latencies from 1us to more than one hour.
+	histogramBuckets = 460
+)
+
+// Histogram records latencies in exponentially sized buckets.
+// It is safe for concurrent use.
+type Histogram struct {
+	lock   sync.Mutex
+	counts [histogramBuckets]uint64
+	count  uint64
+	sum    time.Duration
+	min    time.Duration
+	max    time.Duration
+}
+
+// NewHistogram returns an empty Histogram
+func NewHistogram() *Histogram {
+	return &Histogram{}
+}
+
+// bucket returns the bucket index for d
+func bucket(d time.Duration) int {
+	us := float64(d) / float64(time.Microsecond)
+	if us < 1 {
+		return 0
+	}
+	i := int(math.Log(us)/math.Log(histogramGrowth)) + 1
+	if i >= histogramBuckets {
+		return histogramBuckets - 1
+	}
+	return i
+}
+
+// bucketValue returns the upper bound of bucket i
+func bucketValue(i int) time.Duration {
+	return time.Duration(math.Pow(histogramGrowth, float64(i)) * float64(time.Microsecond))
+}
+
+// Record adds a latency sample, negative values are ignored.
+func (h *Histogram) Record(d time.Duration) {
+	if d < 0 {
+		return
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.counts[bucket(d)]++
+	if h.count == 0 || d < h.min {
+		h.min = d
+	}
+	if d > h.max {
+		h.max = d
+	}
+	h.count++
+	h.sum += d
+}
+
+// percentile returns the p-th (0..100) percentile, the lock must be held.
+func (h *Histogram) percentile(p float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+
+	rank := uint64(math.Ceil(p / 100 * float64(h.count)))
+	if rank == 0 {
+		rank = 1
+	}
+
+	var seen uint64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= rank {
+			v := bucketValue(i)
+			if v > h.max {
+				v = h.max
+			}
+			if v < h.min {
+				v = h.min
+			}
+			return v
+		}
+	}
+
+	return h.max
+}
+
+// Percentile returns the p-th (0..100) percentile of the recorded samples,
+// or 0 if no samples were recorded.
+func (h *Histogram) Percentile(p float64) time.Duration {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return h.percentile(p)
+}
+
+// Snapshot returns a summary of the recorded samples
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	s := HistogramSnapshot{
+		Count: h.count,
+		Min:   h.min,
+		Max:   h.max,
+		P50:   h.percentile(50),
+		P90:   h.percentile(90),
+		P99:   h.percentile(99),
+		P999:  h.percentile(99.9),
+	}
+	if h.count > 0 {
+		s.Mean = h.sum / time.Duration(h.count)
+	}
+
+	return s
+}
+
+// HistogramSnapshot summarizes a Histogram
+type HistogramSnapshot struct {
+	Count uint64        `json:"count"`
+	Min   time.Duration `json:"min_ns"`
+	Max   time.Duration `json:"max_ns"`
+	Mean  time.Duration `json:"mean_ns"`
+	P50   time.Duration `json:"p50_ns"`
+	P90   time.Duration `json:"p90_ns"`
+	P99   time.Duration `json:"p99_ns"`
+	P999  time.Duration `json:"p999_ns"`
+}
+
+func (s HistogramSnapshot) String() string {
+	return fmt.Sprintf("count %d, min %v, mean %v, p50 %v, p90 %v, p99 %v, p99.9 %v, max %v",
+		s.Count, s.Min, s.Mean, s.P50, s.P90, s.P99, s.P999, s.Max)
+}
@@ -0,0 +1,160 @@
+package harness
+
+/**
+ * Copyright 2022 Confluent Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// SizeDistribution returns message value sizes, in bytes
+type SizeDistribution interface {
+	Size(r *rand.Rand) int
+}
+
+// FixedSize is a SizeDistribution always returning the same size
+type FixedSize int
+
+// Size implements SizeDistribution
+func (s FixedSize) Size(r *rand.Rand) int {
+	return int(s)
+}
+
+// UniformSize is a SizeDistribution returning sizes uniformly
+// distributed between Min and Max, inclusive
+type UniformSize struct {
+	Min int
+	Max int
+}
+
+// Size implements SizeDistribution
+func (s UniformSize) Size(r *rand.Rand) int {
+	if s.Max <= s.Min {
+		return s.Min
+	}
+	return s.Min + r.Intn(s.Max-s.Min+1)
+}
+
+// NormalSize is a SizeDistribution returning normally distributed sizes,
+// negative sizes are returned as 0
+type NormalSize struct {
+	Mean   float64
+	StdDev float64
+}
+
+// Size implements SizeDistribution
+func (s NormalSize) Size(r *rand.Rand) int {
+	size := int(r.NormFloat64()*s.StdDev + s.Mean)
+	if size < 0 {
+		return 0
+	}
+	return size
+}
+
+// ConsumerGroup describes a consumer group consuming the scenario topic
+type ConsumerGroup struct {
+	// GroupID is the group.id, a unique group id is generated if empty
+	GroupID string
+	// Consumers is the number of consumers in the group, default 1
+	Consumers int
+}
+
+// Chaos describes the faults injected while a scenario is running
+type Chaos struct {
+	// RestartConsumerInterval, if set, is the interval at which a
+	// randomly chosen consumer is closed and re-created, triggering
+	// a rebalance of its group
+	RestartConsumerInterval time.Duration
+	// RestartProducerInterval, if set, is the interval at which a
+	// randomly chosen producer is flushed, closed and re-created
+	RestartProducerInterval time.Duration
+}
+
+// Scenario describes a load test, see Run()
+type Scenario struct {
+	// Name of the scenario, used in the Report
+	Name string
+	// BootstrapServers is the bootstrap.servers of the cluster (required)
+	BootstrapServers string
+	// Topic to produce to and consume from (required),
+	// the topic must exist
+	Topic string
+	// Duration to produce for (required)
+	Duration time.Duration
+	// DrainTimeout is the maximum time to wait for consumers to consume
+	// all delivered messages once producing stopped, default 30s
+	DrainTimeout time.Duration
+	// Producers is the number of producer instances, default 1
+	Producers int
+	// Rate is the total produce rate in messages per second, shared
+	// among the producers. Zero produces as fast as possible.
+	Rate int
+	// MessageSize is the distribution of message value sizes,
+	// default FixedSize(100). Values are at least 8 bytes, the size
+	// of the embedded send timestamp.
+	MessageSize SizeDistribution
+	// ConsumerGroups consume the produced messages, measuring
+	// end-to-end latency
+	ConsumerGroups []ConsumerGroup
+	// Chaos configures fault injection
+	Chaos Chaos
+	// ProducerConfig holds additional producer configuration properties
+	ProducerConfig kafka.ConfigMap
+	// ConsumerConfig holds additional consumer configuration properties
+	ConsumerConfig kafka.ConfigMap
+}
+
+// withDefaults validates the scenario and returns a copy with
+// defaults applied.
+func (s Scenario) withDefaults() (Scenario, error) {
+	if s.BootstrapServers == "" || s.Topic == "" {
+		return s, fmt.Errorf("scenario %s: BootstrapServers and Topic are required", s.Name)
+	}
+	if s.Duration <= 0 {
+		return s, fmt.Errorf("scenario %s: Duration must be positive", s.Name)
+	}
+	if s.Rate < 0 {
+		return s, fmt.Errorf("scenario %s: Rate must not be negative", s.Name)
+	}
+
+	if s.DrainTimeout == 0 {
+		s.DrainTimeout = 30 * time.Second
+	}
+	if s.Producers <= 0 {
+		s.Producers = 1
+	}
+	if s.MessageSize == nil {
+		s.MessageSize = FixedSize(100)
+	}
+
+	groups := make([]ConsumerGroup, len(s.ConsumerGroups))
+	for i, g := range s.ConsumerGroups {
+		if g.GroupID == "" {
+			g.GroupID = fmt.Sprintf("harness-%s-%d-%d", s.Name, i, time.Now().UnixNano())
+		}
+		if g.Consumers <= 0 {
+			g.Consumers = 1
+		}
+		groups[i] = g
+	}
+	s.ConsumerGroups = groups
+
+	return s, nil
+}